@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/services"
+)
+
+const usage = `Usage: dlq <command> [flags]
+
+Commands:
+  list     List dead-lettered messages (left in the DLQ)
+  inspect  Print the full dead letter for one or more message IDs
+  replay   Publish matching dead letters back to the main queue for reprocessing
+
+Flags:
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command := os.Args[1]
+
+	flags := flag.NewFlagSet("dlq", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flags.PrintDefaults()
+	}
+	queue := flags.String("queue", "", "main queue whose DLQ to read (default: RABBITMQ_USER_MESSAGES_QUEUE)")
+	ids := flags.String("id", "", "comma-separated message IDs to select")
+	userNumber := flags.String("user-number", "", "only select messages from this user number")
+	since := flags.String("since", "", "only select messages enqueued at or after this RFC3339 time")
+	until := flags.String("until", "", "only select messages enqueued at or before this RFC3339 time")
+	limit := flags.Int("limit", 0, "maximum number of messages to select (0 = all)")
+	dryRun := flags.Bool("dry-run", false, "replay: report matching messages without publishing them")
+	_ = flags.Parse(os.Args[2:])
+
+	if command != "list" && command != "inspect" && command != "replay" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	filter, err := buildFilter(*ids, *userNumber, *since, *until)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if command == "inspect" && len(filter.MessageIDs) == 0 {
+		fmt.Fprintln(os.Stderr, "inspect requires -id")
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load configuration")
+	}
+
+	// Logs go to stderr so stdout carries only the JSON output
+	log := logrus.New()
+	log.SetOutput(os.Stderr)
+	log.SetLevel(cfg.GetLogLevel())
+
+	queueName := *queue
+	if queueName == "" {
+		queueName = cfg.RabbitMQ.UserMessagesQueue
+	}
+
+	redisService, err := services.NewRedisService(cfg, log)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to initialize Redis service")
+	}
+	defer func() { _ = redisService.Close() }()
+
+	rabbitMQService, err := services.NewRabbitMQService(cfg, log)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to initialize RabbitMQ service")
+	}
+	defer func() { _ = rabbitMQService.Close() }()
+
+	dlqService := services.NewDLQService(cfg, log, rabbitMQService, redisService)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var letters []services.DeadLetter
+	switch command {
+	case "list", "inspect":
+		letters, err = dlqService.List(ctx, queueName, filter, *limit)
+	case "replay":
+		letters, err = dlqService.Replay(ctx, queueName, filter, *limit, *dryRun)
+	}
+	if err != nil {
+		log.WithError(err).Error("DLQ command failed")
+	}
+
+	if command == "list" {
+		printSummary(letters)
+	} else {
+		printJSON(letters)
+	}
+
+	log.WithFields(logrus.Fields{
+		"command": command,
+		"queue":   services.DeadLetterQueueName(queueName),
+		"count":   len(letters),
+		"dry_run": *dryRun,
+	}).Info("DLQ command completed")
+
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// buildFilter parses the selection flags into a dead-letter filter
+func buildFilter(ids, userNumber, since, until string) (services.DeadLetterFilter, error) {
+	filter := services.DeadLetterFilter{UserNumber: userNumber}
+
+	for _, id := range strings.Split(ids, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			filter.MessageIDs = append(filter.MessageIDs, id)
+		}
+	}
+
+	var err error
+	if since != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return filter, fmt.Errorf("invalid -since: %w", err)
+		}
+	}
+	if until != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return filter, fmt.Errorf("invalid -until: %w", err)
+		}
+	}
+
+	return filter, nil
+}
+
+// printSummary writes one JSON line per dead letter with its identifying fields
+func printSummary(letters []services.DeadLetter) {
+	encoder := json.NewEncoder(os.Stdout)
+	for _, letter := range letters {
+		_ = encoder.Encode(map[string]interface{}{
+			"message_id":   letter.Message.ID,
+			"user_number":  letter.Message.UserNumber,
+			"timestamp":    letter.Message.Timestamp,
+			"reason":       letter.Reason,
+			"decode_error": letter.DecodeError,
+		})
+	}
+}
+
+// printJSON writes the full dead letters as indented JSON
+func printJSON(letters []services.DeadLetter) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(letters)
+}
@@ -28,8 +28,19 @@
 //	@tag.name					Debug
 //	@tag.description			Debug and troubleshooting endpoints
 //
+//	@tag.name					Tasks
+//	@tag.description			Task status and result lookup
+//
+//	@tag.name					Admin
+//	@tag.description			Administrative endpoints (require ADMIN_API_TOKEN)
+//
 //	@securityDefinitions.basic	BasicAuth
 //
+//	@securityDefinitions.apikey	BearerAuth
+//	@in							header
+//	@name						Authorization
+//	@description				Admin token as "Bearer <ADMIN_API_TOKEN>"
+//
 //	@x-extension-openapi		{"example": "value on a json format"}
 package main
 
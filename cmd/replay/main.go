@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+	workerhandlers "github.com/prefeitura-rio/app-eai-agent-gateway/internal/handlers/workers"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/services"
+)
+
+const usage = `Usage: replay -id <message IDs> [flags]
+
+Reprocesses tasks from the raw agent responses stored in Redis (GOOGLE_AGENT_ENGINE_STORE_RAW_RESPONSE),
+re-running only the response transformation, post-processing and formatting with the current
+configuration. The agent is not called; updated results replace the stored ones.
+
+Flags:
+`
+
+func main() {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flags.PrintDefaults()
+	}
+	ids := flags.String("id", "", "comma-separated message IDs to replay")
+	tenantID := flags.String("tenant-id", "", "tenant whose isolated task data holds the tasks (default: shared namespace)")
+	dryRun := flags.Bool("dry-run", false, "print the replayed results without storing them")
+	_ = flags.Parse(os.Args[1:])
+
+	var taskIDs []string
+	for _, id := range strings.Split(*ids, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			taskIDs = append(taskIDs, id)
+		}
+	}
+	if len(taskIDs) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load configuration")
+	}
+
+	// Logs go to stderr so stdout carries only the JSON output
+	log := logrus.New()
+	log.SetOutput(os.Stderr)
+	log.SetLevel(cfg.GetLogLevel())
+
+	redisService, err := services.NewRedisService(cfg, log)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to initialize Redis service")
+	}
+	defer func() { _ = redisService.Close() }()
+
+	if err := workerhandlers.ValidatePostProcessors(cfg); err != nil {
+		log.WithError(err).Fatal("Invalid RESPONSE_POST_PROCESSORS")
+	}
+
+	var redactor services.Redactor
+	if cfg.Security.ToolOutputRedactionEnabled {
+		regexRedactor, err := services.NewRegexRedactor(cfg, log)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to initialize tool output redactor")
+		}
+		redactor = regexRedactor
+	}
+
+	moderator, err := services.NewModerator(cfg, log)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to initialize content moderation")
+	}
+
+	tenantResolver, err := services.NewTenantResolver(cfg, log, redisService)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to initialize tenant routing")
+	}
+
+	// Only what the transform pipeline uses; tool output summaries are skipped since they would call the agent
+	deps := &workerhandlers.MessageHandlerDependencies{
+		Logger:           log,
+		Config:           cfg,
+		RedisService:     redisService,
+		MessageFormatter: services.NewMessageFormatterService(cfg, log),
+		AgentIDResolver:  workerhandlers.DefaultAgentIDResolver,
+		Redactor:         redactor,
+		TenantResolver:   tenantResolver,
+		Moderator:        moderator,
+	}
+	if err := deps.CheckFormatter(); err != nil {
+		log.WithError(err).Fatal("Invalid message formatting setup")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	encoder := json.NewEncoder(os.Stdout)
+	failed := 0
+	for _, taskID := range taskIDs {
+		result, err := workerhandlers.ReplayTask(ctx, taskID, *tenantID, deps, *dryRun)
+		if err != nil {
+			failed++
+			log.WithError(err).WithField("message_id", taskID).Error("Failed to replay task")
+			continue
+		}
+		_ = encoder.Encode(result)
+	}
+
+	log.WithFields(logrus.Fields{
+		"count":   len(taskIDs) - failed,
+		"failed":  failed,
+		"dry_run": *dryRun,
+	}).Info("Replay completed")
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
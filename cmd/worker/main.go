@@ -80,6 +80,10 @@ func main() {
 		log.WithError(err).Fatal("Failed to initialize RabbitMQ service")
 	}
 
+	if otelService != nil {
+		rabbitMQService.SetOTelService(otelService)
+	}
+
 	// Initialize consumer manager for workers
 	consumerManager := services.NewConsumerManager(log)
 
@@ -92,12 +96,23 @@ func main() {
 		log.WithError(err).Fatal("Failed to initialize Google Agent Engine service")
 	}
 
-	// Initialize transcribe service (optional for development)
-	var transcribeService *services.TranscribeService
-	transcribeService, err = services.NewTranscribeService(cfg, log, rateLimiterService)
+	// Initialize the fallback agent deployments if configured (optional)
+	agentFallbacks, err := workerhandlers.NewAgentProviderFallbacks(cfg, log, rateLimiterService, redisService)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to initialize agent provider fallbacks")
+	}
+
+	// Initialize the configured transcription backend (optional for development)
+	transcribeBackend, err := workerhandlers.NewTranscribeBackend(cfg, log, rateLimiterService)
+	if err != nil {
+		log.WithError(err).Warn("Failed to initialize transcription backend, audio transcription will be disabled")
+		transcribeBackend = workerhandlers.NewNoopTranscribeBackend(cfg)
+	}
+
+	// Initialize the re-transcription backend if configured (optional)
+	escalationTranscribeBackend, err := workerhandlers.NewEscalationTranscribeBackend(cfg, log, rateLimiterService)
 	if err != nil {
-		log.WithError(err).Warn("Failed to initialize transcribe service, audio transcription will be disabled")
-		transcribeService = nil
+		log.WithError(err).Warn("Failed to initialize re-transcription backend, unusable transcripts will not be retried")
 	}
 
 	// Initialize message formatter service
@@ -117,8 +132,94 @@ func main() {
 		log.Info("Callback service disabled by configuration")
 	}
 
-	// Create transcribe service adapter (always create adapter, but with potentially nil service)
-	transcribeAdapter := workerhandlers.NewTranscribeServiceAdapter(transcribeService)
+	// Initialize task event notifier if a webhook URL is configured
+	var taskEventNotifier *services.TaskEventNotifier
+	if cfg.Callback.EventWebhookURL != "" {
+		taskEventNotifier = services.NewTaskEventNotifier(log, cfg)
+		log.Info("Task event notifier initialized")
+	}
+
+	// Initialize provider notifications if a webhook URL is configured
+	var providerNotifier workerhandlers.ProviderNotifier
+	if cfg.Callback.ProviderNotifyURL != "" {
+		providerNotifier = services.NewProviderWebhookNotifier(log, cfg)
+		log.Info("Provider notifier initialized")
+	}
+
+	// Initialize outbound delivery if a provider send URL is configured
+	var messageSender services.MessageSender
+	if cfg.Callback.ProviderSendURL != "" {
+		messageSender = services.NewProviderWebhookSender(log, cfg)
+		log.Info("Outbound message sender initialized")
+	}
+
+	// Initialize tool output redaction if enabled
+	var redactor services.Redactor
+	if cfg.Security.ToolOutputRedactionEnabled {
+		regexRedactor, err := services.NewRegexRedactor(cfg, log)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to initialize tool output redactor")
+		}
+		redactor = regexRedactor
+	}
+
+	// Initialize content moderation (no-op unless a moderation pattern is configured)
+	moderator, err := services.NewModerator(cfg, log)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to initialize content moderation")
+	}
+
+	// Optional text extraction for documents the agent can't read itself
+	documentExtractor := services.NewDocumentExtractor(cfg, log)
+
+	// Initialize processed message export (no-op unless a Pub/Sub topic is configured)
+	messageSink, err := services.NewMessageSink(cfg, log, otelService)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to initialize processed message export")
+	}
+
+	// Initialize the audit trail if an output is configured
+	var auditLogger services.AuditLogger
+	var ndjsonAuditLogger *services.NDJSONAuditLogger
+	if cfg.Observability.AuditLogOutput != "" {
+		ndjsonAuditLogger, err = services.NewNDJSONAuditLogger(cfg, log)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to initialize audit logging")
+		}
+		auditLogger = ndjsonAuditLogger
+	}
+
+	// Pause consumption during sustained Redis write failures (disabled unless a threshold is set)
+	redisOutageGuard := services.NewRedisOutageGuard(cfg, log, redisService)
+	if redisOutageGuard != nil {
+		consumerManager.SetGate(redisOutageGuard)
+	}
+
+	// Initialize tool output summarization (disabled unless a size threshold is set)
+	toolOutputSummarizer, err := workerhandlers.NewToolOutputSummarizer(cfg, log, googleAgentService)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to initialize tool output summarization")
+	}
+
+	// Catch typos in the response post-processing chain before consuming anything
+	if err := workerhandlers.ValidatePostProcessors(cfg); err != nil {
+		log.WithError(err).Fatal("Invalid RESPONSE_POST_PROCESSORS")
+	}
+
+	// Park messages scheduled for later in Redis and republish them once due (disabled with a 0 poll interval)
+	messageScheduler := services.NewMessageScheduler(cfg, log, redisService, rabbitMQService)
+	if messageScheduler != nil {
+		messageScheduler.Start()
+	}
+
+	// Route isolated tenants' task data to their own namespace
+	tenantResolver, err := services.NewTenantResolver(cfg, log, redisService)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to initialize tenant routing")
+	}
+
+	// Log 1 in N messages at debug level if configured (optional)
+	logSampler := workerhandlers.NewLogSampler(cfg, log)
 
 	// Create message handler dependencies
 	ctx := context.Background()
@@ -127,7 +228,7 @@ func main() {
 		Config:             cfg,
 		RedisService:       redisService,
 		GoogleAgentService: googleAgentService,
-		TranscribeService:  transcribeAdapter,
+		TranscribeService:  transcribeBackend,
 		MessageFormatter:   messageFormatterService,
 		CallbackService:    callbackService,   // Optional callback service
 		OTelWorkerWrapper:  otelWorkerWrapper, // Optional OTel wrapper
@@ -137,6 +238,43 @@ func main() {
 			}
 			return nil
 		}(), // Optional trace propagator for distributed tracing
+		AgentIDResolver:   workerhandlers.DefaultAgentIDResolver, // Swap for tenant-aware derivation
+		TaskEventNotifier: taskEventNotifier,                     // Optional task event webhook
+		Redactor:          redactor,                              // Optional tool output redaction
+		ProviderNotifier:  providerNotifier,                      // Optional typing indicator
+		MessageCodecs:     services.NewMessageCodecRegistry(),    // Register protobuf or other codecs here
+		MessageSink:       messageSink,                           // Optional analytics export
+		RedisOutageGuard:  redisOutageGuard,                      // Optional pause during Redis outages
+		MessageSender:     messageSender,                         // Optional delivery of replies to users
+
+		ToolOutputSummarizer: toolOutputSummarizer, // Optional summaries of long tool outputs
+		MessageScheduler:     messageScheduler,     // Optional delayed processing of scheduled messages
+		TenantResolver:       tenantResolver,       // Optional per-tenant task data routing
+
+		EscalationTranscribeService: escalationTranscribeBackend, // Optional retry of unusable transcripts
+		AuditLogger:                 auditLogger,                 // Optional audit trail
+		AgentFallbacks:              agentFallbacks,              // Optional fallback agent deployments
+		LogSampler:                  logSampler,                  // Optional verbose logging of sampled messages
+		Moderator:                   moderator,                   // Optional content moderation
+		IntentClassifier:            nil,                         // Plug in a classifier to send AGENT_INTENT_EXAMPLES
+		DocumentExtractor:           documentExtractor,           // Optional text extraction for agents without document support
+	}
+
+	if err := handlerDeps.CheckFormatter(); err != nil {
+		log.WithError(err).Fatal("Invalid message formatting setup")
+	}
+
+	// Optionally keep recently active users' threads warm
+	var threadWarmer *services.ThreadWarmer
+	if cfg.GoogleAgentEngine.ThreadWarmerEnabled {
+		threadWarmer = services.NewThreadWarmer(cfg, log, googleAgentService, redisService)
+		threadWarmer.Start()
+	}
+
+	// Optionally fail tasks left in processing by crashed workers (one replica sweeps at a time)
+	staleTaskJanitor := services.NewStaleTaskJanitor(cfg, log, redisService, tenantResolver, taskEventNotifier)
+	if staleTaskJanitor != nil {
+		staleTaskJanitor.Start()
 	}
 
 	// Create message handler
@@ -151,9 +289,24 @@ func main() {
 		log.WithField("requested", concurrency).Warn("MAX_PARALLEL is very high, consider if this is intentional")
 	}
 
-	log.WithField("concurrency", concurrency).Info("Setting up user message consumer")
-	if err := consumerManager.AddConsumer(ctx, rabbitMQService, cfg.RabbitMQ.UserMessagesQueue, concurrency, userMessageHandler); err != nil {
-		log.WithError(err).Fatal("Failed to add user message consumer")
+	// Consume the user messages queue plus this worker's shards; the concurrency is split across them
+	userMessageQueues, err := cfg.GetConsumedUserMessageQueues()
+	if err != nil {
+		log.WithError(err).Fatal("Invalid WORKER_CONSUMED_SHARDS")
+	}
+	queueConcurrency := concurrency / len(userMessageQueues)
+	if queueConcurrency < 1 {
+		queueConcurrency = 1
+	}
+
+	log.WithFields(logrus.Fields{
+		"concurrency": concurrency,
+		"queues":      userMessageQueues,
+	}).Info("Setting up user message consumers")
+	for _, queueName := range userMessageQueues {
+		if err := consumerManager.AddConsumer(ctx, rabbitMQService, queueName, queueConcurrency, userMessageHandler); err != nil {
+			log.WithError(err).WithField("queue", queueName).Fatal("Failed to add user message consumer")
+		}
 	}
 
 	log.Info("Worker started successfully - consuming messages from RabbitMQ")
@@ -161,7 +314,14 @@ func main() {
 	// Wait for interrupt signal to gracefully shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	exitCode := 0
+	select {
+	case <-quit:
+	case err := <-rabbitMQService.ReconnectFailed():
+		// Exit nonzero so orchestration restarts the worker with a fresh connection
+		log.WithError(err).Error("Lost RabbitMQ connection for good, shutting down worker")
+		exitCode = 1
+	}
 
 	log.Info("Shutting down worker...")
 
@@ -182,15 +342,30 @@ func main() {
 		log.WithError(err).Error("Failed to stop consumers during shutdown")
 	}
 
+	// Stop the thread warmer before its services are closed
+	if threadWarmer != nil {
+		threadWarmer.Stop()
+	}
+	if staleTaskJanitor != nil {
+		staleTaskJanitor.Stop()
+	}
+	if messageScheduler != nil {
+		messageScheduler.Stop()
+	}
+
 	// Close Google Agent Engine service
 	if err := googleAgentService.Close(); err != nil {
 		log.WithError(err).Error("Failed to close Google Agent Engine service during shutdown")
 	}
+	workerhandlers.CloseAgentProviders(agentFallbacks, log)
 
-	// Close transcribe service
-	if transcribeService != nil {
-		if err := transcribeService.Close(); err != nil {
-			log.WithError(err).Error("Failed to close transcribe service during shutdown")
+	// Close transcription backends
+	if err := transcribeBackend.Close(); err != nil {
+		log.WithError(err).Error("Failed to close transcription backend during shutdown")
+	}
+	if escalationTranscribeBackend != nil {
+		if err := escalationTranscribeBackend.Close(); err != nil {
+			log.WithError(err).Error("Failed to close re-transcription backend during shutdown")
 		}
 	}
 
@@ -199,7 +374,17 @@ func main() {
 		log.WithError(err).Error("Failed to close RabbitMQ connection during shutdown")
 	}
 
-	// Close Redis connection
+	// Close the audit log after the last message has been processed
+	if ndjsonAuditLogger != nil {
+		if err := ndjsonAuditLogger.Close(); err != nil {
+			log.WithError(err).Error("Failed to close audit log during shutdown")
+		}
+	}
+
+	// Close Redis connections
+	if tenantResolver != nil {
+		tenantResolver.Close()
+	}
 	if err := redisService.Close(); err != nil {
 		log.WithError(err).Error("Failed to close Redis connection during shutdown")
 	}
@@ -211,4 +396,5 @@ func main() {
 	default:
 		log.Info("Worker shutdown complete")
 	}
+	os.Exit(exitCode)
 }
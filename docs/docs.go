@@ -27,6 +27,127 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/api/v1/admin/users/{user_number}/data": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete the user's thread mapping, recent-task index, latest result, cached audio transcripts, pending coalesced and scheduled messages and the status, result and error keys of their indexed tasks, in the default namespace and every routed tenant's (REDIS_TENANT_ROUTES), including dedicated result stores. Requires the admin bearer token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Purge user data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User number",
+                        "name": "user_number",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User data purged",
+                        "schema": {
+                            "$ref": "#/definitions/models.UserDataPurgeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user number",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/message/cancel": {
+            "post": {
+                "description": "Flag a pending or processing message for cancellation. The worker aborts cooperatively and marks the task as cancelled.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Messages"
+                ],
+                "summary": "Cancel message processing",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Message ID (UUID)",
+                        "name": "message_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant the message was submitted for (selects its task store when routed)",
+                        "name": "tenant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Cancellation requested",
+                        "schema": {
+                            "$ref": "#/definitions/models.CancelTaskResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or message ID format",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Message not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "Task already finished",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
         "/api/v1/message/debug/task-status": {
             "get": {
                 "description": "Get detailed debug information about message processing task status",
@@ -47,6 +168,12 @@ const docTemplate = `{
                         "name": "message_id",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant the message was submitted for (selects its task store when routed)",
+                        "name": "tenant_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -100,11 +227,17 @@ const docTemplate = `{
                         "name": "message_id",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant the message was submitted for (selects its task store when routed)",
+                        "name": "tenant_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Message completed or failed",
+                        "description": "Message completed, degraded or failed",
                         "schema": {
                             "$ref": "#/definitions/models.MessageResponse"
                         }
@@ -187,6 +320,201 @@ const docTemplate = `{
                 }
             }
         },
+        "/api/v1/tasks/events": {
+            "get": {
+                "description": "Upgrade to a WebSocket that receives a JSON models.TaskEvent each time a task finishes (completed, degraded, empty, blocked, failed, cancelled or expired). Subscribe to one task with task_id, in which case the socket closes after its event (sent right away if the task had already finished), or to all of a user's tasks with user_number. Requires REDIS_PUBLISH_TASK_EVENTS.",
+                "tags": [
+                    "Tasks"
+                ],
+                "summary": "Subscribe to task events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task (message) ID to wait for",
+                        "name": "task_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User whose task events to receive",
+                        "name": "user_number",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant the messages were submitted for (selects its task store when routed)",
+                        "name": "tenant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Switching to WebSocket; each frame is a task event",
+                        "schema": {
+                            "$ref": "#/definitions/models.TaskEvent"
+                        }
+                    },
+                    "400": {
+                        "description": "Missing or invalid subscription",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Origin not allowed by the CORS settings",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "503": {
+                        "description": "Task events unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/tasks/{id}": {
+            "get": {
+                "description": "Get the status of a task by ID, with its result once finished or its error if it failed. Tasks still in flight report \"processing\".",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tasks"
+                ],
+                "summary": "Get task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID (message ID, UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant the message was submitted for (selects its task store when routed)",
+                        "name": "tenant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Task finished",
+                        "schema": {
+                            "$ref": "#/definitions/models.TaskStatusResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Task still processing",
+                        "schema": {
+                            "$ref": "#/definitions/models.TaskStatusResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid task ID format",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Task not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/tasks/{id}/retry": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Republish the original message of a failed task and reset its status to pending, so it is processed again without the user resending it. Requires the admin bearer token.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tasks"
+                ],
+                "summary": "Retry a failed task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task ID (message ID, UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tenant the message was submitted for (selects its task store when routed)",
+                        "name": "tenant_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Task requeued",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid task ID format",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Task or its original message not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "Task has not failed",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
         "/health": {
             "get": {
                 "description": "Returns the health status of the service and its dependencies",
@@ -238,6 +566,13 @@ const docTemplate = `{
                             "type": "object",
                             "additionalProperties": true
                         }
+                    },
+                    "503": {
+                        "description": "Service is deadlocked (no liveness response within timeout)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
                     }
                 }
             }
@@ -275,6 +610,60 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "models.CancelTaskResponse": {
+            "type": "object",
+            "properties": {
+                "message_id": {
+                    "type": "string",
+                    "example": "123e4567-e89b-12d3-a456-426614174000"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "cancel_requested"
+                }
+            }
+        },
+        "models.ErrorResult": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Error category (validation, agent_timeout, ..., unknown)",
+                    "type": "string",
+                    "example": "agent_timeout"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "agent call failed: context deadline exceeded"
+                },
+                "retryable": {
+                    "description": "Whether submitting the message again may succeed",
+                    "type": "boolean",
+                    "example": true
+                },
+                "timestamp": {
+                    "description": "When the task failed (RFC3339)",
+                    "type": "string",
+                    "example": "2025-01-01T12:00:03Z"
+                }
+            }
+        },
+        "models.MessageMedia": {
+            "type": "object",
+            "properties": {
+                "caption": {
+                    "type": "string",
+                    "example": "Segue meu áudio"
+                },
+                "mime_type": {
+                    "type": "string",
+                    "example": "audio/ogg"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://whatsapp.dados.rio/media/audio.ogg"
+                }
+            }
+        },
         "models.MessageResponse": {
             "description": "Message processing response",
             "type": "object",
@@ -286,6 +675,14 @@ const docTemplate = `{
                     "type": "string",
                     "example": "Error message if processing failed"
                 },
+                "error_details": {
+                    "description": "Structured form of Error",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ErrorResult"
+                        }
+                    ]
+                },
                 "status": {
                     "type": "string",
                     "example": "completed"
@@ -325,25 +722,117 @@ const docTemplate = `{
                 }
             }
         },
+        "models.TaskEvent": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "message_id": {
+                    "type": "string"
+                },
+                "result_pointer": {
+                    "description": "Polling endpoint for the stored result (completions only)",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
         "models.TaskStatus": {
             "type": "string",
             "enum": [
                 "pending",
                 "processing",
                 "completed",
-                "failed"
+                "failed",
+                "degraded",
+                "cancelled",
+                "expired",
+                "scheduled",
+                "empty",
+                "blocked"
+            ],
+            "x-enum-comments": {
+                "TaskStatusBlocked": "Completed with the moderation safe message because the message or response was blocked",
+                "TaskStatusDegraded": "Completed with a fallback response because the agent backend was unavailable",
+                "TaskStatusEmpty": "Completed with a fallback reply because the agent produced no messages",
+                "TaskStatusExpired": "Skipped because it waited in the queue longer than the maximum message age",
+                "TaskStatusScheduled": "Held back until its scheduled_at time"
+            },
+            "x-enum-descriptions": [
+                "",
+                "",
+                "",
+                "",
+                "Completed with a fallback response because the agent backend was unavailable",
+                "",
+                "Skipped because it waited in the queue longer than the maximum message age",
+                "Held back until its scheduled_at time",
+                "Completed with a fallback reply because the agent produced no messages",
+                "Completed with the moderation safe message because the message or response was blocked"
             ],
             "x-enum-varnames": [
                 "TaskStatusPending",
                 "TaskStatusProcessing",
                 "TaskStatusCompleted",
-                "TaskStatusFailed"
+                "TaskStatusFailed",
+                "TaskStatusDegraded",
+                "TaskStatusCancelled",
+                "TaskStatusExpired",
+                "TaskStatusScheduled",
+                "TaskStatusEmpty",
+                "TaskStatusBlocked"
             ]
         },
+        "models.TaskStatusResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Error message if processing failed"
+                },
+                "error_details": {
+                    "description": "Structured form of Error",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ErrorResult"
+                        }
+                    ]
+                },
+                "result": {
+                    "type": "object"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "completed"
+                },
+                "task_id": {
+                    "type": "string",
+                    "example": "123e4567-e89b-12d3-a456-426614174000"
+                }
+            }
+        },
+        "models.UserDataPurgeResponse": {
+            "type": "object",
+            "properties": {
+                "keys_removed": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "user_number": {
+                    "type": "string",
+                    "example": "5521999999999"
+                }
+            }
+        },
         "models.UserWebhookRequest": {
             "type": "object",
             "required": [
-                "message",
                 "user_number"
             ],
             "properties": {
@@ -351,7 +840,21 @@ const docTemplate = `{
                     "type": "string",
                     "example": "https://example.com/webhook/callback"
                 },
+                "channel": {
+                    "description": "Delivery channel selecting the reply format (whatsapp, web); defaults to whatsapp",
+                    "type": "string",
+                    "example": "whatsapp"
+                },
+                "locale": {
+                    "description": "Language of gateway-generated replies (pt, es, en)",
+                    "type": "string",
+                    "example": "pt-BR"
+                },
+                "media": {
+                    "$ref": "#/definitions/models.MessageMedia"
+                },
                 "message": {
+                    "description": "Optional when media.url is set",
                     "type": "string",
                     "example": "Hello, how can you help me?"
                 },
@@ -359,14 +862,35 @@ const docTemplate = `{
                     "type": "object",
                     "additionalProperties": true
                 },
+                "model": {
+                    "description": "Agent model to answer with, one of AGENT_ALLOWED_MODELS (defaults to AGENT_DEFAULT_MODEL)",
+                    "type": "string",
+                    "example": "gemini-2.5-flash"
+                },
                 "previous_message": {
                     "type": "string",
                     "example": "Previous message context"
                 },
+                "priority": {
+                    "description": "Processed ahead of lower-priority messages, up to RABBITMQ_MAX_PRIORITY (default 0)",
+                    "type": "integer",
+                    "example": 5
+                },
                 "provider": {
+                    "description": "Case-insensitive, with - or _ and PROVIDER_ALIASES accepted",
                     "type": "string",
                     "example": "google_agent_engine"
                 },
+                "scheduled_at": {
+                    "description": "Process no earlier than this time (e.g. reminders)",
+                    "type": "string",
+                    "example": "2026-01-01T09:00:00Z"
+                },
+                "tenant_id": {
+                    "description": "Selects the tenant's agent preamble and, when routed, its task store",
+                    "type": "string",
+                    "example": "smas"
+                },
                 "user_number": {
                     "type": "string",
                     "example": "5521999999999"
@@ -394,6 +918,13 @@ const docTemplate = `{
     "securityDefinitions": {
         "BasicAuth": {
             "type": "basic"
+        },
+        "BearerAuth": {
+            "description": "Admin token as \"Bearer \u003cADMIN_API_TOKEN\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header",
+            "x-extension-openapi": "{\"example\": \"value on a json format\"}"
         }
     },
     "tags": [
@@ -408,11 +939,16 @@ const docTemplate = `{
         {
             "description": "Debug and troubleshooting endpoints",
             "name": "Debug"
+        },
+        {
+            "description": "Task status and result lookup",
+            "name": "Tasks"
+        },
+        {
+            "description": "Administrative endpoints (require ADMIN_API_TOKEN)",
+            "name": "Admin"
         }
-    ],
-    "x-extension-openapi": {
-        "example": "value on a json format"
-    }
+    ]
 }`
 
 // SwaggerInfo holds exported Swagger Info so clients can modify it
@@ -25,7 +25,11 @@ type Server struct {
 	httpServer      *http.Server
 	healthHandler   *handlers.HealthHandler
 	messageHandler  *handlers.MessageHandler
+	adminHandler    *handlers.AdminHandler      // Only set when ADMIN_API_TOKEN is configured
+	taskEvents      *handlers.TaskEventsHandler // Only set when REDIS_PUBLISH_TASK_EVENTS is enabled
+	taskEventHub    *services.TaskEventHub
 	redisService    *services.RedisService
+	tenantResolver  *services.TenantResolver // Only set when REDIS_TENANT_ROUTES is configured
 	rabbitMQService *services.RabbitMQService
 	otelService     *services.OTelService // Optional OTel service
 }
@@ -45,6 +49,12 @@ func NewServer(cfg *config.Config, logger *logrus.Logger, otelService *services.
 		return nil, fmt.Errorf("failed to initialize Redis service: %w", err)
 	}
 
+	// Route isolated tenants' task data to their own namespace
+	tenantResolver, err := services.NewTenantResolver(cfg, logger, redisService)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tenant routing: %w", err)
+	}
+
 	// Initialize RabbitMQ service
 	rabbitMQService, err := services.NewRabbitMQService(cfg, logger)
 	if err != nil {
@@ -56,6 +66,7 @@ func NewServer(cfg *config.Config, logger *logrus.Logger, otelService *services.
 		logger:          logger,
 		router:          gin.New(),
 		redisService:    redisService,
+		tenantResolver:  tenantResolver,
 		rabbitMQService: rabbitMQService,
 		otelService:     otelService,
 		healthHandler: handlers.NewHealthHandler(
@@ -68,7 +79,28 @@ func NewServer(cfg *config.Config, logger *logrus.Logger, otelService *services.
 				return middleware.NewTraceCorrelationPropagator(otelService)
 			}
 			return nil
-		}()),
+		}(), tenantResolver),
+	}
+
+	// The admin API needs the agent service to reset threads
+	if cfg.Security.AdminAPIToken != "" {
+		rateLimiterService := services.NewRateLimiterService(cfg, logger, redisService)
+		agentService, err := services.NewGoogleAgentEngineService(cfg, logger, rateLimiterService, redisService)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Google Agent Engine service for admin API: %w", err)
+		}
+		// Routed tenants keep their task data in their own namespaces, which a purge must cover too
+		var userData handlers.UserDataStore = redisService
+		if tenantResolver != nil {
+			userData = tenantResolver
+		}
+		server.adminHandler = handlers.NewAdminHandler(logger, userData, agentService)
+	}
+
+	// Push task completions over WebSocket when the workers publish them
+	if taskEventHub := services.NewTaskEventHub(cfg, logger, redisService); taskEventHub != nil {
+		server.taskEventHub = taskEventHub
+		server.taskEvents = handlers.NewTaskEventsHandler(logger, cfg, taskEventHub, redisService, tenantResolver)
 	}
 
 	// Add services to health checks
@@ -175,9 +207,28 @@ func (s *Server) setupRoutes() {
 			{
 				message.POST("/webhook/user", s.messageHandler.HandleUserWebhook)
 				message.GET("/response", s.messageHandler.HandleMessageResponse)
+				message.POST("/cancel", s.messageHandler.HandleCancelTask)
 				message.GET("/debug/task-status", s.messageHandler.HandleDebugTaskStatus)
 			}
 
+			// Task endpoints
+			v1.GET("/tasks/:id", s.messageHandler.HandleGetTask)
+			if s.taskEvents != nil {
+				v1.GET("/tasks/events", s.taskEvents.HandleTaskEvents)
+			}
+			if s.adminHandler != nil {
+				// Requeuing is admin-only, so it shares the admin token and is off without one
+				v1.POST("/tasks/:id/retry", middleware.AdminAuth(s.config.Security.AdminAPIToken), s.messageHandler.HandleRetryTask)
+			}
+
+			// Admin endpoints (token-protected, disabled without ADMIN_API_TOKEN)
+			if s.adminHandler != nil {
+				admin := v1.Group("/admin", middleware.AdminAuth(s.config.Security.AdminAPIToken))
+				{
+					admin.DELETE("/users/:user_number/data", s.adminHandler.HandlePurgeUserData)
+				}
+			}
+
 			// Note: Agent management endpoints removed - were Letta-specific
 			// Google Agent Engine handles agent lifecycle automatically
 		}
@@ -236,7 +287,15 @@ func (s *Server) Stop(ctx context.Context) error {
 		}
 	}
 
-	// Close Redis connection
+	// Close Redis connections
+	if s.taskEventHub != nil {
+		if err := s.taskEventHub.Close(); err != nil {
+			s.logger.WithError(err).Error("Failed to close task event subscriptions during shutdown")
+		}
+	}
+	if s.tenantResolver != nil {
+		s.tenantResolver.Close()
+	}
 	if s.redisService != nil {
 		if err := s.redisService.Close(); err != nil {
 			s.logger.WithError(err).Error("Failed to close Redis connection during shutdown")
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAudioFormatsComeFromOneList(t *testing.T) {
+	tests := []struct {
+		name       string
+		transcribe TranscribeConfig
+		want       []string
+	}{
+		{"default", TranscribeConfig{}, []string{"mp3", "wav", "m4a", "aac", "ogg", "oga", "flac", "wma", "opus", "webm", "mp4"}},
+		{"configured", TranscribeConfig{AudioExtensions: " .MP3, ogg ,,"}, []string{"mp3", "ogg"}},
+		{"deprecated fallback", TranscribeConfig{SupportedFormats: "wav,flac"}, []string{"wav", "flac"}},
+		{"configured wins over deprecated", TranscribeConfig{AudioExtensions: "opus", SupportedFormats: "wav"}, []string{"opus"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Transcribe: tt.transcribe}
+
+			if got := cfg.GetTranscribeSupportedFormats(); !slices.Equal(got, tt.want) {
+				t.Errorf("GetTranscribeSupportedFormats() = %v, want %v", got, tt.want)
+			}
+			extensions := cfg.GetAudioExtensions()
+			if len(extensions) != len(tt.want) {
+				t.Fatalf("GetAudioExtensions() = %v, want %d extensions", extensions, len(tt.want))
+			}
+			for i, ext := range extensions {
+				if want := "." + tt.want[i]; ext != want {
+					t.Errorf("GetAudioExtensions()[%d] = %q, want %q", i, ext, want)
+				}
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,6 +17,13 @@ type Config struct {
 	AppPrefix   string `mapstructure:"APP_PREFIX"`
 	MaxParallel int    `mapstructure:"MAX_PARALLEL"`
 
+	// Agent provider used for queued messages that don't specify one
+	DefaultProvider string `mapstructure:"DEFAULT_PROVIDER"`
+
+	// JSON object mapping alternative provider names to canonical ones (e.g. {"gae": "google_agent_engine"}),
+	// matched after NormalizeProvider's case and separator folding
+	ProviderAliases string `mapstructure:"PROVIDER_ALIASES"`
+
 	// HTTP Server
 	Server ServerConfig `mapstructure:",squash"`
 
@@ -66,8 +75,78 @@ type RabbitMQConfig struct {
 	MessageTimeout    time.Duration `mapstructure:"RABBITMQ_MESSAGE_TIMEOUT"`
 	SoftTimeLimit     int           `mapstructure:"CELERY_SOFT_TIME_LIMIT"`
 	HardTimeLimit     int           `mapstructure:"CELERY_TIME_LIMIT"`
+
+	// Per-user debounce window for coalescing rapid messages into one agent call (0 = disabled)
+	CoalesceWindow time.Duration `mapstructure:"MESSAGE_COALESCE_WINDOW"`
+
+	// Maximum simultaneous message handler invocations across all of a worker's consumers; also caps prefetch (0 = unlimited)
+	MaxConcurrency int `mapstructure:"WORKER_MAX_CONCURRENCY"`
+
+	// Unacknowledged deliveries each consumer may hold (RabbitMQ per-consumer prefetch)
+	PrefetchCount int `mapstructure:"RABBITMQ_PREFETCH_COUNT"`
+
+	// When deliveries are acknowledged: "early" (on receipt), "processed" (after the handler returns)
+	// or "durable" (only once the task's result and final status are stored in Redis)
+	AckMode string `mapstructure:"RABBITMQ_ACK_MODE"`
+
+	// Messages enqueued longer ago than this are marked expired and acknowledged without processing (0 = no limit)
+	MaxMessageAge time.Duration `mapstructure:"RABBITMQ_MAX_MESSAGE_AGE"`
+
+	// How often workers republish messages whose scheduled_at has arrived; messages scheduled in the future
+	// are parked in Redis until then (0 = scheduling disabled, such messages are processed on arrival)
+	ScheduledPollInterval time.Duration `mapstructure:"SCHEDULED_MESSAGES_POLL_INTERVAL"`
+
+	// Number of shard queues user messages are spread over; a user's messages always go to the same shard,
+	// picked by consistent hashing of the user number (0 = the single user messages queue)
+	UserMessageShards int `mapstructure:"RABBITMQ_USER_MESSAGE_SHARDS"`
+
+	// Comma-separated shard indexes this worker consumes (empty = all shards)
+	ConsumedShards string `mapstructure:"WORKER_CONSUMED_SHARDS"`
+
+	// Deliver each shard queue to one consumer at a time, so a user's messages are processed strictly in order
+	ShardSingleActiveConsumer bool `mapstructure:"RABBITMQ_SHARD_SINGLE_ACTIVE_CONSUMER"`
+
+	// Highest priority of the user message queues (1-255); messages with a higher "priority" are delivered
+	// before lower ones waiting in the same queue (0 = queues without priorities)
+	MaxPriority int `mapstructure:"RABBITMQ_MAX_PRIORITY"`
+
+	// Reconnection after a lost connection: the delay starts at the initial value and is multiplied after each
+	// failed attempt up to the maximum; once the attempts are exhausted the process exits (0 attempts = unlimited)
+	ReconnectInitialDelay time.Duration `mapstructure:"RABBITMQ_RECONNECT_INITIAL_DELAY"`
+	ReconnectMaxDelay     time.Duration `mapstructure:"RABBITMQ_RECONNECT_MAX_DELAY"`
+	ReconnectMaxAttempts  int           `mapstructure:"RABBITMQ_RECONNECT_MAX_ATTEMPTS"`
+	ReconnectMultiplier   float64       `mapstructure:"RABBITMQ_RECONNECT_MULTIPLIER"`
 }
 
+// DefaultAudioExtensions are the audio formats used when TRANSCRIBE_AUDIO_EXTENSIONS is unset: URLs with
+// these suffixes are detected as audio and files in these formats are accepted for transcription
+const DefaultAudioExtensions = "mp3,wav,m4a,aac,ogg,oga,flac,wma,opus,webm,mp4"
+
+// DefaultDocumentExtensions are the URL suffixes treated as documents when DOCUMENT_EXTENSIONS is unset
+const DefaultDocumentExtensions = "pdf,doc,docx,odt,rtf,txt,csv,xls,xlsx,ods,ppt,pptx,odp"
+
+// Output modes for RESPONSE_OUTPUT_MODE
+const (
+	OutputModePythonCompat = "python_compat"
+	OutputModeMessagesOnly = "messages_only"
+)
+
+// Formatting modes for MESSAGE_FORMATTING_MODE
+const (
+	FormattingModeRequired = "required" // Replies are formatted for their channel by the worker's formatter
+	FormattingModeNone     = "none"     // Replies are delivered as the agent wrote them
+)
+
+// DefaultPostProcessors is the response post-processing chain used when RESPONSE_POST_PROCESSORS is unset
+const DefaultPostProcessors = "cap_messages,redact_tool_output,summarize_tool_output,whatsapp_format,dedup_assistant,split_long_messages"
+
+// Acknowledgement modes for RABBITMQ_ACK_MODE
+const (
+	AckModeEarly     = "early"
+	AckModeProcessed = "processed"
+	AckModeDurable   = "durable"
+)
+
 type RedisConfig struct {
 	DSN             string        `mapstructure:"REDIS_DSN"`
 	Backend         string        `mapstructure:"REDIS_BACKEND"`
@@ -76,6 +155,58 @@ type RedisConfig struct {
 	CacheTTL        time.Duration `mapstructure:"CACHE_TTL_SECONDS"`
 	AgentIDCacheTTL time.Duration `mapstructure:"AGENT_ID_CACHE_TTL"`
 
+	// Per-status TTLs (0 = fall back to REDIS_TASK_STATUS_TTL)
+	TaskProcessingStatusTTL time.Duration `mapstructure:"REDIS_TASK_PROCESSING_STATUS_TTL"` // pending, processing
+	TaskCompletedStatusTTL  time.Duration `mapstructure:"REDIS_TASK_COMPLETED_STATUS_TTL"`  // completed, degraded
+	TaskFailedStatusTTL     time.Duration `mapstructure:"REDIS_TASK_FAILED_STATUS_TTL"`     // failed, cancelled, expired (and stored errors)
+
+	// How often workers poll a task's cancellation flag during the agent call (0 = only check before/after)
+	TaskCancelPollInterval time.Duration `mapstructure:"TASK_CANCEL_POLL_INTERVAL"`
+
+	// Serializes a user's thread lookup and agent call across workers, holding the lock for at most this long.
+	// Should exceed the longest expected agent call (0 = disabled, messages from one user may run in parallel)
+	UserLockTTL time.Duration `mapstructure:"USER_LOCK_TTL"`
+
+	// Key namespacing (prepended to every key as "<prefix>:<key>", empty = no prefix)
+	KeyPrefix string `mapstructure:"REDIS_KEY_PREFIX"`
+
+	// Gzip compression of stored task results at or above the threshold size in bytes
+	ResultCompression          bool `mapstructure:"REDIS_RESULT_COMPRESSION"`
+	ResultCompressionThreshold int  `mapstructure:"REDIS_RESULT_COMPRESSION_THRESHOLD"`
+
+	// Per-user index of recent task IDs: how many are kept (0 = disabled) and how long an idle index lives
+	UserTaskIndexSize int           `mapstructure:"REDIS_USER_TASK_INDEX_SIZE"`
+	UserTaskIndexTTL  time.Duration `mapstructure:"REDIS_USER_TASK_INDEX_TTL"`
+
+	// Also store each completed result as the user's latest ("user:latest_result:<user_number>"), overwriting
+	// the previous one, so it can be read without the task ID
+	StoreLatestResult bool `mapstructure:"REDIS_STORE_LATEST_RESULT"`
+
+	// Publish task completions on Redis pub/sub (workers) and serve them over the /api/v1/tasks/events
+	// WebSocket (gateway)
+	PublishTaskEvents bool `mapstructure:"REDIS_PUBLISH_TASK_EVENTS"`
+
+	// Workers stop consuming after this many consecutive failed result/status writes and resume once Redis
+	// answers a ping, checked every recheck interval (0 = disabled, messages are acknowledged regardless)
+	OutagePauseThreshold  int           `mapstructure:"REDIS_OUTAGE_PAUSE_THRESHOLD"`
+	OutageRecheckInterval time.Duration `mapstructure:"REDIS_OUTAGE_RECHECK_INTERVAL"`
+
+	// Archive tier for task results: every result is also copied to this store, kept for the archive TTL,
+	// and result reads that miss the primary Redis fall back to it
+	ArchiveEnabled   bool          `mapstructure:"REDIS_ARCHIVE_ENABLED"`
+	ArchiveDSN       string        `mapstructure:"REDIS_ARCHIVE_DSN"`
+	ArchiveResultTTL time.Duration `mapstructure:"REDIS_ARCHIVE_RESULT_TTL"`
+
+	// Per-tenant isolation of task data, as a JSON object keyed by tenant ID (see TenantRedisRoute):
+	// {"tenant-a": {"key_prefix": "tenant-a", "result_store_dsn": "redis://..."}}. Empty = all tenants share
+	// REDIS_KEY_PREFIX
+	TenantRoutes string `mapstructure:"REDIS_TENANT_ROUTES"`
+
+	// Tasks still pending/processing after StaleTaskMaxAge are marked failed by a worker janitor sweeping
+	// every StaleTaskSweepInterval (jittered; one replica at a time). 0 = disabled
+	StaleTaskMaxAge        time.Duration `mapstructure:"REDIS_STALE_TASK_MAX_AGE"`
+	StaleTaskSweepInterval time.Duration `mapstructure:"REDIS_STALE_TASK_SWEEP_INTERVAL"`
+
 	// Connection Pool Settings
 	PoolSize              int `mapstructure:"REDIS_POOL_SIZE"`
 	MinIdleConnections    int `mapstructure:"REDIS_MIN_IDLE_CONNECTIONS"`
@@ -113,10 +244,129 @@ type GoogleAgentEngineConfig struct {
 	CredentialsJSON string `mapstructure:"SERVICE_ACCOUNT"`
 
 	// Timeouts and Limits
-	RequestTimeout   time.Duration `mapstructure:"GOOGLE_AGENT_ENGINE_REQUEST_TIMEOUT"`
-	MaxMessageLength int           `mapstructure:"GOOGLE_AGENT_ENGINE_MAX_MESSAGE_LENGTH"`
-	MaxRetries       int           `mapstructure:"GOOGLE_AGENT_ENGINE_MAX_RETRIES"`
-	RetryBackoff     time.Duration `mapstructure:"GOOGLE_AGENT_ENGINE_RETRY_BACKOFF"`
+	RequestTimeout time.Duration `mapstructure:"GOOGLE_AGENT_ENGINE_REQUEST_TIMEOUT"`
+	// Longest user message accepted, in bytes of UTF-8
+	MaxMessageLength int `mapstructure:"GOOGLE_AGENT_ENGINE_MAX_MESSAGE_LENGTH"`
+	// What to do with messages over MaxMessageLength: "reject" (reply with an error) or "truncate"
+	MessageLengthPolicy string        `mapstructure:"GOOGLE_AGENT_ENGINE_MESSAGE_LENGTH_POLICY"`
+	MaxRetries          int           `mapstructure:"GOOGLE_AGENT_ENGINE_MAX_RETRIES"`
+	RetryBackoff        time.Duration `mapstructure:"GOOGLE_AGENT_ENGINE_RETRY_BACKOFF"`
+
+	// Most user/assistant messages returned per response, keeping the latest and dropping tool messages (0 = no cap)
+	MaxResponseMessages int `mapstructure:"GOOGLE_AGENT_ENGINE_MAX_RESPONSE_MESSAGES"`
+
+	// Most tool_call/tool_return messages processed from one response; later ones are dropped and the result
+	// is flagged as truncated (0 = no cap)
+	MaxToolMessages int `mapstructure:"GOOGLE_AGENT_ENGINE_MAX_TOOL_MESSAGES"`
+
+	// Collapse consecutive assistant messages with identical formatted content (any other message in between keeps both)
+	DedupAssistantMessages bool `mapstructure:"GOOGLE_AGENT_ENGINE_DEDUP_ASSISTANT_MESSAGES"`
+
+	// Assistant messages longer than this many characters are split into consecutive messages at paragraph,
+	// line, sentence or word boundaries (0 = never split)
+	MaxResponseMessageLength int `mapstructure:"RESPONSE_MAX_MESSAGE_LENGTH"`
+
+	// Tool outputs of at least this many bytes are replaced in user-facing messages by a one-line summary,
+	// keeping the raw output in "raw_tool_return" (0 = disabled). The summary comes from the agent ("agent",
+	// on a throwaway thread) or from a POST to the summary URL ("http")
+	ToolOutputSummaryThreshold int           `mapstructure:"TOOL_OUTPUT_SUMMARY_THRESHOLD"`
+	ToolOutputSummaryBackend   string        `mapstructure:"TOOL_OUTPUT_SUMMARY_BACKEND"`
+	ToolOutputSummaryURL       string        `mapstructure:"TOOL_OUTPUT_SUMMARY_URL"`
+	ToolOutputSummaryTimeout   time.Duration `mapstructure:"TOOL_OUTPUT_SUMMARY_TIMEOUT"`
+
+	// Comma-separated, ordered post-processors applied to the transformed messages (see
+	// workers.RegisterPostProcessor); leave one out to disable it
+	PostProcessors string `mapstructure:"RESPONSE_POST_PROCESSORS"`
+
+	// Shape of the processed messages: "python_compat" (usage_statistics appended as the last message)
+	// or "messages_only" (usage reported in the top-level "usage" field instead)
+	OutputMode string `mapstructure:"RESPONSE_OUTPUT_MODE"`
+
+	// "required" formats replies for their channel and keeps a worker without a message formatter from starting;
+	// "none" delivers the agent's text unformatted. Content validation and error messages are the same in both
+	FormattingMode string `mapstructure:"MESSAGE_FORMATTING_MODE"`
+
+	// Leave out the usage_statistics entry (or top-level usage) when the agent reported no token counts,
+	// instead of emitting zeros
+	OmitUnknownUsage bool `mapstructure:"RESPONSE_OMIT_UNKNOWN_USAGE"`
+
+	// Echo the inbound message (as received and as sent to the agent) in processed results
+	IncludeInboundMessage bool `mapstructure:"RESPONSE_INCLUDE_INBOUND_MESSAGE"`
+
+	// Keep the raw agent entries that could not be transformed in usage_statistics ("dropped_entries");
+	// their count ("dropped_messages") is always reported
+	IncludeDroppedMessages bool `mapstructure:"RESPONSE_INCLUDE_DROPPED_MESSAGES"`
+
+	// "sender_id" and "name" given to transformed messages, as a JSON object keyed by message_type overriding
+	// DefaultMessageSenders; see MessageSender
+	MessageSenders string `mapstructure:"RESPONSE_MESSAGE_SENDERS"`
+
+	// System framing prepended to the message sent to the agent (never to the stored user message), as a
+	// JSON object keyed by tenant ID, with provider names as fallback keys: {"tenant-a": "...", "google_agent_engine": "..."}
+	Preambles string `mapstructure:"AGENT_PREAMBLES"`
+
+	// Model requested from the agent when a message names none (empty = the agent's own default), and the
+	// comma-separated models a message may request in its "model" field (empty = per-message selection disabled)
+	DefaultModel  string `mapstructure:"AGENT_DEFAULT_MODEL"`
+	AllowedModels string `mapstructure:"AGENT_ALLOWED_MODELS"`
+
+	// JSON list of fallback agent deployments, tried in order when the message's provider fails or its circuit
+	// is open (empty = no fallback); see AgentProviderFallback
+	ProviderFallbacks string `mapstructure:"AGENT_PROVIDER_FALLBACKS"`
+
+	// Few-shot examples sent ahead of the message (never stored as user content) when the intent classifier
+	// detects an intent, as a JSON object of example lists keyed by intent; see IntentExample
+	IntentExamples string `mapstructure:"AGENT_INTENT_EXAMPLES"`
+
+	// Token prices used to estimate each response's cost in usage_statistics ("estimated_cost"), as a JSON
+	// object keyed by model name; see ModelPrice (empty = no estimate)
+	ModelPrices string `mapstructure:"AGENT_MODEL_PRICES"`
+
+	// Per-call budgets for thread lookup/creation and the agent call while processing a message (0 = no limit)
+	ThreadTimeout time.Duration `mapstructure:"GOOGLE_AGENT_ENGINE_THREAD_TIMEOUT"`
+	SendTimeout   time.Duration `mapstructure:"GOOGLE_AGENT_ENGINE_SEND_TIMEOUT"`
+
+	// Conversations unused for this long start over in a fresh thread (0 = threads never expire)
+	ThreadIdleTTL time.Duration `mapstructure:"THREAD_IDLE_TTL"`
+
+	// Background thread warming for users active within the window, up to batch size users per run
+	ThreadWarmerEnabled        bool          `mapstructure:"THREAD_WARMER_ENABLED"`
+	ThreadWarmerInterval       time.Duration `mapstructure:"THREAD_WARMER_INTERVAL"`
+	ThreadWarmerActivityWindow time.Duration `mapstructure:"THREAD_WARMER_ACTIVITY_WINDOW"`
+	ThreadWarmerBatchSize      int           `mapstructure:"THREAD_WARMER_BATCH_SIZE"`
+
+	// Dry run returns a canned agent response instead of calling the agent (for load testing)
+	DryRun bool `mapstructure:"GOOGLE_AGENT_ENGINE_DRY_RUN"`
+
+	// Persist the agent's raw response under task:raw_response:<id> for debugging and replays (cmd/replay)
+	StoreRawResponse bool `mapstructure:"GOOGLE_AGENT_ENGINE_STORE_RAW_RESPONSE"`
+
+	// Circuit Breaker (threshold <= 0 disables it)
+	CircuitFailureThreshold int           `mapstructure:"GOOGLE_AGENT_ENGINE_CIRCUIT_FAILURE_THRESHOLD"`
+	CircuitResetTimeout     time.Duration `mapstructure:"GOOGLE_AGENT_ENGINE_CIRCUIT_RESET_TIMEOUT"`
+
+	// Rate-limited (429) messages are requeued after the Retry-After hint, or the default delay when
+	// absent, capped at the max delay (0 = no cap)
+	RateLimitRetryDelay    time.Duration `mapstructure:"GOOGLE_AGENT_ENGINE_RATE_LIMIT_RETRY_DELAY"`
+	RateLimitMaxRetryDelay time.Duration `mapstructure:"GOOGLE_AGENT_ENGINE_RATE_LIMIT_MAX_RETRY_DELAY"`
+
+	// Image inputs (forwarded as multimodal content only when the agent is vision-capable)
+	SupportsImages          bool   `mapstructure:"GOOGLE_AGENT_ENGINE_SUPPORTS_IMAGES"`
+	ImageUnsupportedMessage string `mapstructure:"IMAGE_UNSUPPORTED_MESSAGE"`
+
+	// Document inputs (PDFs, office files, text): forwarded to document-capable agents, else their text is
+	// extracted by DOCUMENT_EXTRACTOR_URL, else the user is told with DOCUMENT_UNSUPPORTED_MESSAGE
+	SupportsDocuments          bool          `mapstructure:"GOOGLE_AGENT_ENGINE_SUPPORTS_DOCUMENTS"`
+	DocumentExtensions         string        `mapstructure:"DOCUMENT_EXTENSIONS"`
+	DocumentExtractorURL       string        `mapstructure:"DOCUMENT_EXTRACTOR_URL"`
+	DocumentExtractorTimeout   time.Duration `mapstructure:"DOCUMENT_EXTRACTOR_TIMEOUT"`
+	DocumentUnsupportedMessage string        `mapstructure:"DOCUMENT_UNSUPPORTED_MESSAGE"`
+
+	// Reply sent when the agent returns no messages (empty = the localized default)
+	EmptyResponseMessage string `mapstructure:"RESPONSE_EMPTY_MESSAGE"`
+
+	// Language of gateway-generated messages when the user's locale is unknown or unsupported (pt, es, en)
+	DefaultLocale string `mapstructure:"DEFAULT_LOCALE"`
 }
 
 type EAIAgentConfig struct {
@@ -129,16 +379,50 @@ type EAIAgentConfig struct {
 }
 
 type TranscribeConfig struct {
+	// Backend selection ("google" or "noop"); options are comma-separated key=value pairs for the backend
+	Backend        string `mapstructure:"TRANSCRIBE_BACKEND"`
+	BackendOptions string `mapstructure:"TRANSCRIBE_BACKEND_OPTIONS"`
+
 	MaxDuration        int           `mapstructure:"TRANSCRIBE_MAX_DURATION"`
 	MaxDurationMinutes int           `mapstructure:"TRANSCRIBE_MAX_DURATION_MINUTES"`
 	AllowedURLs        string        `mapstructure:"TRANSCRIBE_ALLOWED_URLS"`
 	MaxFileSizeMB      int           `mapstructure:"TRANSCRIBE_MAX_FILE_SIZE_MB"`
-	SupportedFormats   string        `mapstructure:"TRANSCRIBE_SUPPORTED_FORMATS"`
+	SupportedFormats   string        `mapstructure:"TRANSCRIBE_SUPPORTED_FORMATS"` // Deprecated: used as TRANSCRIBE_AUDIO_EXTENSIONS when that is unset
+	AudioExtensions    string        `mapstructure:"TRANSCRIBE_AUDIO_EXTENSIONS"`  // Audio formats, for URL detection and transcription (comma-separated)
 	TempDir            string        `mapstructure:"TRANSCRIBE_TEMP_DIR"`
 	CleanupInterval    time.Duration `mapstructure:"TRANSCRIBE_CLEANUP_INTERVAL"`
 	RequestTimeout     time.Duration `mapstructure:"TRANSCRIBE_REQUEST_TIMEOUT"`
 	DownloadTimeout    time.Duration `mapstructure:"TRANSCRIBE_DOWNLOAD_TIMEOUT"`
 
+	// Budget for transcribing one audio message, download included (0 = no limit)
+	OperationTimeout time.Duration `mapstructure:"TRANSCRIBE_OPERATION_TIMEOUT"`
+
+	// Transient transcription failures (network errors, rate limits, unavailable service) are retried this many
+	// more times, RetryDelay apart and within the operation timeout, before falling back (0 = no retry)
+	RetryAttempts int           `mapstructure:"TRANSCRIBE_RETRY_ATTEMPTS"`
+	RetryDelay    time.Duration `mapstructure:"TRANSCRIBE_RETRY_DELAY"`
+
+	// Keep the transcript and audio URL in stored results for auditing (disable for privacy-sensitive deployments)
+	StoreTranscripts bool `mapstructure:"TRANSCRIBE_STORE_TRANSCRIPTS"`
+
+	// Successful transcripts are cached in Redis by a hash of the audio URL for CacheTTL (0 = no caching);
+	// CacheBypass skips both the lookup and the write
+	CacheTTL    time.Duration `mapstructure:"TRANSCRIBE_CACHE_TTL"`
+	CacheBypass bool          `mapstructure:"TRANSCRIBE_CACHE_BYPASS"`
+
+	// Re-transcription: a transcript that is empty, the empty sentinel, or below EscalationMinConfidence
+	// (0 = confidence is ignored) is retried once on EscalationBackend (empty = no retry) before falling back
+	EscalationBackend        string  `mapstructure:"TRANSCRIBE_ESCALATION_BACKEND"`
+	EscalationBackendOptions string  `mapstructure:"TRANSCRIBE_ESCALATION_BACKEND_OPTIONS"`
+	EscalationMinConfidence  float64 `mapstructure:"TRANSCRIBE_ESCALATION_MIN_CONFIDENCE"`
+
+	// Transcript returned for audio without recognizable speech, handled like an empty transcript
+	EmptySentinel string `mapstructure:"TRANSCRIBE_EMPTY_SENTINEL"`
+
+	// Audio URL host policy: comma-separated hosts (subdomains included); empty allowlist = any public host
+	AllowedHosts string `mapstructure:"TRANSCRIBE_ALLOWED_HOSTS"`
+	DeniedHosts  string `mapstructure:"TRANSCRIBE_DENIED_HOSTS"`
+
 	// Google Cloud Speech configuration
 	LanguageCode          string `mapstructure:"TRANSCRIBE_LANGUAGE_CODE"`
 	SampleRateHertz       int    `mapstructure:"TRANSCRIBE_SAMPLE_RATE_HERTZ"`
@@ -166,6 +450,16 @@ type ObservabilityConfig struct {
 	LogFormat string `mapstructure:"LOG_FORMAT"`
 	LogOutput string `mapstructure:"LOG_OUTPUT"`
 
+	// Log message bodies at debug level, with phone numbers, emails and CPF/CNPJ numbers masked
+	LogMessageBodies bool `mapstructure:"LOG_MESSAGE_BODIES"`
+
+	// Process 1 in N messages with debug logging whatever LOG_LEVEL is (0 = disabled)
+	LogVerboseSampleRate int `mapstructure:"LOG_VERBOSE_SAMPLE_RATE"`
+
+	// Audit trail of processed messages as newline-delimited JSON: "stdout", "stderr" or a file path
+	// (empty = disabled); written apart from the operational logs
+	AuditLogOutput string `mapstructure:"AUDIT_LOG_OUTPUT"`
+
 	// Health Checks
 	HealthCheckTimeout    time.Duration `mapstructure:"HEALTH_CHECK_TIMEOUT"`
 	ReadinessCheckTimeout time.Duration `mapstructure:"READINESS_CHECK_TIMEOUT"`
@@ -186,6 +480,27 @@ type SecurityConfig struct {
 	AllowedDomains      string `mapstructure:"SECURITY_ALLOWED_DOMAINS"`
 	BlockedDomains      string `mapstructure:"SECURITY_BLOCKED_DOMAINS"`
 	StrictMode          bool   `mapstructure:"SECURITY_STRICT_MODE"`
+
+	// Redaction of tool_return content (credentials, SQL, stack traces) before it's formatted for the user;
+	// the pattern is an extra regular expression applied on top of the built-in ones
+	ToolOutputRedactionEnabled     bool   `mapstructure:"TOOL_OUTPUT_REDACTION_ENABLED"`
+	ToolOutputRedactionPattern     string `mapstructure:"TOOL_OUTPUT_REDACTION_PATTERN"`
+	ToolOutputRedactionPlaceholder string `mapstructure:"TOOL_OUTPUT_REDACTION_PLACEHOLDER"`
+
+	// Content moderation: messages matching the input pattern are not sent to the agent and responses
+	// matching the output pattern are withheld; both are answered with the blocked message (empty = the
+	// localized default) and finish with status "blocked". No pattern = no moderation.
+	ModerationInputPattern   string `mapstructure:"MODERATION_INPUT_PATTERN"`
+	ModerationOutputPattern  string `mapstructure:"MODERATION_OUTPUT_PATTERN"`
+	ModerationBlockedMessage string `mapstructure:"MODERATION_BLOCKED_MESSAGE"`
+
+	// Bearer token required by the admin API (empty = admin endpoints disabled)
+	AdminAPIToken string `mapstructure:"ADMIN_API_TOKEN"`
+
+	// HMAC-SHA256 of queue message bodies: publishes are signed whenever the secret is set, and workers
+	// dead-letter unsigned or tampered messages only when verification is required
+	QueueSignatureSecret   string `mapstructure:"QUEUE_SIGNATURE_SECRET"`
+	QueueSignatureRequired bool   `mapstructure:"QUEUE_SIGNATURE_REQUIRED"`
 }
 
 type CallbackConfig struct {
@@ -196,6 +511,25 @@ type CallbackConfig struct {
 	HMACSecret    string `mapstructure:"CALLBACK_HMAC_SECRET"`
 	RequireHTTPS  bool   `mapstructure:"CALLBACK_REQUIRE_HTTPS"`
 	AllowedDomain string `mapstructure:"CALLBACK_ALLOWED_DOMAIN"`
+
+	// Task event webhook (notified on every task completion/failure; empty URL = disabled)
+	EventWebhookURL        string `mapstructure:"TASK_EVENT_WEBHOOK_URL"`
+	EventWebhookTimeout    int    `mapstructure:"TASK_EVENT_WEBHOOK_TIMEOUT"`
+	EventWebhookMaxRetries int    `mapstructure:"TASK_EVENT_WEBHOOK_MAX_RETRIES"`
+
+	// Provider webhook told when processing starts so it can show a typing indicator (empty URL = disabled)
+	ProviderNotifyURL     string        `mapstructure:"PROVIDER_NOTIFY_URL"`
+	ProviderNotifyTimeout time.Duration `mapstructure:"PROVIDER_NOTIFY_TIMEOUT"`
+
+	// Provider endpoint the worker posts replies to after processing, so the gateway delivers them to the
+	// user itself (empty URL = disabled; results are then only stored for polling and callbacks)
+	ProviderSendURL     string        `mapstructure:"PROVIDER_SEND_URL"`
+	ProviderSendTimeout time.Duration `mapstructure:"PROVIDER_SEND_TIMEOUT"`
+
+	// Copy of every processed message published to a Pub/Sub topic for analytics (empty topic = disabled).
+	// The topic is a full "projects/<project>/topics/<topic>" name or a topic ID in PROJECT_ID
+	MessageExportTopic   string        `mapstructure:"MESSAGE_EXPORT_PUBSUB_TOPIC"`
+	MessageExportTimeout time.Duration `mapstructure:"MESSAGE_EXPORT_TIMEOUT"`
 }
 
 // Load loads configuration from environment variables and files
@@ -219,6 +553,39 @@ func Load() (*Config, error) {
 	if err := validateRequired(&config); err != nil {
 		return nil, fmt.Errorf("configuration validation failed: %w", err)
 	}
+	if _, err := config.parseAgentPreambles(); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+	if _, err := config.GetTenantRedisRoutes(); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+	if _, err := config.GetAgentProviderFallbacks(); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+	if _, err := config.GetIntentExamples(); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+	if _, err := config.GetModelPrices(); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+	if _, err := config.GetMessageSenders(); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+	if _, err := config.GetProviderAliases(); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+	if mode := config.GoogleAgentEngine.FormattingMode; mode != FormattingModeRequired && mode != FormattingModeNone {
+		return nil, fmt.Errorf("configuration validation failed: MESSAGE_FORMATTING_MODE must be %q or %q", FormattingModeRequired, FormattingModeNone)
+	}
+	if config.RabbitMQ.MaxPriority < 0 || config.RabbitMQ.MaxPriority > 255 {
+		return nil, fmt.Errorf("configuration validation failed: RABBITMQ_MAX_PRIORITY must be between 0 and 255")
+	}
+	if config.Security.QueueSignatureRequired && config.Security.QueueSignatureSecret == "" {
+		return nil, fmt.Errorf("configuration validation failed: QUEUE_SIGNATURE_REQUIRED needs QUEUE_SIGNATURE_SECRET")
+	}
+	if config.Transcribe.EscalationMinConfidence < 0 || config.Transcribe.EscalationMinConfidence > 1 {
+		return nil, fmt.Errorf("configuration validation failed: TRANSCRIBE_ESCALATION_MIN_CONFIDENCE must be between 0 and 1")
+	}
 
 	// Duration fields are already converted by viper automatically
 
@@ -228,6 +595,8 @@ func Load() (*Config, error) {
 func setDefaults() {
 	// Core Application
 	viper.SetDefault("MAX_PARALLEL", 8)
+	viper.SetDefault("DEFAULT_PROVIDER", "google_agent_engine")
+	viper.SetDefault("PROVIDER_ALIASES", `{"gae": "google_agent_engine"}`)
 
 	// HTTP Server
 	viper.SetDefault("SERVER_PORT", 8000)
@@ -248,12 +617,46 @@ func setDefaults() {
 	viper.SetDefault("RABBITMQ_MESSAGE_TIMEOUT", "2000s") // 33+ minutes to allow Google API calls
 	viper.SetDefault("CELERY_SOFT_TIME_LIMIT", 90)
 	viper.SetDefault("CELERY_TIME_LIMIT", 120)
+	viper.SetDefault("MESSAGE_COALESCE_WINDOW", "0s")
+	viper.SetDefault("WORKER_MAX_CONCURRENCY", 0)
+	viper.SetDefault("RABBITMQ_PREFETCH_COUNT", 1)
+	viper.SetDefault("RABBITMQ_ACK_MODE", AckModeProcessed)
+	viper.SetDefault("RABBITMQ_MAX_MESSAGE_AGE", "0s")
+	viper.SetDefault("SCHEDULED_MESSAGES_POLL_INTERVAL", "1s")
+	viper.SetDefault("RABBITMQ_USER_MESSAGE_SHARDS", 0)
+	viper.SetDefault("WORKER_CONSUMED_SHARDS", "")
+	viper.SetDefault("RABBITMQ_SHARD_SINGLE_ACTIVE_CONSUMER", false)
+	viper.SetDefault("RABBITMQ_MAX_PRIORITY", 0)
+	viper.SetDefault("RABBITMQ_RECONNECT_INITIAL_DELAY", "1s")
+	viper.SetDefault("RABBITMQ_RECONNECT_MAX_DELAY", "30s")
+	viper.SetDefault("RABBITMQ_RECONNECT_MAX_ATTEMPTS", 10)
+	viper.SetDefault("RABBITMQ_RECONNECT_MULTIPLIER", 2.0)
 
 	// Redis
 	viper.SetDefault("REDIS_TASK_RESULT_TTL", "120s")
 	viper.SetDefault("REDIS_TASK_STATUS_TTL", "600s")
 	viper.SetDefault("CACHE_TTL_SECONDS", "720s")
 	viper.SetDefault("AGENT_ID_CACHE_TTL", "86400s")
+	viper.SetDefault("TASK_CANCEL_POLL_INTERVAL", "2s")
+	viper.SetDefault("USER_LOCK_TTL", "0s")
+	viper.SetDefault("REDIS_TASK_PROCESSING_STATUS_TTL", "0s")
+	viper.SetDefault("REDIS_TASK_COMPLETED_STATUS_TTL", "0s")
+	viper.SetDefault("REDIS_TASK_FAILED_STATUS_TTL", "0s")
+	viper.SetDefault("REDIS_KEY_PREFIX", "") // Empty = no prefix (legacy key layout)
+	viper.SetDefault("REDIS_RESULT_COMPRESSION", false)
+	viper.SetDefault("REDIS_RESULT_COMPRESSION_THRESHOLD", 1024)
+	viper.SetDefault("REDIS_USER_TASK_INDEX_SIZE", 50)
+	viper.SetDefault("REDIS_USER_TASK_INDEX_TTL", "168h")
+	viper.SetDefault("REDIS_STORE_LATEST_RESULT", false)
+	viper.SetDefault("REDIS_PUBLISH_TASK_EVENTS", false)
+	viper.SetDefault("REDIS_OUTAGE_PAUSE_THRESHOLD", 0)
+	viper.SetDefault("REDIS_OUTAGE_RECHECK_INTERVAL", "5s")
+	viper.SetDefault("REDIS_ARCHIVE_ENABLED", false)
+	viper.SetDefault("REDIS_ARCHIVE_DSN", "")
+	viper.SetDefault("REDIS_ARCHIVE_RESULT_TTL", "720h")
+	viper.SetDefault("REDIS_TENANT_ROUTES", "")
+	viper.SetDefault("REDIS_STALE_TASK_MAX_AGE", "0s")
+	viper.SetDefault("REDIS_STALE_TASK_SWEEP_INTERVAL", "1m")
 
 	// Redis Connection Pool
 	viper.SetDefault("REDIS_POOL_SIZE", 20)
@@ -275,19 +678,77 @@ func setDefaults() {
 	// Google Agent Engine (very large timeout for complex reasoning tasks - 30 minutes)
 	viper.SetDefault("GOOGLE_AGENT_ENGINE_REQUEST_TIMEOUT", "1800s")
 	viper.SetDefault("GOOGLE_AGENT_ENGINE_MAX_MESSAGE_LENGTH", 32000)
+	viper.SetDefault("GOOGLE_AGENT_ENGINE_MESSAGE_LENGTH_POLICY", "reject")
+	viper.SetDefault("GOOGLE_AGENT_ENGINE_MAX_RESPONSE_MESSAGES", 0)
+	viper.SetDefault("GOOGLE_AGENT_ENGINE_MAX_TOOL_MESSAGES", 0)
+	viper.SetDefault("GOOGLE_AGENT_ENGINE_DEDUP_ASSISTANT_MESSAGES", false)
+	viper.SetDefault("RESPONSE_MAX_MESSAGE_LENGTH", 0) // 0 = never split
+	viper.SetDefault("TOOL_OUTPUT_SUMMARY_THRESHOLD", 0)
+	viper.SetDefault("TOOL_OUTPUT_SUMMARY_BACKEND", "agent")
+	viper.SetDefault("TOOL_OUTPUT_SUMMARY_URL", "")
+	viper.SetDefault("TOOL_OUTPUT_SUMMARY_TIMEOUT", "15s")
+	viper.SetDefault("RESPONSE_POST_PROCESSORS", DefaultPostProcessors)
+	viper.SetDefault("RESPONSE_OUTPUT_MODE", OutputModePythonCompat)
+	viper.SetDefault("MESSAGE_FORMATTING_MODE", FormattingModeRequired)
+	viper.SetDefault("RESPONSE_OMIT_UNKNOWN_USAGE", false)
+	viper.SetDefault("RESPONSE_INCLUDE_INBOUND_MESSAGE", false)
+	viper.SetDefault("RESPONSE_INCLUDE_DROPPED_MESSAGES", false)
+	viper.SetDefault("RESPONSE_MESSAGE_SENDERS", "")
+	viper.SetDefault("AGENT_PREAMBLES", "")
+	viper.SetDefault("AGENT_DEFAULT_MODEL", "")
+	viper.SetDefault("AGENT_ALLOWED_MODELS", "")
+	viper.SetDefault("AGENT_PROVIDER_FALLBACKS", "")
+	viper.SetDefault("AGENT_INTENT_EXAMPLES", "")
+	viper.SetDefault("AGENT_MODEL_PRICES", "")
 	viper.SetDefault("GOOGLE_AGENT_ENGINE_MAX_RETRIES", 3)
+	viper.SetDefault("GOOGLE_AGENT_ENGINE_THREAD_TIMEOUT", "30s")
+	viper.SetDefault("GOOGLE_AGENT_ENGINE_SEND_TIMEOUT", "0s")
 	viper.SetDefault("GOOGLE_AGENT_ENGINE_RETRY_BACKOFF", "1s")
+	viper.SetDefault("THREAD_IDLE_TTL", "0s")
+	viper.SetDefault("THREAD_WARMER_ENABLED", false)
+	viper.SetDefault("THREAD_WARMER_INTERVAL", "5m")
+	viper.SetDefault("THREAD_WARMER_ACTIVITY_WINDOW", "1h")
+	viper.SetDefault("THREAD_WARMER_BATCH_SIZE", 100)
+	viper.SetDefault("GOOGLE_AGENT_ENGINE_DRY_RUN", false)
+	viper.SetDefault("GOOGLE_AGENT_ENGINE_STORE_RAW_RESPONSE", false)
+	viper.SetDefault("GOOGLE_AGENT_ENGINE_CIRCUIT_FAILURE_THRESHOLD", 5)
+	viper.SetDefault("GOOGLE_AGENT_ENGINE_CIRCUIT_RESET_TIMEOUT", "30s")
+	viper.SetDefault("GOOGLE_AGENT_ENGINE_RATE_LIMIT_RETRY_DELAY", "30s")
+	viper.SetDefault("GOOGLE_AGENT_ENGINE_RATE_LIMIT_MAX_RETRY_DELAY", "5m")
+	viper.SetDefault("GOOGLE_AGENT_ENGINE_SUPPORTS_IMAGES", false)
+	viper.SetDefault("DEFAULT_LOCALE", "pt")
+	viper.SetDefault("IMAGE_UNSUPPORTED_MESSAGE", "Desculpe, ainda não consigo analisar imagens. Por favor, descreva sua solicitação em texto.")
+	viper.SetDefault("GOOGLE_AGENT_ENGINE_SUPPORTS_DOCUMENTS", false)
+	viper.SetDefault("DOCUMENT_EXTENSIONS", DefaultDocumentExtensions)
+	viper.SetDefault("DOCUMENT_EXTRACTOR_URL", "")
+	viper.SetDefault("DOCUMENT_EXTRACTOR_TIMEOUT", "30s")
+	viper.SetDefault("DOCUMENT_UNSUPPORTED_MESSAGE", "Desculpe, ainda não consigo ler documentos. Por favor, copie o trecho que importa e envie em texto.")
+	viper.SetDefault("RESPONSE_EMPTY_MESSAGE", "")
 
 	// Audio Transcription
+	viper.SetDefault("TRANSCRIBE_BACKEND", "google")
+	viper.SetDefault("TRANSCRIBE_BACKEND_OPTIONS", "")
 	viper.SetDefault("TRANSCRIBE_MAX_DURATION", 60)
 	viper.SetDefault("TRANSCRIBE_MAX_DURATION_MINUTES", 10)
 	viper.SetDefault("TRANSCRIBE_ALLOWED_URLS", "https://whatsapp.dados.rio/")
 	viper.SetDefault("TRANSCRIBE_MAX_FILE_SIZE_MB", 25)
-	viper.SetDefault("TRANSCRIBE_SUPPORTED_FORMATS", "mp3,wav,ogg,oga,webm,mp4,m4a,flac,opus")
+	viper.SetDefault("TRANSCRIBE_AUDIO_EXTENSIONS", "") // Empty = the deprecated TRANSCRIBE_SUPPORTED_FORMATS or DefaultAudioExtensions
 	viper.SetDefault("TRANSCRIBE_TEMP_DIR", "/tmp")
 	viper.SetDefault("TRANSCRIBE_CLEANUP_INTERVAL", "5m")
 	viper.SetDefault("TRANSCRIBE_REQUEST_TIMEOUT", "60s")
 	viper.SetDefault("TRANSCRIBE_DOWNLOAD_TIMEOUT", "30s")
+	viper.SetDefault("TRANSCRIBE_OPERATION_TIMEOUT", "90s")
+	viper.SetDefault("TRANSCRIBE_RETRY_ATTEMPTS", 1)
+	viper.SetDefault("TRANSCRIBE_RETRY_DELAY", "500ms")
+	viper.SetDefault("TRANSCRIBE_STORE_TRANSCRIPTS", false)
+	viper.SetDefault("TRANSCRIBE_CACHE_TTL", "24h")
+	viper.SetDefault("TRANSCRIBE_CACHE_BYPASS", false)
+	viper.SetDefault("TRANSCRIBE_ESCALATION_BACKEND", "")
+	viper.SetDefault("TRANSCRIBE_ESCALATION_BACKEND_OPTIONS", "")
+	viper.SetDefault("TRANSCRIBE_ESCALATION_MIN_CONFIDENCE", 0.0)
+	viper.SetDefault("TRANSCRIBE_EMPTY_SENTINEL", "Áudio sem conteúdo reconhecível")
+	viper.SetDefault("TRANSCRIBE_ALLOWED_HOSTS", "")
+	viper.SetDefault("TRANSCRIBE_DENIED_HOSTS", "")
 	viper.SetDefault("TRANSCRIBE_LANGUAGE_CODE", "pt-BR")
 	viper.SetDefault("TRANSCRIBE_SAMPLE_RATE_HERTZ", 16000)
 	viper.SetDefault("TRANSCRIBE_ENABLE_WORD_TIME_OFFSETS", false)
@@ -311,6 +772,9 @@ func setDefaults() {
 	viper.SetDefault("LOG_LEVEL", "info")
 	viper.SetDefault("LOG_FORMAT", "json")
 	viper.SetDefault("LOG_OUTPUT", "stdout")
+	viper.SetDefault("LOG_MESSAGE_BODIES", false)
+	viper.SetDefault("LOG_VERBOSE_SAMPLE_RATE", 0) // 0 = disabled
+	viper.SetDefault("AUDIT_LOG_OUTPUT", "")       // Empty = disabled
 	viper.SetDefault("HEALTH_CHECK_TIMEOUT", "10s")
 	viper.SetDefault("READINESS_CHECK_TIMEOUT", "5s")
 
@@ -329,6 +793,15 @@ func setDefaults() {
 	viper.SetDefault("SECURITY_ALLOWED_DOMAINS", "") // Empty = allow all
 	viper.SetDefault("SECURITY_BLOCKED_DOMAINS", "localhost,127.0.0.1,0.0.0.0,192.168.,10.,172.")
 	viper.SetDefault("SECURITY_STRICT_MODE", false)
+	viper.SetDefault("TOOL_OUTPUT_REDACTION_ENABLED", false)
+	viper.SetDefault("TOOL_OUTPUT_REDACTION_PATTERN", "")
+	viper.SetDefault("TOOL_OUTPUT_REDACTION_PLACEHOLDER", "[REDACTED]")
+	viper.SetDefault("MODERATION_INPUT_PATTERN", "")
+	viper.SetDefault("MODERATION_OUTPUT_PATTERN", "")
+	viper.SetDefault("MODERATION_BLOCKED_MESSAGE", "") // Empty = localized default
+	viper.SetDefault("ADMIN_API_TOKEN", "")
+	viper.SetDefault("QUEUE_SIGNATURE_SECRET", "")
+	viper.SetDefault("QUEUE_SIGNATURE_REQUIRED", false)
 
 	// Callback
 	viper.SetDefault("CALLBACK_ENABLED", true)
@@ -338,6 +811,15 @@ func setDefaults() {
 	viper.SetDefault("CALLBACK_HMAC_SECRET", "")
 	viper.SetDefault("CALLBACK_REQUIRE_HTTPS", true)
 	viper.SetDefault("CALLBACK_ALLOWED_DOMAIN", "") // Empty = allow all
+	viper.SetDefault("TASK_EVENT_WEBHOOK_URL", "")  // Empty = disabled
+	viper.SetDefault("TASK_EVENT_WEBHOOK_TIMEOUT", 5)
+	viper.SetDefault("TASK_EVENT_WEBHOOK_MAX_RETRIES", 3)
+	viper.SetDefault("PROVIDER_NOTIFY_URL", "") // Empty = disabled
+	viper.SetDefault("PROVIDER_NOTIFY_TIMEOUT", "2s")
+	viper.SetDefault("PROVIDER_SEND_URL", "") // Empty = disabled
+	viper.SetDefault("PROVIDER_SEND_TIMEOUT", "10s")
+	viper.SetDefault("MESSAGE_EXPORT_PUBSUB_TOPIC", "") // Empty = disabled
+	viper.SetDefault("MESSAGE_EXPORT_TIMEOUT", "5s")
 }
 
 func validateRequired(config *Config) error {
@@ -378,6 +860,8 @@ func bindEnvironmentVariables() {
 	// Core Application
 	_ = viper.BindEnv("APP_PREFIX")
 	_ = viper.BindEnv("MAX_PARALLEL")
+	_ = viper.BindEnv("DEFAULT_PROVIDER")
+	_ = viper.BindEnv("PROVIDER_ALIASES")
 
 	// Server
 	_ = viper.BindEnv("SERVER_PORT")
@@ -399,6 +883,20 @@ func bindEnvironmentVariables() {
 	_ = viper.BindEnv("RABBITMQ_MESSAGE_TIMEOUT")
 	_ = viper.BindEnv("CELERY_SOFT_TIME_LIMIT")
 	_ = viper.BindEnv("CELERY_TIME_LIMIT")
+	_ = viper.BindEnv("MESSAGE_COALESCE_WINDOW")
+	_ = viper.BindEnv("WORKER_MAX_CONCURRENCY")
+	_ = viper.BindEnv("RABBITMQ_PREFETCH_COUNT")
+	_ = viper.BindEnv("RABBITMQ_ACK_MODE")
+	_ = viper.BindEnv("RABBITMQ_MAX_MESSAGE_AGE")
+	_ = viper.BindEnv("SCHEDULED_MESSAGES_POLL_INTERVAL")
+	_ = viper.BindEnv("RABBITMQ_USER_MESSAGE_SHARDS")
+	_ = viper.BindEnv("WORKER_CONSUMED_SHARDS")
+	_ = viper.BindEnv("RABBITMQ_SHARD_SINGLE_ACTIVE_CONSUMER")
+	_ = viper.BindEnv("RABBITMQ_MAX_PRIORITY")
+	_ = viper.BindEnv("RABBITMQ_RECONNECT_INITIAL_DELAY")
+	_ = viper.BindEnv("RABBITMQ_RECONNECT_MAX_DELAY")
+	_ = viper.BindEnv("RABBITMQ_RECONNECT_MAX_ATTEMPTS")
+	_ = viper.BindEnv("RABBITMQ_RECONNECT_MULTIPLIER")
 
 	// Redis
 	_ = viper.BindEnv("REDIS_DSN")
@@ -407,6 +905,26 @@ func bindEnvironmentVariables() {
 	_ = viper.BindEnv("REDIS_TASK_STATUS_TTL")
 	_ = viper.BindEnv("CACHE_TTL_SECONDS")
 	_ = viper.BindEnv("AGENT_ID_CACHE_TTL")
+	_ = viper.BindEnv("TASK_CANCEL_POLL_INTERVAL")
+	_ = viper.BindEnv("USER_LOCK_TTL")
+	_ = viper.BindEnv("REDIS_TASK_PROCESSING_STATUS_TTL")
+	_ = viper.BindEnv("REDIS_TASK_COMPLETED_STATUS_TTL")
+	_ = viper.BindEnv("REDIS_TASK_FAILED_STATUS_TTL")
+	_ = viper.BindEnv("REDIS_KEY_PREFIX")
+	_ = viper.BindEnv("REDIS_RESULT_COMPRESSION")
+	_ = viper.BindEnv("REDIS_RESULT_COMPRESSION_THRESHOLD")
+	_ = viper.BindEnv("REDIS_USER_TASK_INDEX_SIZE")
+	_ = viper.BindEnv("REDIS_USER_TASK_INDEX_TTL")
+	_ = viper.BindEnv("REDIS_STORE_LATEST_RESULT")
+	_ = viper.BindEnv("REDIS_PUBLISH_TASK_EVENTS")
+	_ = viper.BindEnv("REDIS_OUTAGE_PAUSE_THRESHOLD")
+	_ = viper.BindEnv("REDIS_OUTAGE_RECHECK_INTERVAL")
+	_ = viper.BindEnv("REDIS_ARCHIVE_ENABLED")
+	_ = viper.BindEnv("REDIS_ARCHIVE_DSN")
+	_ = viper.BindEnv("REDIS_ARCHIVE_RESULT_TTL")
+	_ = viper.BindEnv("REDIS_TENANT_ROUTES")
+	_ = viper.BindEnv("REDIS_STALE_TASK_MAX_AGE")
+	_ = viper.BindEnv("REDIS_STALE_TASK_SWEEP_INTERVAL")
 	_ = viper.BindEnv("REDIS_POOL_SIZE")
 	_ = viper.BindEnv("REDIS_MIN_IDLE_CONNECTIONS")
 	_ = viper.BindEnv("REDIS_MAX_IDLE_CONNECTIONS")
@@ -433,9 +951,53 @@ func bindEnvironmentVariables() {
 	// PROJECT_ID, LOCATION, REASONING_ENGINE_ID, SERVICE_ACCOUNT already bound above
 	_ = viper.BindEnv("GOOGLE_AGENT_ENGINE_CREDENTIALS_PATH")
 	_ = viper.BindEnv("GOOGLE_AGENT_ENGINE_REQUEST_TIMEOUT")
+	_ = viper.BindEnv("GOOGLE_AGENT_ENGINE_THREAD_TIMEOUT")
+	_ = viper.BindEnv("GOOGLE_AGENT_ENGINE_SEND_TIMEOUT")
 	_ = viper.BindEnv("GOOGLE_AGENT_ENGINE_MAX_MESSAGE_LENGTH")
+	_ = viper.BindEnv("GOOGLE_AGENT_ENGINE_MESSAGE_LENGTH_POLICY")
+	_ = viper.BindEnv("GOOGLE_AGENT_ENGINE_MAX_RESPONSE_MESSAGES")
+	_ = viper.BindEnv("GOOGLE_AGENT_ENGINE_MAX_TOOL_MESSAGES")
+	_ = viper.BindEnv("GOOGLE_AGENT_ENGINE_DEDUP_ASSISTANT_MESSAGES")
+	_ = viper.BindEnv("RESPONSE_MAX_MESSAGE_LENGTH")
+	_ = viper.BindEnv("TOOL_OUTPUT_SUMMARY_THRESHOLD")
+	_ = viper.BindEnv("TOOL_OUTPUT_SUMMARY_BACKEND")
+	_ = viper.BindEnv("TOOL_OUTPUT_SUMMARY_URL")
+	_ = viper.BindEnv("TOOL_OUTPUT_SUMMARY_TIMEOUT")
+	_ = viper.BindEnv("RESPONSE_POST_PROCESSORS")
+	_ = viper.BindEnv("RESPONSE_OUTPUT_MODE")
+	_ = viper.BindEnv("MESSAGE_FORMATTING_MODE")
+	_ = viper.BindEnv("RESPONSE_OMIT_UNKNOWN_USAGE")
+	_ = viper.BindEnv("RESPONSE_INCLUDE_INBOUND_MESSAGE")
+	_ = viper.BindEnv("RESPONSE_INCLUDE_DROPPED_MESSAGES")
+	_ = viper.BindEnv("RESPONSE_MESSAGE_SENDERS")
+	_ = viper.BindEnv("AGENT_PREAMBLES")
+	_ = viper.BindEnv("AGENT_DEFAULT_MODEL")
+	_ = viper.BindEnv("AGENT_ALLOWED_MODELS")
+	_ = viper.BindEnv("AGENT_PROVIDER_FALLBACKS")
+	_ = viper.BindEnv("AGENT_INTENT_EXAMPLES")
+	_ = viper.BindEnv("AGENT_MODEL_PRICES")
 	_ = viper.BindEnv("GOOGLE_AGENT_ENGINE_MAX_RETRIES")
 	_ = viper.BindEnv("GOOGLE_AGENT_ENGINE_RETRY_BACKOFF")
+	_ = viper.BindEnv("THREAD_IDLE_TTL")
+	_ = viper.BindEnv("THREAD_WARMER_ENABLED")
+	_ = viper.BindEnv("THREAD_WARMER_INTERVAL")
+	_ = viper.BindEnv("THREAD_WARMER_ACTIVITY_WINDOW")
+	_ = viper.BindEnv("THREAD_WARMER_BATCH_SIZE")
+	_ = viper.BindEnv("GOOGLE_AGENT_ENGINE_DRY_RUN")
+	_ = viper.BindEnv("GOOGLE_AGENT_ENGINE_STORE_RAW_RESPONSE")
+	_ = viper.BindEnv("GOOGLE_AGENT_ENGINE_CIRCUIT_FAILURE_THRESHOLD")
+	_ = viper.BindEnv("GOOGLE_AGENT_ENGINE_CIRCUIT_RESET_TIMEOUT")
+	_ = viper.BindEnv("GOOGLE_AGENT_ENGINE_RATE_LIMIT_RETRY_DELAY")
+	_ = viper.BindEnv("GOOGLE_AGENT_ENGINE_RATE_LIMIT_MAX_RETRY_DELAY")
+	_ = viper.BindEnv("GOOGLE_AGENT_ENGINE_SUPPORTS_IMAGES")
+	_ = viper.BindEnv("DEFAULT_LOCALE")
+	_ = viper.BindEnv("IMAGE_UNSUPPORTED_MESSAGE")
+	_ = viper.BindEnv("GOOGLE_AGENT_ENGINE_SUPPORTS_DOCUMENTS")
+	_ = viper.BindEnv("DOCUMENT_EXTENSIONS")
+	_ = viper.BindEnv("DOCUMENT_EXTRACTOR_URL")
+	_ = viper.BindEnv("DOCUMENT_EXTRACTOR_TIMEOUT")
+	_ = viper.BindEnv("DOCUMENT_UNSUPPORTED_MESSAGE")
+	_ = viper.BindEnv("RESPONSE_EMPTY_MESSAGE")
 
 	// EAI Agent
 	_ = viper.BindEnv("EAI_AGENT_URL")
@@ -446,15 +1008,30 @@ func bindEnvironmentVariables() {
 	_ = viper.BindEnv("EMBEDDING_MODEL")
 
 	// Transcribe
+	_ = viper.BindEnv("TRANSCRIBE_BACKEND")
+	_ = viper.BindEnv("TRANSCRIBE_BACKEND_OPTIONS")
 	_ = viper.BindEnv("TRANSCRIBE_MAX_DURATION")
 	_ = viper.BindEnv("TRANSCRIBE_MAX_DURATION_MINUTES")
 	_ = viper.BindEnv("TRANSCRIBE_ALLOWED_URLS")
 	_ = viper.BindEnv("TRANSCRIBE_MAX_FILE_SIZE_MB")
 	_ = viper.BindEnv("TRANSCRIBE_SUPPORTED_FORMATS")
+	_ = viper.BindEnv("TRANSCRIBE_AUDIO_EXTENSIONS")
 	_ = viper.BindEnv("TRANSCRIBE_TEMP_DIR")
 	_ = viper.BindEnv("TRANSCRIBE_CLEANUP_INTERVAL")
 	_ = viper.BindEnv("TRANSCRIBE_REQUEST_TIMEOUT")
 	_ = viper.BindEnv("TRANSCRIBE_DOWNLOAD_TIMEOUT")
+	_ = viper.BindEnv("TRANSCRIBE_OPERATION_TIMEOUT")
+	_ = viper.BindEnv("TRANSCRIBE_RETRY_ATTEMPTS")
+	_ = viper.BindEnv("TRANSCRIBE_RETRY_DELAY")
+	_ = viper.BindEnv("TRANSCRIBE_STORE_TRANSCRIPTS")
+	_ = viper.BindEnv("TRANSCRIBE_CACHE_TTL")
+	_ = viper.BindEnv("TRANSCRIBE_CACHE_BYPASS")
+	_ = viper.BindEnv("TRANSCRIBE_ESCALATION_BACKEND")
+	_ = viper.BindEnv("TRANSCRIBE_ESCALATION_BACKEND_OPTIONS")
+	_ = viper.BindEnv("TRANSCRIBE_ESCALATION_MIN_CONFIDENCE")
+	_ = viper.BindEnv("TRANSCRIBE_EMPTY_SENTINEL")
+	_ = viper.BindEnv("TRANSCRIBE_ALLOWED_HOSTS")
+	_ = viper.BindEnv("TRANSCRIBE_DENIED_HOSTS")
 	_ = viper.BindEnv("TRANSCRIBE_LANGUAGE_CODE")
 	_ = viper.BindEnv("TRANSCRIBE_SAMPLE_RATE_HERTZ")
 	_ = viper.BindEnv("TRANSCRIBE_ENABLE_WORD_TIME_OFFSETS")
@@ -474,6 +1051,9 @@ func bindEnvironmentVariables() {
 	_ = viper.BindEnv("LOG_LEVEL")
 	_ = viper.BindEnv("LOG_FORMAT")
 	_ = viper.BindEnv("LOG_OUTPUT")
+	_ = viper.BindEnv("LOG_MESSAGE_BODIES")
+	_ = viper.BindEnv("LOG_VERBOSE_SAMPLE_RATE")
+	_ = viper.BindEnv("AUDIT_LOG_OUTPUT")
 	_ = viper.BindEnv("HEALTH_CHECK_TIMEOUT")
 	_ = viper.BindEnv("READINESS_CHECK_TIMEOUT")
 
@@ -488,6 +1068,15 @@ func bindEnvironmentVariables() {
 	_ = viper.BindEnv("MAX_AGENT_ID_LENGTH")
 	_ = viper.BindEnv("ENABLE_CONTENT_FILTER")
 	_ = viper.BindEnv("SECURITY_STRICT_MODE")
+	_ = viper.BindEnv("TOOL_OUTPUT_REDACTION_ENABLED")
+	_ = viper.BindEnv("TOOL_OUTPUT_REDACTION_PATTERN")
+	_ = viper.BindEnv("TOOL_OUTPUT_REDACTION_PLACEHOLDER")
+	_ = viper.BindEnv("MODERATION_INPUT_PATTERN")
+	_ = viper.BindEnv("MODERATION_OUTPUT_PATTERN")
+	_ = viper.BindEnv("MODERATION_BLOCKED_MESSAGE")
+	_ = viper.BindEnv("ADMIN_API_TOKEN")
+	_ = viper.BindEnv("QUEUE_SIGNATURE_SECRET")
+	_ = viper.BindEnv("QUEUE_SIGNATURE_REQUIRED")
 
 	// Callback
 	_ = viper.BindEnv("CALLBACK_ENABLED")
@@ -497,6 +1086,15 @@ func bindEnvironmentVariables() {
 	_ = viper.BindEnv("CALLBACK_HMAC_SECRET")
 	_ = viper.BindEnv("CALLBACK_REQUIRE_HTTPS")
 	_ = viper.BindEnv("CALLBACK_ALLOWED_DOMAIN")
+	_ = viper.BindEnv("TASK_EVENT_WEBHOOK_URL")
+	_ = viper.BindEnv("TASK_EVENT_WEBHOOK_TIMEOUT")
+	_ = viper.BindEnv("TASK_EVENT_WEBHOOK_MAX_RETRIES")
+	_ = viper.BindEnv("PROVIDER_NOTIFY_URL")
+	_ = viper.BindEnv("PROVIDER_NOTIFY_TIMEOUT")
+	_ = viper.BindEnv("PROVIDER_SEND_URL")
+	_ = viper.BindEnv("PROVIDER_SEND_TIMEOUT")
+	_ = viper.BindEnv("MESSAGE_EXPORT_PUBSUB_TOPIC")
+	_ = viper.BindEnv("MESSAGE_EXPORT_TIMEOUT")
 }
 
 // GetLogLevel returns the logrus log level from config
@@ -522,9 +1120,323 @@ func (c *Config) GetTranscribeAllowedDomains() []string {
 	return strings.Split(c.Transcribe.AllowedURLs, ",")
 }
 
-// GetTranscribeSupportedFormats returns transcribe supported formats as a slice
+// GetTranscribeSupportedFormats returns the audio formats accepted for transcription, without dots. They are
+// the formats GetAudioExtensions detects in URLs, so every detected audio URL can be transcribed.
 func (c *Config) GetTranscribeSupportedFormats() []string {
-	return strings.Split(c.Transcribe.SupportedFormats, ",")
+	extensions := c.GetAudioExtensions()
+	formats := make([]string, len(extensions))
+	for i, ext := range extensions {
+		formats[i] = strings.TrimPrefix(ext, ".")
+	}
+	return formats
+}
+
+// GetTranscribeAllowedHosts returns the audio URL host allowlist, lowercased (empty = any public host)
+func (c *Config) GetTranscribeAllowedHosts() []string {
+	return parseHostList(c.Transcribe.AllowedHosts)
+}
+
+// GetTranscribeDeniedHosts returns the audio URL host denylist, lowercased
+func (c *Config) GetTranscribeDeniedHosts() []string {
+	return parseHostList(c.Transcribe.DeniedHosts)
+}
+
+// parseHostList splits a comma-separated host list, dropping blanks
+func parseHostList(list string) []string {
+	var hosts []string
+	for _, host := range strings.Split(list, ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			hosts = append(hosts, strings.TrimSuffix(host, "."))
+		}
+	}
+	return hosts
+}
+
+// GetTranscribeBackendOptions parses the transcription backend options ("key=value,key=value") into a map
+func (c *Config) GetTranscribeBackendOptions() map[string]string {
+	return parseBackendOptions(c.Transcribe.BackendOptions)
+}
+
+// GetTranscribeEscalationBackendOptions parses the re-transcription backend options, in the same format
+func (c *Config) GetTranscribeEscalationBackendOptions() map[string]string {
+	return parseBackendOptions(c.Transcribe.EscalationBackendOptions)
+}
+
+// parseBackendOptions parses comma-separated key=value pairs, skipping pairs without a key
+func parseBackendOptions(raw string) map[string]string {
+	options := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		key, value, found := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			continue
+		}
+		options[key] = strings.TrimSpace(value)
+	}
+	return options
+}
+
+// GetAgentPreamble returns the preamble configured for the tenant, falling back to the provider's (empty if none)
+func (c *Config) GetAgentPreamble(tenantID, provider string) string {
+	preambles, err := c.parseAgentPreambles()
+	if err != nil {
+		return ""
+	}
+	if preamble, ok := preambles[tenantID]; ok && tenantID != "" {
+		return preamble
+	}
+	return preambles[provider]
+}
+
+// parseAgentPreambles decodes AGENT_PREAMBLES into a map keyed by tenant ID or provider
+func (c *Config) parseAgentPreambles() (map[string]string, error) {
+	preambles := make(map[string]string)
+	if strings.TrimSpace(c.GoogleAgentEngine.Preambles) == "" {
+		return preambles, nil
+	}
+	if err := json.Unmarshal([]byte(c.GoogleAgentEngine.Preambles), &preambles); err != nil {
+		return nil, fmt.Errorf("AGENT_PREAMBLES must be a JSON object of strings: %w", err)
+	}
+	return preambles, nil
+}
+
+// TenantRedisRoute is where a tenant's task data is kept: every key is namespaced with KeyPrefix instead of
+// REDIS_KEY_PREFIX, and task results go to the Redis at ResultStoreDSN when set
+type TenantRedisRoute struct {
+	KeyPrefix      string `json:"key_prefix"`
+	ResultStoreDSN string `json:"result_store_dsn,omitempty"`
+}
+
+// GetTenantRedisRoutes decodes REDIS_TENANT_ROUTES into a map keyed by tenant ID. Every route needs its own
+// key prefix, distinct from REDIS_KEY_PREFIX and from the other tenants', or the data would not be isolated.
+func (c *Config) GetTenantRedisRoutes() (map[string]TenantRedisRoute, error) {
+	routes := make(map[string]TenantRedisRoute)
+	if strings.TrimSpace(c.Redis.TenantRoutes) == "" {
+		return routes, nil
+	}
+	if err := json.Unmarshal([]byte(c.Redis.TenantRoutes), &routes); err != nil {
+		return nil, fmt.Errorf("REDIS_TENANT_ROUTES must be a JSON object of tenant routes: %w", err)
+	}
+
+	prefixes := map[string]string{c.Redis.KeyPrefix: ""}
+	for tenantID, route := range routes {
+		if tenantID == "" || route.KeyPrefix == "" {
+			return nil, fmt.Errorf("REDIS_TENANT_ROUTES: tenant %q needs a non-empty tenant ID and key_prefix", tenantID)
+		}
+		if owner, taken := prefixes[route.KeyPrefix]; taken {
+			if owner == "" {
+				return nil, fmt.Errorf("REDIS_TENANT_ROUTES: tenant %q key_prefix %q is REDIS_KEY_PREFIX", tenantID, route.KeyPrefix)
+			}
+			return nil, fmt.Errorf("REDIS_TENANT_ROUTES: tenants %q and %q share key_prefix %q", owner, tenantID, route.KeyPrefix)
+		}
+		prefixes[route.KeyPrefix] = tenantID
+	}
+	return routes, nil
+}
+
+// AgentProviderFallback is a secondary Google Agent Engine deployment serving messages when the primary
+// fails. Name identifies it in results and logs; an empty ProjectID or Location is taken from the primary.
+type AgentProviderFallback struct {
+	Name              string `json:"name"`
+	ProjectID         string `json:"project_id,omitempty"`
+	Location          string `json:"location,omitempty"`
+	ReasoningEngineID string `json:"reasoning_engine_id"`
+}
+
+// GetAgentProviderFallbacks decodes AGENT_PROVIDER_FALLBACKS in the order they are tried. Every fallback needs
+// a reasoning engine ID and a unique name other than the primary provider's.
+func (c *Config) GetAgentProviderFallbacks() ([]AgentProviderFallback, error) {
+	var fallbacks []AgentProviderFallback
+	if strings.TrimSpace(c.GoogleAgentEngine.ProviderFallbacks) == "" {
+		return nil, nil
+	}
+	if err := json.Unmarshal([]byte(c.GoogleAgentEngine.ProviderFallbacks), &fallbacks); err != nil {
+		return nil, fmt.Errorf("AGENT_PROVIDER_FALLBACKS must be a JSON list of agent deployments: %w", err)
+	}
+
+	names := map[string]bool{"google_agent_engine": true}
+	for i, fallback := range fallbacks {
+		if fallback.Name == "" || fallback.ReasoningEngineID == "" {
+			return nil, fmt.Errorf("AGENT_PROVIDER_FALLBACKS: entry %d needs a name and reasoning_engine_id", i)
+		}
+		if names[fallback.Name] {
+			return nil, fmt.Errorf("AGENT_PROVIDER_FALLBACKS: name %q is already taken", fallback.Name)
+		}
+		names[fallback.Name] = true
+	}
+	return fallbacks, nil
+}
+
+// IntentExample is a sample exchange showing the agent how to answer messages of an intent
+type IntentExample struct {
+	User      string `json:"user"`
+	Assistant string `json:"assistant"`
+}
+
+// GetIntentExamples decodes AGENT_INTENT_EXAMPLES into example lists keyed by intent. Every example needs both
+// the user's message and the assistant's answer.
+func (c *Config) GetIntentExamples() (map[string][]IntentExample, error) {
+	examples := make(map[string][]IntentExample)
+	if strings.TrimSpace(c.GoogleAgentEngine.IntentExamples) == "" {
+		return examples, nil
+	}
+	if err := json.Unmarshal([]byte(c.GoogleAgentEngine.IntentExamples), &examples); err != nil {
+		return nil, fmt.Errorf("AGENT_INTENT_EXAMPLES must be a JSON object of example lists: %w", err)
+	}
+
+	for intent, list := range examples {
+		for i, example := range list {
+			if strings.TrimSpace(example.User) == "" || strings.TrimSpace(example.Assistant) == "" {
+				return nil, fmt.Errorf("AGENT_INTENT_EXAMPLES: example %d of intent %q needs user and assistant text", i, intent)
+			}
+		}
+	}
+	return examples, nil
+}
+
+// ModelPrice is what a model charges per million prompt (input) and completion (output) tokens
+type ModelPrice struct {
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+}
+
+// GetModelPrices decodes AGENT_MODEL_PRICES into prices keyed by model name. Prices can't be negative.
+func (c *Config) GetModelPrices() (map[string]ModelPrice, error) {
+	prices := make(map[string]ModelPrice)
+	if strings.TrimSpace(c.GoogleAgentEngine.ModelPrices) == "" {
+		return prices, nil
+	}
+	if err := json.Unmarshal([]byte(c.GoogleAgentEngine.ModelPrices), &prices); err != nil {
+		return nil, fmt.Errorf("AGENT_MODEL_PRICES must be a JSON object of prices keyed by model: %w", err)
+	}
+
+	for model, price := range prices {
+		if price.InputPerMillion < 0 || price.OutputPerMillion < 0 {
+			return nil, fmt.Errorf("AGENT_MODEL_PRICES: prices of model %q can't be negative", model)
+		}
+	}
+	return prices, nil
+}
+
+// MessageSender sets the "sender_id" and "name" of a message type. Both are templates in which {agent_id},
+// {user_number}, {tool_name} and {name} (the name the agent gave the message) are replaced; an empty template
+// leaves the field as transformed.
+type MessageSender struct {
+	SenderID string `json:"sender_id"`
+	Name     string `json:"name"`
+}
+
+// DefaultMessageSenders attributes assistant and tool call messages to the agent, user messages to the user
+// and tool returns to their tool
+var DefaultMessageSenders = map[string]MessageSender{
+	"user_message":        {SenderID: "{user_number}", Name: "user"},
+	"assistant_message":   {SenderID: "{agent_id}", Name: "assistant"},
+	"tool_call_message":   {SenderID: "{agent_id}", Name: "{tool_name}"},
+	"tool_return_message": {SenderID: "{tool_name}", Name: "{tool_name}"},
+}
+
+// GetMessageSenders returns DefaultMessageSenders with the message types in RESPONSE_MESSAGE_SENDERS replaced
+func (c *Config) GetMessageSenders() (map[string]MessageSender, error) {
+	senders := make(map[string]MessageSender, len(DefaultMessageSenders))
+	for messageType, sender := range DefaultMessageSenders {
+		senders[messageType] = sender
+	}
+	if strings.TrimSpace(c.GoogleAgentEngine.MessageSenders) == "" {
+		return senders, nil
+	}
+
+	var overrides map[string]MessageSender
+	if err := json.Unmarshal([]byte(c.GoogleAgentEngine.MessageSenders), &overrides); err != nil {
+		return nil, fmt.Errorf("RESPONSE_MESSAGE_SENDERS must be a JSON object keyed by message type: %w", err)
+	}
+	for messageType, sender := range overrides {
+		senders[messageType] = sender
+	}
+	return senders, nil
+}
+
+// GetProviderAliases decodes PROVIDER_ALIASES, with aliases and canonical names folded like NormalizeProvider
+func (c *Config) GetProviderAliases() (map[string]string, error) {
+	aliases := make(map[string]string)
+	if strings.TrimSpace(c.ProviderAliases) == "" {
+		return aliases, nil
+	}
+
+	var configured map[string]string
+	if err := json.Unmarshal([]byte(c.ProviderAliases), &configured); err != nil {
+		return nil, fmt.Errorf("PROVIDER_ALIASES must be a JSON object of provider names keyed by alias: %w", err)
+	}
+	for alias, provider := range configured {
+		provider = foldProviderName(provider)
+		if provider == "" {
+			return nil, fmt.Errorf("PROVIDER_ALIASES: alias %q must map to a provider", alias)
+		}
+		aliases[foldProviderName(alias)] = provider
+	}
+	return aliases, nil
+}
+
+// NormalizeProvider returns the canonical name of a provider as publishers may spell it: case, surrounding
+// space and hyphens versus underscores are ignored ("Google-Agent-Engine" is "google_agent_engine"), and
+// PROVIDER_ALIASES is applied. Unknown providers come back folded, for the supported-provider check to reject.
+func (c *Config) NormalizeProvider(provider string) string {
+	folded := foldProviderName(provider)
+	// Validated at startup
+	aliases, _ := c.GetProviderAliases()
+	if canonical, ok := aliases[folded]; ok {
+		return canonical
+	}
+	return folded
+}
+
+// foldProviderName lowercases a provider name and spells its word separators as underscores
+func foldProviderName(provider string) string {
+	return strings.NewReplacer("-", "_", " ", "_").Replace(strings.ToLower(strings.TrimSpace(provider)))
+}
+
+// GetAudioExtensions returns the audio URL extensions as lowercase suffixes with a leading dot, from
+// TRANSCRIBE_AUDIO_EXTENSIONS, else the deprecated TRANSCRIBE_SUPPORTED_FORMATS, else DefaultAudioExtensions
+func (c *Config) GetAudioExtensions() []string {
+	list := DefaultAudioExtensions
+	if c != nil && strings.TrimSpace(c.Transcribe.AudioExtensions) != "" {
+		list = c.Transcribe.AudioExtensions
+	} else if c != nil && strings.TrimSpace(c.Transcribe.SupportedFormats) != "" {
+		list = c.Transcribe.SupportedFormats
+	}
+
+	var extensions []string
+	for _, ext := range strings.Split(list, ",") {
+		ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		if ext != "" {
+			extensions = append(extensions, "."+ext)
+		}
+	}
+	return extensions
+}
+
+// GetDocumentExtensions returns the document URL extensions as lowercase suffixes with a leading dot
+func (c *Config) GetDocumentExtensions() []string {
+	list := DefaultDocumentExtensions
+	if c != nil && strings.TrimSpace(c.GoogleAgentEngine.DocumentExtensions) != "" {
+		list = c.GoogleAgentEngine.DocumentExtensions
+	}
+
+	var extensions []string
+	for _, ext := range strings.Split(list, ",") {
+		ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		if ext != "" {
+			extensions = append(extensions, "."+ext)
+		}
+	}
+	return extensions
+}
+
+// GetDocumentExtractorTimeout returns the document text extraction timeout, defaulting to 30s
+func (c *Config) GetDocumentExtractorTimeout() time.Duration {
+	if c.GoogleAgentEngine.DocumentExtractorTimeout > 0 {
+		return c.GoogleAgentEngine.DocumentExtractorTimeout
+	}
+	return 30 * time.Second
 }
 
 // GetSecurityAllowedDomains returns security allowed domains as a slice
@@ -542,3 +1454,164 @@ func (c *Config) GetSecurityBlockedDomains() []string {
 	}
 	return strings.Split(c.Security.BlockedDomains, ",")
 }
+
+// GetTaskStatusTTL returns the TTL for a task status value, falling back to REDIS_TASK_STATUS_TTL
+// when the per-status TTL is not configured
+func (c *Config) GetTaskStatusTTL(status string) time.Duration {
+	var ttl time.Duration
+	switch status {
+	case "pending", "processing":
+		ttl = c.Redis.TaskProcessingStatusTTL
+	case "completed", "degraded", "empty", "blocked":
+		ttl = c.Redis.TaskCompletedStatusTTL
+	case "failed", "cancelled", "expired":
+		ttl = c.Redis.TaskFailedStatusTTL
+	}
+
+	if ttl <= 0 {
+		return c.Redis.TaskStatusTTL
+	}
+	return ttl
+}
+
+// GetRateLimitRetryDelay returns how long to wait before retrying a rate-limited agent call, using the
+// server's Retry-After hint when present and capping it at the configured maximum
+func (c *Config) GetRateLimitRetryDelay(retryAfter time.Duration) time.Duration {
+	delay := retryAfter
+	if delay <= 0 {
+		delay = c.GoogleAgentEngine.RateLimitRetryDelay
+	}
+	if maxDelay := c.GoogleAgentEngine.RateLimitMaxRetryDelay; maxDelay > 0 && delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// GetAckMode returns the configured acknowledgement mode, defaulting to AckModeProcessed for unknown values
+func (c *Config) GetAckMode() string {
+	switch mode := strings.ToLower(strings.TrimSpace(c.RabbitMQ.AckMode)); mode {
+	case AckModeEarly, AckModeDurable:
+		return mode
+	default:
+		return AckModeProcessed
+	}
+}
+
+// GetPrefetchCount returns the per-consumer prefetch, at least 1
+func (c *Config) GetPrefetchCount() int {
+	if c.RabbitMQ.PrefetchCount < 1 {
+		return 1
+	}
+	return c.RabbitMQ.PrefetchCount
+}
+
+// GetReconnectDelay returns the wait before the given reconnection attempt (0-based): the initial delay
+// multiplied once per earlier attempt, capped at the maximum delay
+func (c *Config) GetReconnectDelay(attempt int) time.Duration {
+	delay := c.RabbitMQ.ReconnectInitialDelay
+	if delay <= 0 {
+		delay = time.Second
+	}
+	maxDelay := c.RabbitMQ.ReconnectMaxDelay
+	if maxDelay < delay {
+		maxDelay = delay
+	}
+	multiplier := c.RabbitMQ.ReconnectMultiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	for i := 0; i < attempt && delay < maxDelay; i++ {
+		delay = time.Duration(float64(delay) * multiplier)
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// GetToolOutputSummaryTimeout returns the budget for summarizing one tool output
+func (c *Config) GetToolOutputSummaryTimeout() time.Duration {
+	if c.GoogleAgentEngine.ToolOutputSummaryTimeout > 0 {
+		return c.GoogleAgentEngine.ToolOutputSummaryTimeout
+	}
+	return 15 * time.Second
+}
+
+// GetPostProcessors returns the response post-processor names in the order they run
+func (c *Config) GetPostProcessors() []string {
+	var names []string
+	for _, name := range strings.Split(c.GoogleAgentEngine.PostProcessors, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// GetUserMessageShardQueue returns the name of a user message shard queue
+func (c *Config) GetUserMessageShardQueue(shard int) string {
+	return fmt.Sprintf("%s.shard.%d", c.RabbitMQ.UserMessagesQueue, shard)
+}
+
+// GetUserMessageShardQueues returns the names of all user message shard queues (none when sharding is disabled)
+func (c *Config) GetUserMessageShardQueues() []string {
+	queues := make([]string, 0, c.RabbitMQ.UserMessageShards)
+	for shard := 0; shard < c.RabbitMQ.UserMessageShards; shard++ {
+		queues = append(queues, c.GetUserMessageShardQueue(shard))
+	}
+	return queues
+}
+
+// GetConsumedUserMessageQueues returns the user message queues this worker consumes: the unsharded queue,
+// which keeps draining messages published before sharding was enabled, followed by the selected shards
+func (c *Config) GetConsumedUserMessageQueues() ([]string, error) {
+	queues := []string{c.RabbitMQ.UserMessagesQueue}
+	if c.RabbitMQ.UserMessageShards <= 0 {
+		return queues, nil
+	}
+	if strings.TrimSpace(c.RabbitMQ.ConsumedShards) == "" {
+		return append(queues, c.GetUserMessageShardQueues()...), nil
+	}
+
+	seen := make(map[int]bool)
+	for _, field := range strings.Split(c.RabbitMQ.ConsumedShards, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		shard, err := strconv.Atoi(field)
+		if err != nil || shard < 0 || shard >= c.RabbitMQ.UserMessageShards {
+			return nil, fmt.Errorf("invalid shard %q in WORKER_CONSUMED_SHARDS (expected 0-%d)", field, c.RabbitMQ.UserMessageShards-1)
+		}
+		if !seen[shard] {
+			seen[shard] = true
+			queues = append(queues, c.GetUserMessageShardQueue(shard))
+		}
+	}
+	return queues, nil
+}
+
+// GetAllowedModels returns the agent models messages may request
+func (c *Config) GetAllowedModels() []string {
+	var models []string
+	for _, model := range strings.Split(c.GoogleAgentEngine.AllowedModels, ",") {
+		if model = strings.TrimSpace(model); model != "" {
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+// IsModelAllowed reports whether a message may request the model; the default model is always allowed
+func (c *Config) IsModelAllowed(model string) bool {
+	if model == c.GoogleAgentEngine.DefaultModel {
+		return true
+	}
+	for _, allowed := range c.GetAllowedModels() {
+		if model == allowed {
+			return true
+		}
+	}
+	return false
+}
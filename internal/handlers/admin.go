@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+)
+
+// UserDataStore defines the Redis operations needed to erase a user's data
+type UserDataStore interface {
+	PurgeUserData(ctx context.Context, userNumber string) (int, error)
+}
+
+// ThreadResetter forgets a user's agent thread
+type ThreadResetter interface {
+	ResetThread(ctx context.Context, userID string) (int, error)
+}
+
+// AdminHandler handles administrative endpoints
+type AdminHandler struct {
+	logger         *logrus.Logger
+	userData       UserDataStore
+	threadResetter ThreadResetter
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(logger *logrus.Logger, userData UserDataStore, threadResetter ThreadResetter) *AdminHandler {
+	return &AdminHandler{
+		logger:         logger,
+		userData:       userData,
+		threadResetter: threadResetter,
+	}
+}
+
+// HandlePurgeUserData erases a user's thread mapping and task history (GDPR/LGPD deletion requests)
+//
+//	@Summary		Purge user data
+//	@Description	Delete the user's thread mapping, recent-task index, latest result, cached audio transcripts, pending coalesced and scheduled messages and the status, result and error keys of their indexed tasks, in the default namespace and every routed tenant's (REDIS_TENANT_ROUTES), including dedicated result stores. Requires the admin bearer token.
+//	@Tags			Admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			user_number	path		string							true	"User number"
+//	@Success		200			{object}	models.UserDataPurgeResponse	"User data purged"
+//	@Failure		400			{object}	map[string]interface{}			"Invalid user number"
+//	@Failure		401			{object}	map[string]interface{}			"Missing or invalid admin token"
+//	@Failure		500			{object}	map[string]interface{}			"Internal server error"
+//	@Router			/api/v1/admin/users/{user_number}/data [delete]
+func (h *AdminHandler) HandlePurgeUserData(c *gin.Context) {
+	userNumber := strings.TrimSpace(c.Param("user_number"))
+	if userNumber == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid parameter",
+			"message": "user_number is required",
+		})
+		return
+	}
+
+	logger := h.logger.WithField("user_number", userNumber)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	threadKeys, err := h.threadResetter.ResetThread(ctx, userNumber)
+	if err != nil {
+		logger.WithError(err).Error("Failed to reset user thread")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to reset user thread",
+		})
+		return
+	}
+
+	dataKeys, err := h.userData.PurgeUserData(ctx, userNumber)
+	if err != nil {
+		logger.WithError(err).Error("Failed to purge user data")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to purge user data",
+		})
+		return
+	}
+
+	logger.WithField("keys_removed", threadKeys+dataKeys).Info("User data purged")
+
+	c.JSON(http.StatusOK, models.UserDataPurgeResponse{
+		UserNumber:  userNumber,
+		KeysRemoved: threadKeys + dataKeys,
+	})
+}
@@ -3,10 +3,12 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -17,6 +19,7 @@ import (
 	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
 	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/middleware"
 	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/services"
 )
 
 // RedisServiceInterface defines Redis operations needed by MessageHandler
@@ -24,10 +27,15 @@ type RedisServiceInterface interface {
 	SetTaskStatus(ctx context.Context, taskID string, status string, ttl time.Duration) error
 	GetTaskStatus(ctx context.Context, taskID string) (string, error)
 	GetTaskResult(ctx context.Context, taskID string, dest interface{}) error
+	GetTaskError(ctx context.Context, taskID string) (*models.ErrorResult, error)
 	Get(ctx context.Context, key string) (string, error)
 	Set(ctx context.Context, key string, value string, ttl time.Duration) error
 	StoreCallbackURL(ctx context.Context, messageID string, callbackURL string, ttl time.Duration) error
 	GetCallbackURL(ctx context.Context, messageID string) (string, error)
+	SetTaskCancelled(ctx context.Context, taskID string, ttl time.Duration) error
+	RequeueFailedTask(ctx context.Context, taskID string, ttl time.Duration) (string, bool, error)
+	StoreTaskMessage(ctx context.Context, taskID string, message string, ttl time.Duration) error
+	GetTaskMessage(ctx context.Context, taskID string) (string, error)
 	Ping(ctx context.Context) error
 }
 
@@ -45,6 +53,7 @@ type MessageHandler struct {
 	redisService    RedisServiceInterface
 	rabbitMQService RabbitMQServiceInterface
 	tracePropagator *middleware.TraceCorrelationPropagator // Optional for distributed tracing
+	tenantResolver  *services.TenantResolver               // Optional per-tenant task data routing
 }
 
 // NewMessageHandler creates a new message handler
@@ -54,6 +63,7 @@ func NewMessageHandler(
 	redisService RedisServiceInterface,
 	rabbitMQService RabbitMQServiceInterface,
 	tracePropagator *middleware.TraceCorrelationPropagator,
+	tenantResolver *services.TenantResolver,
 ) *MessageHandler {
 	return &MessageHandler{
 		logger:          logger,
@@ -61,9 +71,18 @@ func NewMessageHandler(
 		redisService:    redisService,
 		rabbitMQService: rabbitMQService,
 		tracePropagator: tracePropagator,
+		tenantResolver:  tenantResolver,
 	}
 }
 
+// taskStore returns the store holding the tenant's task data
+func (h *MessageHandler) taskStore(tenantID string) RedisServiceInterface {
+	if h.tenantResolver != nil && h.tenantResolver.IsRouted(tenantID) {
+		return h.tenantResolver.Redis(tenantID)
+	}
+	return h.redisService
+}
+
 // HandleUserWebhook processes user messages and queues them for processing
 //
 //	@Summary		Process user message webhook
@@ -87,6 +106,15 @@ func (h *MessageHandler) HandleUserWebhook(c *gin.Context) {
 		return
 	}
 
+	// A media message (e.g. an audio) may come without text
+	if strings.TrimSpace(req.Message) == "" && (req.Media == nil || strings.TrimSpace(req.Media.URL) == "") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": "message or media.url is required",
+		})
+		return
+	}
+
 	// Validate callback URL if provided
 	if req.CallbackURL != nil && *req.CallbackURL != "" {
 		if err := validateCallbackURL(*req.CallbackURL); err != nil {
@@ -99,14 +127,28 @@ func (h *MessageHandler) HandleUserWebhook(c *gin.Context) {
 		}
 	}
 
+	// Reject models the workers would refuse, rather than failing the task later
+	if req.Model != "" && !h.config.IsModelAllowed(req.Model) {
+		h.logger.WithField("model", req.Model).Error("Requested agent model is not allowed")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid model",
+			"message": fmt.Sprintf("model %q is not allowed", req.Model),
+		})
+		return
+	}
+
 	// Generate message ID for tracking
 	messageID := models.GenerateMessageID()
 
-	// Set default provider if not specified
-	provider := "google_agent_engine" // Default provider
+	// Set default provider if not specified; publishers' spellings and aliases map to the canonical name
+	provider := h.config.DefaultProvider
+	if provider == "" {
+		provider = models.ProviderGoogleAgentEngine
+	}
 	if req.Provider != nil && *req.Provider != "" {
 		provider = *req.Provider
 	}
+	provider = h.config.NormalizeProvider(provider)
 
 	logger := h.logger.WithFields(logrus.Fields{
 		"message_id":           messageID,
@@ -127,9 +169,9 @@ func (h *MessageHandler) HandleUserWebhook(c *gin.Context) {
 			attribute.String("user.number", req.UserNumber),
 			attribute.String("provider", provider),
 			attribute.Int("message.length", len(req.Message)),
-			attribute.Bool("message.is_audio", isAudioURL(req.Message)),
+			attribute.Bool("message.is_audio", services.IsAudioURL(h.config, req.Message)),
 			attribute.String("message.type", func() string {
-				if isAudioURL(req.Message) {
+				if services.IsAudioURL(h.config, req.Message) {
 					return "audio"
 				}
 				return "text"
@@ -145,12 +187,14 @@ func (h *MessageHandler) HandleUserWebhook(c *gin.Context) {
 	}
 
 	logger.Info("Processing user webhook request")
+	services.LogMessageBody(h.config, logger, "message_body", req.Message, "User webhook message body")
 
 	// Store initial status to handle immediate polling (like Python API)
 	ctxTimeout, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	if err := h.redisService.SetTaskStatus(ctxTimeout, messageID, string(models.TaskStatusProcessing), h.config.Redis.TaskStatusTTL); err != nil {
+	store := h.taskStore(req.TenantID)
+	if err := store.SetTaskStatus(ctxTimeout, messageID, string(models.TaskStatusProcessing), h.config.GetTaskStatusTTL(string(models.TaskStatusProcessing))); err != nil {
 		logger.WithError(err).Error("Failed to set initial task status")
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Internal server error",
@@ -169,6 +213,16 @@ func (h *MessageHandler) HandleUserWebhook(c *gin.Context) {
 		Provider:        provider,
 		Timestamp:       time.Now(),
 		Metadata:        req.Metadata,
+		Media:           req.Media,
+		CorrelationID:   c.GetString("correlation_id"),
+		TenantID:        req.TenantID,
+		Channel:         req.Channel,
+		ScheduledAt:     req.ScheduledAt,
+		Model:           req.Model,
+		Priority:        req.Priority,
+	}
+	if req.Locale != nil {
+		queueMessage.Locale = *req.Locale
 	}
 
 	// Add request metadata
@@ -185,39 +239,39 @@ func (h *MessageHandler) HandleUserWebhook(c *gin.Context) {
 	}
 	if metadataBytes, err := json.Marshal(metadataForResponse); err == nil {
 		metadataKey := "task:metadata:" + messageID
-		_ = h.redisService.Set(ctxTimeout, metadataKey, string(metadataBytes), h.config.Redis.TaskStatusTTL)
+		_ = store.Set(ctxTimeout, metadataKey, string(metadataBytes), h.config.Redis.TaskStatusTTL)
 	}
 
 	// Store callback URL if provided
 	if req.CallbackURL != nil && *req.CallbackURL != "" {
-		if err := h.redisService.StoreCallbackURL(ctxTimeout, messageID, *req.CallbackURL, h.config.Redis.TaskStatusTTL); err != nil {
+		if err := store.StoreCallbackURL(ctxTimeout, messageID, *req.CallbackURL, h.config.Redis.TaskStatusTTL); err != nil {
 			logger.WithError(err).Warn("Failed to store callback URL, continuing with processing")
 		} else {
 			logger.WithField("callback_url", *req.CallbackURL).Debug("Callback URL stored for message")
 		}
 	}
 
-	// Queue message for processing with trace headers
-	var err error
-	if traceHeaders != nil && h.rabbitMQService != nil {
-		// Use interface that supports headers if tracing is enabled
-		if publisherWithHeaders, ok := h.rabbitMQService.(interface {
-			PublishMessageWithHeaders(ctx context.Context, queueName string, message interface{}, headers map[string]interface{}) error
-		}); ok {
-			err = publisherWithHeaders.PublishMessageWithHeaders(ctxTimeout, h.config.RabbitMQ.UserMessagesQueue, queueMessage, traceHeaders)
-		} else {
-			// Fallback to regular publish
-			err = h.rabbitMQService.PublishMessage(ctxTimeout, h.config.RabbitMQ.UserMessagesQueue, queueMessage)
+	// Carry the correlation ID in the headers as well, so it survives retries and DLQ replays
+	if queueMessage.CorrelationID != "" {
+		if traceHeaders == nil {
+			traceHeaders = make(map[string]interface{})
 		}
-	} else {
-		err = h.rabbitMQService.PublishMessage(ctxTimeout, h.config.RabbitMQ.UserMessagesQueue, queueMessage)
+		traceHeaders[models.CorrelationIDHeader] = queueMessage.CorrelationID
 	}
 
-	if err != nil {
+	// Keep the original message so a failed task can be requeued (POST /api/v1/tasks/{id}/retry)
+	if messageBytes, err := json.Marshal(queueMessage); err == nil {
+		if err := store.StoreTaskMessage(ctxTimeout, messageID, string(messageBytes), h.taskMessageTTL()); err != nil {
+			logger.WithError(err).Warn("Failed to store original message, the task won't be retryable")
+		}
+	}
+
+	// Queue message for processing with trace and correlation headers, on the user's shard when sharded
+	if err := h.publishQueueMessage(ctxTimeout, queueMessage, traceHeaders); err != nil {
 		logger.WithError(err).Error("Failed to queue user message")
 
 		// Update task status to failed
-		_ = h.redisService.SetTaskStatus(ctxTimeout, messageID, string(models.TaskStatusFailed), h.config.Redis.TaskStatusTTL)
+		_ = store.SetTaskStatus(ctxTimeout, messageID, string(models.TaskStatusFailed), h.config.GetTaskStatusTTL(string(models.TaskStatusFailed)))
 
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Internal server error",
@@ -232,10 +286,31 @@ func (h *MessageHandler) HandleUserWebhook(c *gin.Context) {
 	c.JSON(http.StatusCreated, models.WebhookResponse{
 		MessageID:       messageID,
 		Status:          string(models.TaskStatusProcessing),
-		PollingEndpoint: "/api/v1/message/response?message_id=" + messageID,
+		PollingEndpoint: models.ResponsePollingPath(messageID, req.TenantID),
 	})
 }
 
+// publishQueueMessage queues the message on its user's queue, with the given headers when the publisher
+// supports them
+func (h *MessageHandler) publishQueueMessage(ctx context.Context, queueMessage models.QueueMessage, headers map[string]interface{}) error {
+	queueName := services.UserMessageQueue(h.config, queueMessage.UserNumber)
+	if headers != nil && h.rabbitMQService != nil {
+		// Use interface that supports headers if tracing is enabled
+		if publisherWithHeaders, ok := h.rabbitMQService.(interface {
+			PublishMessageWithHeaders(ctx context.Context, queueName string, message interface{}, headers map[string]interface{}) error
+		}); ok {
+			return publisherWithHeaders.PublishMessageWithHeaders(ctx, queueName, queueMessage, headers)
+		}
+	}
+	return h.rabbitMQService.PublishMessage(ctx, queueName, queueMessage)
+}
+
+// taskMessageTTL is how long a task's original message is kept: long enough to retry the task for as long
+// as a failed status is kept after it was processed
+func (h *MessageHandler) taskMessageTTL() time.Duration {
+	return h.config.GetTaskStatusTTL(string(models.TaskStatusProcessing)) + h.config.GetTaskStatusTTL(string(models.TaskStatusFailed))
+}
+
 // HandleMessageResponse handles polling for message processing results
 //
 //	@Summary		Get message response
@@ -244,7 +319,8 @@ func (h *MessageHandler) HandleUserWebhook(c *gin.Context) {
 //	@Accept			json
 //	@Produce		json
 //	@Param			message_id	query		string					true	"Message ID (UUID)"
-//	@Success		200			{object}	models.MessageResponse	"Message completed or failed"
+//	@Param			tenant_id	query		string					false	"Tenant the message was submitted for (selects its task store when routed)"
+//	@Success		200			{object}	models.MessageResponse	"Message completed, degraded or failed"
 //	@Success		202			{object}	models.MessageResponse	"Message still processing"
 //	@Failure		400			{object}	map[string]interface{}	"Invalid request or message ID format"
 //	@Failure		404			{object}	map[string]interface{}	"Message not found"
@@ -266,11 +342,12 @@ func (h *MessageHandler) HandleMessageResponse(c *gin.Context) {
 
 	// Start with request context
 	ctx := c.Request.Context()
+	store := h.taskStore(req.TenantID)
 
 	// Try to extract trace context from stored result if available
 	if h.tracePropagator != nil {
 		traceKey := "task:trace:" + req.MessageID
-		if traceData, err := h.redisService.Get(ctx, traceKey); err == nil && traceData != "" {
+		if traceData, err := store.Get(ctx, traceKey); err == nil && traceData != "" {
 			var traceHeaders map[string]string
 			if err := json.Unmarshal([]byte(traceData), &traceHeaders); err == nil && len(traceHeaders) > 0 {
 				ctx = h.tracePropagator.ExtractTraceContext(ctx, traceHeaders)
@@ -292,7 +369,7 @@ func (h *MessageHandler) HandleMessageResponse(c *gin.Context) {
 	defer cancel()
 
 	// Get task status from Redis
-	status, err := h.redisService.GetTaskStatus(ctxTimeout, req.MessageID)
+	status, err := store.GetTaskStatus(ctxTimeout, req.MessageID)
 	if err != nil {
 		logger.WithError(err).Error("Failed to get task status")
 		c.JSON(http.StatusNotFound, gin.H{
@@ -306,10 +383,10 @@ func (h *MessageHandler) HandleMessageResponse(c *gin.Context) {
 		Status: status,
 	}
 
-	// If task is completed, get the result
-	if status == string(models.TaskStatusCompleted) {
+	// If task is completed (or degraded/empty/blocked with a fallback response), get the result
+	if models.TaskStatus(status).HasResult() {
 		var result string
-		if err := h.redisService.GetTaskResult(ctxTimeout, req.MessageID, &result); err != nil {
+		if err := store.GetTaskResult(ctxTimeout, req.MessageID, &result); err != nil {
 			logger.WithError(err).Warn("Task completed but no result found")
 		} else {
 			// The result is already processed by the worker and contains the final ProcessedMessageData
@@ -334,9 +411,9 @@ func (h *MessageHandler) HandleMessageResponse(c *gin.Context) {
 	// If task failed, try to get error information
 	if status == string(models.TaskStatusFailed) {
 		// Try to get error details from Redis (could be stored by worker)
-		errorKey := "task:error:" + req.MessageID
-		if errorMsg, err := h.redisService.Get(ctxTimeout, errorKey); err == nil {
-			response.Error = &errorMsg
+		if errorResult, err := store.GetTaskError(ctxTimeout, req.MessageID); err == nil {
+			response.Error = &errorResult.Message
+			response.ErrorDetails = errorResult
 		}
 	}
 
@@ -354,10 +431,10 @@ func (h *MessageHandler) HandleMessageResponse(c *gin.Context) {
 	// Return appropriate HTTP status code based on task status (matches Python API)
 	var httpStatus int
 	switch status {
-	case string(models.TaskStatusCompleted), string(models.TaskStatusFailed):
-		httpStatus = http.StatusOK // 200 for completed/failed
-	case string(models.TaskStatusPending), string(models.TaskStatusProcessing):
-		httpStatus = http.StatusAccepted // 202 for pending/processing
+	case string(models.TaskStatusCompleted), string(models.TaskStatusDegraded), string(models.TaskStatusEmpty), string(models.TaskStatusBlocked), string(models.TaskStatusFailed), string(models.TaskStatusCancelled), string(models.TaskStatusExpired):
+		httpStatus = http.StatusOK // 200 for completed/degraded/empty/blocked/failed/cancelled/expired
+	case string(models.TaskStatusPending), string(models.TaskStatusProcessing), string(models.TaskStatusScheduled):
+		httpStatus = http.StatusAccepted // 202 for pending/processing/scheduled
 	default:
 		httpStatus = http.StatusOK // Default to 200 for unknown statuses
 	}
@@ -365,6 +442,303 @@ func (h *MessageHandler) HandleMessageResponse(c *gin.Context) {
 	c.JSON(httpStatus, response)
 }
 
+// HandleGetTask returns a task's status and its result or error
+//
+//	@Summary		Get task
+//	@Description	Get the status of a task by ID, with its result once finished or its error if it failed. Tasks still in flight report "processing".
+//	@Tags			Tasks
+//	@Produce		json
+//	@Param			id			path		string						true	"Task ID (message ID, UUID)"
+//	@Param			tenant_id	query		string						false	"Tenant the message was submitted for (selects its task store when routed)"
+//	@Success		200			{object}	models.TaskStatusResponse	"Task finished"
+//	@Success		202			{object}	models.TaskStatusResponse	"Task still processing"
+//	@Failure		400			{object}	map[string]interface{}		"Invalid task ID format"
+//	@Failure		404			{object}	map[string]interface{}		"Task not found"
+//	@Failure		500			{object}	map[string]interface{}		"Internal server error"
+//	@Router			/api/v1/tasks/{id} [get]
+func (h *MessageHandler) HandleGetTask(c *gin.Context) {
+	taskID := c.Param("id")
+	if !models.IsValidUUID(taskID) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid parameter",
+			"message": "task id must be a valid UUID",
+		})
+		return
+	}
+
+	logger := h.logger.WithField("task_id", taskID)
+	store := h.taskStore(c.Query("tenant_id"))
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	status, err := store.GetTaskStatus(ctx, taskID)
+	if err != nil {
+		if errors.Is(err, services.ErrKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Task not found",
+				"message": "No task found with the provided ID",
+			})
+			return
+		}
+		logger.WithError(err).Error("Failed to get task status")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to read task status",
+		})
+		return
+	}
+
+	response := models.TaskStatusResponse{
+		TaskID: taskID,
+		Status: status,
+	}
+
+	switch models.TaskStatus(status) {
+	case models.TaskStatusCompleted, models.TaskStatusDegraded, models.TaskStatusEmpty, models.TaskStatusBlocked:
+		var result string
+		if err := store.GetTaskResult(ctx, taskID, &result); err != nil {
+			logger.WithError(err).Warn("Task finished but no result found")
+			break
+		}
+		var processedData models.ProcessedMessageData
+		if err := json.Unmarshal([]byte(result), &processedData); err != nil {
+			logger.WithError(err).Error("Failed to parse processed result from worker")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Internal server error",
+				"message": "Failed to parse worker response",
+			})
+			return
+		}
+		response.Result = processedData
+	case models.TaskStatusFailed:
+		if errorResult, err := store.GetTaskError(ctx, taskID); err == nil {
+			response.Error = &errorResult.Message
+			response.ErrorDetails = errorResult
+		}
+	case models.TaskStatusCancelled, models.TaskStatusExpired:
+		// Finished without a result or error
+	default:
+		// Pending, processing, scheduled and requeued tasks are all still in flight
+		response.Status = string(models.TaskStatusProcessing)
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// HandleCancelTask requests cancellation of a pending or in-progress task
+//
+//	@Summary		Cancel message processing
+//	@Description	Flag a pending or processing message for cancellation. The worker aborts cooperatively and marks the task as cancelled.
+//	@Tags			Messages
+//	@Accept			json
+//	@Produce		json
+//	@Param			message_id	query		string						true	"Message ID (UUID)"
+//	@Param			tenant_id	query		string						false	"Tenant the message was submitted for (selects its task store when routed)"
+//	@Success		202			{object}	models.CancelTaskResponse	"Cancellation requested"
+//	@Failure		400			{object}	map[string]interface{}		"Invalid request or message ID format"
+//	@Failure		404			{object}	map[string]interface{}		"Message not found"
+//	@Failure		409			{object}	map[string]interface{}		"Task already finished"
+//	@Failure		500			{object}	map[string]interface{}		"Internal server error"
+//	@Router			/api/v1/message/cancel [post]
+func (h *MessageHandler) HandleCancelTask(c *gin.Context) {
+	var req models.MessageResponseRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.logger.WithError(err).Error("Invalid cancel task request")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	logger := h.logger.WithField("message_id", req.MessageID)
+	store := h.taskStore(req.TenantID)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	status, err := store.GetTaskStatus(ctx, req.MessageID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to get task status")
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Task not found",
+			"message": "No task found with the provided message ID",
+		})
+		return
+	}
+
+	if models.TaskStatus(status).IsTerminal() {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Task already finished",
+			"message": fmt.Sprintf("Task is already %s and cannot be cancelled", status),
+		})
+		return
+	}
+
+	if err := store.SetTaskCancelled(ctx, req.MessageID, h.config.Redis.TaskStatusTTL); err != nil {
+		logger.WithError(err).Error("Failed to set task cancellation flag")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to cancel task",
+		})
+		return
+	}
+
+	logger.WithField("status", status).Info("Task cancellation requested")
+
+	c.JSON(http.StatusAccepted, models.CancelTaskResponse{
+		MessageID: req.MessageID,
+		Status:    "cancel_requested",
+	})
+}
+
+// HandleRetryTask requeues a failed task's original message under the same task ID
+//
+//	@Summary		Retry a failed task
+//	@Description	Republish the original message of a failed task and reset its status to pending, so it is processed again without the user resending it. Requires the admin bearer token.
+//	@Tags			Tasks
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id			path		string					true	"Task ID (message ID, UUID)"
+//	@Param			tenant_id	query		string					false	"Tenant the message was submitted for (selects its task store when routed)"
+//	@Success		202			{object}	models.WebhookResponse	"Task requeued"
+//	@Failure		400			{object}	map[string]interface{}	"Invalid task ID format"
+//	@Failure		401			{object}	map[string]interface{}	"Missing or invalid admin token"
+//	@Failure		404			{object}	map[string]interface{}	"Task or its original message not found"
+//	@Failure		409			{object}	map[string]interface{}	"Task has not failed"
+//	@Failure		500			{object}	map[string]interface{}	"Internal server error"
+//	@Router			/api/v1/tasks/{id}/retry [post]
+func (h *MessageHandler) HandleRetryTask(c *gin.Context) {
+	taskID := c.Param("id")
+	if !models.IsValidUUID(taskID) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid parameter",
+			"message": "task id must be a valid UUID",
+		})
+		return
+	}
+
+	tenantID := c.Query("tenant_id")
+	logger := h.logger.WithField("task_id", taskID)
+	store := h.taskStore(tenantID)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	status, err := store.GetTaskStatus(ctx, taskID)
+	if err != nil {
+		if errors.Is(err, services.ErrKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Task not found",
+				"message": "No task found with the provided ID",
+			})
+			return
+		}
+		logger.WithError(err).Error("Failed to get task status")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to read task status",
+		})
+		return
+	}
+	if status != string(models.TaskStatusFailed) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Task not failed",
+			"message": fmt.Sprintf("Task is %s; only failed tasks can be retried", status),
+		})
+		return
+	}
+
+	messageJSON, err := store.GetTaskMessage(ctx, taskID)
+	if err != nil {
+		if errors.Is(err, services.ErrKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Original message not found",
+				"message": "The task's original message is no longer stored",
+			})
+			return
+		}
+		logger.WithError(err).Error("Failed to get original task message")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to read original message",
+		})
+		return
+	}
+
+	var queueMessage models.QueueMessage
+	if err := json.Unmarshal([]byte(messageJSON), &queueMessage); err != nil {
+		logger.WithError(err).Error("Failed to parse original task message")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to parse original message",
+		})
+		return
+	}
+
+	// A fresh timestamp keeps the worker from discarding the retry as too old; it is due right away
+	queueMessage.Timestamp = time.Now()
+	queueMessage.ScheduledAt = nil
+
+	headers := map[string]interface{}{}
+	if h.tracePropagator != nil {
+		for k, v := range h.tracePropagator.InjectTraceContext(ctx) {
+			headers[k] = v
+		}
+	}
+	if queueMessage.CorrelationID != "" {
+		headers[models.CorrelationIDHeader] = queueMessage.CorrelationID
+	}
+
+	// Only the retry that moves the task out of failed publishes it; concurrent ones get a conflict
+	taskError, requeued, err := store.RequeueFailedTask(ctx, taskID, h.config.GetTaskStatusTTL(string(models.TaskStatusPending)))
+	if err != nil {
+		logger.WithError(err).Error("Failed to reset task status")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to reset task status",
+		})
+		return
+	}
+	if !requeued {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Task not failed",
+			"message": "Task is no longer failed; it may have been retried already",
+		})
+		return
+	}
+
+	if err := h.publishQueueMessage(ctx, queueMessage, headers); err != nil {
+		logger.WithError(err).Error("Failed to requeue task")
+		failedTTL := h.config.GetTaskStatusTTL(string(models.TaskStatusFailed))
+		if taskError != "" {
+			_ = store.Set(ctx, fmt.Sprintf("task:error:%s", taskID), taskError, failedTTL)
+		}
+		_ = store.SetTaskStatus(ctx, taskID, string(models.TaskStatusFailed), failedTTL)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal server error",
+			"message": "Failed to queue message for processing",
+		})
+		return
+	}
+
+	// The retry may fail again, so the message is kept for another full window
+	if err := store.StoreTaskMessage(ctx, taskID, messageJSON, h.taskMessageTTL()); err != nil {
+		logger.WithError(err).Warn("Failed to refresh original message")
+	}
+
+	logger.WithField("user_number", queueMessage.UserNumber).Info("Failed task requeued")
+
+	c.JSON(http.StatusAccepted, models.WebhookResponse{
+		MessageID:       taskID,
+		Status:          string(models.TaskStatusPending),
+		PollingEndpoint: models.ResponsePollingPath(taskID, tenantID),
+	})
+}
+
 // HandleDebugTaskStatus provides debug information about task processing
 //
 //	@Summary		Get task debug status
@@ -373,6 +747,7 @@ func (h *MessageHandler) HandleMessageResponse(c *gin.Context) {
 //	@Accept			json
 //	@Produce		json
 //	@Param			message_id	query		string					true	"Message ID (UUID)"
+//	@Param			tenant_id	query		string					false	"Tenant the message was submitted for (selects its task store when routed)"
 //	@Success		200			{object}	models.TaskDebugInfo	"Task debug information"
 //	@Failure		400			{object}	map[string]interface{}	"Invalid request or message ID format"
 //	@Failure		404			{object}	map[string]interface{}	"Task not found"
@@ -399,11 +774,13 @@ func (h *MessageHandler) HandleDebugTaskStatus(c *gin.Context) {
 	logger := h.logger.WithField("message_id", messageID)
 	logger.Debug("Handling debug task status request")
 
+	store := h.taskStore(c.Query("tenant_id"))
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	// Get task status
-	status, err := h.redisService.GetTaskStatus(ctx, messageID)
+	status, err := store.GetTaskStatus(ctx, messageID)
 	if err != nil {
 		logger.WithError(err).Error("Failed to get task status")
 		c.JSON(http.StatusNotFound, gin.H{
@@ -422,17 +799,17 @@ func (h *MessageHandler) HandleDebugTaskStatus(c *gin.Context) {
 
 	// Try to get additional debug information from Redis
 	// These keys would be set by the message processing workers
-	if retryCount, err := h.redisService.Get(ctx, "task:retry:"+messageID); err == nil {
+	if retryCount, err := store.Get(ctx, "task:retry:"+messageID); err == nil {
 		if count := parseRetryCount(retryCount); count >= 0 {
 			debugInfo.RetryCount = count
 		}
 	}
 
-	if errorMsg, err := h.redisService.Get(ctx, "task:error:"+messageID); err == nil {
-		debugInfo.LastError = &errorMsg
+	if errorResult, err := store.GetTaskError(ctx, messageID); err == nil {
+		debugInfo.LastError = &errorResult.Message
 	}
 
-	if createdAt, err := h.redisService.Get(ctx, "task:created:"+messageID); err == nil {
+	if createdAt, err := store.Get(ctx, "task:created:"+messageID); err == nil {
 		if timestamp, err := time.Parse(time.RFC3339, createdAt); err == nil {
 			debugInfo.CreatedAt = timestamp
 		}
@@ -467,17 +844,6 @@ func parseRetryCount(s string) int {
 	return -1 // Invalid
 }
 
-// isAudioURL checks if the URL appears to be an audio file
-func isAudioURL(url string) bool {
-	audioExtensions := []string{".mp3", ".wav", ".m4a", ".aac", ".ogg", ".oga", ".flac", ".wma", ".opus"}
-	for _, ext := range audioExtensions {
-		if len(url) >= len(ext) && url[len(url)-len(ext):] == ext {
-			return true
-		}
-	}
-	return false
-}
-
 // validateCallbackURL validates callback URL format and security requirements
 func validateCallbackURL(callbackURL string) error {
 	// Check URL length
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/services"
+)
+
+// taskEventWriteTimeout bounds how long a push may block on a slow WebSocket client
+const taskEventWriteTimeout = 10 * time.Second
+
+// TaskEventsHandler pushes task completions to WebSocket clients as the workers publish them
+type TaskEventsHandler struct {
+	logger         *logrus.Logger
+	config         *config.Config
+	hub            *services.TaskEventHub
+	redisService   *services.RedisService
+	tenantResolver *services.TenantResolver // Optional per-tenant task data routing
+}
+
+// NewTaskEventsHandler creates a new task events handler
+func NewTaskEventsHandler(logger *logrus.Logger, config *config.Config, hub *services.TaskEventHub, redisService *services.RedisService, tenantResolver *services.TenantResolver) *TaskEventsHandler {
+	return &TaskEventsHandler{
+		logger:         logger,
+		config:         config,
+		hub:            hub,
+		redisService:   redisService,
+		tenantResolver: tenantResolver,
+	}
+}
+
+// HandleTaskEvents upgrades to a WebSocket pushing task events instead of polling for results
+//
+//	@Summary		Subscribe to task events
+//	@Description	Upgrade to a WebSocket that receives a JSON models.TaskEvent each time a task finishes (completed, degraded, empty, blocked, failed, cancelled or expired). Subscribe to one task with task_id, in which case the socket closes after its event (sent right away if the task had already finished), or to all of a user's tasks with user_number. Requires REDIS_PUBLISH_TASK_EVENTS.
+//	@Tags			Tasks
+//	@Param			task_id		query		string					false	"Task (message) ID to wait for"
+//	@Param			user_number	query		string					false	"User whose task events to receive"
+//	@Param			tenant_id	query		string					false	"Tenant the messages were submitted for (selects its task store when routed)"
+//	@Success		101			{object}	models.TaskEvent		"Switching to WebSocket; each frame is a task event"
+//	@Failure		400			{object}	map[string]interface{}	"Missing or invalid subscription"
+//	@Failure		403			{object}	map[string]interface{}	"Origin not allowed by the CORS settings"
+//	@Failure		503			{object}	map[string]interface{}	"Task events unavailable"
+//	@Router			/api/v1/tasks/events [get]
+func (h *TaskEventsHandler) HandleTaskEvents(c *gin.Context) {
+	taskID := c.Query("task_id")
+	userNumber := strings.TrimSpace(c.Query("user_number"))
+	if (taskID == "") == (userNumber == "") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid parameter",
+			"message": "exactly one of task_id or user_number is required",
+		})
+		return
+	}
+	if taskID != "" && !models.IsValidUUID(taskID) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid parameter",
+			"message": "task_id must be a valid UUID",
+		})
+		return
+	}
+
+	tenantID := c.Query("tenant_id")
+	store := h.taskStore(tenantID)
+	channel := store.TaskEventChannel(taskID)
+	if userNumber != "" {
+		channel = store.UserEventChannel(userNumber)
+	}
+	logger := h.logger.WithFields(logrus.Fields{
+		"task_id":     taskID,
+		"user_number": userNumber,
+	})
+
+	// Subscribe before reading the task's status so an event published in between isn't missed
+	events, unsubscribe, err := h.hub.Subscribe(c.Request.Context(), channel)
+	if err != nil {
+		logger.WithError(err).Error("Failed to subscribe to task events")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Service unavailable",
+			"message": "Task events are temporarily unavailable",
+		})
+		return
+	}
+	defer unsubscribe()
+
+	if !h.originAllowed(c.Request) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "origin not allowed",
+		})
+		return
+	}
+
+	// The origin was checked against the CORS settings above
+	ws, err := websocket.Accept(c.Writer, c.Request, &websocket.AcceptOptions{InsecureSkipVerify: true})
+	if err != nil {
+		logger.WithError(err).Debug("WebSocket handshake failed")
+		return
+	}
+	h.streamTaskEvents(c.Request.Context(), ws, store, taskID, tenantID, events, logger)
+}
+
+// streamTaskEvents writes events to the client until it disconnects or, for a task subscription, the task
+// has finished
+func (h *TaskEventsHandler) streamTaskEvents(ctx context.Context, ws *websocket.Conn, store *services.RedisService, taskID, tenantID string, events <-chan models.TaskEvent, logger *logrus.Entry) {
+	defer func() { _ = ws.CloseNow() }()
+
+	// Clients only listen; the context ends once they close the socket
+	ctx = ws.CloseRead(ctx)
+
+	if taskID != "" {
+		if event, finished := h.finishedTaskEvent(ctx, store, taskID, tenantID); finished {
+			h.sendTaskEvent(ctx, ws, event, logger)
+			_ = ws.Close(websocket.StatusNormalClosure, "")
+			return
+		}
+	}
+
+	for {
+		select {
+		case event := <-events:
+			if !h.sendTaskEvent(ctx, ws, event, logger) {
+				return
+			}
+			if taskID != "" && models.TaskStatus(event.Status).IsTerminal() {
+				_ = ws.Close(websocket.StatusNormalClosure, "")
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// finishedTaskEvent rebuilds the event of a task that finished before the client subscribed
+func (h *TaskEventsHandler) finishedTaskEvent(ctx context.Context, store *services.RedisService, taskID, tenantID string) (models.TaskEvent, bool) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	status, err := store.GetTaskStatus(ctx, taskID)
+	if err != nil || !models.TaskStatus(status).IsTerminal() {
+		return models.TaskEvent{}, false
+	}
+
+	event := models.TaskEvent{
+		MessageID: taskID,
+		Status:    status,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+	if models.TaskStatus(status).HasResult() {
+		event.ResultPointer = models.ResponsePollingPath(taskID, tenantID)
+	}
+	if status == string(models.TaskStatusFailed) {
+		if errorResult, err := store.GetTaskError(ctx, taskID); err == nil {
+			event.Error = &errorResult.Message
+		}
+	}
+	return event, true
+}
+
+// sendTaskEvent writes one event as a JSON frame, returning false once the client can't be written to
+func (h *TaskEventsHandler) sendTaskEvent(ctx context.Context, ws *websocket.Conn, event models.TaskEvent, logger *logrus.Entry) bool {
+	ctx, cancel := context.WithTimeout(ctx, taskEventWriteTimeout)
+	defer cancel()
+
+	if err := wsjson.Write(ctx, ws, event); err != nil {
+		logger.WithError(err).Debug("Failed to push task event, closing WebSocket")
+		return false
+	}
+	return true
+}
+
+// originAllowed rejects browser handshakes from origins CORS doesn't allow; clients without an Origin pass
+func (h *TaskEventsHandler) originAllowed(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" || !h.config.Security.CORSEnabled {
+		return true
+	}
+	allowed := h.config.GetCORSOrigins()
+	return slices.Contains(allowed, "*") || slices.Contains(allowed, origin)
+}
+
+// taskStore returns the store holding the tenant's task data
+func (h *TaskEventsHandler) taskStore(tenantID string) *services.RedisService {
+	if h.tenantResolver != nil && h.tenantResolver.IsRouted(tenantID) {
+		return h.tenantResolver.Redis(tenantID)
+	}
+	return h.redisService
+}
@@ -0,0 +1,219 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/services"
+)
+
+// AgentProvider is an agent backend able to serve a message, named as it is reported in results
+type AgentProvider struct {
+	Name    string
+	Service GoogleAgentServiceInterface
+}
+
+// NewAgentProviderFallbacks creates the fallback agent deployments of AGENT_PROVIDER_FALLBACKS in the order
+// they are tried. Threads belong to a single deployment, so each fallback keeps its users' threads under its
+// own Redis key prefix. Returns nil when no fallbacks are configured.
+func NewAgentProviderFallbacks(cfg *config.Config, logger *logrus.Logger, rateLimiter services.RateLimiterInterface, redisService *services.RedisService) ([]AgentProvider, error) {
+	fallbacks, err := cfg.GetAgentProviderFallbacks()
+	if err != nil {
+		return nil, err
+	}
+
+	providers := make([]AgentProvider, 0, len(fallbacks))
+	for _, fallback := range fallbacks {
+		fallbackCfg := *cfg
+		fallbackCfg.GoogleAgentEngine.ReasoningEngineID = fallback.ReasoningEngineID
+		if fallback.ProjectID != "" {
+			fallbackCfg.GoogleAgentEngine.ProjectID = fallback.ProjectID
+		}
+		if fallback.Location != "" {
+			fallbackCfg.GoogleAgentEngine.Location = fallback.Location
+		}
+
+		threads := redisService.WithKeyPrefix(cfg.Redis.KeyPrefix+"provider:"+fallback.Name+":", nil)
+		service, err := services.NewGoogleAgentEngineService(&fallbackCfg, logger, rateLimiter, threads)
+		if err != nil {
+			CloseAgentProviders(providers, logger)
+			return nil, fmt.Errorf("agent provider fallback %q: %w", fallback.Name, err)
+		}
+		providers = append(providers, AgentProvider{Name: fallback.Name, Service: service})
+
+		logger.WithFields(logrus.Fields{
+			"provider":            fallback.Name,
+			"location":            fallbackCfg.GoogleAgentEngine.Location,
+			"reasoning_engine_id": fallback.ReasoningEngineID,
+		}).Info("Agent provider fallback initialized")
+	}
+	return providers, nil
+}
+
+// CloseAgentProviders releases the providers' services that hold resources
+func CloseAgentProviders(providers []AgentProvider, logger *logrus.Logger) {
+	for _, provider := range providers {
+		closer, ok := provider.Service.(interface{ Close() error })
+		if !ok {
+			continue
+		}
+		if err := closer.Close(); err != nil {
+			logger.WithError(err).WithField("provider", provider.Name).Error("Failed to close agent provider")
+		}
+	}
+}
+
+// agentProviders returns the providers to try for a message, in order: its own, then the fallbacks
+func (deps *MessageHandlerDependencies) agentProviders(provider string) []AgentProvider {
+	providers := make([]AgentProvider, 0, 1+len(deps.AgentFallbacks))
+	providers = append(providers, AgentProvider{Name: provider, Service: deps.GoogleAgentService})
+	return append(providers, deps.AgentFallbacks...)
+}
+
+// allCircuitsOpen reports whether none of the providers would currently accept a call
+func allCircuitsOpen(providers []AgentProvider) bool {
+	for _, provider := range providers {
+		if !provider.Service.IsCircuitOpen() {
+			return false
+		}
+	}
+	return true
+}
+
+// agentRequest is what is sent to the agent for a message
+type agentRequest struct {
+	message      string // As traced; the user's text without the tenant preamble
+	agentMessage string // As sent, with the preamble
+	imageURLs    []string
+	documents    []services.DocumentInput // Forwarded to document-capable agents
+	model        string
+	dryRun       bool
+}
+
+// callAgentProvider gets the user's thread on the provider and sends it the message, returning the response
+// and the thread ID. Errors are categorized agent call errors, ErrTaskCancelled, or wrap
+// services.ErrCircuitOpen when the provider's circuit is open.
+func callAgentProvider(ctx context.Context, provider AgentProvider, msg *models.QueueMessage, deps *MessageHandlerDependencies, logger *logrus.Entry, request agentRequest) (*models.AgentResponse, string, error) {
+	logger = logger.WithField("provider", provider.Name)
+	agent := provider.Service
+
+	if !request.dryRun && agent.IsCircuitOpen() {
+		logger.WithField("circuit_state", agent.CircuitState().String()).Warn("Agent provider circuit open, skipping it")
+		return nil, "", fmt.Errorf("agent provider %s unavailable: %w", provider.Name, services.ErrCircuitOpen)
+	}
+
+	// Trace thread creation step
+	var threadCtx context.Context
+	var threadSpan trace.Span
+	if deps.OTelWorkerWrapper != nil {
+		threadCtx, threadSpan = deps.OTelWorkerWrapper.StartSpan(ctx, "thread_management",
+			attribute.String("user.number", msg.UserNumber),
+			attribute.String("correlation.id", msg.CorrelationID),
+			attribute.String("agent.provider", provider.Name))
+		defer threadSpan.End()
+	} else {
+		threadCtx = ctx
+	}
+
+	// Get or create thread for user (thread ID corresponds to agent ID in Python logic)
+	threadCtx, cancelThread := withOperationTimeout(threadCtx, deps.Config.GoogleAgentEngine.ThreadTimeout)
+	threadID, err := agent.GetOrCreateThread(threadCtx, msg.UserNumber)
+	cancelThread()
+	if err != nil {
+		logger.WithError(err).Error("Failed to get or create thread")
+		if deps.OTelWorkerWrapper != nil && threadSpan != nil {
+			threadSpan.SetAttributes(
+				attribute.String("thread.result", "error"),
+				attribute.String("thread.error", err.Error()))
+		}
+		return nil, "", services.NewAgentCallError("failed to get thread", err)
+	}
+
+	if deps.OTelWorkerWrapper != nil && threadSpan != nil {
+		threadSpan.SetAttributes(
+			attribute.String("thread.result", "success"),
+			attribute.String("thread.id", threadID))
+	}
+
+	logger.WithField("thread_id", threadID).Debug("Using thread for conversation")
+
+	// Trace Google Agent Engine call
+	var agentCtx context.Context
+	var agentSpan trace.Span
+	if deps.OTelWorkerWrapper != nil {
+		agentCtx, agentSpan = deps.OTelWorkerWrapper.StartSpan(ctx, "google_agent_engine_call",
+			attribute.String("thread.id", threadID),
+			attribute.String("correlation.id", msg.CorrelationID),
+			attribute.String("message.content", request.message),
+			attribute.Int("message.length", len(request.message)),
+			attribute.String("agent.model", request.model),
+			attribute.String("agent.provider", provider.Name))
+		defer agentSpan.End()
+	} else {
+		agentCtx = ctx
+	}
+
+	// Abort before calling the agent if the task was cancelled while queued or transcribing
+	if isTaskCancelled(ctx, deps, msg.ID) {
+		logger.Info("Task cancelled before agent call")
+		return nil, "", ErrTaskCancelled
+	}
+
+	// Bound the agent call independently of the time already spent on transcription and thread setup
+	agentCtx, cancelSend := withOperationTimeout(agentCtx, deps.Config.GoogleAgentEngine.SendTimeout)
+	defer cancelSend()
+
+	// Cancel the agent call as soon as the task's cancellation flag is set
+	agentCtx, cancelAgent := context.WithCancel(agentCtx)
+	defer cancelAgent()
+	if interval := deps.Config.Redis.TaskCancelPollInterval; interval > 0 {
+		go watchTaskCancellation(agentCtx, deps, msg.ID, interval, cancelAgent)
+	}
+
+	// Send message to Google Agent Engine
+	// The Google Agent Engine automatically handles previous message context via thread ID
+	var agentResponse *models.AgentResponse
+	if request.dryRun {
+		logger.Info("Dry run enabled, skipping agent call and using canned response")
+		agentResponse, err = dryRunAgentResponse(threadID, request.agentMessage)
+	} else {
+		agentResponse, err = agent.SendMultimodalMessage(agentCtx, threadID, request.agentMessage, request.imageURLs, services.WithModel(request.model), services.WithDocuments(request.documents...))
+	}
+
+	// Discard the response (or the error caused by aborting the call) if the task was cancelled meanwhile
+	if isTaskCancelled(ctx, deps, msg.ID) {
+		logger.Info("Task cancelled during agent call")
+		if deps.OTelWorkerWrapper != nil && agentSpan != nil {
+			agentSpan.SetAttributes(attribute.String("agent.result", "cancelled"))
+		}
+		return nil, "", ErrTaskCancelled
+	}
+
+	if err != nil {
+		logger.WithError(err).Error("Failed to send message to Google Agent Engine")
+		if deps.OTelWorkerWrapper != nil && agentSpan != nil {
+			agentSpan.SetAttributes(
+				attribute.String("agent.result", "error"),
+				attribute.String("agent.error", err.Error()))
+		}
+		// The breaker may have opened (or be probing) between the check above and this call
+		if errors.Is(err, services.ErrCircuitOpen) {
+			return nil, "", err
+		}
+		return nil, "", services.NewAgentCallError("failed to get AI response", err)
+	}
+
+	if deps.OTelWorkerWrapper != nil && agentSpan != nil {
+		agentSpan.SetAttributes(
+			attribute.String("agent.result", "success"),
+			attribute.Int("agent.response_length", len(agentResponse.Content)))
+	}
+	return agentResponse, threadID, nil
+}
@@ -0,0 +1,51 @@
+package workers
+
+import (
+	"path"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/services"
+)
+
+func TestAudioURLDetectionAgrees(t *testing.T) {
+	urls := []string{
+		"https://whatsapp.dados.rio/media/voice.ogg",
+		"https://whatsapp.dados.rio/media/voice.OPUS",
+		"https://whatsapp.dados.rio/media/song.aac",
+		"https://whatsapp.dados.rio/media/song.wma",
+		"https://whatsapp.dados.rio/media/clip.webm",
+		"https://whatsapp.dados.rio/media/photo.jpg",
+		"https://whatsapp.dados.rio/media/report.pdf",
+		"olá, tudo bem?",
+		"",
+	}
+	configs := map[string]*config.Config{
+		"default":    {},
+		"configured": {Transcribe: config.TranscribeConfig{AudioExtensions: "ogg,aac"}},
+	}
+
+	for name, cfg := range configs {
+		adapter := NewTranscribeServiceAdapter(nil, cfg)
+		noop := NewNoopTranscribeBackend(cfg)
+		supported := cfg.GetTranscribeSupportedFormats()
+
+		for _, url := range urls {
+			want := services.IsAudioURL(cfg, url)
+			if got := adapter.IsAudioURL(url); got != want {
+				t.Errorf("%s: adapter.IsAudioURL(%q) = %v, standalone = %v", name, url, got, want)
+			}
+			if got := noop.IsAudioURL(url); got != want {
+				t.Errorf("%s: noop.IsAudioURL(%q) = %v, standalone = %v", name, url, got, want)
+			}
+
+			// Every URL detected as audio must be in a format transcription accepts
+			format := strings.ToLower(strings.TrimPrefix(path.Ext(url), "."))
+			if want && !slices.Contains(supported, format) {
+				t.Errorf("%s: %q is detected as audio but %q is not a supported transcription format", name, url, format)
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/services"
+)
+
+// newAuditEvent fills in who sent the message and to which agent
+func newAuditEvent(msg *models.QueueMessage, deps *MessageHandlerDependencies, event string) services.AuditEvent {
+	return services.AuditEvent{
+		Event:         event,
+		MessageID:     msg.ID,
+		CorrelationID: msg.CorrelationID,
+		TenantID:      msg.TenantID,
+		UserNumber:    msg.UserNumber,
+		Provider:      msg.Provider,
+		AgentID:       deps.resolveAgentID(msg),
+	}
+}
+
+// recordAudit writes an audit event when audit logging is enabled. A failed write is logged and never fails
+// the message.
+func recordAudit(ctx context.Context, deps *MessageHandlerDependencies, event services.AuditEvent) {
+	if deps.AuditLogger == nil {
+		return
+	}
+	if err := deps.AuditLogger.Record(ctx, event); err != nil {
+		deps.Logger.WithError(err).WithFields(logrus.Fields{
+			"message_id":  event.MessageID,
+			"audit_event": event.Event,
+		}).Error("Failed to write audit event")
+	}
+}
+
+// auditProcessed records the outcome of a message that produced a result
+func auditProcessed(ctx context.Context, msg *models.QueueMessage, deps *MessageHandlerDependencies, data models.ProcessedMessageData, startedAt time.Time) {
+	event := newAuditEvent(msg, deps, services.AuditEventProcessed)
+	event.ThreadID = data.ThreadID
+	event.Model = data.Model
+	event.Status = string(processedTaskStatus(data))
+	event.FallbackReason = data.FallbackReason
+	event.DurationMs = time.Since(startedAt).Milliseconds()
+	recordAudit(ctx, deps, event)
+}
+
+// auditProcessingError records why a message produced no result
+func auditProcessingError(ctx context.Context, msg *models.QueueMessage, deps *MessageHandlerDependencies, err error, startedAt time.Time) {
+	var event services.AuditEvent
+	var rateLimitErr *services.RateLimitError
+	switch {
+	case errors.Is(err, ErrMessageCoalesced):
+		event = newAuditEvent(msg, deps, services.AuditEventCoalesced)
+	case errors.Is(err, ErrTaskCancelled):
+		event = newAuditEvent(msg, deps, services.AuditEventCancelled)
+	default:
+		event = newAuditEvent(msg, deps, services.AuditEventFailed)
+		event.ErrorCategory = services.ErrorCategory(err)
+		event.Retriable = errors.As(err, &rateLimitErr) || shouldRetryError(err)
+	}
+	event.DurationMs = time.Since(startedAt).Milliseconds()
+	recordAudit(ctx, deps, event)
+}
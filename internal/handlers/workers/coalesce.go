@@ -0,0 +1,239 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/services"
+)
+
+// ErrMessageCoalesced signals that the message was buffered and is answered by another task's batch
+var ErrMessageCoalesced = errors.New("message coalesced into another task")
+
+// coalescedEntry is a message waiting in a user's coalescing buffer
+type coalescedEntry struct {
+	TaskID    string    `json:"task_id"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"` // When the message was enqueued; orders entries put back after a failed batch
+}
+
+// coalescedBatch holds the messages drained from a user's buffer and the tasks they belong to
+type coalescedBatch struct {
+	TaskIDs []string
+	Message string
+	entries []coalescedEntry // Drained entries, put back in the buffer when the batch is retried
+}
+
+// retriesLeftKey marks a context whose message is redelivered if processing fails with a retriable error
+type retriesLeftKey struct{}
+
+// withRetriesLeft records that a retriable failure of the message will be retried
+func withRetriesLeft(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retriesLeftKey{}, true)
+}
+
+// willBeRedelivered reports whether the message being processed goes back to the queue after failing
+// with err: rate-limited and deferred messages always do, retriable errors while retries are left
+func willBeRedelivered(ctx context.Context, err error) bool {
+	var rateLimitErr *services.RateLimitError
+	var retryLater *services.RetryLaterError
+	if errors.As(err, &rateLimitErr) || errors.As(err, &retryLater) {
+		return true
+	}
+	retriesLeft, _ := ctx.Value(retriesLeftKey{}).(bool)
+	return retriesLeft && shouldRetryError(err)
+}
+
+// includes reports whether the task's message is part of the batch
+func (b *coalescedBatch) includes(taskID string) bool {
+	for _, id := range b.TaskIDs {
+		if id == taskID {
+			return true
+		}
+	}
+	return false
+}
+
+// coalesceUserMessage buffers the message in the user's coalescing buffer. The first task to arrive
+// becomes the batch leader: it waits for the debounce window, drains the buffer and returns the batch
+// to send as a single agent call. Followers (and leaders that find the buffer already drained) get a
+// nil batch, since their message is answered by another task. Redis failures fall back to sending
+// the message on its own.
+func coalesceUserMessage(ctx context.Context, msg *models.QueueMessage, message string, deps *MessageHandlerDependencies, logger *logrus.Entry) *coalescedBatch {
+	window := deps.Config.RabbitMQ.CoalesceWindow
+	bufferKey := "coalesce:buffer:" + msg.UserNumber
+	leaderKey := "coalesce:leader:" + msg.UserNumber
+	single := &coalescedBatch{TaskIDs: []string{msg.ID}, Message: message}
+
+	enqueuedAt := msg.Timestamp
+	if enqueuedAt.IsZero() {
+		enqueuedAt = time.Now()
+	}
+	entry, err := json.Marshal(coalescedEntry{TaskID: msg.ID, Message: message, Timestamp: enqueuedAt})
+	if err != nil {
+		logger.WithError(err).Warn("Failed to encode message for coalescing, sending it on its own")
+		return single
+	}
+
+	if err := deps.RedisService.AppendToList(ctx, bufferKey, string(entry), deps.Config.Redis.TaskStatusTTL); err != nil {
+		logger.WithError(err).Warn("Failed to buffer message for coalescing, sending it on its own")
+		return single
+	}
+
+	// Leader lock outlives the window so a crashed leader doesn't block the user forever
+	isLeader, err := deps.RedisService.SetNX(ctx, leaderKey, msg.ID, 2*window+5*time.Second)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to acquire coalescing leadership, sending message on its own")
+		return single
+	}
+	if !isLeader {
+		logger.Info("Message buffered for coalescing with a batch already in progress")
+		return nil
+	}
+
+	logger.WithField("window", window).Debug("Coalescing leader waiting for more messages")
+	select {
+	case <-ctx.Done():
+	case <-time.After(window):
+	}
+
+	// Release leadership before draining so messages arriving from now on start a new batch
+	_ = deps.RedisService.Delete(ctx, leaderKey)
+
+	rawEntries, err := deps.RedisService.DrainList(ctx, bufferKey)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to drain coalescing buffer, sending message on its own")
+		return single
+	}
+
+	batch := &coalescedBatch{}
+	for _, raw := range rawEntries {
+		var e coalescedEntry
+		if err := json.Unmarshal([]byte(raw), &e); err != nil {
+			logger.WithError(err).Warn("Skipping malformed coalescing buffer entry")
+			continue
+		}
+		batch.entries = append(batch.entries, e)
+	}
+
+	if len(batch.entries) == 0 {
+		// A previous leader already drained (and answered) this message
+		return nil
+	}
+
+	// Entries put back by a failed batch sit behind newer ones; restore the order the user sent them in
+	sort.SliceStable(batch.entries, func(i, j int) bool {
+		return batch.entries[i].Timestamp.Before(batch.entries[j].Timestamp)
+	})
+	messages := make([]string, 0, len(batch.entries))
+	for _, e := range batch.entries {
+		batch.TaskIDs = append(batch.TaskIDs, e.TaskID)
+		messages = append(messages, e.Message)
+	}
+	batch.Message = strings.Join(messages, "\n")
+
+	logger.WithFields(logrus.Fields{
+		"coalesced_count": len(batch.TaskIDs),
+		"task_ids":        batch.TaskIDs,
+	}).Info("Coalesced buffered messages into a single agent call")
+
+	return batch
+}
+
+// finalizeCoalescedTasks stores the shared outcome of a batch for every coalesced task other than the
+// handler's own message, which it finalizes itself. Coalesced tasks all belong to that message's user.
+// When the handler's message will be redelivered, the other tasks' messages go back in the buffer
+// instead, so the retried batch still answers them.
+func finalizeCoalescedTasks(ctx context.Context, deps *MessageHandlerDependencies, own *models.QueueMessage, batch *coalescedBatch, processedData models.ProcessedMessageData, processErr error, logger *logrus.Entry) {
+	if processErr != nil && willBeRedelivered(ctx, processErr) {
+		restoreCoalescedEntries(ctx, deps, own, batch, processErr, logger)
+		return
+	}
+
+	status := processedTaskStatus(processedData)
+
+	for _, taskID := range batch.TaskIDs {
+		if taskID == own.ID {
+			continue
+		}
+
+		taskLogger := logger.WithField("coalesced_task_id", taskID)
+
+		if processErr != nil {
+			failCoalescedTask(ctx, deps, own, taskID, processErr, taskLogger)
+			continue
+		}
+
+		taskResponse, err := responseForTask(processedData, taskID)
+		if err != nil {
+			taskLogger.WithError(err).Error("Failed to marshal coalesced task result")
+			continue
+		}
+		// The handler's own task records the batch's answer as the user's latest result
+		if err := deps.RedisService.CompleteTask(ctx, taskID, "", taskResponse, string(status), deps.Config.Redis.TaskResultTTL, deps.Config.GetTaskStatusTTL(string(status))); err != nil {
+			taskLogger.WithError(err).Error("Failed to store coalesced task result and status")
+		}
+		notifyTaskEvent(deps, taskID, own.UserNumber, status, nil)
+
+		if deps.CallbackService != nil {
+			callbackURL, err := deps.RedisService.GetCallbackURL(ctx, taskID)
+			if err == nil && callbackURL != "" {
+				go executeCallback(context.Background(), deps, taskID, callbackURL, taskResponse, status, nil, taskLogger)
+			}
+		}
+	}
+}
+
+// restoreCoalescedEntries puts the batch's other messages back in the user's buffer for the handler's
+// redelivered message to drain again; its own message is buffered anew when it is redelivered
+func restoreCoalescedEntries(ctx context.Context, deps *MessageHandlerDependencies, own *models.QueueMessage, batch *coalescedBatch, processErr error, logger *logrus.Entry) {
+	bufferKey := "coalesce:buffer:" + own.UserNumber
+	restored := 0
+	for _, e := range batch.entries {
+		if e.TaskID == own.ID {
+			continue
+		}
+		taskLogger := logger.WithField("coalesced_task_id", e.TaskID)
+		entry, err := json.Marshal(e)
+		if err != nil {
+			taskLogger.WithError(err).Error("Failed to encode coalesced message for the retried batch")
+			continue
+		}
+		if err := deps.RedisService.AppendToList(ctx, bufferKey, string(entry), deps.Config.Redis.TaskStatusTTL); err != nil {
+			taskLogger.WithError(err).Error("Failed to put coalesced message back for the retried batch")
+			failCoalescedTask(ctx, deps, own, e.TaskID, processErr, taskLogger)
+			continue
+		}
+		restored++
+	}
+	if restored > 0 {
+		logger.WithField("restored_count", restored).Warn("Batch failed with a retriable error, coalesced messages put back for the retry")
+	}
+}
+
+// failCoalescedTask marks a coalesced task failed with the batch's error
+func failCoalescedTask(ctx context.Context, deps *MessageHandlerDependencies, own *models.QueueMessage, taskID string, processErr error, logger *logrus.Entry) {
+	_ = storeTaskError(ctx, deps, taskID, fmt.Errorf("coalesced batch failed: %w", processErr))
+	if err := deps.RedisService.SetTaskStatus(ctx, taskID, string(models.TaskStatusFailed), deps.Config.GetTaskStatusTTL(string(models.TaskStatusFailed))); err != nil {
+		logger.WithError(err).Error("Failed to mark coalesced task as failed")
+	}
+	notifyTaskEvent(deps, taskID, own.UserNumber, models.TaskStatusFailed, processErr)
+}
+
+// responseForTask marshals the shared processed result with the message ID of a coalesced task
+func responseForTask(processedData models.ProcessedMessageData, taskID string) (string, error) {
+	processedData.MessageID = taskID
+	processedBytes, err := json.Marshal(processedData)
+	if err != nil {
+		return "", err
+	}
+	return string(processedBytes), nil
+}
@@ -0,0 +1,44 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/services"
+)
+
+func TestWillBeRedelivered(t *testing.T) {
+	rateLimited := fmt.Errorf("failed to get AI response: %w", &services.RateLimitError{RetryAfter: time.Second})
+	deferred := services.NewRetryLaterError(time.Second, errors.New("redis unavailable"))
+	timeout := services.NewProcessingError(services.ErrAgentTimeout, "agent call timed out", errors.New("context deadline exceeded"))
+	invalid := services.NewProcessingError(services.ErrValidation, "invalid message content", errors.New("empty"))
+
+	tests := []struct {
+		name        string
+		err         error
+		retriesLeft bool
+		want        bool
+	}{
+		{"rate limited, last attempt", rateLimited, false, true},
+		{"deferred, last attempt", deferred, false, true},
+		{"retriable with retries left", timeout, true, true},
+		{"retriable on the last attempt", timeout, false, false},
+		{"permanent with retries left", invalid, true, false},
+		{"permanent on the last attempt", invalid, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.retriesLeft {
+				ctx = withRetriesLeft(ctx)
+			}
+			if got := willBeRedelivered(ctx, tt.err); got != tt.want {
+				t.Errorf("willBeRedelivered(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package workers
+
+import (
+	"math"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+)
+
+// unknownModel names tokens reported without a model when they can't be priced
+const unknownModel = "unknown"
+
+// addCostEstimate sets usage_statistics "estimated_cost" from the tokens each model used and
+// AGENT_MODEL_PRICES, leaving usage untouched when no prices are configured. Models missing from the
+// table are estimated at zero and logged.
+func addCostEstimate(usage map[string]interface{}, messages []interface{}, deps *MessageHandlerDependencies, logger *logrus.Entry) {
+	// Validated at startup
+	prices, _ := deps.Config.GetModelPrices()
+	if len(prices) == 0 {
+		return
+	}
+
+	cost, unpriced := estimateCost(messages, prices)
+	usage["estimated_cost"] = cost
+	if len(unpriced) > 0 {
+		logger.WithField("models", unpriced).Warn("No price configured in AGENT_MODEL_PRICES, estimating their tokens at zero cost")
+	}
+}
+
+// estimateCost prices the prompt and completion tokens of each message at its model's rates, returning the
+// total and the distinct models that have no price
+func estimateCost(messages []interface{}, prices map[string]config.ModelPrice) (float64, []string) {
+	var cost float64
+	var unpriced []string
+	seen := make(map[string]bool)
+
+	for _, msg := range messages {
+		msgMap, ok := msg.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		usageMap, ok := msgMap["usage_metadata"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		prompt := tokenCount(usageMap["prompt_token_count"])
+		completion := tokenCount(usageMap["candidates_token_count"])
+		if prompt == 0 && completion == 0 {
+			continue
+		}
+
+		model, _ := msgMap["model_name"].(string)
+		if model == "" {
+			model = unknownModel
+		}
+		price, ok := prices[model]
+		if !ok {
+			if !seen[model] {
+				seen[model] = true
+				unpriced = append(unpriced, model)
+			}
+			continue
+		}
+		cost += (float64(prompt)*price.InputPerMillion + float64(completion)*price.OutputPerMillion) / 1e6
+	}
+
+	// Drop float noise below a millionth of a cent
+	return math.Round(cost*1e8) / 1e8, unpriced
+}
@@ -0,0 +1,62 @@
+package workers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+)
+
+// dryRunModelName identifies canned responses in the transformed output
+const dryRunModelName = "dry-run"
+
+// isDryRun reports whether the message should skip the real agent call, either because the
+// message requests it or because dry-run mode is enabled for the whole deployment
+func isDryRun(msg *models.QueueMessage, deps *MessageHandlerDependencies) bool {
+	return msg.DryRun || deps.Config.GoogleAgentEngine.DryRun
+}
+
+// dryRunAgentResponse builds a canned agent response shaped like a real Google Agent Engine reply,
+// so parsing, transformation and formatting run exactly as they would for the real agent
+func dryRunAgentResponse(threadID string, message string) (*models.AgentResponse, error) {
+	usage := map[string]interface{}{
+		"input_tokens":  len(message),
+		"output_tokens": 0,
+		"total_tokens":  len(message),
+	}
+
+	raw := map[string]interface{}{
+		"output": map[string]interface{}{
+			"messages": []interface{}{
+				map[string]interface{}{
+					"id":      "dry-run-" + generateStepID(),
+					"type":    "human",
+					"content": message,
+				},
+				map[string]interface{}{
+					"id":      "dry-run-" + generateStepID(),
+					"type":    "ai",
+					"content": fmt.Sprintf("[dry run] Mensagem recebida (%d caracteres).", len(message)),
+					"response_metadata": map[string]interface{}{
+						"model_name":     dryRunModelName,
+						"finish_reason":  "STOP",
+						"usage_metadata": usage,
+					},
+				},
+			},
+		},
+	}
+
+	content, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build dry run response: %w", err)
+	}
+
+	return &models.AgentResponse{
+		Content:  string(content),
+		ThreadID: threadID,
+		Metadata: map[string]interface{}{
+			"dry_run": true,
+		},
+	}, nil
+}
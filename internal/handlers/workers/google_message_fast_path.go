@@ -0,0 +1,276 @@
+package workers
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// simpleExchangeMessage holds the fields of a human or ai message read by the fast path, in the form the
+// generic transformer copies them to the transformed message (nil where it would find nothing)
+type simpleExchangeMessage struct {
+	id      interface{}
+	name    interface{}
+	content string
+	extra   map[string]interface{}
+
+	modelName     interface{}
+	finishReason  interface{}
+	avgLogprobs   interface{}
+	usageMetadata interface{} // Already in the transformed usage_metadata shape
+}
+
+// simpleExchangeResponse is the envelope of a Google Agent Engine reply. Messages stay raw per field so
+// every message key is seen exactly as sent, including the ones passed through as "extra".
+type simpleExchangeResponse struct {
+	Output *struct {
+		Messages []map[string]json.RawMessage `json:"messages"`
+	} `json:"output"`
+}
+
+// simpleResponseMetadata holds the response_metadata fields read by the transformation
+type simpleResponseMetadata struct {
+	ModelName     *string  `json:"model_name"`
+	FinishReason  *string  `json:"finish_reason"`
+	AvgLogprobs   *float64 `json:"avg_logprobs"`
+	UsageMetadata *struct {
+		InputTokens        *float64        `json:"input_tokens"`
+		OutputTokens       *float64        `json:"output_tokens"`
+		TotalTokens        *float64        `json:"total_tokens"`
+		OutputTokenDetails json.RawMessage `json:"output_token_details"`
+		InputTokenDetails  json.RawMessage `json:"input_token_details"`
+	} `json:"usage_metadata"`
+}
+
+// transformSimpleExchange is the fast path of GoogleMessageTransformer for the most common reply: a bare
+// JSON object whose output.messages holds exactly one human message and one ai reply, both with string
+// content and no tool calls. The response is decoded once into typed structs, skipping what is never read
+// (response_metadata carries safety ratings and other data), instead of into a generic map of the whole
+// response. The result is the same as transformGoogleAgentMessages'; false is returned for any other shape
+// or unexpected value so the caller uses the generic transformer. Unlike the generic path, names of the
+// envelope and response_metadata fields are matched case-insensitively (encoding/json struct semantics).
+func transformSimpleExchange(rawResponse string) ([]interface{}, bool) {
+	trimmed := strings.TrimSpace(rawResponse)
+	if !strings.HasPrefix(trimmed, "{") {
+		return nil, false
+	}
+
+	var response simpleExchangeResponse
+	if err := json.Unmarshal([]byte(trimmed), &response); err != nil {
+		return nil, false
+	}
+	if response.Output == nil || len(response.Output.Messages) != 2 {
+		return nil, false
+	}
+	messages := response.Output.Messages
+
+	human, ok := decodeSimpleExchangeMessage(messages[0], "human")
+	if !ok {
+		return nil, false
+	}
+	reply, ok := decodeSimpleExchangeMessage(messages[1], "ai")
+	if !ok || !isRawEmptyList(messages[1]["tool_calls"]) {
+		return nil, false
+	}
+
+	transformed := []interface{}{
+		human.transformed("user_message"),
+		reply.transformed("assistant_message"),
+	}
+	return append(transformed, newUsageStatistics(transformed)), true
+}
+
+// decodeSimpleExchangeMessage reads a message of the given type, reporting false when a field the
+// transformation reads has a type the fast path doesn't handle
+func decodeSimpleExchangeMessage(fields map[string]json.RawMessage, wantType string) (simpleExchangeMessage, bool) {
+	var msg simpleExchangeMessage
+	if msgType, ok := rawString(fields["type"]); !ok || msgType != wantType {
+		return msg, false
+	}
+
+	var ok bool
+	if msg.content, ok = rawString(fields["content"]); !ok {
+		return msg, false
+	}
+	if msg.id, ok = rawNullableString(fields["id"]); !ok {
+		return msg, false
+	}
+	if msg.name, ok = rawNullableString(fields["name"]); !ok {
+		return msg, false
+	}
+
+	// Like the generic transformer, metadata that isn't an object is ignored
+	if raw := fields["response_metadata"]; isRawObject(raw) {
+		var metadata simpleResponseMetadata
+		if err := json.Unmarshal(raw, &metadata); err != nil {
+			return msg, false
+		}
+		msg.setMetadata(&metadata)
+	}
+
+	for key, raw := range fields {
+		if mappedMessageFields[key] {
+			continue
+		}
+		value, ok := rawValue(raw)
+		if !ok {
+			return msg, false
+		}
+		if msg.extra == nil {
+			msg.extra = make(map[string]interface{})
+		}
+		msg.extra[key] = value
+	}
+
+	return msg, true
+}
+
+// setMetadata copies the response metadata in the form the generic transformer produces (see
+// extractUsageMetadata for the usage_metadata shape)
+func (m *simpleExchangeMessage) setMetadata(metadata *simpleResponseMetadata) {
+	if metadata.ModelName != nil {
+		m.modelName = *metadata.ModelName
+	}
+	if metadata.FinishReason != nil {
+		m.finishReason = *metadata.FinishReason
+	}
+	if metadata.AvgLogprobs != nil {
+		m.avgLogprobs = *metadata.AvgLogprobs
+	}
+
+	usage := metadata.UsageMetadata
+	if usage == nil {
+		return
+	}
+	result := map[string]interface{}{
+		"prompt_token_count":     optionalCount(usage.InputTokens),
+		"candidates_token_count": optionalCount(usage.OutputTokens),
+		"total_token_count":      optionalCount(usage.TotalTokens),
+	}
+	if count, ok := rawDetailCount(usage.OutputTokenDetails, "reasoning"); ok {
+		result["thoughts_token_count"] = count
+	}
+	if count, ok := rawDetailCount(usage.InputTokenDetails, "cache_read"); ok {
+		result["cached_content_token_count"] = count
+	}
+	m.usageMetadata = result
+}
+
+// optionalCount returns the count, or nil when it is missing
+func optionalCount(count *float64) interface{} {
+	if count == nil {
+		return nil
+	}
+	return *count
+}
+
+// rawDetailCount reads a numeric count from a token details object, reporting false when there is none
+func rawDetailCount(raw json.RawMessage, key string) (float64, bool) {
+	if !isRawObject(raw) {
+		return 0, false
+	}
+	var details map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &details); err != nil {
+		return 0, false
+	}
+	value := details[key]
+	if len(value) == 0 || (value[0] != '-' && (value[0] < '0' || value[0] > '9')) {
+		return 0, false
+	}
+	count, err := strconv.ParseFloat(string(value), 64)
+	return count, err == nil
+}
+
+// transformed builds the gateway message for the given message type, as transformGoogleAgentMessages does
+func (m simpleExchangeMessage) transformed(messageType string) map[string]interface{} {
+	transformedMsg := map[string]interface{}{
+		"id":                      m.id,
+		"date":                    nil,
+		"session_id":              nil,
+		"time_since_last_message": nil,
+		"name":                    m.name,
+		"otid":                    m.id,
+		"sender_id":               nil,
+		"step_id":                 "step-" + generateStepID(),
+		"is_err":                  nil,
+		"model_name":              m.modelName,
+		"finish_reason":           m.finishReason,
+		"avg_logprobs":            m.avgLogprobs,
+		"usage_metadata":          m.usageMetadata,
+		"message_type":            messageType,
+		"content":                 m.content,
+	}
+	if len(m.extra) > 0 {
+		transformedMsg["extra"] = m.extra
+	}
+	return transformedMsg
+}
+
+var rawJSONNull = []byte("null")
+
+// isRawObject reports whether a raw JSON value is an object
+func isRawObject(raw json.RawMessage) bool {
+	return len(raw) > 0 && raw[0] == '{'
+}
+
+// isRawEmptyList reports whether a raw JSON value is missing, null or an empty array
+func isRawEmptyList(raw json.RawMessage) bool {
+	if len(raw) == 0 || bytes.Equal(raw, rawJSONNull) {
+		return true
+	}
+	var list []json.RawMessage
+	return raw[0] == '[' && json.Unmarshal(raw, &list) == nil && len(list) == 0
+}
+
+// rawValue decodes any JSON value as encoding/json would into an interface{}, without a decoder pass for the
+// empty objects, empty arrays and literals that make up most passed-through fields
+func rawValue(raw json.RawMessage) (interface{}, bool) {
+	switch string(raw) {
+	case "{}":
+		return map[string]interface{}{}, true
+	case "[]":
+		return []interface{}{}, true
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	case "null":
+		return nil, true
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// rawString decodes a JSON string, reporting false for any other value
+func rawString(raw json.RawMessage) (string, bool) {
+	if len(raw) == 0 || raw[0] != '"' {
+		return "", false
+	}
+	// Most strings have no escapes and are used as-is
+	if len(raw) >= 2 && raw[len(raw)-1] == '"' && bytes.IndexByte(raw, '\\') < 0 && utf8.Valid(raw) {
+		return string(raw[1 : len(raw)-1]), true
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", false
+	}
+	return value, true
+}
+
+// rawNullableString decodes a JSON string as a string and a missing or null value as nil, reporting false
+// for any other value
+func rawNullableString(raw json.RawMessage) (interface{}, bool) {
+	if len(raw) == 0 || bytes.Equal(raw, rawJSONNull) {
+		return nil, true
+	}
+	value, ok := rawString(raw)
+	if !ok {
+		return nil, false
+	}
+	return value, true
+}
@@ -0,0 +1,184 @@
+package workers
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// geminiReply is a realistic single-exchange Google Agent Engine reply: safety ratings, token details and
+// metadata fields the transformation passes through
+const geminiReply = `{
+  "output": {
+    "messages": [
+      {
+        "content": "Qual o horário de funcionamento da Clínica da Família em Botafogo?",
+        "additional_kwargs": {},
+        "response_metadata": {},
+        "type": "human",
+        "name": null,
+        "id": "6f1d3c2a-8b4e-4f0a-9c1d-2e3f4a5b6c7d",
+        "example": false
+      },
+      {
+        "content": "A Clínica da Família funciona de segunda a sexta, das 7h às 18h, e aos sábados das 8h às 12h.\n\nPosso ajudar com mais alguma coisa?",
+        "additional_kwargs": {},
+        "response_metadata": {
+          "is_blocked": false,
+          "safety_ratings": [
+            {"category": "HARM_CATEGORY_HATE_SPEECH", "probability_label": "NEGLIGIBLE", "probability_score": 0.0213, "blocked": false, "severity": "HARM_SEVERITY_NEGLIGIBLE", "severity_score": 0.0156},
+            {"category": "HARM_CATEGORY_DANGEROUS_CONTENT", "probability_label": "NEGLIGIBLE", "probability_score": 0.0402, "blocked": false, "severity": "HARM_SEVERITY_NEGLIGIBLE", "severity_score": 0.0288},
+            {"category": "HARM_CATEGORY_HARASSMENT", "probability_label": "NEGLIGIBLE", "probability_score": 0.0311, "blocked": false, "severity": "HARM_SEVERITY_NEGLIGIBLE", "severity_score": 0.0197},
+            {"category": "HARM_CATEGORY_SEXUALLY_EXPLICIT", "probability_label": "NEGLIGIBLE", "probability_score": 0.0154, "blocked": false, "severity": "HARM_SEVERITY_NEGLIGIBLE", "severity_score": 0.0102}
+          ],
+          "usage_metadata": {
+            "input_tokens": 1520,
+            "output_tokens": 87,
+            "total_tokens": 1607,
+            "input_token_details": {"cache_read": 1024},
+            "output_token_details": {"reasoning": 40}
+          },
+          "finish_reason": "STOP",
+          "model_name": "gemini-2.5-flash",
+          "avg_logprobs": -0.1834
+        },
+        "type": "ai",
+        "name": null,
+        "id": "run--4b1c7e9a-2d3f-4e5a-8b6c-7d8e9f0a1b2c-0",
+        "example": false,
+        "tool_calls": [],
+        "invalid_tool_calls": [],
+        "usage_metadata": {"input_tokens": 1520, "output_tokens": 87, "total_tokens": 1607}
+      }
+    ]
+  }
+}`
+
+// transformGeneric runs GoogleMessageTransformer's generic path, bypassing the fast path
+func transformGeneric(t testing.TB, rawResponse string, logger *logrus.Entry) []interface{} {
+	cleaned, err := extractJSONObject(rawResponse)
+	if err != nil {
+		t.Fatalf("extract JSON: %v", err)
+	}
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(cleaned), &parsed); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	output := parsed["output"].(map[string]interface{})
+	return transformGoogleAgentMessages(logger.Logger, output["messages"])
+}
+
+// withoutVolatileFields drops the randomly generated step IDs and the processing timestamp so two
+// transformations of the same response can be compared
+func withoutVolatileFields(messages []interface{}) []interface{} {
+	for _, msg := range messages {
+		msgMap := msg.(map[string]interface{})
+		delete(msgMap, "step_id")
+		delete(msgMap, "processed_at")
+	}
+	return messages
+}
+
+func TestTransformSimpleExchangeMatchesGenericTransformer(t *testing.T) {
+	logger := logrus.NewEntry(logrus.New())
+	tests := map[string]string{
+		"gemini reply": geminiReply,
+		"no metadata": `{"output": {"messages": [
+			{"type": "human", "content": "Oi"},
+			{"type": "ai", "content": "Olá!", "id": "run-1"}]}}`,
+		"escaped content": `{"output": {"messages": [
+			{"type": "human", "content": "Diga \"olá\"\\n"},
+			{"type": "ai", "content": "É \"olá\" 😀", "tool_calls": null}]}}`,
+		"partial usage": `{"output": {"messages": [
+			{"type": "human", "content": "Oi"},
+			{"type": "ai", "content": "Olá!", "response_metadata": {
+				"usage_metadata": {"input_tokens": 10, "output_token_details": {"reasoning": "n/a"}}}}]}}`,
+		"non-object metadata": `{"output": {"messages": [
+			{"type": "human", "content": "Oi", "response_metadata": []},
+			{"type": "ai", "content": "Olá!", "response_metadata": "none"}]}}`,
+	}
+
+	for name, rawResponse := range tests {
+		t.Run(name, func(t *testing.T) {
+			fast, ok := transformSimpleExchange(rawResponse)
+			if !ok {
+				t.Fatal("transformSimpleExchange() = false, want the fast path to handle the reply")
+			}
+			generic := transformGeneric(t, rawResponse, logger)
+
+			fast, generic = withoutVolatileFields(fast), withoutVolatileFields(generic)
+			if !reflect.DeepEqual(fast, generic) {
+				fastJSON, _ := json.MarshalIndent(fast, "", "  ")
+				genericJSON, _ := json.MarshalIndent(generic, "", "  ")
+				t.Errorf("fast path output differs from the generic transformer\nfast:    %s\ngeneric: %s", fastJSON, genericJSON)
+			}
+		})
+	}
+}
+
+func TestTransformSimpleExchangeFallsBack(t *testing.T) {
+	tests := map[string]string{
+		"code fence":     "```json\n" + geminiReply + "\n```",
+		"prose prefix":   "Resposta: " + geminiReply,
+		"tool call":      `{"output": {"messages": [{"type": "human", "content": "Oi"}, {"type": "ai", "content": "", "tool_calls": [{"name": "buscar", "args": {}, "id": "1"}]}]}}`,
+		"three messages": `{"output": {"messages": [{"type": "human", "content": "a"}, {"type": "ai", "content": "b"}, {"type": "ai", "content": "c"}]}}`,
+		"index-keyed":    `{"output": {"messages": {"0": {"type": "human", "content": "a"}, "1": {"type": "ai", "content": "b"}}}}`,
+		"list content":   `{"output": {"messages": [{"type": "human", "content": "a"}, {"type": "ai", "content": [{"type": "text", "text": "b"}]}]}}`,
+		"numeric id":     `{"output": {"messages": [{"type": "human", "content": "a", "id": 1}, {"type": "ai", "content": "b"}]}}`,
+		"swapped order":  `{"output": {"messages": [{"type": "ai", "content": "b"}, {"type": "human", "content": "a"}]}}`,
+		"no output":      `{"messages": []}`,
+	}
+
+	for name, rawResponse := range tests {
+		t.Run(name, func(t *testing.T) {
+			if messages, ok := transformSimpleExchange(rawResponse); ok {
+				t.Errorf("transformSimpleExchange() = %v, want false so the generic transformer is used", messages)
+			}
+		})
+	}
+}
+
+func BenchmarkTransformGeneric(b *testing.B) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	entry := logrus.NewEntry(logger)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		transformGeneric(b, geminiReply, entry)
+	}
+}
+
+func BenchmarkTransformSimpleExchange(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, ok := transformSimpleExchange(geminiReply); !ok {
+			b.Fatal("transformSimpleExchange() = false")
+		}
+	}
+}
+
+func BenchmarkGoogleMessageTransformer(b *testing.B) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	entry := logrus.NewEntry(logger)
+	responses := map[string]string{
+		"simple exchange": geminiReply,
+		"fenced":          "```json\n" + geminiReply + "\n```",
+	}
+
+	for name, rawResponse := range responses {
+		b.Run(strings.ReplaceAll(name, " ", "_"), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := (GoogleMessageTransformer{}).Transform(rawResponse, entry); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,449 @@
+package workers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/services"
+)
+
+// GoogleMessageTransformer converts Google Agent Engine responses ({"output": {"messages": [...]}}) into the
+// gateway's message format
+type GoogleMessageTransformer struct{}
+
+// Transform parses the response JSON, tolerating code fences and surrounding prose, and transforms its
+// messages. Responses without messages are wrapped as a single structured_data message. Single-exchange
+// replies take the transformSimpleExchange fast path.
+func (GoogleMessageTransformer) Transform(rawResponse string, logger *logrus.Entry) ([]interface{}, error) {
+	// Most replies are a single human/ai exchange, decoded without building a map of the whole response
+	if messages, ok := transformSimpleExchange(rawResponse); ok {
+		return messages, nil
+	}
+
+	// Extract the JSON object, tolerating code fences, surrounding prose and raw line breaks
+	cleanedResponse, extractErr := extractJSONObject(rawResponse)
+	if extractErr != nil {
+		logger.WithError(extractErr).Warn("Could not locate a JSON object in agent response, parsing it as-is")
+		cleanedResponse = strings.TrimSpace(rawResponse)
+	}
+
+	var parsedResponse map[string]interface{}
+	if err := json.Unmarshal([]byte(cleanedResponse), &parsedResponse); err != nil {
+		logger.WithFields(logrus.Fields{
+			"error":       err.Error(),
+			"raw_json":    cleanedResponse,
+			"json_length": len(cleanedResponse),
+		}).Error("Failed to parse Google Agent Engine JSON response")
+		return nil, services.NewProcessingError(services.ErrResponseParse, "failed to parse AI response JSON", err)
+	}
+
+	// Extract the 'output' field which contains the messages
+	output, exists := parsedResponse["output"]
+	if !exists {
+		logger.Error("No 'output' field found in Google Agent Engine response")
+		return nil, services.NewProcessingError(services.ErrResponseParse, "invalid Google Agent Engine response format - missing 'output' field", nil)
+	}
+
+	outputMap, ok := output.(map[string]interface{})
+	if !ok {
+		logger.Error("'output' field is not a map in Google Agent Engine response")
+		return nil, services.NewProcessingError(services.ErrResponseParse, "invalid Google Agent Engine response format - 'output' is not an object", nil)
+	}
+
+	// Extract messages array from the output structure
+	if messagesArray, exists := outputMap["messages"]; exists {
+		return transformGoogleAgentMessages(logger.Logger, messagesArray), nil
+	}
+
+	// Fallback: if no 'messages' field, wrap the entire output as structured data
+	logger.Warn("No 'messages' field found in output, wrapping output as structured data message")
+	structuredMessage := map[string]interface{}{
+		"message_type": "structured_data",
+		"content":      outputMap, // Include all output fields
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}
+	return []interface{}{structuredMessage}, nil
+}
+
+// transformGoogleAgentMessages transforms Google Agent Engine messages to Python API format
+func transformGoogleAgentMessages(logger *logrus.Logger, messagesData interface{}) []interface{} {
+	var transformedMessages []interface{}
+
+	// Handle both slice and single message cases
+	var messagesList []interface{}
+
+	switch v := messagesData.(type) {
+	case []interface{}:
+		messagesList = v
+	case map[string]interface{}:
+		// Some responses key the messages by stringified index instead of returning an array
+		if indexed, ok := indexedMessagesToList(v); ok {
+			messagesList = indexed
+		} else {
+			messagesList = []interface{}{v}
+		}
+	case interface{}:
+		messagesList = []interface{}{v}
+	default:
+		logger.Warn("Unexpected messages data type, returning empty array")
+		return transformedMessages
+	}
+
+	// Entries that aren't message objects can't be transformed; they are reported rather than lost silently
+	var droppedEntries []interface{}
+
+	for _, msgData := range messagesList {
+		msgMap, ok := msgData.(map[string]interface{})
+		if !ok {
+			droppedEntries = append(droppedEntries, msgData)
+			continue
+		}
+
+		// Transform Google Agent Engine message to Python API format
+		transformedMsg := map[string]interface{}{
+			"id":                      msgMap["id"],
+			"date":                    nil,
+			"session_id":              nil,
+			"time_since_last_message": nil,
+			"name":                    msgMap["name"],
+			"otid":                    msgMap["id"], // Use same ID as otid
+			"sender_id":               nil,
+			"step_id":                 "step-" + generateStepID(), // Generate step ID
+			"is_err":                  nil,
+			"model_name":              extractModelName(msgMap),
+			"finish_reason":           extractFinishReason(msgMap),
+			"avg_logprobs":            extractAvgLogprobs(msgMap),
+			"usage_metadata":          extractUsageMetadata(msgMap),
+			"message_type":            mapMessageType(msgMap),
+			"content":                 msgMap["content"],
+		}
+
+		// Add type-specific fields
+		if msgType := mapMessageType(msgMap); msgType == "tool_call_message" {
+			if toolCalls, exists := msgMap["tool_calls"].([]interface{}); exists && len(toolCalls) > 0 {
+				if toolCall, ok := toolCalls[0].(map[string]interface{}); ok {
+					transformedMsg["tool_call"] = map[string]interface{}{
+						"name":         toolCall["name"],
+						"arguments":    normalizeToolArguments(toolCall["args"]),
+						"tool_call_id": normalizeToolCallID(toolCall["id"]),
+					}
+				}
+			}
+		} else if msgType == "tool_return_message" {
+			// For tool messages, extract tool return information
+			if name, exists := msgMap["name"]; exists {
+				transformedMsg["tool_return"] = msgMap["content"]
+				transformedMsg["status"] = "success"
+				transformedMsg["tool_call_id"] = normalizeToolCallID(msgMap["tool_call_id"])
+				transformedMsg["stdout"] = nil
+				transformedMsg["stderr"] = nil
+				transformedMsg["name"] = name
+			}
+		}
+
+		// Pass through fields we don't map so new agent metadata isn't dropped
+		if extra := unmappedMessageFields(msgMap); len(extra) > 0 {
+			transformedMsg["extra"] = extra
+		}
+
+		transformedMessages = append(transformedMessages, transformedMsg)
+	}
+
+	// Add usage statistics message at the end (matching Python API)
+	usageStats := newUsageStatistics(transformedMessages)
+	if len(droppedEntries) > 0 {
+		usageStats["dropped_messages"] = len(droppedEntries)
+		usageStats[droppedEntriesField] = droppedEntries
+		logger.WithFields(logrus.Fields{
+			"dropped_messages": len(droppedEntries),
+			"total_messages":   len(messagesList),
+		}).Warn("Dropped malformed agent messages that could not be transformed")
+	}
+	transformedMessages = append(transformedMessages, usageStats)
+
+	return transformedMessages
+}
+
+// newUsageStatistics builds the usage_statistics message that ends the transformed messages, aggregating
+// token usage and model names across them
+func newUsageStatistics(transformedMessages []interface{}) map[string]interface{} {
+	usage := aggregateUsage(transformedMessages)
+	return map[string]interface{}{
+		"message_type":      "usage_statistics",
+		"completion_tokens": usage.completionTokens,
+		"prompt_tokens":     usage.promptTokens,
+		"total_tokens":      usage.totalTokens,
+		"step_count":        len(transformedMessages),
+		"steps_messages":    nil,
+		"run_ids":           nil,
+		"agent_id":          "", // Will be filled by calling function
+		"processed_at":      time.Now().Format(time.RFC3339),
+		"status":            "done",
+		"model_names":       usage.modelNames,
+	}
+}
+
+// droppedEntriesField holds the raw agent entries that could not be transformed, kept in usage_statistics
+// only when RESPONSE_INCLUDE_DROPPED_MESSAGES is enabled
+const droppedEntriesField = "dropped_entries"
+
+// mappedMessageFields are the Google Agent Engine message fields consumed by transformGoogleAgentMessages
+var mappedMessageFields = map[string]bool{
+	"id":                true,
+	"name":              true,
+	"type":              true,
+	"content":           true,
+	"tool_calls":        true,
+	"tool_call_id":      true,
+	"response_metadata": true,
+}
+
+// unmappedMessageFields returns the top-level message fields that have no explicit mapping
+func unmappedMessageFields(msgMap map[string]interface{}) map[string]interface{} {
+	extra := make(map[string]interface{})
+	for key, value := range msgMap {
+		if !mappedMessageFields[key] {
+			extra[key] = value
+		}
+	}
+	return extra
+}
+
+// usageTotals holds token counts and model names aggregated across messages
+type usageTotals struct {
+	promptTokens     int
+	completionTokens int
+	totalTokens      int
+	modelNames       []string
+}
+
+// aggregateUsage sums the usage_metadata of each transformed message and collects
+// the distinct model names seen, preserving the order in which they first appear
+func aggregateUsage(messages []interface{}) usageTotals {
+	totals := usageTotals{modelNames: []string{}}
+	seenModels := make(map[string]bool)
+
+	for _, msg := range messages {
+		msgMap, ok := msg.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if modelName, ok := msgMap["model_name"].(string); ok && modelName != "" && !seenModels[modelName] {
+			seenModels[modelName] = true
+			totals.modelNames = append(totals.modelNames, modelName)
+		}
+
+		usageMap, ok := msgMap["usage_metadata"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		prompt := tokenCount(usageMap["prompt_token_count"])
+		completion := tokenCount(usageMap["candidates_token_count"])
+		total := tokenCount(usageMap["total_token_count"])
+		if total == 0 {
+			// Some responses omit the total, derive it from its parts
+			total = prompt + completion
+		}
+
+		totals.promptTokens += prompt
+		totals.completionTokens += completion
+		totals.totalTokens += total
+	}
+
+	return totals
+}
+
+// tokenCount converts a token count (see numericTokenCount) to int, 0 when missing or non-numeric
+func tokenCount(value interface{}) int {
+	count, _ := numericTokenCount(value)
+	return int(count)
+}
+
+// Helper functions for message transformation
+func extractModelName(msgMap map[string]interface{}) interface{} {
+	if responseMetadata, exists := msgMap["response_metadata"].(map[string]interface{}); exists {
+		if modelName, exists := responseMetadata["model_name"]; exists {
+			return modelName
+		}
+	}
+	return nil
+}
+
+func extractFinishReason(msgMap map[string]interface{}) interface{} {
+	if responseMetadata, exists := msgMap["response_metadata"].(map[string]interface{}); exists {
+		if finishReason, exists := responseMetadata["finish_reason"]; exists {
+			return finishReason
+		}
+	}
+	return nil
+}
+
+func extractAvgLogprobs(msgMap map[string]interface{}) interface{} {
+	if responseMetadata, exists := msgMap["response_metadata"].(map[string]interface{}); exists {
+		if avgLogprobs, exists := responseMetadata["avg_logprobs"]; exists {
+			return avgLogprobs
+		}
+	}
+	return nil
+}
+
+func extractUsageMetadata(msgMap map[string]interface{}) interface{} {
+	responseMetadata, ok := msgMap["response_metadata"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	usageMap, ok := responseMetadata["usage_metadata"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	// Transform to Python API format; counts are reported as float64 like the Python API does
+	result := map[string]interface{}{
+		"prompt_token_count":     usageMap["input_tokens"],
+		"candidates_token_count": usageMap["output_tokens"],
+		"total_token_count":      usageMap["total_tokens"],
+	}
+	for field, source := range map[string]string{
+		"prompt_token_count":     "input_tokens",
+		"candidates_token_count": "output_tokens",
+		"total_token_count":      "total_tokens",
+	} {
+		if count, ok := numericTokenCount(usageMap[source]); ok {
+			result[field] = count
+		}
+	}
+
+	if outputDetails, ok := usageMap["output_token_details"].(map[string]interface{}); ok {
+		if reasoning, ok := numericTokenCount(outputDetails["reasoning"]); ok {
+			result["thoughts_token_count"] = reasoning
+		}
+	}
+	if inputDetails, ok := usageMap["input_token_details"].(map[string]interface{}); ok {
+		if cacheRead, ok := numericTokenCount(inputDetails["cache_read"]); ok {
+			result["cached_content_token_count"] = cacheRead
+		}
+	}
+
+	return result
+}
+
+// numericTokenCount converts a token count decoded from JSON (float64 or json.Number) or set in Go
+// (int, int64) to float64, reporting false for missing or non-numeric values
+func numericTokenCount(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// maxArgumentDecodeDepth bounds how many layers of JSON string encoding are unwrapped from tool arguments
+const maxArgumentDecodeDepth = 3
+
+// normalizeToolArguments returns tool call arguments as a JSON object. Google sometimes sends them as a
+// JSON-encoded string (occasionally encoded twice), which is parsed; missing arguments become an empty
+// object, and anything that isn't an object is wrapped as {"value": ...} (unparseable text included).
+func normalizeToolArguments(args interface{}) map[string]interface{} {
+	for depth := 0; depth <= maxArgumentDecodeDepth; depth++ {
+		switch value := args.(type) {
+		case nil:
+			return map[string]interface{}{}
+		case map[string]interface{}:
+			return value
+		case string:
+			trimmed := strings.TrimSpace(value)
+			if trimmed == "" {
+				return map[string]interface{}{}
+			}
+			var decoded interface{}
+			if err := json.Unmarshal([]byte(trimmed), &decoded); err != nil {
+				return map[string]interface{}{"value": value}
+			}
+			args = decoded
+		default:
+			return map[string]interface{}{"value": value}
+		}
+	}
+	return map[string]interface{}{"value": args}
+}
+
+// normalizeToolCallID returns a tool call ID as a string, formatting numeric IDs and mapping a missing ID to ""
+func normalizeToolCallID(id interface{}) string {
+	switch value := id.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func mapMessageType(msgMap map[string]interface{}) string {
+	msgType, exists := msgMap["type"].(string)
+	if !exists {
+		return "user_message" // Default fallback
+	}
+
+	switch msgType {
+	case "human":
+		return "user_message"
+	case "ai":
+		// Check if it has tool calls
+		if toolCalls, exists := msgMap["tool_calls"].([]interface{}); exists && len(toolCalls) > 0 {
+			return "tool_call_message"
+		}
+		return "assistant_message"
+	case "tool":
+		return "tool_return_message"
+	default:
+		return "user_message"
+	}
+}
+
+// indexedMessagesToList converts a messages object keyed by stringified indices ({"0": ..., "1": ...})
+// into a list ordered by index. It returns false if any key is not an index.
+func indexedMessagesToList(indexed map[string]interface{}) ([]interface{}, bool) {
+	if len(indexed) == 0 {
+		return nil, false
+	}
+
+	type indexedMessage struct {
+		index   int
+		message interface{}
+	}
+
+	entries := make([]indexedMessage, 0, len(indexed))
+	for key, message := range indexed {
+		index, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, false
+		}
+		entries = append(entries, indexedMessage{index: index, message: message})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].index < entries[j].index })
+
+	messagesList := make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		messagesList = append(messagesList, entry.message)
+	}
+	return messagesList, true
+}
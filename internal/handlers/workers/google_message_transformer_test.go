@@ -0,0 +1,279 @@
+package workers
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// messageContents returns the content of each transformed message, without the trailing usage statistics
+func messageContents(t *testing.T, transformed []interface{}) []interface{} {
+	t.Helper()
+	if len(transformed) == 0 {
+		t.Fatal("transformed messages are empty, want at least the usage statistics")
+	}
+	var contents []interface{}
+	for _, msg := range transformed[:len(transformed)-1] {
+		contents = append(contents, msg.(map[string]interface{})["content"])
+	}
+	return contents
+}
+
+// decodeMessages decodes a messages payload the way the transformer receives it
+func decodeMessages(t *testing.T, payload string) interface{} {
+	t.Helper()
+	var messages interface{}
+	if err := json.Unmarshal([]byte(payload), &messages); err != nil {
+		t.Fatalf("decode %s: %v", payload, err)
+	}
+	return messages
+}
+
+func TestTransformGoogleAgentMessagesShapes(t *testing.T) {
+	logger := logrus.New()
+	tests := []struct {
+		name    string
+		payload string
+		want    []interface{}
+	}{
+		{
+			name:    "array",
+			payload: `[{"type": "human", "content": "Oi"}, {"type": "ai", "content": "Olá!"}]`,
+			want:    []interface{}{"Oi", "Olá!"},
+		},
+		{
+			name:    "index-keyed map",
+			payload: `{"1": {"type": "ai", "content": "Olá!"}, "0": {"type": "human", "content": "Oi"}}`,
+			want:    []interface{}{"Oi", "Olá!"},
+		},
+		{
+			name: "index-keyed map sorted numerically",
+			payload: `{"10": {"type": "ai", "content": "k"}, "2": {"type": "ai", "content": "c"},
+				"0": {"type": "human", "content": "a"}, "1": {"type": "ai", "content": "b"}}`,
+			want: []interface{}{"a", "b", "c", "k"},
+		},
+		{
+			name:    "single message object",
+			payload: `{"type": "ai", "content": "Olá!"}`,
+			want:    []interface{}{"Olá!"},
+		},
+		{
+			name:    "map with a non-index key is one message",
+			payload: `{"0": {"type": "ai", "content": "a"}, "content": "b"}`,
+			want:    []interface{}{"b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transformed := transformGoogleAgentMessages(logger, decodeMessages(t, tt.payload))
+			if got := messageContents(t, transformed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("contents = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexedMessagesToListIsDeterministic(t *testing.T) {
+	indexed := map[string]interface{}{}
+	var want []interface{}
+	for i, key := range []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11"} {
+		indexed[key] = i
+		want = append(want, i)
+	}
+
+	// Map iteration order is randomized, so repeat to catch order depending on it
+	for run := 0; run < 50; run++ {
+		got, ok := indexedMessagesToList(indexed)
+		if !ok {
+			t.Fatal("indexedMessagesToList() = false, want the keys recognized as indices")
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("run %d: indexedMessagesToList() = %v, want %v", run, got, want)
+		}
+	}
+}
+
+func TestIndexedMessagesToListRejectsOtherMaps(t *testing.T) {
+	for name, indexed := range map[string]map[string]interface{}{
+		"empty":         {},
+		"non-index key": {"0": "a", "type": "ai"},
+	} {
+		if got, ok := indexedMessagesToList(indexed); ok {
+			t.Errorf("%s: indexedMessagesToList() = %v, want false", name, got)
+		}
+	}
+}
+
+// usagePayload is an agent reply as Google Agent Engine reports token usage for it
+const usagePayload = `[
+	{"type": "human", "content": "Oi"},
+	{
+		"type": "ai",
+		"content": "Olá! Como posso ajudar?",
+		"response_metadata": {"model_name": "gemini-2.5-flash", "finish_reason": "STOP"}
+	}
+]`
+
+// withUsage adds usage metadata to the payload's assistant message
+func withUsage(t *testing.T, usage string) string {
+	t.Helper()
+	return strings.Replace(usagePayload, `"response_metadata": {"model_name": "gemini-2.5-flash", "finish_reason": "STOP"}`,
+		`"response_metadata": {"model_name": "gemini-2.5-flash", "finish_reason": "STOP", "usage_metadata": `+usage+`}`, 1)
+}
+
+func TestTokenCountsFromJSON(t *testing.T) {
+	logger := logrus.New()
+	usage := `{"input_tokens": 1520, "output_tokens": 87, "total_tokens": 1607,
+		"input_token_details": {"cache_read": 1024}, "output_token_details": {"reasoning": 40}}`
+	wantMetadata := map[string]interface{}{
+		"prompt_token_count":         float64(1520),
+		"candidates_token_count":     float64(87),
+		"total_token_count":          float64(1607),
+		"cached_content_token_count": float64(1024),
+		"thoughts_token_count":       float64(40),
+	}
+
+	decoders := map[string]func(t *testing.T, payload string) interface{}{
+		"float64": decodeMessages,
+		"json.Number": func(t *testing.T, payload string) interface{} {
+			decoder := json.NewDecoder(strings.NewReader(payload))
+			decoder.UseNumber()
+			var messages interface{}
+			if err := decoder.Decode(&messages); err != nil {
+				t.Fatalf("decode %s: %v", payload, err)
+			}
+			return messages
+		},
+	}
+
+	for name, decode := range decoders {
+		t.Run(name, func(t *testing.T) {
+			transformed := transformGoogleAgentMessages(logger, decode(t, withUsage(t, usage)))
+
+			assistant := transformed[1].(map[string]interface{})
+			if got := assistant["usage_metadata"]; !reflect.DeepEqual(got, wantMetadata) {
+				t.Errorf("usage_metadata = %v, want %v", got, wantMetadata)
+			}
+
+			stats := transformed[len(transformed)-1].(map[string]interface{})
+			for field, want := range map[string]int{"prompt_tokens": 1520, "completion_tokens": 87, "total_tokens": 1607} {
+				if got := stats[field]; got != want {
+					t.Errorf("usage_statistics %s = %v, want %d", field, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTokenCountsDeriveMissingTotal(t *testing.T) {
+	transformed := transformGoogleAgentMessages(logrus.New(), decodeMessages(t, withUsage(t, `{"input_tokens": 100, "output_tokens": 25}`)))
+
+	stats := transformed[len(transformed)-1].(map[string]interface{})
+	if got := stats["total_tokens"]; got != 125 {
+		t.Errorf("usage_statistics total_tokens = %v, want 125", got)
+	}
+}
+
+func TestNumericTokenCount(t *testing.T) {
+	tests := []struct {
+		value  interface{}
+		want   float64
+		wantOK bool
+	}{
+		{float64(42), 42, true},
+		{json.Number("42"), 42, true},
+		{json.Number("4.2e1"), 42, true},
+		{42, 42, true},
+		{int64(42), 42, true},
+		{json.Number("many"), 0, false},
+		{"42", 0, false},
+		{nil, 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := numericTokenCount(tt.value)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("numericTokenCount(%#v) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestNormalizeToolArguments(t *testing.T) {
+	object := map[string]interface{}{"cpf": "12345678909", "servico": "iptu"}
+	encoded, _ := json.Marshal(object)
+	doubleEncoded, _ := json.Marshal(string(encoded))
+
+	tests := []struct {
+		name string
+		args interface{}
+		want map[string]interface{}
+	}{
+		{"object", object, object},
+		{"string-encoded", string(encoded), object},
+		{"double-encoded", string(doubleEncoded), object},
+		{"padded string", "  " + string(encoded) + "\n", object},
+		{"missing", nil, map[string]interface{}{}},
+		{"empty string", "  ", map[string]interface{}{}},
+		{"unparseable text", "cpf=123", map[string]interface{}{"value": "cpf=123"}},
+		{"encoded array", `["a", "b"]`, map[string]interface{}{"value": []interface{}{"a", "b"}}},
+		{"number", float64(7), map[string]interface{}{"value": float64(7)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeToolArguments(tt.args); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalizeToolArguments(%#v) = %v, want %v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeToolArgumentsStopsAtDecodeDepth(t *testing.T) {
+	args := `{"a": 1}`
+	for i := 0; i <= maxArgumentDecodeDepth; i++ {
+		encoded, _ := json.Marshal(args)
+		args = string(encoded)
+	}
+
+	// Too deeply encoded to unwrap completely: kept as the remaining string rather than looping forever
+	got := normalizeToolArguments(args)
+	if _, ok := got["value"].(string); !ok || len(got) != 1 {
+		t.Errorf("normalizeToolArguments() = %v, want the remaining string wrapped as value", got)
+	}
+}
+
+func TestNormalizeToolCallID(t *testing.T) {
+	for _, tt := range []struct {
+		id   interface{}
+		want string
+	}{
+		{"call_abc", "call_abc"},
+		{float64(12), "12"},
+		{json.Number("12"), "12"},
+		{nil, ""},
+	} {
+		if got := normalizeToolCallID(tt.id); got != tt.want {
+			t.Errorf("normalizeToolCallID(%#v) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestTransformToolCallArguments(t *testing.T) {
+	payload := `[{"type": "ai", "content": "", "tool_calls": [
+		{"name": "consultar_iptu", "args": "{\"inscricao\": \"0123456\"}", "id": 3}
+	]}]`
+
+	transformed := transformGoogleAgentMessages(logrus.New(), decodeMessages(t, payload))
+
+	toolCall := transformed[0].(map[string]interface{})["tool_call"].(map[string]interface{})
+	if want := map[string]interface{}{"inscricao": "0123456"}; !reflect.DeepEqual(toolCall["arguments"], want) {
+		t.Errorf("tool_call arguments = %v, want %v", toolCall["arguments"], want)
+	}
+	if toolCall["tool_call_id"] != "3" {
+		t.Errorf("tool_call_id = %#v, want %q", toolCall["tool_call_id"], "3")
+	}
+}
@@ -0,0 +1,71 @@
+package workers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+)
+
+// IntentClassifier detects the intent of an inbound message so the matching AGENT_INTENT_EXAMPLES can steer
+// the agent. It returns "" when no intent applies.
+type IntentClassifier interface {
+	ClassifyIntent(ctx context.Context, msg *models.QueueMessage, message string) (string, error)
+}
+
+// intentExamples returns the few-shot examples block for the message's intent, or "" when no classifier is
+// configured, no intent is detected or the intent has no examples. A classifier error is logged and the
+// message is sent without examples.
+func intentExamples(ctx context.Context, msg *models.QueueMessage, message string, deps *MessageHandlerDependencies, logger *logrus.Entry) string {
+	if deps.IntentClassifier == nil {
+		return ""
+	}
+
+	intent, err := deps.IntentClassifier.ClassifyIntent(ctx, msg, message)
+	if err != nil {
+		logger.WithError(err).Warn("Intent classification failed, sending message without examples")
+		return ""
+	}
+	if intent == "" {
+		return ""
+	}
+
+	// Validated at startup
+	examples, _ := deps.Config.GetIntentExamples()
+	block := formatIntentExamples(examples[intent])
+
+	logger.WithFields(logrus.Fields{
+		"intent":   intent,
+		"examples": len(examples[intent]),
+	}).Debug("Detected message intent")
+	if span := trace.SpanFromContext(ctx); span.IsRecording() {
+		span.SetAttributes(
+			attribute.String("message.intent", intent),
+			attribute.Int("message.intent_examples", len(examples[intent])))
+	}
+	return block
+}
+
+// formatIntentExamples renders example exchanges as a block the agent reads before the user's message
+func formatIntentExamples(examples []config.IntentExample) string {
+	if len(examples) == 0 {
+		return ""
+	}
+
+	var block strings.Builder
+	block.WriteString("<examples>")
+	for _, example := range examples {
+		block.WriteString("\nUser: ")
+		block.WriteString(strings.TrimSpace(example.User))
+		block.WriteString("\nAssistant: ")
+		block.WriteString(strings.TrimSpace(example.Assistant))
+		block.WriteString("\n")
+	}
+	block.WriteString("</examples>")
+	return block.String()
+}
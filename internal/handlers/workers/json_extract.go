@@ -0,0 +1,88 @@
+package workers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// extractJSONObject pulls the first balanced JSON object out of a raw agent response.
+// It tolerates the shapes seen in production: markdown code fences (```json ... ```),
+// leading or trailing prose, and pretty-printed output whose string values contain raw
+// line breaks (which are escaped so the result is valid JSON).
+func extractJSONObject(raw string) (string, error) {
+	text := stripCodeFences(strings.TrimSpace(raw))
+
+	start := strings.IndexByte(text, '{')
+	if start < 0 {
+		return "", fmt.Errorf("no JSON object found in response")
+	}
+
+	var out strings.Builder
+	out.Grow(len(text) - start)
+
+	depth := 0
+	inString := false
+	escaped := false
+
+	for i := start; i < len(text); i++ {
+		c := text[i]
+
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			case c == '\n':
+				out.WriteString(`\n`)
+				continue
+			case c == '\r':
+				continue
+			case c == '\t':
+				out.WriteString(`\t`)
+				continue
+			}
+			out.WriteByte(c)
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+		out.WriteByte(c)
+
+		if depth == 0 {
+			return out.String(), nil
+		}
+	}
+
+	return "", fmt.Errorf("unbalanced JSON object in response")
+}
+
+// stripCodeFences removes a surrounding markdown code fence (``` or ```json), keeping its body
+func stripCodeFences(text string) string {
+	open := strings.Index(text, "```")
+	// Fences inside the JSON itself (e.g. code in message content) are left untouched
+	if brace := strings.IndexByte(text, '{'); open < 0 || (brace >= 0 && brace < open) {
+		return text
+	}
+
+	body := text[open+3:]
+	// Drop the language tag on the opening fence line, if any
+	if newline := strings.IndexByte(body, '\n'); newline >= 0 && !strings.ContainsAny(body[:newline], "{[") {
+		body = body[newline+1:]
+	}
+
+	if closing := strings.LastIndex(body, "```"); closing >= 0 {
+		body = body[:closing]
+	}
+
+	return strings.TrimSpace(body)
+}
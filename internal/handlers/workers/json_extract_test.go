@@ -0,0 +1,97 @@
+package workers
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExtractJSONObject(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "bare object",
+			raw:  `{"output": {"messages": []}}`,
+			want: `{"output": {"messages": []}}`,
+		},
+		{
+			name: "json fence",
+			raw:  "```json\n{\"output\": {\"messages\": []}}\n```",
+			want: `{"output": {"messages": []}}`,
+		},
+		{
+			name: "untagged fence with surrounding prose",
+			raw:  "Here is the response:\n```\n{\"ok\": true}\n```\nLet me know if you need anything else.",
+			want: `{"ok": true}`,
+		},
+		{
+			name: "prose prefix",
+			raw:  `Sure! The result is {"status": "done"}`,
+			want: `{"status": "done"}`,
+		},
+		{
+			name: "trailing prose and second object",
+			raw:  `{"first": 1} and also {"second": 2}`,
+			want: `{"first": 1}`,
+		},
+		{
+			name: "nested braces",
+			raw:  `{"output": {"messages": [{"type": "ai", "kwargs": {"content": "Olá"}}]}} trailing`,
+			want: `{"output": {"messages": [{"type": "ai", "kwargs": {"content": "Olá"}}]}}`,
+		},
+		{
+			name: "braces inside strings",
+			raw:  `{"content": "use {placeholders} and } stray { braces"}`,
+			want: `{"content": "use {placeholders} and } stray { braces"}`,
+		},
+		{
+			name: "escaped quotes inside strings",
+			raw:  `{"content": "she said \"{hi}\"", "n": 1} extra`,
+			want: `{"content": "she said \"{hi}\"", "n": 1}`,
+		},
+		{
+			name: "raw line breaks inside strings",
+			raw:  "{\"content\": \"line one\nline two\r\n\tindented\"}",
+			want: `{"content": "line one\nline two\n\tindented"}`,
+		},
+		{
+			name: "fence inside message content",
+			raw:  "{\"content\": \"```go\\nfmt.Println()\\n```\"}",
+			want: "{\"content\": \"```go\\nfmt.Println()\\n```\"}",
+		},
+		{
+			name:    "no object",
+			raw:     "Desculpe, não consegui processar sua solicitação.",
+			wantErr: true,
+		},
+		{
+			name:    "truncated object",
+			raw:     `{"output": {"messages": [`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractJSONObject(tt.raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("extractJSONObject() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractJSONObject() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("extractJSONObject() = %q, want %q", got, tt.want)
+			}
+			if !json.Valid([]byte(got)) {
+				t.Errorf("extractJSONObject() = %q is not valid JSON", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package workers
+
+import (
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+)
+
+// LogSampler picks 1 in LOG_VERBOSE_SAMPLE_RATE messages to be processed with debug logging, so the
+// pipeline's step-by-step logs can be seen in production without enabling them for every message
+type LogSampler struct {
+	rate    uint64
+	count   atomic.Uint64
+	verbose *logrus.Logger
+}
+
+// NewLogSampler creates the sampler, or returns nil when sampling is disabled or every message is already
+// logged at debug level
+func NewLogSampler(cfg *config.Config, logger *logrus.Logger) *LogSampler {
+	rate := cfg.Observability.LogVerboseSampleRate
+	if rate <= 0 || logger.IsLevelEnabled(logrus.DebugLevel) {
+		return nil
+	}
+
+	// Same destination, format and hooks as the worker's logger, one level more verbose
+	verbose := &logrus.Logger{
+		Out:          logger.Out,
+		Hooks:        logger.Hooks,
+		Formatter:    logger.Formatter,
+		ReportCaller: logger.ReportCaller,
+		Level:        logrus.DebugLevel,
+		ExitFunc:     logger.ExitFunc,
+		BufferPool:   logger.BufferPool,
+	}
+
+	logger.WithField("sample_rate", rate).Info("Verbose log sampling enabled")
+	return &LogSampler{rate: uint64(rate), verbose: verbose}
+}
+
+// sample reports whether the next message is logged verbosely
+func (s *LogSampler) sample() bool {
+	return s.count.Add(1)%s.rate == 0
+}
+
+// withLogSampling returns the dependencies to process a message with: a copy logging at debug level when
+// the message is sampled, otherwise deps itself
+func (deps *MessageHandlerDependencies) withLogSampling() (*MessageHandlerDependencies, bool) {
+	if deps.LogSampler == nil || !deps.LogSampler.sample() {
+		return deps, false
+	}
+	sampledDeps := *deps
+	sampledDeps.Logger = deps.LogSampler.verbose
+	return &sampledDeps, true
+}
@@ -0,0 +1,58 @@
+package workers
+
+import (
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/services"
+)
+
+func TestMessageEnqueuedAt(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	dayAgo := now.Add(-24 * time.Hour)
+	hourAgo := now.Add(-time.Hour)
+	inAnHour := now.Add(time.Hour)
+	replayed := amqp.Table{services.ReplayedAtHeader: now.Format(time.RFC3339)}
+
+	tests := []struct {
+		name     string
+		delivery amqp.Delivery
+		msg      models.QueueMessage
+		want     time.Time
+	}{
+		{"message timestamp", amqp.Delivery{Timestamp: hourAgo}, models.QueueMessage{Timestamp: dayAgo}, dayAgo},
+		{"delivery timestamp", amqp.Delivery{Timestamp: hourAgo}, models.QueueMessage{}, hourAgo},
+		{"neither", amqp.Delivery{}, models.QueueMessage{}, time.Time{}},
+		{"scheduled", amqp.Delivery{}, models.QueueMessage{Timestamp: dayAgo, ScheduledAt: &hourAgo}, hourAgo},
+		{"scheduled before enqueue", amqp.Delivery{}, models.QueueMessage{Timestamp: hourAgo, ScheduledAt: &dayAgo}, hourAgo},
+		{"replayed dead letter", amqp.Delivery{Headers: replayed, Timestamp: dayAgo}, models.QueueMessage{Timestamp: dayAgo}, now},
+		{"replayed, scheduled later", amqp.Delivery{Headers: replayed}, models.QueueMessage{Timestamp: dayAgo, ScheduledAt: &inAnHour}, inAnHour},
+		{"malformed replay header", amqp.Delivery{Headers: amqp.Table{services.ReplayedAtHeader: "yesterday"}}, models.QueueMessage{Timestamp: dayAgo}, dayAgo},
+		{"non-string replay header", amqp.Delivery{Headers: amqp.Table{services.ReplayedAtHeader: now}}, models.QueueMessage{Timestamp: dayAgo}, dayAgo},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := messageEnqueuedAt(tt.delivery, &tt.msg); !got.Equal(tt.want) {
+				t.Errorf("messageEnqueuedAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplayedDeadLetterIsNotExpired(t *testing.T) {
+	maxAge := 10 * time.Minute
+	msg := models.QueueMessage{Timestamp: time.Now().Add(-48 * time.Hour)}
+	delivery := amqp.Delivery{Headers: amqp.Table{services.ReplayedAtHeader: time.Now().UTC().Format(time.RFC3339)}}
+
+	if age := time.Since(messageEnqueuedAt(delivery, &msg)); age > maxAge {
+		t.Errorf("replayed message age = %v, want it within RABBITMQ_MAX_MESSAGE_AGE (%v)", age, maxAge)
+	}
+	delivery.Headers = nil
+	if age := time.Since(messageEnqueuedAt(delivery, &msg)); age <= maxAge {
+		t.Errorf("message age without replay = %v, want it over %v", age, maxAge)
+	}
+}
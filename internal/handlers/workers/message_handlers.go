@@ -4,11 +4,15 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
+	"reflect"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/google/uuid"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/sirupsen/logrus"
 	"go.opentelemetry.io/otel/attribute"
@@ -25,14 +29,122 @@ type MessageHandlerDependencies struct {
 	Logger             *logrus.Logger
 	Config             *config.Config
 	RedisService       *services.RedisService
-	GoogleAgentService *services.GoogleAgentEngineService
+	GoogleAgentService GoogleAgentServiceInterface
 	TranscribeService  TranscribeServiceInterface
 	MessageFormatter   MessageFormatterInterface
 	CallbackService    *services.CallbackService              // Optional callback service
 	OTelWorkerWrapper  *middleware.OTelWorkerWrapper          // Optional OTel wrapper
 	TracePropagator    *middleware.TraceCorrelationPropagator // Optional trace propagator
+	AgentIDResolver    AgentIDResolver                        // Optional; defaults to DefaultAgentIDResolver
+	TaskEventNotifier  *services.TaskEventNotifier            // Optional task completion/failure webhook
+	Redactor           services.Redactor                      // Optional tool output redaction
+	ProviderNotifier   ProviderNotifier                       // Optional typing indicator while processing
+	MessageCodecs      *services.MessageCodecRegistry         // Optional; defaults to services.DefaultMessageCodecs
+	MessageSink        services.MessageSink                   // Optional export of processed messages
+	RedisOutageGuard   *services.RedisOutageGuard             // Optional; requeues messages during a Redis outage
+	MessageSender      services.MessageSender                 // Optional; delivers replies to the user after processing
+
+	ToolOutputSummarizer services.ToolOutputSummarizer // Optional; shortens long tool outputs for the user
+	MessageScheduler     *services.MessageScheduler    // Optional; without it scheduled messages run on arrival
+	TenantResolver       *services.TenantResolver      // Optional; keeps routed tenants' task data in their own namespace
+
+	EscalationTranscribeService TranscribeServiceInterface // Optional; re-transcribes empty or low-confidence transcripts
+	AuditLogger                 services.AuditLogger       // Optional audit trail of processed messages
+	AgentFallbacks              []AgentProvider            // Optional; tried in order when the agent fails or its circuit is open
+	LogSampler                  *LogSampler                // Optional; logs 1 in N messages at debug level
+	Moderator                   services.Moderator         // Optional; screens messages and responses (nil = no moderation)
+	IntentClassifier            IntentClassifier           // Optional; selects AGENT_INTENT_EXAMPLES for each message
+	DocumentExtractor           services.DocumentExtractor // Optional; extracts document text for agents that can't read documents
+
+	tenantID string // Set on the per-message copy made by forTenant when the tenant is routed
 }
 
+// ProviderNotifier tells the messaging provider that a user's message is being processed
+type ProviderNotifier interface {
+	MarkProcessing(ctx context.Context, userNumber string) error
+}
+
+// AgentIDResolver derives the agent ID reported for a queue message
+type AgentIDResolver func(msg *models.QueueMessage) string
+
+// DefaultAgentIDResolver derives the agent ID as "user_" + user number
+func DefaultAgentIDResolver(msg *models.QueueMessage) string {
+	return "user_" + msg.UserNumber
+}
+
+// resolveAgentID returns the agent ID for the message using the configured resolver
+func (deps *MessageHandlerDependencies) resolveAgentID(msg *models.QueueMessage) string {
+	if deps.AgentIDResolver != nil {
+		return deps.AgentIDResolver(msg)
+	}
+	return DefaultAgentIDResolver(msg)
+}
+
+// forTenant returns the dependencies to process a tenant's message with: a copy whose RedisService keeps the
+// tenant's task data apart when it is routed, otherwise deps itself
+func (deps *MessageHandlerDependencies) forTenant(tenantID string) *MessageHandlerDependencies {
+	if deps.TenantResolver == nil || !deps.TenantResolver.IsRouted(tenantID) {
+		return deps
+	}
+	tenantDeps := *deps
+	tenantDeps.RedisService = deps.TenantResolver.Redis(tenantID)
+	tenantDeps.tenantID = tenantID
+	return &tenantDeps
+}
+
+// messageEnqueuedAt returns when the message was enqueued: its scheduled time once it has come, else its own
+// timestamp, else the delivery's (zero if none is set). A dead letter replayed to the queue counts as enqueued
+// when it was replayed.
+func messageEnqueuedAt(delivery amqp.Delivery, msg *models.QueueMessage) time.Time {
+	enqueuedAt := msg.Timestamp
+	if enqueuedAt.IsZero() {
+		enqueuedAt = delivery.Timestamp
+	}
+	if replayed, ok := delivery.Headers[services.ReplayedAtHeader].(string); ok {
+		if replayedAt, err := time.Parse(time.RFC3339, replayed); err == nil && replayedAt.After(enqueuedAt) {
+			enqueuedAt = replayedAt
+		}
+	}
+	if msg.ScheduledAt != nil && msg.ScheduledAt.After(enqueuedAt) {
+		return *msg.ScheduledAt
+	}
+	return enqueuedAt
+}
+
+// deliveryRetryCount returns how many times the message has been retried, from its RabbitMQ headers
+func deliveryRetryCount(delivery amqp.Delivery) int64 {
+	if count, ok := delivery.Headers["x-retry-count"].(int64); ok {
+		return count
+	}
+	return 0
+}
+
+// decodeQueueMessage parses a delivery body with the codec selected by its content type
+func (deps *MessageHandlerDependencies) decodeQueueMessage(delivery amqp.Delivery, msg *models.QueueMessage) error {
+	codecs := deps.MessageCodecs
+	if codecs == nil {
+		codecs = services.DefaultMessageCodecs()
+	}
+	return codecs.Decode(delivery.ContentType, delivery.Body, msg)
+}
+
+// GoogleAgentServiceInterface defines the agent and thread operations used while processing messages.
+// It is implemented by services.GoogleAgentEngineService; workerstest.FakeAgentService stands in for it in tests.
+type GoogleAgentServiceInterface interface {
+	CreateThread(ctx context.Context, userID string) (string, error)
+	GetOrCreateThread(ctx context.Context, userID string) (string, error)
+	ExpireThread(ctx context.Context, userID string) error
+	ResetThread(ctx context.Context, userID string) (int, error)
+	SendMessage(ctx context.Context, threadID string, content string, opts ...services.SendOption) (*models.AgentResponse, error)
+	SendMultimodalMessage(ctx context.Context, threadID string, content string, imageURLs []string, opts ...services.SendOption) (*models.AgentResponse, error)
+	SupportsImages() bool
+	SupportsDocuments() bool
+	IsCircuitOpen() bool
+	CircuitState() services.CircuitState
+}
+
+var _ GoogleAgentServiceInterface = (*services.GoogleAgentEngineService)(nil)
+
 // TranscribeServiceInterface defines audio transcription operations
 type TranscribeServiceInterface interface {
 	TranscribeAudio(ctx context.Context, audioURL string) (string, error)
@@ -40,14 +152,21 @@ type TranscribeServiceInterface interface {
 	ValidateAudioURL(url string) error
 }
 
+// ConfidenceTranscriber is implemented by transcription backends that report the recognizer's confidence
+// in a transcript, from 0 to 1 (0 when the recognizer gave none)
+type ConfidenceTranscriber interface {
+	TranscribeAudioWithConfidence(ctx context.Context, audioURL string) (string, float32, error)
+}
+
 // TranscribeServiceAdapter adapts the services.TranscribeService to the handler interface
 type TranscribeServiceAdapter struct {
 	service *services.TranscribeService
+	config  *config.Config
 }
 
 // NewTranscribeServiceAdapter creates a new adapter
-func NewTranscribeServiceAdapter(service *services.TranscribeService) *TranscribeServiceAdapter {
-	return &TranscribeServiceAdapter{service: service}
+func NewTranscribeServiceAdapter(service *services.TranscribeService, cfg *config.Config) *TranscribeServiceAdapter {
+	return &TranscribeServiceAdapter{service: service, config: cfg}
 }
 
 // TranscribeAudio implements the interface by calling TranscribeFromURL
@@ -55,24 +174,27 @@ func (a *TranscribeServiceAdapter) TranscribeAudio(ctx context.Context, audioURL
 	if a.service == nil {
 		return "", fmt.Errorf("transcribe service is not available")
 	}
+	transcript, _, err := a.TranscribeAudioWithConfidence(ctx, audioURL)
+	return transcript, err
+}
+
+// TranscribeAudioWithConfidence transcribes the audio, also returning the confidence of the transcript
+func (a *TranscribeServiceAdapter) TranscribeAudioWithConfidence(ctx context.Context, audioURL string) (string, float32, error) {
+	if a.service == nil {
+		return "", 0, fmt.Errorf("transcribe service is not available")
+	}
 	result, err := a.service.TranscribeFromURL(ctx, audioURL)
 	if err != nil {
-		return "", err
+		return "", 0, err
 	}
-	return result.Text, nil
+	return result.Text, result.Confidence, nil
 }
 
 // IsAudioURL checks if the URL appears to be an audio file
 func (a *TranscribeServiceAdapter) IsAudioURL(url string) bool {
 	// Check for audio file extensions regardless of service availability
 	// This allows detection even when transcribe service is not configured
-	audioExtensions := []string{".mp3", ".wav", ".m4a", ".aac", ".ogg", ".oga", ".flac", ".wma", ".opus"}
-	for _, ext := range audioExtensions {
-		if strings.HasSuffix(strings.ToLower(url), ext) {
-			return true
-		}
-	}
-	return false
+	return services.IsAudioURL(a.config, url)
 }
 
 // ValidateAudioURL validates the audio URL format
@@ -86,16 +208,44 @@ func (a *TranscribeServiceAdapter) ValidateAudioURL(url string) error {
 	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
 		return fmt.Errorf("audio URL must start with http:// or https://")
 	}
-	return nil
+	return a.service.ValidateAudioURL(url)
+}
+
+// Close releases the underlying transcribe service, if any
+func (a *TranscribeServiceAdapter) Close() error {
+	if a.service == nil {
+		return nil
+	}
+	return a.service.Close()
 }
 
 // MessageFormatterInterface defines message formatting operations
 type MessageFormatterInterface interface {
 	FormatForWhatsApp(ctx context.Context, response *models.AgentResponse) (string, error)
+	// FormatForChannel formats for the message's channel (models.Channel*); empty or unknown means WhatsApp
+	FormatForChannel(ctx context.Context, channel string, response *models.AgentResponse) (string, error)
 	FormatErrorMessage(ctx context.Context, err error) string
 	ValidateMessageContent(content string) error
 }
 
+// formatter returns the message formatter. Without one (allowed with MESSAGE_FORMATTING_MODE=none) content is
+// validated and errors are worded the same way, and replies are left unformatted.
+func (deps *MessageHandlerDependencies) formatter() MessageFormatterInterface {
+	if deps.MessageFormatter != nil {
+		return deps.MessageFormatter
+	}
+	return services.NewUnformattedMessageFormatter(deps.Config, deps.Logger)
+}
+
+// CheckFormatter fails when MESSAGE_FORMATTING_MODE requires a message formatter and none is configured, so a
+// worker doesn't start silently delivering unformatted replies
+func (deps *MessageHandlerDependencies) CheckFormatter() error {
+	if deps.MessageFormatter == nil && deps.Config.GoogleAgentEngine.FormattingMode != config.FormattingModeNone {
+		return fmt.Errorf("MESSAGE_FORMATTING_MODE=%s requires a message formatter", deps.Config.GoogleAgentEngine.FormattingMode)
+	}
+	return nil
+}
+
 // CreateUserMessageHandler creates a handler for user messages
 func CreateUserMessageHandler(deps *MessageHandlerDependencies) func(context.Context, amqp.Delivery) error {
 	return func(ctx context.Context, delivery amqp.Delivery) error {
@@ -119,37 +269,136 @@ func CreateUserMessageHandler(deps *MessageHandlerDependencies) func(context.Con
 			}
 		}
 
-		logger.Info("Processing user message")
+		logger.Debug("Received user message delivery")
+
+		// Only trust messages signed by our publishers when verification is required
+		if deps.Config.Security.QueueSignatureRequired {
+			if err := services.VerifyQueueMessageSignature(delivery, deps.Config.Security.QueueSignatureSecret); err != nil {
+				logger.WithError(err).Error("Queue message failed signature verification")
+				return services.NewRejectError(fmt.Errorf("invalid message signature: %w", err))
+			}
+		}
 
 		// Parse the queue message
 		var queueMsg models.QueueMessage
-		if err := json.Unmarshal(delivery.Body, &queueMsg); err != nil {
-			logger.WithError(err).Error("Failed to unmarshal queue message")
+		if err := deps.decodeQueueMessage(delivery, &queueMsg); err != nil {
+			logger.WithError(err).WithField("content_type", delivery.ContentType).Error("Failed to decode queue message")
 			// Return error for malformed messages (service layer will handle nack)
 			return err
 		}
 
+		// From here on, the message's task data is read and written in its tenant's namespace
+		deps := deps.forTenant(queueMsg.TenantID)
+
+		// Sampled messages are logged step by step whatever LOG_LEVEL is
+		deps, sampled := deps.withLogSampling()
+		if sampled {
+			logger = deps.Logger.WithFields(logger.Data).WithField("log_sampled", true)
+		}
+
+		// Resolve the correlation ID so every log line, span, stored result and agent call carries it
+		queueMsg.CorrelationID = correlationIDFromDelivery(delivery, &queueMsg)
+		ctx = services.WithCorrelationID(ctx, queueMsg.CorrelationID)
+
 		logger = logger.WithFields(logrus.Fields{
 			"queue_message_id": queueMsg.ID,
 			"user_number":      queueMsg.UserNumber,
 			"message_type":     queueMsg.Type,
 			"provider":         queueMsg.Provider,
+			"correlation_id":   queueMsg.CorrelationID,
 		})
 
+		// Publishers spell providers differently ("Google-Agent-Engine", "gae"); check the canonical name
+		if queueMsg.Provider != "" {
+			queueMsg.Provider = deps.Config.NormalizeProvider(queueMsg.Provider)
+		}
+
+		// Reject malformed-but-parseable messages up front; retrying them can never succeed
+		if err := queueMsg.Validate(); err != nil {
+			logger.WithError(err).Error("Invalid queue message, marking task as failed without retry")
+			if queueMsg.ID != "" {
+				validationErr := services.NewProcessingError(services.ErrValidation, "invalid queue message", err)
+				if redisErr := storeTaskError(ctx, deps, queueMsg.ID, validationErr); redisErr != nil {
+					logger.WithError(redisErr).Error("Failed to store validation error in Redis")
+				}
+				if statusErr := deps.RedisService.SetTaskStatus(ctx, queueMsg.ID, string(models.TaskStatusFailed), deps.Config.GetTaskStatusTTL(string(models.TaskStatusFailed))); statusErr != nil {
+					logger.WithError(statusErr).WithField("redis_failure", redisFailureStatusUpdate).Error("Failed to update task status to failed")
+				}
+				notifyTaskEvent(deps, queueMsg.ID, queueMsg.UserNumber, models.TaskStatusFailed, err)
+				if deps.CallbackService != nil {
+					callbackURL, getErr := deps.RedisService.GetCallbackURL(ctx, queueMsg.ID)
+					if getErr == nil && callbackURL != "" {
+						go executeCallbackOnError(context.Background(), deps, queueMsg.ID, callbackURL, err, &queueMsg, logger)
+					}
+				}
+			}
+			// Return nil so the message is acknowledged rather than retried
+			return nil
+		}
+
+		// Don't pay for answers to messages the user has long stopped waiting for
+		if maxAge := deps.Config.RabbitMQ.MaxMessageAge; maxAge > 0 {
+			enqueuedAt := messageEnqueuedAt(delivery, &queueMsg)
+			if age := time.Since(enqueuedAt); !enqueuedAt.IsZero() && age > maxAge {
+				logger.WithFields(logrus.Fields{
+					"message_age": age.String(),
+					"max_age":     maxAge.String(),
+				}).Warn("Queue message expired before processing, skipping agent call")
+				if statusErr := deps.RedisService.SetTaskStatus(ctx, queueMsg.ID, string(models.TaskStatusExpired), deps.Config.GetTaskStatusTTL(string(models.TaskStatusExpired))); statusErr != nil {
+					logger.WithError(statusErr).WithField("redis_failure", redisFailureStatusUpdate).Error("Failed to update task status to expired")
+				}
+				notifyTaskEvent(deps, queueMsg.ID, queueMsg.UserNumber, models.TaskStatusExpired, nil)
+				return nil
+			}
+		}
+
+		// Hold back messages scheduled for later (e.g. reminders) until they are due
+		if deferred, err := deferScheduledMessage(ctx, delivery, &queueMsg, deps, logger); deferred {
+			return err
+		}
+
+		// In durable mode the delivery is only acknowledged once the outcome is stored, so failed writes fail the message
+		durableAck := deps.Config.GetAckMode() == config.AckModeDurable
+
 		// Update task status to processing
-		if err := deps.RedisService.SetTaskStatus(ctx, queueMsg.ID, string(models.TaskStatusProcessing), deps.Config.Redis.TaskStatusTTL); err != nil {
-			logger.WithError(err).Error("Failed to update task status to processing")
+		err := deps.RedisService.SetTaskStatus(ctx, queueMsg.ID, string(models.TaskStatusProcessing), deps.Config.GetTaskStatusTTL(string(models.TaskStatusProcessing)))
+		if err != nil {
+			logger.WithError(err).WithField("redis_failure", redisFailureStatusUpdate).Error("Failed to update task status to processing")
+		}
+		if outageErr := redisOutageError(deps, err); outageErr != nil {
+			logger.Warn("Redis outage in progress, returning message to the queue before processing")
+			return outageErr
+		}
+		markProcessing(ctx, deps, queueMsg.UserNumber, logger)
+
+		// Index the task under its user so recent interactions can be listed without scanning keys
+		if size := deps.Config.Redis.UserTaskIndexSize; size > 0 {
+			createdAt := queueMsg.Timestamp
+			if createdAt.IsZero() {
+				createdAt = time.Now()
+			}
+			if err := deps.RedisService.RecordUserTask(ctx, queueMsg.UserNumber, queueMsg.ID, createdAt, size, deps.Config.Redis.UserTaskIndexTTL); err != nil {
+				logger.WithError(err).Warn("Failed to index task for user")
+			}
+		}
+
+		// A coalesced batch that fails while this message still has retries puts its other messages back
+		if deliveryRetryCount(delivery) < int64(deps.Config.RabbitMQ.MaxRetries) {
+			ctx = withRetriesLeft(ctx)
 		}
 
 		// Process the user message with optional OTel tracing
 		var response string
-		var err error
+		var status models.TaskStatus
 
 		if deps.OTelWorkerWrapper != nil {
 			// Wrap with OpenTelemetry tracing
 			err = deps.OTelWorkerWrapper.WrapWorkerTask(ctx, "user_message_worker", "process_user_message", func(tracedCtx context.Context) error {
 				// Detect message type early for tracing attributes
-				isAudio := isAudioURL(queueMsg.Message)
+				_, _, hasAudioMedia := audioMediaFromMessage(deps.Config, &queueMsg)
+				isAudio := hasAudioMedia || services.IsAudioURL(deps.Config, queueMsg.Message)
+				_, _, isImage := imageFromMessage(&queueMsg)
+				_, _, isDocument := documentFromMessage(deps.Config, &queueMsg)
 
 				// Add message type attribute to current span if possible
 				if span := trace.SpanFromContext(tracedCtx); span.IsRecording() {
@@ -159,56 +408,111 @@ func CreateUserMessageHandler(deps *MessageHandlerDependencies) func(context.Con
 							if isAudio {
 								return "audio"
 							}
+							if isImage {
+								return "image"
+							}
+							if isDocument {
+								return "document"
+							}
 							return "text"
 						}()),
 						attribute.Int("message.length", len(queueMsg.Message)),
+						attribute.String("correlation.id", queueMsg.CorrelationID),
 					)
 				}
 
-				response, err = processUserMessage(tracedCtx, &queueMsg, deps)
+				response, status, err = processUserMessage(tracedCtx, &queueMsg, deps)
 				return err
 			})
 		} else {
 			// Process without tracing
-			response, err = processUserMessage(ctx, &queueMsg, deps)
+			response, status, err = processUserMessage(ctx, &queueMsg, deps)
+		}
+
+		// Coalesced messages are answered by the batch leader, which also sets their status
+		if errors.Is(err, ErrMessageCoalesced) {
+			logger.Info("User message coalesced into another task's batch")
+			return nil
+		}
+
+		// Cancelled tasks are acknowledged without retry or error callback
+		if errors.Is(err, ErrTaskCancelled) {
+			logger.Info("User message processing cancelled")
+			if statusErr := deps.RedisService.SetTaskStatus(ctx, queueMsg.ID, string(models.TaskStatusCancelled), deps.Config.GetTaskStatusTTL(string(models.TaskStatusCancelled))); statusErr != nil {
+				logger.WithError(statusErr).WithField("redis_failure", redisFailureStatusUpdate).Error("Failed to update task status to cancelled")
+			}
+			notifyTaskEvent(deps, queueMsg.ID, queueMsg.UserNumber, models.TaskStatusCancelled, nil)
+			if deps.OTelWorkerWrapper != nil {
+				if span := trace.SpanFromContext(ctx); span.IsRecording() {
+					span.SetAttributes(
+						attribute.Bool("task.will_retry", false),
+						attribute.String("task.error_type", "cancelled"),
+					)
+				}
+			}
+			return nil
+		}
+
+		// Rate-limited agent calls are requeued after the Retry-After delay rather than failed or counted as a retry
+		var rateLimitErr *services.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			delay := deps.Config.GetRateLimitRetryDelay(rateLimitErr.RetryAfter)
+			logger.WithFields(logrus.Fields{
+				"error_category": services.ErrorCategory(err),
+				"retry_after":    rateLimitErr.RetryAfter,
+				"retry_delay":    delay,
+			}).Warn("Agent rate limited, requeueing message after delay")
+			if statusErr := deps.RedisService.SetTaskStatus(ctx, queueMsg.ID, string(models.TaskStatusProcessing), deps.Config.GetTaskStatusTTL(string(models.TaskStatusProcessing))); statusErr != nil {
+				logger.WithError(statusErr).WithField("redis_failure", redisFailureStatusUpdate).Error("Failed to update task status to processing for retry")
+			}
+			if deps.OTelWorkerWrapper != nil {
+				if span := trace.SpanFromContext(ctx); span.IsRecording() {
+					span.SetAttributes(
+						attribute.Bool("task.will_retry", true),
+						attribute.String("task.error_type", "rate_limited"),
+						attribute.String("task.error_category", services.ErrorCategory(err)),
+						attribute.Int64("task.retry_delay_ms", delay.Milliseconds()),
+					)
+				}
+			}
+			return services.NewRetryLaterError(delay, err)
 		}
 
 		if err != nil {
 			logger.WithError(err).Error("Failed to process user message")
 
 			// Store error in Redis
-			errorKey := "task:error:" + queueMsg.ID
-			if redisErr := deps.RedisService.Set(ctx, errorKey, err.Error(), deps.Config.Redis.TaskStatusTTL); redisErr != nil {
+			if redisErr := storeTaskError(ctx, deps, queueMsg.ID, err); redisErr != nil {
 				logger.WithError(redisErr).Error("Failed to store error in Redis")
 			}
 
-			// Extract retry count from RabbitMQ headers
-			retryCount := int64(0)
-			if delivery.Headers != nil {
-				if count, ok := delivery.Headers["x-retry-count"].(int64); ok {
-					retryCount = count
-				}
-			}
+			retryCount := deliveryRetryCount(delivery)
 			maxRetries := int64(deps.Config.RabbitMQ.MaxRetries)
 
-			// Determine if this error should be retried
-			shouldRetry := isRetriableError(err)
+			// Determine if this error should be retried based on its category
+			shouldRetry := shouldRetryError(err)
+			errorCategory := services.ErrorCategory(err)
 
 			if shouldRetry {
 				// Check if max retries reached
 				if retryCount >= maxRetries {
 					logger.WithFields(logrus.Fields{
-						"retriable":     true,
-						"error_type":    "retriable_max_retries_exceeded",
-						"error_message": err.Error(),
-						"retry_count":   retryCount,
-						"max_retries":   maxRetries,
+						"retriable":      true,
+						"error_type":     "retriable_max_retries_exceeded",
+						"error_category": errorCategory,
+						"error_message":  err.Error(),
+						"retry_count":    retryCount,
+						"max_retries":    maxRetries,
 					}).Error("Retriable error but max retries reached, marking as failed")
 
 					// Update task status to failed
-					if statusErr := deps.RedisService.SetTaskStatus(ctx, queueMsg.ID, string(models.TaskStatusFailed), deps.Config.Redis.TaskStatusTTL); statusErr != nil {
-						logger.WithError(statusErr).Error("Failed to update task status to failed")
+					if statusErr := deps.RedisService.SetTaskStatus(ctx, queueMsg.ID, string(models.TaskStatusFailed), deps.Config.GetTaskStatusTTL(string(models.TaskStatusFailed))); statusErr != nil {
+						logger.WithError(statusErr).WithField("redis_failure", redisFailureStatusUpdate).Error("Failed to update task status to failed")
+						if durableAck {
+							return fmt.Errorf("failed to store failed task status: %w", statusErr)
+						}
 					}
+					notifyTaskEvent(deps, queueMsg.ID, queueMsg.UserNumber, models.TaskStatusFailed, err)
 
 					// Execute error callback if configured
 					if deps.CallbackService != nil {
@@ -225,6 +529,7 @@ func CreateUserMessageHandler(deps *MessageHandlerDependencies) func(context.Con
 							span.SetAttributes(
 								attribute.Bool("task.will_retry", false),
 								attribute.String("task.error_type", "retriable_max_retries_exceeded"),
+								attribute.String("task.error_category", errorCategory),
 								attribute.String("task.error", err.Error()),
 								attribute.Int64("task.retry_count", retryCount),
 								attribute.Int64("task.max_retries", maxRetries),
@@ -237,15 +542,16 @@ func CreateUserMessageHandler(deps *MessageHandlerDependencies) func(context.Con
 				}
 
 				logger.WithFields(logrus.Fields{
-					"retriable":     true,
-					"error_type":    "retriable",
-					"error_message": err.Error(),
-					"retry_count":   retryCount,
-					"max_retries":   maxRetries,
+					"retriable":      true,
+					"error_type":     "retriable",
+					"error_category": errorCategory,
+					"error_message":  err.Error(),
+					"retry_count":    retryCount,
+					"max_retries":    maxRetries,
 				}).Warn("Error is retriable, will be retried by RabbitMQ")
 				// Update task status to processing (keep it processing for retry)
-				if statusErr := deps.RedisService.SetTaskStatus(ctx, queueMsg.ID, string(models.TaskStatusProcessing), deps.Config.Redis.TaskStatusTTL); statusErr != nil {
-					logger.WithError(statusErr).Error("Failed to update task status to processing for retry")
+				if statusErr := deps.RedisService.SetTaskStatus(ctx, queueMsg.ID, string(models.TaskStatusProcessing), deps.Config.GetTaskStatusTTL(string(models.TaskStatusProcessing))); statusErr != nil {
+					logger.WithError(statusErr).WithField("redis_failure", redisFailureStatusUpdate).Error("Failed to update task status to processing for retry")
 				}
 				// Add retriable error attributes to the main span if available
 				if deps.OTelWorkerWrapper != nil {
@@ -253,6 +559,7 @@ func CreateUserMessageHandler(deps *MessageHandlerDependencies) func(context.Con
 						span.SetAttributes(
 							attribute.Bool("task.will_retry", true),
 							attribute.String("task.error_type", "retriable"),
+							attribute.String("task.error_category", errorCategory),
 							attribute.String("task.error", err.Error()),
 							attribute.Int64("task.retry_count", retryCount),
 							attribute.Int64("task.max_retries", maxRetries),
@@ -263,14 +570,19 @@ func CreateUserMessageHandler(deps *MessageHandlerDependencies) func(context.Con
 				return err
 			} else {
 				logger.WithFields(logrus.Fields{
-					"retriable":     false,
-					"error_type":    "permanent",
-					"error_message": err.Error(),
+					"retriable":      false,
+					"error_type":     "permanent",
+					"error_category": errorCategory,
+					"error_message":  err.Error(),
 				}).Error("Error is permanent, marking task as failed")
 				// Update task status to failed for permanent errors
-				if statusErr := deps.RedisService.SetTaskStatus(ctx, queueMsg.ID, string(models.TaskStatusFailed), deps.Config.Redis.TaskStatusTTL); statusErr != nil {
-					logger.WithError(statusErr).Error("Failed to update task status to failed")
+				if statusErr := deps.RedisService.SetTaskStatus(ctx, queueMsg.ID, string(models.TaskStatusFailed), deps.Config.GetTaskStatusTTL(string(models.TaskStatusFailed))); statusErr != nil {
+					logger.WithError(statusErr).WithField("redis_failure", redisFailureStatusUpdate).Error("Failed to update task status to failed")
+					if durableAck {
+						return fmt.Errorf("failed to store failed task status: %w", statusErr)
+					}
 				}
+				notifyTaskEvent(deps, queueMsg.ID, queueMsg.UserNumber, models.TaskStatusFailed, err)
 				// Execute error callback if configured
 				if deps.CallbackService != nil {
 					callbackURL, getErr := deps.RedisService.GetCallbackURL(ctx, queueMsg.ID)
@@ -285,6 +597,7 @@ func CreateUserMessageHandler(deps *MessageHandlerDependencies) func(context.Con
 						span.SetAttributes(
 							attribute.Bool("task.will_retry", false),
 							attribute.String("task.error_type", "permanent"),
+							attribute.String("task.error_category", errorCategory),
 							attribute.String("task.error", err.Error()),
 						)
 					}
@@ -294,12 +607,6 @@ func CreateUserMessageHandler(deps *MessageHandlerDependencies) func(context.Con
 			}
 		}
 
-		// Store the response in Redis
-		if err := deps.RedisService.SetTaskResult(ctx, queueMsg.ID, response, deps.Config.Redis.TaskResultTTL); err != nil {
-			logger.WithError(err).Error("Failed to store task result")
-			// Don't fail the message processing for Redis storage issues
-		}
-
 		// Store trace context with result for end-to-end tracing
 		if deps.TracePropagator != nil {
 			traceHeaders := deps.TracePropagator.InjectTraceContext(ctx)
@@ -311,10 +618,31 @@ func CreateUserMessageHandler(deps *MessageHandlerDependencies) func(context.Con
 			}
 		}
 
-		// Update task status to completed
-		if err := deps.RedisService.SetTaskStatus(ctx, queueMsg.ID, string(models.TaskStatusCompleted), deps.Config.Redis.TaskStatusTTL); err != nil {
-			logger.WithError(err).Error("Failed to update task status to completed")
+		// Store the response with the final status (completed, or degraded/empty/blocked for fallback responses)
+		// in one round trip, also as the user's latest result when enabled
+		err = deps.RedisService.CompleteTask(ctx, queueMsg.ID, queueMsg.UserNumber, response, string(status), deps.Config.Redis.TaskResultTTL, deps.Config.GetTaskStatusTTL(string(status)))
+		if err != nil {
+			redisFailure := redisFailureResultStore
+			if errors.Is(err, services.ErrTaskStatusNotStored) {
+				redisFailure = redisFailureStatusUpdate
+			}
+			logger.WithError(err).WithFields(logrus.Fields{
+				"status":        status,
+				"redis_failure": redisFailure,
+			}).Error("Failed to store task result and final status")
+		}
+		if outageErr := redisOutageError(deps, err); outageErr != nil {
+			logger.Warn("Redis outage in progress, returning message to the queue instead of dropping its result")
+			return outageErr
 		}
+		// Only durable acknowledgement fails the message for Redis storage issues
+		if err != nil && durableAck {
+			return fmt.Errorf("failed to store task result and final status: %w", err)
+		}
+		notifyTaskEvent(deps, queueMsg.ID, queueMsg.UserNumber, status, nil)
+
+		// Deliver the reply when the gateway owns delivery; the stored result stays available either way
+		sendReply(ctx, deps, &queueMsg, response, logger)
 
 		// Add success attributes to the main span if available
 		if deps.OTelWorkerWrapper != nil {
@@ -323,6 +651,7 @@ func CreateUserMessageHandler(deps *MessageHandlerDependencies) func(context.Con
 					attribute.Bool("task.will_retry", false),
 					attribute.String("task.error_type", "none"),
 					attribute.Bool("task.success", true),
+					attribute.String("task.status", string(status)),
 					attribute.Int("task.response_length", len(response)),
 				)
 			}
@@ -333,21 +662,91 @@ func CreateUserMessageHandler(deps *MessageHandlerDependencies) func(context.Con
 			callbackURL, err := deps.RedisService.GetCallbackURL(ctx, queueMsg.ID)
 			if err == nil && callbackURL != "" {
 				// Execute callback asynchronously to avoid blocking worker
-				go executeCallback(context.Background(), deps, queueMsg.ID, callbackURL, response, &queueMsg, logger)
+				go executeCallback(context.Background(), deps, queueMsg.ID, callbackURL, response, status, &queueMsg, logger)
 			}
 		}
 
-		logger.WithField("response_length", len(response)).Info("User message processed successfully")
+		logger.WithFields(logrus.Fields{
+			"response_length": len(response),
+			"status":          status,
+		}).Info("User message processed successfully")
 
 		// Return success (service layer will handle acknowledgment)
 		return nil
 	}
 }
 
-// isAudioURL checks if the URL appears to be an audio file (standalone function)
-func isAudioURL(url string) bool {
-	audioExtensions := []string{".mp3", ".wav", ".m4a", ".aac", ".ogg", ".oga", ".flac", ".wma", ".opus"}
-	for _, ext := range audioExtensions {
+// markProcessing notifies the provider in the background so a slow provider never delays processing
+func markProcessing(ctx context.Context, deps *MessageHandlerDependencies, userNumber string, logger *logrus.Entry) {
+	if deps.ProviderNotifier == nil {
+		return
+	}
+
+	notifyCtx := context.WithoutCancel(ctx)
+	go func() {
+		if err := deps.ProviderNotifier.MarkProcessing(notifyCtx, userNumber); err != nil {
+			logger.WithError(err).Warn("Failed to notify provider that processing started")
+		}
+	}()
+}
+
+// exportProcessedMessage publishes a copy of the processed message to the configured sink in the background.
+// Failures are logged (and metered by the sink) but never affect the task.
+func exportProcessedMessage(ctx context.Context, deps *MessageHandlerDependencies, data models.ProcessedMessageData) {
+	if deps.MessageSink == nil {
+		return
+	}
+
+	exportCtx := context.WithoutCancel(ctx)
+	go func() {
+		if err := deps.MessageSink.Publish(exportCtx, data); err != nil {
+			deps.Logger.WithError(err).WithFields(logrus.Fields{
+				"message_id":     data.MessageID,
+				"correlation_id": data.CorrelationID,
+			}).Warn("Failed to export processed message")
+		}
+	}()
+}
+
+// correlationIDFromDelivery returns the message's correlation ID, preferring the AMQP header,
+// then the queue message field, and generating a new one for messages published without it
+func correlationIDFromDelivery(delivery amqp.Delivery, msg *models.QueueMessage) string {
+	if id, ok := delivery.Headers[models.CorrelationIDHeader].(string); ok && id != "" {
+		return id
+	}
+	if msg.CorrelationID != "" {
+		return msg.CorrelationID
+	}
+	return uuid.New().String()
+}
+
+// truncatedMessageMarker is appended to messages cut down to the maximum length
+const truncatedMessageMarker = "\n\n[mensagem truncada]"
+
+// truncateMessage cuts the message so that, with the truncation marker appended, it fits in maxLength
+// bytes, never splitting a multi-byte character. Limits too small for the marker cut without it.
+func truncateMessage(message string, maxLength int) string {
+	if len(message) <= maxLength {
+		return message
+	}
+
+	marker := truncatedMessageMarker
+	limit := maxLength - len(marker)
+	if limit <= 0 {
+		marker, limit = "", maxLength
+	}
+
+	// Back off to the first byte of the rune that straddles the limit
+	for limit > 0 && !utf8.RuneStart(message[limit]) {
+		limit--
+	}
+	return message[:limit] + marker
+}
+
+// isImageURL checks if the URL appears to be an image file
+func isImageURL(url string) bool {
+	imageExtensions := []string{".jpg", ".jpeg", ".png", ".gif", ".webp", ".bmp", ".heic"}
+	for _, ext := range imageExtensions {
 		if strings.HasSuffix(strings.ToLower(url), ext) {
 			return true
 		}
@@ -355,9 +754,55 @@ func isAudioURL(url string) bool {
 	return false
 }
 
-// processUserMessage handles the actual user message processing logic (matches Python process_user_message)
-func processUserMessage(ctx context.Context, msg *models.QueueMessage, deps *MessageHandlerDependencies) (string, error) {
-	logger := deps.Logger.WithField("function", "processUserMessage")
+// processUserMessage runs ProcessMessage and marshals the result for storage in Redis.
+// The returned status is TaskStatusCompleted, TaskStatusDegraded when the agent backend was short-circuited,
+// TaskStatusEmpty when the agent produced no messages, or TaskStatusBlocked when moderation blocked the
+// message or response. Each attempt is recorded in the audit trail.
+func processUserMessage(ctx context.Context, msg *models.QueueMessage, deps *MessageHandlerDependencies) (string, models.TaskStatus, error) {
+	startedAt := time.Now()
+	recordAudit(ctx, deps, newAuditEvent(msg, deps, services.AuditEventReceived))
+
+	processedData, err := ProcessMessage(ctx, msg, deps)
+	if err != nil {
+		auditProcessingError(ctx, msg, deps, err, startedAt)
+		return "", "", err
+	}
+
+	exportProcessedMessage(ctx, deps, processedData)
+
+	processedBytes, err := json.Marshal(processedData)
+	if err != nil {
+		deps.Logger.WithError(err).WithField("message_id", msg.ID).Error("Failed to marshal processed data to JSON")
+		err = services.NewProcessingError(services.ErrInternal, "failed to marshal processed response", err)
+		auditProcessingError(ctx, msg, deps, err, startedAt)
+		return "", "", err
+	}
+
+	auditProcessed(ctx, msg, deps, processedData, startedAt)
+	return string(processedBytes), processedTaskStatus(processedData), nil
+}
+
+// ProcessMessage runs the full user message pipeline (transcription, validation, agent call and response
+// transformation) for a queue message, independently of RabbitMQ (matches Python process_user_message).
+// The result's Status is "degraded" when the agent backend was short-circuited, "empty" when the agent
+// produced no messages and "blocked" when moderation blocked the message or response. The result carries the
+// message's Metadata. Errors are categorized ProcessingErrors, or ErrMessageCoalesced / ErrTaskCancelled
+// when no result is produced for this message.
+func ProcessMessage(ctx context.Context, msg *models.QueueMessage, deps *MessageHandlerDependencies) (processedData models.ProcessedMessageData, err error) {
+	startedAt := time.Now()
+	logger := deps.Logger.WithFields(logrus.Fields{
+		"function":       "ProcessMessage",
+		"message_id":     msg.ID,
+		"correlation_id": msg.CorrelationID,
+	})
+
+	// Round-trip the publisher's opaque context so results can be tied back to their domain objects.
+	// Registered first so it runs last: coalesced tasks sharing this result keep their own (absent) metadata.
+	defer func() {
+		if err == nil {
+			processedData.Metadata = msg.Metadata
+		}
+	}()
 
 	logger.WithFields(logrus.Fields{
 		"user_number":          msg.UserNumber,
@@ -365,459 +810,995 @@ func processUserMessage(ctx context.Context, msg *models.QueueMessage, deps *Mes
 		"has_previous_message": msg.PreviousMessage != nil,
 		"provider":             msg.Provider,
 	}).Info("Processing user message")
+	services.LogMessageBody(deps.Config, logger, "message_body", msg.Message, "User message body")
+
+	logger.Debug("Starting ProcessMessage")
 
-	logger.Info("DEBUG: Starting processUserMessage function execution")
+	// Gateway-generated messages (errors, fallbacks) are written in the user's language
+	ctx = services.WithLocale(ctx, resolveLocale(ctx, msg, deps, logger))
+
+	// Publishers may omit the provider; fall back to the configured default
+	if msg.Provider == "" {
+		msg.Provider = deps.Config.DefaultProvider
+		if msg.Provider == "" {
+			msg.Provider = models.ProviderGoogleAgentEngine
+		}
+		logger.WithField("provider", msg.Provider).Info("Message has no provider, applying default provider")
+	}
+	msg.Provider = deps.Config.NormalizeProvider(msg.Provider)
 
 	// Validate provider - currently only support google_agent_engine
-	if msg.Provider != "google_agent_engine" {
+	if msg.Provider != models.ProviderGoogleAgentEngine {
 		logger.WithField("provider", msg.Provider).Error("Unsupported provider")
-		return "", fmt.Errorf("unsupported provider: %s (currently only 'google_agent_engine' is supported)", msg.Provider)
+		return models.ProcessedMessageData{}, services.NewProcessingError(services.ErrUnsupportedProvider, fmt.Sprintf("unsupported provider: %s (currently only 'google_agent_engine' is supported)", msg.Provider), nil)
+	}
+
+	// Only models the deployment allows may be requested; retrying an unknown model can never succeed
+	model, err := resolveAgentModel(msg, deps)
+	if err != nil {
+		logger.WithField("model", msg.Model).Error("Requested agent model is not allowed")
+		return models.ProcessedMessageData{}, err
 	}
 
 	// Check if Google Agent service is available
 	if deps.GoogleAgentService == nil {
 		logger.Error("Google Agent Engine service not available")
-		return "", fmt.Errorf("google Agent Engine service is required but not available")
+		return models.ProcessedMessageData{}, services.NewProcessingError(services.ErrInternal, "google Agent Engine service is required but not available", nil)
 	}
 
-	// Handle audio transcription if message is an audio URL
+	// Handle audio transcription if the message carries an audio media object or is an audio URL
 	message := msg.Message
 	var transcriptText *string
+	var imageURLs []string
+	var documents []services.DocumentInput
+	var fallbackReason string
+	var audioSourceURL string
 
-	// Check if message is an audio URL (independent of service availability)
-	isAudioURL := isAudioURL(message)
+	if audioURL, caption, ok := audioMediaFromMessage(deps.Config, msg); ok {
+		// Structured media takes precedence; the caption (if any) is combined with the transcript
+		logger.WithFields(logrus.Fields{
+			"audio_url":   audioURL,
+			"mime_type":   msg.Media.MimeType,
+			"has_caption": caption != "",
+		}).Info("Detected audio media object, attempting transcription")
+		audioSourceURL = audioURL
+
+		transcript, reason := transcribeAudioMessage(ctx, msg.UserNumber, audioURL, deps, logger)
+		fallbackReason = reason
+		if reason == "" {
+			transcriptText = &transcript
+			message = combineCaptionAndTranscript(caption, transcript)
+		} else if caption != "" {
+			message = caption
+		} else {
+			message = localizedMessage(ctx, deps, services.MsgFallbackInput)
+		}
+	} else if services.IsAudioURL(deps.Config, message) {
+		// Fallback: the message itself is a bare audio URL
+		logger.WithField("audio_url", message).Info("Detected audio URL, attempting transcription")
+		audioSourceURL = message
 
-	if isAudioURL {
-		// Trace audio transcription step
-		var transcribeCtx context.Context
-		var transcribeSpan trace.Span
-		if deps.OTelWorkerWrapper != nil {
-			transcribeCtx, transcribeSpan = deps.OTelWorkerWrapper.StartSpan(ctx, "audio_transcription",
-				attribute.String("audio.url", message),
-				attribute.Bool("audio.detected", true),
-				attribute.String("audio.format", getAudioFormatFromURL(message)))
-			defer transcribeSpan.End()
+		transcript, reason := transcribeAudioMessage(ctx, msg.UserNumber, message, deps, logger)
+		fallbackReason = reason
+		if reason == "" {
+			transcriptText = &transcript
+			message = transcript
 		} else {
-			transcribeCtx = ctx
+			// Fallback to not block the flow (matches Python logic)
+			message = localizedMessage(ctx, deps, services.MsgFallbackInput)
 		}
+	} else if imageURL, caption, ok := imageFromMessage(msg); ok {
+		// Images are forwarded as multimodal content to vision-capable agents only
+		logger.WithFields(logrus.Fields{
+			"image_url":   imageURL,
+			"has_caption": caption != "",
+		}).Info("Detected image input")
 
-		logger.WithField("audio_url", message).Info("Detected audio URL, attempting transcription")
+		if !deps.GoogleAgentService.SupportsImages() {
+			logger.Info("Agent does not support images, returning configured fallback message")
+			return buildFallbackResponse(msg, deps, startedAt, deps.Config.GoogleAgentEngine.ImageUnsupportedMessage, models.TaskStatusCompleted, false)
+		}
 
-		if deps.TranscribeService == nil {
-			logger.Warn("Transcribe service not available, using fallback")
-			message = "Ajuda"
-			if deps.OTelWorkerWrapper != nil && transcribeSpan != nil {
-				transcribeSpan.SetAttributes(
-					attribute.Bool("transcription.success", false),
-					attribute.String("transcription.error_type", "service_not_available"),
-					attribute.Bool("transcription.fallback_used", true))
-			}
+		imageURLs = []string{imageURL}
+		message = caption
+	} else if document, caption, ok := documentFromMessage(deps.Config, msg); ok {
+		// Documents are forwarded to document-capable agents, otherwise their extracted text is sent
+		logger.WithFields(logrus.Fields{
+			"document_url": document.URL,
+			"mime_type":    document.MimeType,
+			"has_caption":  caption != "",
+		}).Info("Detected document input")
+
+		if deps.GoogleAgentService.SupportsDocuments() {
+			documents = []services.DocumentInput{document}
+			message = caption
+		} else if text, ok := extractDocumentText(ctx, document, deps, logger); ok {
+			message = combineCaptionAndDocument(caption, text)
 		} else {
-			transcript, err := deps.TranscribeService.TranscribeAudio(transcribeCtx, message)
-			if err != nil {
-				logger.WithError(err).Warn("Failed to transcribe audio, using fallback")
-				// Fallback to not block the flow (matches Python logic)
-				message = "Ajuda"
-				if deps.OTelWorkerWrapper != nil && transcribeSpan != nil {
-					transcribeSpan.SetAttributes(
-						attribute.Bool("transcription.success", false),
-						attribute.String("transcription.error_type", classifyTranscriptionError(err)),
-						attribute.String("transcription.error", err.Error()),
-						attribute.Bool("transcription.fallback_used", true))
-				}
-			} else if transcript != "" && strings.TrimSpace(transcript) != "" && transcript != "Áudio sem conteúdo reconhecível" {
-				transcriptText = &transcript
-				message = transcript
-				logger.WithField("transcript_length", len(transcript)).Info("Audio transcribed successfully")
-				if deps.OTelWorkerWrapper != nil && transcribeSpan != nil {
-					transcribeSpan.SetAttributes(
-						attribute.Bool("transcription.success", true),
-						attribute.Int("transcription.transcript_length", len(transcript)),
-						attribute.Bool("transcription.fallback_used", false))
-				}
-			} else {
-				logger.Warn("Transcription returned no useful content, using fallback")
-				message = "Ajuda"
-				if deps.OTelWorkerWrapper != nil && transcribeSpan != nil {
-					transcribeSpan.SetAttributes(
-						attribute.Bool("transcription.success", false),
-						attribute.String("transcription.error_type", "empty_or_invalid_content"),
-						attribute.Bool("transcription.fallback_used", true))
-				}
-			}
+			logger.Info("Document can't be read by the agent, returning configured fallback message")
+			return buildFallbackResponse(msg, deps, startedAt, deps.Config.GoogleAgentEngine.DocumentUnsupportedMessage, models.TaskStatusCompleted, false)
 		}
 	}
 
-	// Validate message content
-	if deps.MessageFormatter != nil {
-		if err := deps.MessageFormatter.ValidateMessageContent(message); err != nil {
+	// Enforce the maximum message length before validation and the agent call
+	if maxLength := deps.Config.GoogleAgentEngine.MaxMessageLength; maxLength > 0 && len(message) > maxLength {
+		logger.WithFields(logrus.Fields{
+			"original_length": len(message),
+			"max_length":      maxLength,
+			"policy":          deps.Config.GoogleAgentEngine.MessageLengthPolicy,
+		}).Warn("Message exceeds maximum length")
+
+		if deps.Config.GoogleAgentEngine.MessageLengthPolicy == "truncate" {
+			message = truncateMessage(message, maxLength)
+		} else {
+			lengthErr := fmt.Errorf("message too large: %d bytes exceeds the limit of %d bytes", len(message), maxLength)
+			content := deps.formatter().FormatErrorMessage(ctx, lengthErr)
+			return buildFallbackResponse(msg, deps, startedAt, content, models.TaskStatusCompleted, true)
+		}
+	}
+
+	// Validate message content (an image or document may be sent without any text)
+	if !(message == "" && len(imageURLs)+len(documents) > 0) {
+		if err := deps.formatter().ValidateMessageContent(message); err != nil {
 			logger.WithError(err).Error("Message content validation failed")
-			return "", fmt.Errorf("invalid message content: %w", err)
+			return models.ProcessedMessageData{}, services.NewProcessingError(services.ErrValidation, "invalid message content", err)
 		}
 	}
 
-	// Trace thread creation step
-	var threadCtx context.Context
-	var threadSpan trace.Span
-	if deps.OTelWorkerWrapper != nil {
-		threadCtx, threadSpan = deps.OTelWorkerWrapper.StartSpan(ctx, "thread_management",
-			attribute.String("user.number", msg.UserNumber))
-		defer threadSpan.End()
-	} else {
-		threadCtx = ctx
+	// Coalesce rapid text messages from the same user into a single agent call
+	if deps.Config.RabbitMQ.CoalesceWindow > 0 && len(imageURLs)+len(documents) == 0 {
+		batch := coalesceUserMessage(ctx, msg, message, deps, logger)
+		if batch == nil {
+			return models.ProcessedMessageData{}, ErrMessageCoalesced
+		}
+		message = batch.Message
+
+		// Share the outcome with every coalesced task; if this task's own message was drained
+		// by an earlier batch, it has already been answered there
+		defer func() {
+			finalizeCoalescedTasks(ctx, deps, msg, batch, processedData, err, logger)
+			if err == nil && !batch.includes(msg.ID) {
+				processedData, err = models.ProcessedMessageData{}, ErrMessageCoalesced
+			}
+		}()
+	}
+
+	// Messages the moderation policy blocks never reach the agent
+	if decision := checkModeration(ctx, deps, logger, moderationStageInput, message); decision.Blocked {
+		return buildBlockedResponse(ctx, msg, deps, startedAt, decision.Reason)
+	}
+
+	dryRun := isDryRun(msg, deps)
+
+	// The message's provider is tried first, then each fallback provider while they fail or are circuit-broken
+	providers := deps.agentProviders(msg.Provider)
+	if dryRun {
+		providers = providers[:1]
 	}
 
-	// Get or create thread for user (thread ID corresponds to agent ID in Python logic)
-	threadID, err := deps.GoogleAgentService.GetOrCreateThread(threadCtx, msg.UserNumber)
+	// Short-circuit while every agent backend's circuit breaker is open instead of hammering them
+	if !dryRun && allCircuitsOpen(providers) {
+		logger.WithField("circuit_state", deps.GoogleAgentService.CircuitState().String()).Warn("Google Agent Engine circuit open, returning degraded response")
+		return buildUnavailableResponse(ctx, msg, deps, startedAt, fmt.Errorf("google agent engine unavailable: %w", services.ErrCircuitOpen))
+	}
+
+	// Serialize this user's thread lookup and agent call with their other in-flight messages
+	releaseUserLock, err := acquireUserLock(ctx, msg, deps, logger)
 	if err != nil {
-		logger.WithError(err).Error("Failed to get or create thread")
-		if deps.OTelWorkerWrapper != nil && threadSpan != nil {
-			threadSpan.SetAttributes(
-				attribute.String("thread.result", "error"),
-				attribute.String("thread.error", err.Error()))
+		return models.ProcessedMessageData{}, err
+	}
+	defer releaseUserLock()
+
+	// Tenant framing and intent examples go only to the agent; they are stripped from the echoed user message below
+	preamble := deps.Config.GetAgentPreamble(msg.TenantID, msg.Provider)
+	if examples := intentExamples(ctx, msg, message, deps, logger); examples != "" {
+		preamble = withPreamble(preamble, examples)
+	}
+	request := agentRequest{
+		message:      message,
+		agentMessage: withPreamble(preamble, message),
+		imageURLs:    imageURLs,
+		documents:    documents,
+		model:        model,
+		dryRun:       dryRun,
+	}
+
+	var agentResponse *models.AgentResponse
+	var threadID, servedBy string
+	for i, provider := range providers {
+		agentResponse, threadID, err = callAgentProvider(ctx, provider, msg, deps, logger, request)
+		if err == nil {
+			servedBy = provider.Name
+			break
+		}
+		if errors.Is(err, ErrTaskCancelled) || ctx.Err() != nil || i == len(providers)-1 {
+			break
 		}
-		return "", fmt.Errorf("failed to get thread: %w", err)
+		logger.WithError(err).WithFields(logrus.Fields{
+			"provider":      provider.Name,
+			"next_provider": providers[i+1].Name,
+		}).Warn("Agent provider failed, falling back to the next provider")
 	}
+	releaseUserLock()
 
-	if deps.OTelWorkerWrapper != nil && threadSpan != nil {
-		threadSpan.SetAttributes(
-			attribute.String("thread.result", "success"),
-			attribute.String("thread.id", threadID))
+	if err != nil {
+		// Every provider's breaker may have opened (or be probing) between the check above and the calls
+		if errors.Is(err, services.ErrCircuitOpen) {
+			return buildUnavailableResponse(ctx, msg, deps, startedAt, err)
+		}
+		return models.ProcessedMessageData{}, err
 	}
 
-	logger.WithField("thread_id", threadID).Info("Using thread for conversation")
+	logger.WithFields(logrus.Fields{
+		"thread_id": threadID,
+		"provider":  servedBy,
+	}).Info("Agent response received")
 
-	// Trace Google Agent Engine call
-	var agentCtx context.Context
-	var agentSpan trace.Span
-	if deps.OTelWorkerWrapper != nil {
-		agentCtx, agentSpan = deps.OTelWorkerWrapper.StartSpan(ctx, "google_agent_engine_call",
+	// Tie the whole message's trace, not just the thread and agent call spans, to the conversation
+	if span := trace.SpanFromContext(ctx); span.IsRecording() {
+		span.SetAttributes(
 			attribute.String("thread.id", threadID),
-			attribute.String("message.content", message),
-			attribute.Int("message.length", len(message)))
-		defer agentSpan.End()
-	} else {
-		agentCtx = ctx
+			attribute.String("agent.provider", servedBy))
 	}
 
-	// Send message to Google Agent Engine
-	// The Google Agent Engine automatically handles previous message context via thread ID
-	agentResponse, err := deps.GoogleAgentService.SendMessage(agentCtx, threadID, message)
-	if err != nil {
-		logger.WithError(err).Error("Failed to send message to Google Agent Engine")
-		if deps.OTelWorkerWrapper != nil && agentSpan != nil {
-			agentSpan.SetAttributes(
-				attribute.String("agent.result", "error"),
-				attribute.String("agent.error", err.Error()))
+	// Track activity so the thread warmer keeps this user's thread ready
+	if deps.Config.GoogleAgentEngine.ThreadWarmerEnabled {
+		if err := deps.RedisService.RecordUserActivity(ctx, msg.UserNumber, time.Now()); err != nil {
+			logger.WithError(err).Warn("Failed to record user activity")
 		}
-		return "", fmt.Errorf("failed to get AI response: %w", err)
 	}
 
-	if deps.OTelWorkerWrapper != nil && agentSpan != nil {
-		agentSpan.SetAttributes(
-			attribute.String("agent.result", "success"),
-			attribute.Int("agent.response_length", len(agentResponse.Content)))
+	exchange := agentExchange{
+		rawResponse:    agentResponse.Content,
+		message:        message,
+		preamble:       preamble,
+		model:          model,
+		threadID:       threadID,
+		provider:       servedBy,
+		fallbackReason: fallbackReason,
+		audioSourceURL: audioSourceURL,
+		transcript:     transcriptText,
+		startedAt:      startedAt,
+	}
+
+	// Keep the untransformed response for debugging discrepancies and replays; never fails the task
+	if msg.StoreRawResponse || deps.Config.GoogleAgentEngine.StoreRawResponse {
+		storeRawResponse(ctx, msg, exchange, deps, logger)
 	}
 
+	return transformAgentResponse(ctx, msg, deps, logger, exchange)
+}
+
+// agentExchange is what the fetch phase of ProcessMessage hands to its transform phase: the agent's raw
+// response and the context it was produced in
+type agentExchange struct {
+	rawResponse    string
+	message        string // Text sent to the agent, without the preamble
+	preamble       string
+	model          string
+	threadID       string
+	provider       string // Agent provider that served the response
+	fallbackReason string
+	audioSourceURL string
+	transcript     *string
+	startedAt      time.Time
+}
+
+// transformAgentResponse is the transform phase of ProcessMessage: it turns the agent's raw response into the
+// processed result (transform, post-process, moderate, shape) without calling the agent, so stored raw
+// responses can be reprocessed with ReplayTask
+func transformAgentResponse(ctx context.Context, msg *models.QueueMessage, deps *MessageHandlerDependencies, logger *logrus.Entry, exchange agentExchange) (models.ProcessedMessageData, error) {
+	var processedData models.ProcessedMessageData
+
 	// Trace response processing step
 	var responseSpan trace.Span
 	if deps.OTelWorkerWrapper != nil {
 		_, responseSpan = deps.OTelWorkerWrapper.StartSpan(ctx, "response_processing",
-			attribute.String("response.raw_length", fmt.Sprintf("%d", len(agentResponse.Content))))
+			attribute.String("response.raw_length", fmt.Sprintf("%d", len(exchange.rawResponse))),
+			attribute.String("correlation.id", msg.CorrelationID))
 		defer responseSpan.End()
 	}
 
 	// Parse Google's raw JSON response immediately after getting it from Google Agent Engine
-	logger.WithField("raw_response_length", len(agentResponse.Content)).Debug("Processing Google Agent Engine response")
-
-	// Clean the JSON string first to handle newlines and other whitespace issues
-	cleanedResponse := strings.ReplaceAll(agentResponse.Content, "\n", "")
-	cleanedResponse = strings.ReplaceAll(cleanedResponse, "\r", "")
-	cleanedResponse = strings.TrimSpace(cleanedResponse)
+	logger.WithField("raw_response_length", len(exchange.rawResponse)).Debug("Processing Google Agent Engine response")
 
-	var parsedResponse map[string]interface{}
-	if err := json.Unmarshal([]byte(cleanedResponse), &parsedResponse); err != nil {
-		logger.WithFields(logrus.Fields{
-			"error":       err.Error(),
-			"raw_json":    cleanedResponse,
-			"json_length": len(cleanedResponse),
-		}).Error("Failed to parse Google Agent Engine JSON response")
+	// Convert the provider's raw response into the gateway's message format
+	transformer, err := messageTransformerFor(msg.Provider)
+	if err != nil {
+		logger.WithError(err).Error("No message transformer for provider")
+		return models.ProcessedMessageData{}, services.NewProcessingError(services.ErrUnsupportedProvider, "no response transformer for provider", err)
+	}
+	transformedMessages, err := transformer.Transform(exchange.rawResponse, logger)
+	if err != nil {
+		logger.WithError(err).Error("Failed to transform agent response")
 		if deps.OTelWorkerWrapper != nil && responseSpan != nil {
 			responseSpan.SetAttributes(
-				attribute.String("response.result", "json_parse_error"),
+				attribute.String("response.result", "transform_error"),
 				attribute.String("response.error", err.Error()))
 		}
-		return "", fmt.Errorf("failed to parse AI response JSON: %w", err)
+		return models.ProcessedMessageData{}, err
 	}
 
-	// Extract the 'output' field which contains the messages
-	output, exists := parsedResponse["output"]
-	if !exists {
-		logger.Error("No 'output' field found in Google Agent Engine response")
-		return "", fmt.Errorf("invalid Google Agent Engine response format - missing 'output' field")
+	// Keep the tenant preamble out of the stored conversation
+	if exchange.preamble != "" {
+		stripPreamble(transformedMessages, exchange.preamble)
 	}
 
-	outputMap, ok := output.(map[string]interface{})
-	if !ok {
-		logger.Error("'output' field is not a map in Google Agent Engine response")
-		return "", fmt.Errorf("invalid Google Agent Engine response format - 'output' is not an object")
+	// An agent turn that produced no messages would leave the user without a reply
+	if !hasAgentReply(transformedMessages) {
+		logger.Warn("Agent returned no messages, replying with the empty response fallback")
+		if deps.OTelWorkerWrapper != nil && responseSpan != nil {
+			responseSpan.SetAttributes(attribute.String("response.result", "empty"))
+		}
+		processedData, err = buildEmptyResponse(ctx, msg, deps, exchange.startedAt)
+		processedData.ThreadID = exchange.threadID
+		return processedData, err
 	}
 
-	// Extract messages array from the output structure
-	var transformedMessages []interface{}
-	if messagesArray, exists := outputMap["messages"]; exists {
-		transformedMessages = transformGoogleAgentMessages(deps.Logger, messagesArray)
-	} else {
-		// Fallback: if no 'messages' field, wrap the entire output as structured data
-		logger.Warn("No 'messages' field found in output, wrapping output as structured data message")
-		structuredMessage := map[string]interface{}{
-			"message_type": "structured_data",
-			"content":      outputMap, // Include all output fields
-			"timestamp":    time.Now().Format(time.RFC3339),
+	// A runaway tool-call chain would bloat the stored result and everything processing it downstream
+	var omittedToolMessages int
+	if maxToolMessages := deps.Config.GoogleAgentEngine.MaxToolMessages; maxToolMessages > 0 {
+		transformedMessages, omittedToolMessages = capToolMessages(transformedMessages, maxToolMessages)
+		if omittedToolMessages > 0 {
+			logger.WithFields(logrus.Fields{
+				"omitted_tool_messages": omittedToolMessages,
+				"max_tool_messages":     maxToolMessages,
+			}).Warn("Agent response exceeded the tool message cap, truncating tool calls")
+			if deps.OTelWorkerWrapper != nil && responseSpan != nil {
+				responseSpan.SetAttributes(attribute.Int("response.omitted_tool_messages", omittedToolMessages))
+			}
 		}
-		transformedMessages = []interface{}{structuredMessage}
 	}
 
-	// Generate agent ID based on user number
-	agentID := "user_" + msg.UserNumber
+	// Resolve agent ID (defaults to "user_" + user number)
+	agentID := deps.resolveAgentID(msg)
 
 	// Set agent_id in the usage statistics message
 	if len(transformedMessages) > 0 {
 		if lastMsg, ok := transformedMessages[len(transformedMessages)-1].(map[string]interface{}); ok {
 			if msgType, exists := lastMsg["message_type"]; exists && msgType == "usage_statistics" {
 				lastMsg["agent_id"] = agentID
+				if exchange.fallbackReason != "" {
+					lastMsg["fallback_reason"] = exchange.fallbackReason
+				}
+				if !deps.Config.GoogleAgentEngine.IncludeDroppedMessages {
+					delete(lastMsg, droppedEntriesField)
+				}
+				addCostEstimate(lastMsg, transformedMessages, deps, logger)
 			}
 		}
 	}
 
-	// Apply WhatsApp formatting to individual message content
-	transformedMessages = applyWhatsAppFormattingToMessages(deps.Logger, deps.MessageFormatter, transformedMessages)
+	// Cap, redact, summarize, format and dedup the messages as configured in RESPONSE_POST_PROCESSORS
+	transformedMessages = runPostProcessors(ctx, &PostProcessContext{Deps: deps, Msg: msg, Logger: logger}, transformedMessages)
+
+	// Attribute every message to its sender as configured in RESPONSE_MESSAGE_SENDERS, once post-processors
+	// no longer need the agent's own names
+	assignMessageSenders(transformedMessages, msg, agentID, deps)
+
+	// Screen the response as the user would see it; a blocked response is replaced by the safe message
+	if decision := checkModeration(ctx, deps, logger, moderationStageOutput, responseText(transformedMessages)); decision.Blocked {
+		if deps.OTelWorkerWrapper != nil && responseSpan != nil {
+			responseSpan.SetAttributes(attribute.String("response.result", "blocked"))
+		}
+		processedData, err = buildBlockedResponse(ctx, msg, deps, exchange.startedAt, decision.Reason)
+		processedData.ThreadID = exchange.threadID
+		processedData.Provider = exchange.provider
+		return processedData, err
+	}
+
+	// Zeros would read as a free response; drop usage entirely when the agent didn't report it
+	if deps.Config.GoogleAgentEngine.OmitUnknownUsage {
+		var dropped bool
+		transformedMessages, dropped = dropUnknownUsage(transformedMessages)
+		if dropped {
+			logger.Debug("Omitted usage statistics, agent reported no token counts")
+		}
+	}
+
+	// Newer consumers get usage as a separate field rather than a synthetic trailing message
+	var usage map[string]interface{}
+	if deps.Config.GoogleAgentEngine.OutputMode == config.OutputModeMessagesOnly {
+		transformedMessages, usage = splitUsageStatistics(transformedMessages)
+	}
 
 	// Build the final response data to match Python API structure
-	processedData := models.ProcessedMessageData{
-		Messages:    transformedMessages,
-		AgentID:     agentID,
-		ProcessedAt: msg.ID, // Use message ID as processed_at identifier
-		Status:      "done",
+	processedData = models.ProcessedMessageData{
+		Messages:       transformedMessages,
+		AgentID:        agentID,
+		MessageID:      msg.ID,
+		Status:         "done",
+		FallbackReason: exchange.fallbackReason,
+		CorrelationID:  msg.CorrelationID,
+		Usage:          usage,
+		Model:          exchange.model,
+		ThreadID:       exchange.threadID,
+		Provider:       exchange.provider,
+
+		ToolMessagesTruncated: omittedToolMessages > 0,
 	}
+	processedData.StampSchemaVersion()
+	processedData.SetTiming(exchange.startedAt, time.Now())
 
-	// Convert the processed data to JSON for storage in Redis
-	processedBytes, err := json.Marshal(processedData)
-	if err != nil {
-		logger.WithError(err).Error("Failed to marshal processed data to JSON")
-		return "", fmt.Errorf("failed to marshal processed response: %w", err)
+	// Make the result self-contained for consumers that log or display it alongside the reply
+	if deps.Config.GoogleAgentEngine.IncludeInboundMessage {
+		processedData.OriginalMessage = msg.Message
+		processedData.EffectiveMessage = exchange.message
 	}
 
-	processedResponse := string(processedBytes)
+	// Keep what the audio was transcribed to so conversations can be reviewed
+	if deps.Config.Transcribe.StoreTranscripts && exchange.audioSourceURL != "" {
+		processedData.AudioURL = exchange.audioSourceURL
+		if exchange.transcript != nil {
+			processedData.Transcript = *exchange.transcript
+		}
+	}
 
 	// Record successful response processing in tracing
 	if deps.OTelWorkerWrapper != nil && responseSpan != nil {
 		responseSpan.SetAttributes(
 			attribute.String("response.result", "success"),
-			attribute.Int("response.messages_count", len(transformedMessages)),
-			attribute.Int("response.processed_length", len(processedResponse)))
+			attribute.Int("response.messages_count", len(transformedMessages)))
 	}
 
 	// Log successful processing (matches Python log format)
 	logger.WithFields(logrus.Fields{
-		"thread_id":           threadID,
+		"thread_id":           exchange.threadID,
 		"agent_id":            agentID,
-		"raw_response_length": len(agentResponse.Content),
-		"processed_length":    len(processedResponse),
+		"raw_response_length": len(exchange.rawResponse),
 		"messages_count":      len(transformedMessages),
-		"had_transcript":      transcriptText != nil,
+		"had_transcript":      exchange.transcript != nil,
+		"duration_ms":         processedData.DurationMs,
 	}).Info("Successfully processed user message with full transformation pipeline")
 
-	return processedResponse, nil
+	return processedData, nil
 }
 
-// transformGoogleAgentMessages transforms Google Agent Engine messages to Python API format
-func transformGoogleAgentMessages(logger *logrus.Logger, messagesData interface{}) []interface{} {
-	var transformedMessages []interface{}
+// resolveAgentModel returns the model the agent should answer the message with: the message's own model when
+// AGENT_ALLOWED_MODELS permits it, else AGENT_DEFAULT_MODEL ("" = the agent's default)
+func resolveAgentModel(msg *models.QueueMessage, deps *MessageHandlerDependencies) (string, error) {
+	if msg.Model == "" {
+		return deps.Config.GoogleAgentEngine.DefaultModel, nil
+	}
+	if !deps.Config.IsModelAllowed(msg.Model) {
+		return "", services.NewProcessingError(services.ErrValidation, fmt.Sprintf("agent model %q is not allowed (allowed: %s)", msg.Model, strings.Join(deps.Config.GetAllowedModels(), ", ")), nil)
+	}
+	return msg.Model, nil
+}
 
-	// Handle both slice and single message cases
-	var messagesList []interface{}
+// preambleSeparator separates a tenant preamble from the user's message in the text sent to the agent
+const preambleSeparator = "\n\n"
 
-	switch v := messagesData.(type) {
-	case []interface{}:
-		messagesList = v
-	case interface{}:
-		messagesList = []interface{}{v}
-	default:
-		logger.Warn("Unexpected messages data type, returning empty array")
-		return transformedMessages
+// withPreamble prepends the preamble to the message sent to the agent
+func withPreamble(preamble, message string) string {
+	if preamble == "" {
+		return message
+	}
+	if message == "" {
+		return preamble
 	}
+	return preamble + preambleSeparator + message
+}
 
-	for _, msgData := range messagesList {
-		msgMap, ok := msgData.(map[string]interface{})
-		if !ok {
+// stripPreamble removes the preamble from user_message entries echoed back by the agent
+func stripPreamble(messages []interface{}, preamble string) {
+	for _, message := range messages {
+		msgMap, ok := message.(map[string]interface{})
+		if !ok || msgMap["message_type"] != "user_message" {
+			continue
+		}
+		content, ok := msgMap["content"].(string)
+		if !ok || !strings.HasPrefix(content, preamble) {
 			continue
 		}
+		msgMap["content"] = strings.TrimPrefix(strings.TrimPrefix(content, preamble), preambleSeparator)
+	}
+}
 
-		// Transform Google Agent Engine message to Python API format
-		transformedMsg := map[string]interface{}{
-			"id":                      msgMap["id"],
-			"date":                    nil,
-			"session_id":              nil,
-			"time_since_last_message": nil,
-			"name":                    msgMap["name"],
-			"otid":                    msgMap["id"], // Use same ID as otid
-			"sender_id":               nil,
-			"step_id":                 "step-" + generateStepID(), // Generate step ID
-			"is_err":                  nil,
-			"model_name":              extractModelName(msgMap),
-			"finish_reason":           extractFinishReason(msgMap),
-			"avg_logprobs":            extractAvgLogprobs(msgMap),
-			"usage_metadata":          extractUsageMetadata(msgMap),
-			"message_type":            mapMessageType(msgMap),
-			"content":                 msgMap["content"],
-		}
-
-		// Add type-specific fields
-		if msgType := mapMessageType(msgMap); msgType == "tool_call_message" {
-			if toolCalls, exists := msgMap["tool_calls"].([]interface{}); exists && len(toolCalls) > 0 {
-				if toolCall, ok := toolCalls[0].(map[string]interface{}); ok {
-					transformedMsg["tool_call"] = map[string]interface{}{
-						"name":         toolCall["name"],
-						"arguments":    toolCall["args"],
-						"tool_call_id": toolCall["id"],
-					}
-				}
+// storeTaskError records why a task failed, coded by the error's category
+func storeTaskError(ctx context.Context, deps *MessageHandlerDependencies, taskID string, taskErr error) error {
+	result := &models.ErrorResult{
+		Code:      services.ErrorCategory(taskErr),
+		Message:   taskErr.Error(),
+		Retryable: shouldRetryError(taskErr),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+	return deps.RedisService.SetTaskError(ctx, taskID, result, deps.Config.GetTaskStatusTTL(string(models.TaskStatusFailed)))
+}
+
+// notifyTaskEvent pushes a task completion/failure event to the webhook notifier and, with
+// REDIS_PUBLISH_TASK_EVENTS, to the task's and user's pub/sub channels for WebSocket clients.
+// Delivery is fire-and-forget, so it never affects message acknowledgment.
+func notifyTaskEvent(deps *MessageHandlerDependencies, messageID, userNumber string, status models.TaskStatus, taskErr error) {
+	if deps.TaskEventNotifier == nil && !deps.Config.Redis.PublishTaskEvents {
+		return
+	}
+
+	event := models.TaskEvent{
+		MessageID: messageID,
+		Status:    string(status),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+	if taskErr != nil {
+		errorMessage := taskErr.Error()
+		event.Error = &errorMessage
+	}
+	if status.HasResult() {
+		event.ResultPointer = models.ResponsePollingPath(messageID, deps.tenantID)
+	}
+
+	if deps.TaskEventNotifier != nil {
+		deps.TaskEventNotifier.Notify(event)
+	}
+	if deps.Config.Redis.PublishTaskEvents {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), taskEventPublishTimeout)
+			defer cancel()
+			if err := deps.RedisService.PublishTaskEvent(ctx, userNumber, event); err != nil {
+				deps.Logger.WithError(err).WithFields(logrus.Fields{
+					"message_id": messageID,
+					"status":     status,
+				}).Warn("Failed to publish task event")
 			}
-		} else if msgType == "tool_return_message" {
-			// For tool messages, extract tool return information
-			if name, exists := msgMap["name"]; exists {
-				transformedMsg["tool_return"] = msgMap["content"]
-				transformedMsg["status"] = "success"
-				transformedMsg["tool_call_id"] = msgMap["tool_call_id"]
-				transformedMsg["stdout"] = nil
-				transformedMsg["stderr"] = nil
-				transformedMsg["name"] = name
+		}()
+	}
+}
+
+// taskEventPublishTimeout bounds publishing a task event to Redis pub/sub
+const taskEventPublishTimeout = 5 * time.Second
+
+// ErrTaskCancelled signals that processing was aborted because the task's cancellation flag was set
+var ErrTaskCancelled = errors.New("task cancelled")
+
+// Values of the redis_failure log field. A result-store failure loses the user's response and should
+// page; a status-update failure only leaves the task status stale.
+const (
+	redisFailureResultStore  = "result_store"
+	redisFailureStatusUpdate = "status_update"
+)
+
+// redisOutageError reports a result/status write to the outage guard and, once the guard has paused
+// consumption, returns a deferred retry so the message goes back to the queue instead of being acknowledged
+func redisOutageError(deps *MessageHandlerDependencies, err error) error {
+	if deps.RedisOutageGuard == nil || !deps.RedisOutageGuard.RecordWrite(err) || err == nil {
+		return nil
+	}
+	return services.NewRetryLaterError(deps.RedisOutageGuard.RecheckInterval(), err)
+}
+
+// isTaskCancelled checks the task's cancellation flag, treating Redis errors as not cancelled
+func isTaskCancelled(ctx context.Context, deps *MessageHandlerDependencies, taskID string) bool {
+	cancelled, err := deps.RedisService.IsTaskCancelled(ctx, taskID)
+	if err != nil {
+		deps.Logger.WithError(err).WithField("task_id", taskID).Warn("Failed to check task cancellation flag")
+		return false
+	}
+	return cancelled
+}
+
+// watchTaskCancellation polls the task's cancellation flag and cancels the context once it is set
+func watchTaskCancellation(ctx context.Context, deps *MessageHandlerDependencies, taskID string, interval time.Duration, cancel context.CancelFunc) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if isTaskCancelled(ctx, deps, taskID) {
+				deps.Logger.WithField("task_id", taskID).Info("Cancellation flag set, aborting agent call")
+				cancel()
+				return
 			}
 		}
+	}
+}
+
+// buildUnavailableResponse builds the degraded response returned while the agent backend is short-circuited
+func buildUnavailableResponse(ctx context.Context, msg *models.QueueMessage, deps *MessageHandlerDependencies, startedAt time.Time, cause error) (models.ProcessedMessageData, error) {
+	content := deps.formatter().FormatErrorMessage(ctx, cause)
+	return buildFallbackResponse(msg, deps, startedAt, content, models.TaskStatusDegraded, true)
+}
 
-		transformedMessages = append(transformedMessages, transformedMsg)
+// buildFallbackResponse builds a processed response holding a single gateway-generated assistant
+// message, used whenever the agent is not called
+func buildFallbackResponse(msg *models.QueueMessage, deps *MessageHandlerDependencies, startedAt time.Time, content string, status models.TaskStatus, isErr bool) (models.ProcessedMessageData, error) {
+	messages := []interface{}{
+		map[string]interface{}{
+			"id":           "message-" + generateStepID(),
+			"date":         time.Now().Format(time.RFC3339),
+			"step_id":      "step-" + generateStepID(),
+			"is_err":       isErr,
+			"message_type": "assistant_message",
+			"content":      content,
+		},
 	}
 
-	// Add usage statistics message at the end (matching Python API)
-	usageStats := map[string]interface{}{
-		"message_type":      "usage_statistics",
-		"completion_tokens": 0,
-		"prompt_tokens":     0,
-		"total_tokens":      0,
-		"step_count":        len(transformedMessages),
-		"steps_messages":    nil,
-		"run_ids":           nil,
-		"agent_id":          "", // Will be filled by calling function
-		"processed_at":      time.Now().Format(time.RFC3339),
-		"status":            "done",
-		"model_names":       []string{},
+	agentID := deps.resolveAgentID(msg)
+	assignMessageSenders(messages, msg, agentID, deps)
+
+	processedData := models.ProcessedMessageData{
+		Messages:      applyChannelFormattingToMessages(deps.Logger, deps.formatter(), msg.Channel, messages),
+		AgentID:       agentID,
+		MessageID:     msg.ID,
+		Status:        string(status),
+		CorrelationID: msg.CorrelationID,
 	}
-	transformedMessages = append(transformedMessages, usageStats)
+	if deps.Config.GoogleAgentEngine.IncludeInboundMessage {
+		processedData.OriginalMessage = msg.Message
+	}
+	processedData.StampSchemaVersion()
+	processedData.SetTiming(startedAt, time.Now())
+
+	return processedData, nil
+}
 
-	return transformedMessages
+// processedTaskStatus returns the task status for a processed result: degraded when the agent
+// backend was short-circuited, empty when the agent produced no messages, blocked when moderation
+// blocked the message or response, completed otherwise
+func processedTaskStatus(processedData models.ProcessedMessageData) models.TaskStatus {
+	switch processedData.Status {
+	case string(models.TaskStatusDegraded):
+		return models.TaskStatusDegraded
+	case string(models.TaskStatusEmpty):
+		return models.TaskStatusEmpty
+	case string(models.TaskStatusBlocked):
+		return models.TaskStatusBlocked
+	default:
+		return models.TaskStatusCompleted
+	}
 }
 
-// Helper functions for message transformation
-func extractModelName(msgMap map[string]interface{}) interface{} {
-	if responseMetadata, exists := msgMap["response_metadata"].(map[string]interface{}); exists {
-		if modelName, exists := responseMetadata["model_name"]; exists {
-			return modelName
+// hasAgentReply reports whether the transformed messages hold anything produced by the agent, as
+// opposed to only the echoed user message and usage statistics
+func hasAgentReply(messages []interface{}) bool {
+	for _, msgInterface := range messages {
+		msgMap, ok := msgInterface.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch msgMap["message_type"] {
+		case "user_message", "usage_statistics":
+			continue
 		}
+		return true
 	}
-	return nil
+	return false
 }
 
-func extractFinishReason(msgMap map[string]interface{}) interface{} {
-	if responseMetadata, exists := msgMap["response_metadata"].(map[string]interface{}); exists {
-		if finishReason, exists := responseMetadata["finish_reason"]; exists {
-			return finishReason
+// buildEmptyResponse builds the response returned when the agent produced no messages, using
+// RESPONSE_EMPTY_MESSAGE or the localized empty-response text
+func buildEmptyResponse(ctx context.Context, msg *models.QueueMessage, deps *MessageHandlerDependencies, startedAt time.Time) (models.ProcessedMessageData, error) {
+	content := deps.Config.GoogleAgentEngine.EmptyResponseMessage
+	if content == "" {
+		content = localizedMessage(ctx, deps, services.MsgEmptyResponse)
+	}
+
+	return buildFallbackResponse(msg, deps, startedAt, content, models.TaskStatusEmpty, false)
+}
+
+// resolveLocale returns the message's locale, remembering it in the user's profile, or the locale
+// stored in the profile when the message carries none ("" = use the default locale)
+func resolveLocale(ctx context.Context, msg *models.QueueMessage, deps *MessageHandlerDependencies, logger *logrus.Entry) string {
+	if msg.Locale != "" {
+		if err := deps.RedisService.SetUserLocale(ctx, msg.UserNumber, msg.Locale); err != nil {
+			logger.WithError(err).Warn("Failed to store user locale")
 		}
+		return msg.Locale
 	}
-	return nil
+
+	locale, err := deps.RedisService.GetUserLocale(ctx, msg.UserNumber)
+	if err != nil {
+		return ""
+	}
+	return locale
 }
 
-func extractAvgLogprobs(msgMap map[string]interface{}) interface{} {
-	if responseMetadata, exists := msgMap["response_metadata"].(map[string]interface{}); exists {
-		if avgLogprobs, exists := responseMetadata["avg_logprobs"]; exists {
-			return avgLogprobs
+// localizedMessage returns a catalog message in the locale carried by ctx
+func localizedMessage(ctx context.Context, deps *MessageHandlerDependencies, key services.MessageKey) string {
+	return services.LocalizedMessage(services.GetLocale(ctx), deps.Config.GoogleAgentEngine.DefaultLocale, key)
+}
+
+// imageFromMessage returns the image URL and caption of the message, from either an image media
+// object or a bare image URL sent as the message text
+func imageFromMessage(msg *models.QueueMessage) (string, string, bool) {
+	if msg.Media != nil && msg.Media.URL != "" {
+		if msg.Media.IsImage() || (msg.Media.MimeType == "" && isImageURL(msg.Media.URL)) {
+			return msg.Media.URL, strings.TrimSpace(msg.Media.Caption), true
 		}
+		return "", "", false
 	}
-	return nil
+
+	if text := strings.TrimSpace(msg.Message); isImageURL(text) {
+		return text, "", true
+	}
+	return "", "", false
 }
 
-func extractUsageMetadata(msgMap map[string]interface{}) interface{} {
-	if responseMetadata, exists := msgMap["response_metadata"].(map[string]interface{}); exists {
-		if usageMetadata, exists := responseMetadata["usage_metadata"]; exists {
-			// Transform to Python API format
-			if usageMap, ok := usageMetadata.(map[string]interface{}); ok {
-				result := map[string]interface{}{
-					"prompt_token_count":     usageMap["input_tokens"],
-					"candidates_token_count": usageMap["output_tokens"],
-					"total_token_count":      usageMap["total_tokens"],
-				}
+// documentFromMessage returns the document and caption of the message, from either a document media
+// object or a bare document URL sent as the message text
+func documentFromMessage(cfg *config.Config, msg *models.QueueMessage) (services.DocumentInput, string, bool) {
+	if msg.Media != nil && msg.Media.URL != "" {
+		if msg.Media.IsDocument() {
+			return services.NewDocumentInput(msg.Media.URL, msg.Media.MimeType), strings.TrimSpace(msg.Media.Caption), true
+		}
+		// Untyped or generic binary media is recognized by its extension
+		if (msg.Media.MimeType == "" || msg.Media.MimeType == "application/octet-stream") && services.IsDocumentURL(cfg, msg.Media.URL) {
+			return services.NewDocumentInput(msg.Media.URL, ""), strings.TrimSpace(msg.Media.Caption), true
+		}
+		return services.DocumentInput{}, "", false
+	}
 
-				// Safely extract nested fields with proper type conversion
-				if outputDetails, exists := usageMap["output_token_details"].(map[string]interface{}); exists {
-					if reasoning, exists := outputDetails["reasoning"]; exists {
-						if reasoningInt, ok := reasoning.(int); ok {
-							result["thoughts_token_count"] = float64(reasoningInt)
-						}
-					}
-				}
+	// Only a lone link counts, not text that merely ends in a file name such as "notes.txt"
+	if text := strings.TrimSpace(msg.Message); !strings.ContainsAny(text, " \n") && strings.HasPrefix(text, "http") && services.IsDocumentURL(cfg, text) {
+		return services.NewDocumentInput(text, ""), "", true
+	}
+	return services.DocumentInput{}, "", false
+}
 
-				if inputDetails, exists := usageMap["input_token_details"].(map[string]interface{}); exists {
-					if cacheRead, exists := inputDetails["cache_read"]; exists {
-						if cacheReadInt, ok := cacheRead.(int); ok {
-							result["cached_content_token_count"] = float64(cacheReadInt)
-						}
-					}
-				}
+// extractDocumentText asks the document extractor for the document's text, returning false when no
+// extractor is configured or extraction fails
+func extractDocumentText(ctx context.Context, document services.DocumentInput, deps *MessageHandlerDependencies, logger *logrus.Entry) (string, bool) {
+	if deps.DocumentExtractor == nil {
+		return "", false
+	}
 
-				// Convert other fields to float64 to match Python API
-				if inputTokens, exists := usageMap["input_tokens"]; exists {
-					if inputInt, ok := inputTokens.(int); ok {
-						result["prompt_token_count"] = float64(inputInt)
-					}
-				}
-				if outputTokens, exists := usageMap["output_tokens"]; exists {
-					if outputInt, ok := outputTokens.(int); ok {
-						result["candidates_token_count"] = float64(outputInt)
-					}
-				}
-				if totalTokens, exists := usageMap["total_tokens"]; exists {
-					if totalInt, ok := totalTokens.(int); ok {
-						result["total_token_count"] = float64(totalInt)
-					}
-				}
+	text, err := deps.DocumentExtractor.ExtractText(ctx, document)
+	if err != nil {
+		logger.WithError(err).WithField("document_url", document.URL).Warn("Document text extraction failed")
+		return "", false
+	}
+	logger.WithField("text_length", len(text)).Info("Extracted document text")
+	return text, true
+}
 
-				return result
-			}
+// combineCaptionAndDocument joins the media caption and the document's extracted text into a single message
+func combineCaptionAndDocument(caption, text string) string {
+	document := "<document>\n" + text + "\n</document>"
+	if caption == "" {
+		return document
+	}
+	return caption + "\n\n" + document
+}
+
+// audioMediaFromMessage returns the audio URL and caption of the message media object, if it holds audio
+func audioMediaFromMessage(cfg *config.Config, msg *models.QueueMessage) (string, string, bool) {
+	if msg.Media == nil || msg.Media.URL == "" {
+		return "", "", false
+	}
+	if !msg.Media.IsAudio() && !(msg.Media.MimeType == "" && services.IsAudioURL(cfg, msg.Media.URL)) {
+		return "", "", false
+	}
+	return msg.Media.URL, strings.TrimSpace(msg.Media.Caption), true
+}
+
+// combineCaptionAndTranscript joins the media caption and the audio transcript into a single message
+func combineCaptionAndTranscript(caption, transcript string) string {
+	if caption == "" {
+		return transcript
+	}
+	return caption + "\n\n" + transcript
+}
+
+// transcribeAudioMessage transcribes the audio userNumber sent at audioURL, tracing the step when OTel is enabled.
+// When the transcript is unavailable it returns the fallback reason code so the caller can apply its fallback.
+func transcribeAudioMessage(ctx context.Context, userNumber, audioURL string, deps *MessageHandlerDependencies, logger *logrus.Entry) (transcript string, fallbackReason string) {
+	// Trace audio transcription step
+	var transcribeCtx context.Context
+	var transcribeSpan trace.Span
+	if deps.OTelWorkerWrapper != nil {
+		transcribeCtx, transcribeSpan = deps.OTelWorkerWrapper.StartSpan(ctx, "audio_transcription",
+			attribute.String("audio.url", audioURL),
+			attribute.Bool("audio.detected", true),
+			attribute.String("audio.format", getAudioFormatFromURL(audioURL)))
+		defer transcribeSpan.End()
+	} else {
+		transcribeCtx = ctx
+	}
+
+	if deps.TranscribeService == nil {
+		logger.Warn("Transcribe service not available, using fallback")
+		if transcribeSpan != nil {
+			transcribeSpan.SetAttributes(
+				attribute.Bool("transcription.success", false),
+				attribute.String("transcription.error_type", "service_not_available"),
+				attribute.Bool("transcription.fallback_used", true))
 		}
+		return "", models.FallbackReasonTranscriptionUnavailable
 	}
-	return nil
+
+	// Reject disallowed hosts before the transcription service fetches anything
+	if err := deps.TranscribeService.ValidateAudioURL(audioURL); errors.Is(err, services.ErrAudioURLNotAllowed) {
+		logger.WithError(err).Warn("Audio URL rejected by host policy, using fallback")
+		if transcribeSpan != nil {
+			transcribeSpan.SetAttributes(
+				attribute.Bool("transcription.success", false),
+				attribute.String("transcription.error_type", "url_not_allowed"),
+				attribute.Bool("transcription.fallback_used", true))
+		}
+		return "", models.FallbackReasonAudioURLNotAllowed
+	}
+
+	// Retries and shared media often repeat an audio URL we already paid to transcribe
+	if cached, ok := cachedTranscript(transcribeCtx, audioURL, deps, logger); ok {
+		logger.WithField("transcript_length", len(cached)).Info("Using cached audio transcript")
+		if transcribeSpan != nil {
+			transcribeSpan.SetAttributes(
+				attribute.Bool("transcription.success", true),
+				attribute.Bool("transcription.cached", true),
+				attribute.Int("transcription.transcript_length", len(cached)),
+				attribute.Bool("transcription.fallback_used", false))
+		}
+		return cached, ""
+	}
+
+	operationCtx, cancelTranscribe := withOperationTimeout(transcribeCtx, deps.Config.Transcribe.OperationTimeout)
+	defer cancelTranscribe()
+
+	transcript, confidence, err := transcribeWithRetry(operationCtx, audioURL, deps, logger, transcribeSpan)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to transcribe audio, using fallback")
+		if transcribeSpan != nil {
+			transcribeSpan.SetAttributes(
+				attribute.Bool("transcription.success", false),
+				attribute.String("transcription.error_type", classifyTranscriptionError(err)),
+				attribute.String("transcription.error", err.Error()),
+				attribute.Bool("transcription.fallback_used", true))
+		}
+		return "", models.FallbackReasonTranscriptionError
+	}
+
+	if reason := transcriptEscalationReason(transcript, confidence, deps.Config); reason != "" && deps.EscalationTranscribeService != nil {
+		transcript = escalateTranscription(transcribeCtx, audioURL, transcript, reason, deps, logger, transcribeSpan)
+	}
+
+	if !isUsableTranscript(transcript, deps.Config) {
+		logger.Warn("Transcription returned no useful content, using fallback")
+		if transcribeSpan != nil {
+			transcribeSpan.SetAttributes(
+				attribute.Bool("transcription.success", false),
+				attribute.String("transcription.error_type", "empty_or_invalid_content"),
+				attribute.Bool("transcription.fallback_used", true))
+		}
+		return "", models.FallbackReasonEmptyTranscription
+	}
+
+	logger.WithField("transcript_length", len(transcript)).Info("Audio transcribed successfully")
+	cacheTranscript(ctx, userNumber, audioURL, transcript, deps, logger)
+	if transcribeSpan != nil {
+		transcribeSpan.SetAttributes(
+			attribute.Bool("transcription.success", true),
+			attribute.Int("transcription.transcript_length", len(transcript)),
+			attribute.Bool("transcription.fallback_used", false))
+	}
+	return transcript, ""
 }
 
-func mapMessageType(msgMap map[string]interface{}) string {
-	msgType, exists := msgMap["type"].(string)
-	if !exists {
-		return "user_message" // Default fallback
+// transcribeWithConfidence transcribes the audio, with the transcript's confidence when the backend reports
+// one (0 otherwise)
+func transcribeWithConfidence(ctx context.Context, transcriber TranscribeServiceInterface, audioURL string) (string, float32, error) {
+	if withConfidence, ok := transcriber.(ConfidenceTranscriber); ok {
+		return withConfidence.TranscribeAudioWithConfidence(ctx, audioURL)
 	}
+	transcript, err := transcriber.TranscribeAudio(ctx, audioURL)
+	return transcript, 0, err
+}
 
-	switch msgType {
-	case "human":
-		return "user_message"
-	case "ai":
-		// Check if it has tool calls
-		if toolCalls, exists := msgMap["tool_calls"].([]interface{}); exists && len(toolCalls) > 0 {
-			return "tool_call_message"
+// transcribeWithRetry transcribes the audio, retrying transient failures up to TRANSCRIBE_RETRY_ATTEMPTS times
+// so a network blip doesn't degrade the message to the fallback. It stops early once ctx is done.
+func transcribeWithRetry(ctx context.Context, audioURL string, deps *MessageHandlerDependencies, logger *logrus.Entry, span trace.Span) (string, float32, error) {
+	retries := deps.Config.Transcribe.RetryAttempts
+	for attempt := 1; ; attempt++ {
+		transcript, confidence, err := transcribeWithConfidence(ctx, deps.TranscribeService, audioURL)
+		if err == nil || attempt > retries || !isTransientTranscriptionError(err) || ctx.Err() != nil {
+			if span != nil && attempt > 1 {
+				span.SetAttributes(attribute.Int("transcription.attempts", attempt))
+			}
+			return transcript, confidence, err
 		}
-		return "assistant_message"
-	case "tool":
-		return "tool_return_message"
+
+		logger.WithError(err).WithField("attempt", attempt).Warn("Transient transcription failure, retrying")
+		select {
+		case <-ctx.Done():
+			return "", 0, err
+		case <-time.After(deps.Config.Transcribe.RetryDelay):
+		}
+	}
+}
+
+// isTransientTranscriptionError reports whether a transcription error is likely to clear up on a retry
+func isTransientTranscriptionError(err error) bool {
+	switch classifyTranscriptionError(err) {
+	case "network_error", "rate_limit_error", "service_error":
+		return true
 	default:
-		return "user_message"
+		return false
+	}
+}
+
+// isUsableTranscript reports whether a transcript has content other than the TRANSCRIBE_EMPTY_SENTINEL
+func isUsableTranscript(transcript string, cfg *config.Config) bool {
+	trimmed := strings.TrimSpace(transcript)
+	return trimmed != "" && (cfg.Transcribe.EmptySentinel == "" || trimmed != strings.TrimSpace(cfg.Transcribe.EmptySentinel))
+}
+
+// transcriptEscalationReason returns why a transcript should be retried on the escalation backend, or ""
+// when it is good enough. A confidence of 0 means the backend reported none and is never counted as low.
+func transcriptEscalationReason(transcript string, confidence float32, cfg *config.Config) string {
+	if !isUsableTranscript(transcript, cfg) {
+		return "empty"
+	}
+	if minConfidence := cfg.Transcribe.EscalationMinConfidence; minConfidence > 0 && confidence > 0 && float64(confidence) < minConfidence {
+		return "low_confidence"
+	}
+	return ""
+}
+
+// escalateTranscription retries the audio once on the escalation backend, under its own operation timeout, and
+// returns the transcript to continue with: the retried one when it is usable, otherwise the original
+func escalateTranscription(ctx context.Context, audioURL, transcript, reason string, deps *MessageHandlerDependencies, logger *logrus.Entry, span trace.Span) string {
+	logger = logger.WithField("escalation_reason", reason)
+	logger.Info("Retrying transcription on the escalation backend")
+
+	escalationCtx, cancel := withOperationTimeout(ctx, deps.Config.Transcribe.OperationTimeout)
+	defer cancel()
+
+	result := "used"
+	escalated, confidence, err := transcribeWithConfidence(escalationCtx, deps.EscalationTranscribeService, audioURL)
+	switch {
+	case err != nil:
+		result = "error"
+		logger.WithError(err).Warn("Escalation transcription failed, keeping the original transcript")
+	case !isUsableTranscript(escalated, deps.Config):
+		result = "empty"
+		logger.Warn("Escalation transcription returned no useful content, keeping the original transcript")
+	default:
+		logger.WithField("confidence", confidence).Info("Using the escalation backend's transcript")
+		transcript = escalated
+	}
+
+	if span != nil {
+		span.SetAttributes(
+			attribute.Bool("transcription.escalated", true),
+			attribute.String("transcription.escalation_reason", reason),
+			attribute.String("transcription.escalation_result", result))
+	}
+	return transcript
+}
+
+// cachedTranscript returns the cached transcript of the audio URL, if caching is enabled and one is stored.
+// Cache failures only cost a fresh transcription.
+func cachedTranscript(ctx context.Context, audioURL string, deps *MessageHandlerDependencies, logger *logrus.Entry) (string, bool) {
+	if deps.Config.Transcribe.CacheBypass || deps.Config.Transcribe.CacheTTL <= 0 {
+		return "", false
+	}
+	transcript, err := deps.RedisService.GetCachedTranscript(ctx, audioURL)
+	if err != nil {
+		if !errors.Is(err, services.ErrKeyNotFound) {
+			logger.WithError(err).Warn("Failed to read transcript cache, transcribing")
+		}
+		return "", false
+	}
+	return transcript, transcript != ""
+}
+
+// cacheTranscript stores a successful transcript for TRANSCRIBE_CACHE_TTL unless caching is disabled or bypassed
+func cacheTranscript(ctx context.Context, userNumber, audioURL, transcript string, deps *MessageHandlerDependencies, logger *logrus.Entry) {
+	if deps.Config.Transcribe.CacheBypass || deps.Config.Transcribe.CacheTTL <= 0 {
+		return
+	}
+	if err := deps.RedisService.SetCachedTranscript(ctx, userNumber, audioURL, transcript, deps.Config.Transcribe.CacheTTL); err != nil {
+		logger.WithError(err).Warn("Failed to cache audio transcript")
+	}
+}
+
+// withOperationTimeout bounds a single external call by timeout; a non-positive timeout leaves ctx unchanged
+func withOperationTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, timeout)
 }
 
 // generateStepID generates a random step ID in the format expected by Python API
@@ -827,6 +1808,22 @@ func generateStepID() string {
 	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
 }
 
+// shouldRetryError decides whether a processing error is retried. Validation, provider, parse and
+// internal failures can never succeed on retry; agent failures defer to the transient-error heuristics.
+func shouldRetryError(err error) bool {
+	switch {
+	case errors.Is(err, services.ErrValidation),
+		errors.Is(err, services.ErrUnsupportedProvider),
+		errors.Is(err, services.ErrResponseParse),
+		errors.Is(err, services.ErrInternal):
+		return false
+	case errors.Is(err, services.ErrAgentTimeout):
+		return true
+	default:
+		return isRetriableError(err)
+	}
+}
+
 // isRetriableError determines if an error should be retried by RabbitMQ
 func isRetriableError(err error) bool {
 	if err == nil {
@@ -914,37 +1911,238 @@ func isRetriableError(err error) bool {
 	return false
 }
 
-// applyWhatsAppFormattingToMessages applies WhatsApp formatting to individual message content
-func applyWhatsAppFormattingToMessages(logger *logrus.Logger, messageFormatter MessageFormatterInterface, messages []interface{}) []interface{} {
-	if messageFormatter == nil {
-		logger.Warn("MessageFormatter is nil, skipping WhatsApp formatting")
-		return messages
-	}
-
+// applyChannelFormattingToMessages applies the channel's formatting (WhatsApp by default) to individual message content
+func applyChannelFormattingToMessages(logger *logrus.Logger, messageFormatter MessageFormatterInterface, channel string, messages []interface{}) []interface{} {
 	for i, msgInterface := range messages {
 		if msgMap, ok := msgInterface.(map[string]interface{}); ok {
 			// Only format message content, not metadata
-			if content, exists := msgMap["content"].(string); exists && content != "" {
-				// Create a temporary AgentResponse to use with the FormatForWhatsApp service
-				tempResponse := &models.AgentResponse{
-					Content:   content,
-					MessageID: "temp", // Not used by the formatter
-					ThreadID:  "temp", // Not used by the formatter
+			switch content := msgMap["content"].(type) {
+			case string:
+				if content != "" {
+					msgMap["content"] = formatContentForChannel(logger, messageFormatter, channel, content)
+				}
+			case []interface{}:
+				// Content blocks: format the text parts and leave the others (images, tool data) intact
+				for j, block := range content {
+					switch part := block.(type) {
+					case string:
+						if part != "" {
+							content[j] = formatContentForChannel(logger, messageFormatter, channel, part)
+						}
+					case map[string]interface{}:
+						if text, ok := part["text"].(string); ok && text != "" && isTextContentBlock(part) {
+							part["text"] = formatContentForChannel(logger, messageFormatter, channel, text)
+						}
+					}
 				}
+			}
+			messages[i] = msgMap
+		}
+	}
+	return messages
+}
+
+// isTextContentBlock reports whether a content block holds text, either typed ("type": "text") or untyped
+func isTextContentBlock(block map[string]interface{}) bool {
+	blockType, hasType := block["type"].(string)
+	return !hasType || blockType == "text"
+}
+
+// formatContentForChannel formats a piece of text for the channel, falling back to the original on error
+func formatContentForChannel(logger *logrus.Logger, messageFormatter MessageFormatterInterface, channel, content string) string {
+	// Create a temporary AgentResponse to use with the formatter service
+	tempResponse := &models.AgentResponse{
+		Content:   content,
+		MessageID: "temp", // Not used by the formatter
+		ThreadID:  "temp", // Not used by the formatter
+	}
+
+	formattedContent, err := messageFormatter.FormatForChannel(context.Background(), channel, tempResponse)
+	if err != nil {
+		logger.WithError(err).WithField("channel", channel).Warn("Failed to format message content, using original content")
+		return content // Fallback to original content
+	}
+	return formattedContent
+}
+
+// capResponseMessages keeps the last maxMessages user/assistant messages, drops tool messages and keeps
+// usage_statistics intact, recording how many messages were omitted in it. Order is preserved.
+func capResponseMessages(messages []interface{}, maxMessages int) ([]interface{}, int) {
+	conversational := 0
+	for _, msgInterface := range messages {
+		if msgMap, ok := msgInterface.(map[string]interface{}); ok && isConversationalMessage(msgMap) {
+			conversational++
+		}
+	}
+	skip := conversational - maxMessages
+
+	capped := make([]interface{}, 0, len(messages))
+	omitted := 0
+	var usageStats map[string]interface{}
+	for _, msgInterface := range messages {
+		msgMap, ok := msgInterface.(map[string]interface{})
+		if !ok {
+			capped = append(capped, msgInterface)
+			continue
+		}
+
+		switch {
+		case msgMap["message_type"] == "usage_statistics":
+			usageStats = msgMap
+			capped = append(capped, msgMap)
+		case !isConversationalMessage(msgMap):
+			omitted++
+		case skip > 0:
+			skip--
+			omitted++
+		default:
+			capped = append(capped, msgMap)
+		}
+	}
+
+	if usageStats != nil && omitted > 0 {
+		usageStats["omitted_messages"] = omitted
+	}
+	return capped, omitted
+}
+
+// capToolMessages keeps the first maxToolMessages tool_call/tool_return messages and every other message,
+// recording how many tool messages were dropped as "omitted_tool_messages" in the usage statistics
+func capToolMessages(messages []interface{}, maxToolMessages int) ([]interface{}, int) {
+	capped := make([]interface{}, 0, len(messages))
+	kept, omitted := 0, 0
+	var usageStats map[string]interface{}
+	for _, msgInterface := range messages {
+		msgMap, ok := msgInterface.(map[string]interface{})
+		if !ok {
+			capped = append(capped, msgInterface)
+			continue
+		}
+
+		switch msgMap["message_type"] {
+		case "usage_statistics":
+			usageStats = msgMap
+		case "tool_call_message", "tool_return_message":
+			if kept >= maxToolMessages {
+				omitted++
+				continue
+			}
+			kept++
+		}
+		capped = append(capped, msgMap)
+	}
+
+	if usageStats != nil && omitted > 0 {
+		usageStats["omitted_tool_messages"] = omitted
+	}
+	return capped, omitted
+}
+
+// dedupAssistantMessages drops assistant_message entries whose content equals that of the message right
+// before them when it is also an assistant_message, returning the remaining messages and how many were dropped
+func dedupAssistantMessages(messages []interface{}) ([]interface{}, int) {
+	deduped := make([]interface{}, 0, len(messages))
+	removed := 0
+	var previous map[string]interface{}
+	for _, msgInterface := range messages {
+		msgMap, ok := msgInterface.(map[string]interface{})
+		if ok && msgMap["message_type"] == "assistant_message" && previous != nil &&
+			previous["message_type"] == "assistant_message" && reflect.DeepEqual(previous["content"], msgMap["content"]) {
+			removed++
+			continue
+		}
+		previous = msgMap
+		deduped = append(deduped, msgInterface)
+	}
+	return deduped, removed
+}
+
+// dropUnknownUsage removes the usage_statistics entry when it carries no token counts, reporting whether it did
+func dropUnknownUsage(messages []interface{}) ([]interface{}, bool) {
+	for i, msgInterface := range messages {
+		msgMap, ok := msgInterface.(map[string]interface{})
+		if !ok || msgMap["message_type"] != "usage_statistics" {
+			continue
+		}
+		for _, field := range []string{"prompt_tokens", "completion_tokens", "total_tokens"} {
+			if tokenCount(msgMap[field]) > 0 {
+				return messages, false
+			}
+		}
+		return append(messages[:i:i], messages[i+1:]...), true
+	}
+	return messages, false
+}
+
+// splitUsageStatistics removes the usage_statistics entry from the messages and returns it separately
+func splitUsageStatistics(messages []interface{}) ([]interface{}, map[string]interface{}) {
+	var usage map[string]interface{}
+	remaining := make([]interface{}, 0, len(messages))
+	for _, msgInterface := range messages {
+		if msgMap, ok := msgInterface.(map[string]interface{}); ok && msgMap["message_type"] == "usage_statistics" {
+			usage = msgMap
+			delete(usage, "message_type")
+			continue
+		}
+		remaining = append(remaining, msgInterface)
+	}
+	return remaining, usage
+}
+
+// isConversationalMessage reports whether a transformed message is meant for the user rather than tool plumbing
+func isConversationalMessage(msgMap map[string]interface{}) bool {
+	switch msgMap["message_type"] {
+	case "tool_call_message", "tool_return_message", "usage_statistics":
+		return false
+	default:
+		return true
+	}
+}
+
+// redactToolReturns runs the redactor over the content of tool_return_message entries, in place
+func redactToolReturns(redactor services.Redactor, messages []interface{}, logger *logrus.Entry) {
+	if redactor == nil {
+		return
+	}
 
-				// Apply WhatsApp formatting using the proper service
-				formattedContent, err := messageFormatter.FormatForWhatsApp(context.Background(), tempResponse)
+	for _, msgInterface := range messages {
+		msgMap, ok := msgInterface.(map[string]interface{})
+		if !ok || msgMap["message_type"] != "tool_return_message" {
+			continue
+		}
+
+		redactions := 0
+		for _, field := range []string{"content", "tool_return"} {
+			value, exists := msgMap[field]
+			if !exists || value == nil {
+				continue
+			}
+
+			// Structured tool output is redacted in its JSON form
+			text, isString := value.(string)
+			if !isString {
+				encoded, err := json.Marshal(value)
 				if err != nil {
-					logger.WithError(err).Warn("Failed to format message content for WhatsApp, using original content")
-					formattedContent = content // Fallback to original content
+					continue
 				}
+				text = string(encoded)
+			}
 
-				msgMap["content"] = formattedContent
-				messages[i] = msgMap
+			redacted, count := redactor.Redact(text)
+			if count > 0 {
+				msgMap[field] = redacted
+				redactions += count
 			}
 		}
+
+		if redactions > 0 {
+			logger.WithFields(logrus.Fields{
+				"tool_name":    msgMap["name"],
+				"tool_call_id": msgMap["tool_call_id"],
+				"redactions":   redactions,
+			}).Warn("Redacted sensitive data from tool output")
+		}
 	}
-	return messages
 }
 
 // getAudioFormatFromURL extracts the audio format from URL extension
@@ -1023,7 +2221,7 @@ func classifyTranscriptionError(err error) string {
 }
 
 // executeCallback handles the callback execution asynchronously
-func executeCallback(ctx context.Context, deps *MessageHandlerDependencies, messageID string, callbackURL string, response string, queueMsg *models.QueueMessage, logger *logrus.Entry) {
+func executeCallback(ctx context.Context, deps *MessageHandlerDependencies, messageID string, callbackURL string, response string, status models.TaskStatus, queueMsg *models.QueueMessage, logger *logrus.Entry) {
 	callbackLogger := logger.WithFields(logrus.Fields{
 		"callback_url": callbackURL,
 		"message_id":   messageID,
@@ -1049,7 +2247,7 @@ func executeCallback(ctx context.Context, deps *MessageHandlerDependencies, mess
 	// Create callback payload
 	payload := models.CallbackPayload{
 		MessageID:   messageID,
-		Status:      "completed",
+		Status:      string(status),
 		Data:        processedData,
 		Error:       nil,
 		Timestamp:   time.Now().UTC().Format(time.RFC3339),
@@ -1086,22 +2284,32 @@ func executeCallbackOnError(ctx context.Context, deps *MessageHandlerDependencie
 
 	// Create callback payload with error information
 	var metadata map[string]interface{}
+	failedAt := time.Now().UTC().Format(time.RFC3339)
 	payload := models.CallbackPayload{
 		MessageID: messageID,
 		Status:    "failed", // Status indicates failure
 		Data: models.ProcessedMessageData{
-			Messages:    []string{errorMessage}, // Error message in messages array
-			AgentID:     "",                     // No agent_id for errors
-			ProcessedAt: messageID,
-			Status:      "error",
-			Metadata:    nil, // Will be set below
+			SchemaVersion: models.ProcessedMessageSchemaVersion,
+			Messages:      []string{errorMessage}, // Error message in messages array
+			AgentID:       "",                     // No agent_id for errors
+			MessageID:     messageID,
+			ProcessedAt:   failedAt,
+			Status:        "error",
+			Metadata:      nil, // Will be set below
 		},
 		Error:       &errorMessage, // Error description
-		Timestamp:   time.Now().UTC().Format(time.RFC3339),
-		ProcessedAt: messageID,
+		Timestamp:   failedAt,
+		ProcessedAt: failedAt,
 		Metadata:    nil, // Will be set below
 	}
 
+	if queueMsg != nil && queueMsg.CorrelationID != "" {
+		if data, ok := payload.Data.(models.ProcessedMessageData); ok {
+			data.CorrelationID = queueMsg.CorrelationID
+			payload.Data = data
+		}
+	}
+
 	// Include original metadata even on error
 	if queueMsg != nil && queueMsg.Metadata != nil {
 		if data, ok := payload.Data.(models.ProcessedMessageData); ok {
@@ -0,0 +1,62 @@
+package workers
+
+import (
+	"strings"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+)
+
+// assignMessageSenders sets each message's "sender_id" and "name" from the RESPONSE_MESSAGE_SENDERS template
+// for its message type, so clients can attribute every message without inspecting its type
+func assignMessageSenders(messages []interface{}, msg *models.QueueMessage, agentID string, deps *MessageHandlerDependencies) {
+	// Validated at startup
+	senders, _ := deps.Config.GetMessageSenders()
+
+	for _, msgInterface := range messages {
+		msgMap, ok := msgInterface.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		messageType, _ := msgMap["message_type"].(string)
+		sender, ok := senders[messageType]
+		if !ok {
+			continue
+		}
+
+		name, _ := msgMap["name"].(string)
+		placeholders := strings.NewReplacer(
+			"{agent_id}", agentID,
+			"{user_number}", msg.UserNumber,
+			"{tool_name}", messageToolName(msgMap),
+			"{name}", name,
+		)
+		if sender.SenderID != "" {
+			msgMap["sender_id"] = nonEmptyOrNil(placeholders.Replace(sender.SenderID))
+		}
+		if sender.Name != "" {
+			msgMap["name"] = nonEmptyOrNil(placeholders.Replace(sender.Name))
+		}
+	}
+}
+
+// messageToolName returns the tool a tool call or tool return message belongs to, or ""
+func messageToolName(msgMap map[string]interface{}) string {
+	if toolCall, ok := msgMap["tool_call"].(map[string]interface{}); ok {
+		if name, ok := toolCall["name"].(string); ok {
+			return name
+		}
+	}
+	if msgMap["message_type"] == "tool_return_message" {
+		name, _ := msgMap["name"].(string)
+		return name
+	}
+	return ""
+}
+
+// nonEmptyOrNil keeps fields whose template expanded to nothing null, as they were before
+func nonEmptyOrNil(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,115 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// splitBoundaries are where long content is preferably cut, best first: paragraphs, lines, sentences, words
+var splitBoundaries = []string{"\n\n", "\n", ". ", "! ", "? ", "; ", " "}
+
+// splitLongMessagesPostProcessor splits assistant messages longer than RESPONSE_MAX_MESSAGE_LENGTH
+// characters into consecutive messages, so providers with a per-message limit accept every part
+func splitLongMessagesPostProcessor(_ context.Context, pc *PostProcessContext, messages []interface{}) []interface{} {
+	maxLength := pc.Deps.Config.GoogleAgentEngine.MaxResponseMessageLength
+	if maxLength <= 0 {
+		return messages
+	}
+
+	messages, added := splitLongMessages(messages, maxLength)
+	if added > 0 {
+		pc.Logger.WithFields(logrus.Fields{
+			"added_messages": added,
+			"max_length":     maxLength,
+		}).Info("Split long assistant messages")
+	}
+	return messages
+}
+
+// splitLongMessages replaces each assistant message whose content exceeds maxLength characters with its
+// parts, in reading order, returning the messages and how many were added. Every part is a copy of the
+// original message with its share of the content, numbered by "split_part" out of "split_parts".
+func splitLongMessages(messages []interface{}, maxLength int) ([]interface{}, int) {
+	var result []interface{}
+	added := 0
+	for i, msgInterface := range messages {
+		msgMap, ok := msgInterface.(map[string]interface{})
+		content, isText := msgMap["content"].(string)
+		if !ok || msgMap["message_type"] != "assistant_message" || !isText || utf8.RuneCountInString(content) <= maxLength {
+			if result != nil {
+				result = append(result, msgInterface)
+			}
+			continue
+		}
+
+		// Copy lazily so responses without long messages are returned untouched
+		if result == nil {
+			result = make([]interface{}, 0, len(messages)+1)
+			result = append(result, messages[:i]...)
+		}
+
+		parts := splitContent(content, maxLength)
+		for n, part := range parts {
+			partMsg := make(map[string]interface{}, len(msgMap)+2)
+			for key, value := range msgMap {
+				partMsg[key] = value
+			}
+			partMsg["content"] = part
+			partMsg["split_part"] = n + 1
+			partMsg["split_parts"] = len(parts)
+			if id, ok := msgMap["id"].(string); ok && n > 0 {
+				partMsg["id"] = fmt.Sprintf("%s-%d", id, n+1)
+			}
+			result = append(result, partMsg)
+		}
+		added += len(parts) - 1
+	}
+
+	if result == nil {
+		return messages, 0
+	}
+	return result, added
+}
+
+// splitContent cuts content into parts of at most maxLength characters, each ending at the best boundary
+// that keeps it at least half full, or mid-word when there is none
+func splitContent(content string, maxLength int) []string {
+	var parts []string
+	rest := strings.TrimSpace(content)
+	for utf8.RuneCountInString(rest) > maxLength {
+		window := rest[:runeOffset(rest, maxLength)]
+
+		cut := len(window)
+		for _, boundary := range splitBoundaries {
+			if i := strings.LastIndex(window, boundary); i > 0 && utf8.RuneCountInString(window[:i]) >= maxLength/2 {
+				// Sentence and clause marks stay with the part they end
+				cut = i + len(strings.TrimRight(boundary, " \n"))
+				break
+			}
+		}
+
+		if part := strings.TrimSpace(rest[:cut]); part != "" {
+			parts = append(parts, part)
+		}
+		rest = strings.TrimSpace(rest[cut:])
+	}
+	if rest != "" {
+		parts = append(parts, rest)
+	}
+	return parts
+}
+
+// runeOffset returns the byte offset of the n-th character of s
+func runeOffset(s string, n int) int {
+	for offset := range s {
+		if n == 0 {
+			return offset
+		}
+		n--
+	}
+	return len(s)
+}
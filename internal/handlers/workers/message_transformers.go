@@ -0,0 +1,50 @@
+package workers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+)
+
+// MessageTransformer converts a provider's raw agent response into the gateway's transformed messages
+// (user/assistant/tool messages followed by usage_statistics). Errors should be categorized ProcessingErrors.
+type MessageTransformer interface {
+	Transform(rawResponse string, logger *logrus.Entry) ([]interface{}, error)
+}
+
+var (
+	messageTransformersMutex sync.RWMutex
+	messageTransformers      = map[string]MessageTransformer{
+		models.ProviderGoogleAgentEngine: GoogleMessageTransformer{},
+	}
+)
+
+// RegisterMessageTransformer sets the transformer used for a provider's responses, replacing any
+// transformer previously registered for it
+func RegisterMessageTransformer(provider string, transformer MessageTransformer) {
+	messageTransformersMutex.Lock()
+	defer messageTransformersMutex.Unlock()
+	messageTransformers[strings.ToLower(provider)] = transformer
+}
+
+// messageTransformerFor returns the transformer registered for the provider
+func messageTransformerFor(provider string) (MessageTransformer, error) {
+	messageTransformersMutex.RLock()
+	defer messageTransformersMutex.RUnlock()
+
+	transformer, exists := messageTransformers[strings.ToLower(provider)]
+	if !exists {
+		names := make([]string, 0, len(messageTransformers))
+		for name := range messageTransformers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown provider %q (available: %s)", provider, strings.Join(names, ", "))
+	}
+	return transformer, nil
+}
@@ -0,0 +1,83 @@
+package workers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/services"
+)
+
+// Moderation stages, logged and traced with blocking decisions
+const (
+	moderationStageInput  = "input"
+	moderationStageOutput = "output"
+)
+
+// checkModeration screens content at the given stage with the configured moderator. A moderator error is
+// logged and the content allowed, so an unavailable policy service doesn't stop users from being answered.
+func checkModeration(ctx context.Context, deps *MessageHandlerDependencies, logger *logrus.Entry, stage string, content string) services.ModerationDecision {
+	if deps.Moderator == nil {
+		return services.ModerationDecision{}
+	}
+
+	check := deps.Moderator.CheckInput
+	if stage == moderationStageOutput {
+		check = deps.Moderator.CheckOutput
+	}
+	decision, err := check(ctx, content)
+	if err != nil {
+		logger.WithError(err).WithField("moderation_stage", stage).Warn("Content moderation failed, allowing content")
+		return services.ModerationDecision{}
+	}
+
+	if decision.Blocked {
+		logger.WithFields(logrus.Fields{
+			"moderation_stage":  stage,
+			"moderation_reason": decision.Reason,
+		}).Warn("Content blocked by moderation")
+		if span := trace.SpanFromContext(ctx); span.IsRecording() {
+			span.SetAttributes(
+				attribute.String("moderation.stage", stage),
+				attribute.String("moderation.reason", decision.Reason))
+		}
+	}
+	return decision
+}
+
+// responseText joins the text content of the messages produced by the agent, as shown to the user
+func responseText(messages []interface{}) string {
+	var parts []string
+	for _, msgInterface := range messages {
+		msgMap, ok := msgInterface.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch msgMap["message_type"] {
+		case "user_message", "usage_statistics":
+			continue
+		}
+		if content, ok := msgMap["content"].(string); ok && content != "" {
+			parts = append(parts, content)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// buildBlockedResponse builds the response returned when moderation blocks the message or the agent's
+// response, using MODERATION_BLOCKED_MESSAGE or the localized default
+func buildBlockedResponse(ctx context.Context, msg *models.QueueMessage, deps *MessageHandlerDependencies, startedAt time.Time, reason string) (models.ProcessedMessageData, error) {
+	content := deps.Config.Security.ModerationBlockedMessage
+	if content == "" {
+		content = localizedMessage(ctx, deps, services.MsgContentBlocked)
+	}
+
+	processedData, err := buildFallbackResponse(msg, deps, startedAt, content, models.TaskStatusBlocked, false)
+	processedData.ModerationReason = reason
+	return processedData, err
+}
@@ -0,0 +1,74 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+)
+
+// sendReply delivers the assistant messages of a stored result to the user through the configured sender.
+// It runs inline so a user's replies go out in the order their messages were processed; failures are
+// logged and never fail the task, since the result is already stored.
+func sendReply(ctx context.Context, deps *MessageHandlerDependencies, msg *models.QueueMessage, response string, logger *logrus.Entry) {
+	if deps.MessageSender == nil {
+		return
+	}
+
+	var processedData models.ProcessedMessageData
+	if err := json.Unmarshal([]byte(response), &processedData); err != nil {
+		logger.WithError(err).Error("Failed to read processed result for delivery")
+		return
+	}
+
+	messages, _ := processedData.Messages.([]interface{})
+	replies := replyTexts(messages)
+	if len(replies) == 0 {
+		logger.Warn("Processed result has no reply text to deliver")
+		return
+	}
+
+	if err := deps.MessageSender.Send(context.WithoutCancel(ctx), msg.UserNumber, replies); err != nil {
+		logger.WithError(err).WithField("message_count", len(replies)).Warn("Failed to deliver reply to user")
+		return
+	}
+	logger.WithField("message_count", len(replies)).Debug("Reply delivered to user")
+}
+
+// replyTexts returns the text of the assistant messages, in order, joining the text blocks of block content
+func replyTexts(messages []interface{}) []string {
+	var replies []string
+	for _, msgInterface := range messages {
+		msgMap, ok := msgInterface.(map[string]interface{})
+		if !ok || msgMap["message_type"] != "assistant_message" {
+			continue
+		}
+
+		var text string
+		switch content := msgMap["content"].(type) {
+		case string:
+			text = content
+		case []interface{}:
+			var parts []string
+			for _, block := range content {
+				switch part := block.(type) {
+				case string:
+					parts = append(parts, part)
+				case map[string]interface{}:
+					if blockText, ok := part["text"].(string); ok && isTextContentBlock(part) {
+						parts = append(parts, blockText)
+					}
+				}
+			}
+			text = strings.Join(parts, "\n")
+		}
+
+		if text = strings.TrimSpace(text); text != "" {
+			replies = append(replies, text)
+		}
+	}
+	return replies
+}
@@ -0,0 +1,147 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+)
+
+// PostProcessContext carries what a post-processor may need besides the messages themselves
+type PostProcessContext struct {
+	Deps   *MessageHandlerDependencies
+	Msg    *models.QueueMessage
+	Logger *logrus.Entry
+}
+
+// PostProcessor transforms the agent's transformed messages before they are stored, returning the
+// messages to pass on (it may modify them in place). Processors run in the order listed in
+// RESPONSE_POST_PROCESSORS, after the usage statistics are tagged and before usage is split out.
+type PostProcessor func(ctx context.Context, pc *PostProcessContext, messages []interface{}) []interface{}
+
+// Names of the built-in post-processors
+const (
+	PostProcessorWhatsAppFormat      = "whatsapp_format"
+	PostProcessorCapMessages         = "cap_messages"
+	PostProcessorRedactToolOutput    = "redact_tool_output"
+	PostProcessorSummarizeToolOutput = "summarize_tool_output"
+	PostProcessorDedupAssistant      = "dedup_assistant"
+	PostProcessorSplitLongMessages   = "split_long_messages"
+)
+
+var (
+	postProcessorsMutex sync.RWMutex
+	postProcessors      = map[string]PostProcessor{
+		PostProcessorWhatsAppFormat:      whatsAppFormatPostProcessor,
+		PostProcessorCapMessages:         capMessagesPostProcessor,
+		PostProcessorRedactToolOutput:    redactToolOutputPostProcessor,
+		PostProcessorSummarizeToolOutput: summarizeToolOutputPostProcessor,
+		PostProcessorDedupAssistant:      dedupAssistantPostProcessor,
+		PostProcessorSplitLongMessages:   splitLongMessagesPostProcessor,
+	}
+)
+
+// RegisterPostProcessor makes a post-processor available under a name for RESPONSE_POST_PROCESSORS,
+// replacing any processor previously registered with it
+func RegisterPostProcessor(name string, processor PostProcessor) {
+	postProcessorsMutex.Lock()
+	defer postProcessorsMutex.Unlock()
+	postProcessors[strings.ToLower(name)] = processor
+}
+
+// postProcessorFor returns the post-processor registered under the name
+func postProcessorFor(name string) (PostProcessor, error) {
+	postProcessorsMutex.RLock()
+	defer postProcessorsMutex.RUnlock()
+
+	processor, exists := postProcessors[strings.ToLower(name)]
+	if !exists {
+		names := make([]string, 0, len(postProcessors))
+		for registered := range postProcessors {
+			names = append(names, registered)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown response post-processor %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	return processor, nil
+}
+
+// ValidatePostProcessors checks that every processor named in RESPONSE_POST_PROCESSORS is registered, so
+// typos are caught at startup rather than silently skipped
+func ValidatePostProcessors(cfg *config.Config) error {
+	for _, name := range cfg.GetPostProcessors() {
+		if _, err := postProcessorFor(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runPostProcessors applies the configured post-processor chain to the messages
+func runPostProcessors(ctx context.Context, pc *PostProcessContext, messages []interface{}) []interface{} {
+	for _, name := range pc.Deps.Config.GetPostProcessors() {
+		processor, err := postProcessorFor(name)
+		if err != nil {
+			pc.Logger.WithError(err).Warn("Skipping unknown response post-processor")
+			continue
+		}
+		messages = processor(ctx, pc, messages)
+	}
+	return messages
+}
+
+// whatsAppFormatPostProcessor formats message content for the message's channel (WhatsApp unless the
+// queue message names another channel)
+func whatsAppFormatPostProcessor(_ context.Context, pc *PostProcessContext, messages []interface{}) []interface{} {
+	return applyChannelFormattingToMessages(pc.Deps.Logger, pc.Deps.formatter(), pc.Msg.Channel, messages)
+}
+
+// capMessagesPostProcessor keeps long tool chains from flooding the channel (GOOGLE_AGENT_ENGINE_MAX_RESPONSE_MESSAGES)
+func capMessagesPostProcessor(_ context.Context, pc *PostProcessContext, messages []interface{}) []interface{} {
+	maxMessages := pc.Deps.Config.GoogleAgentEngine.MaxResponseMessages
+	if maxMessages <= 0 {
+		return messages
+	}
+
+	messages, omitted := capResponseMessages(messages, maxMessages)
+	if omitted > 0 {
+		pc.Logger.WithFields(logrus.Fields{
+			"omitted_messages": omitted,
+			"max_messages":     maxMessages,
+		}).Info("Capped number of response messages")
+	}
+	return messages
+}
+
+// redactToolOutputPostProcessor strips internal data from tool output; it should run before anything
+// is formatted for the user
+func redactToolOutputPostProcessor(_ context.Context, pc *PostProcessContext, messages []interface{}) []interface{} {
+	redactToolReturns(pc.Deps.Redactor, messages, pc.Logger)
+	return messages
+}
+
+// summarizeToolOutputPostProcessor replaces large tool output blobs with a readable line, keeping the raw data alongside
+func summarizeToolOutputPostProcessor(ctx context.Context, pc *PostProcessContext, messages []interface{}) []interface{} {
+	summarizeToolReturns(ctx, pc.Deps, messages, pc.Logger)
+	return messages
+}
+
+// dedupAssistantPostProcessor avoids sending the same bubble twice when the agent repeats itself
+// (GOOGLE_AGENT_ENGINE_DEDUP_ASSISTANT_MESSAGES)
+func dedupAssistantPostProcessor(_ context.Context, pc *PostProcessContext, messages []interface{}) []interface{} {
+	if !pc.Deps.Config.GoogleAgentEngine.DedupAssistantMessages {
+		return messages
+	}
+
+	messages, removed := dedupAssistantMessages(messages)
+	if removed > 0 {
+		pc.Logger.WithField("removed_messages", removed).Info("Collapsed duplicate consecutive assistant messages")
+	}
+	return messages
+}
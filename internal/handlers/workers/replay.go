@@ -0,0 +1,118 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/services"
+)
+
+// replayContext is what the agent was asked, stored with its raw response so ReplayTask can rebuild the
+// agentExchange without calling the agent again
+type replayContext struct {
+	Message        models.QueueMessage `json:"message"`
+	AgentMessage   string              `json:"agent_message"` // Text sent to the agent, without the preamble
+	Preamble       string              `json:"preamble,omitempty"`
+	Model          string              `json:"model,omitempty"`
+	ThreadID       string              `json:"thread_id,omitempty"`
+	Provider       string              `json:"provider,omitempty"`
+	FallbackReason string              `json:"fallback_reason,omitempty"`
+	AudioURL       string              `json:"audio_url,omitempty"`
+	Transcript     *string             `json:"transcript,omitempty"`
+}
+
+// storeRawResponse keeps the agent's raw response and its replay context for the task result TTL, logging
+// rather than failing when they can't be stored
+func storeRawResponse(ctx context.Context, msg *models.QueueMessage, exchange agentExchange, deps *MessageHandlerDependencies, logger *logrus.Entry) {
+	ttl := deps.Config.Redis.TaskResultTTL
+	if err := deps.RedisService.SetTaskRawResponse(ctx, msg.ID, exchange.rawResponse, ttl); err != nil {
+		logger.WithError(err).Warn("Failed to store raw agent response")
+		return
+	}
+
+	replay, err := json.Marshal(replayContext{
+		Message:        *msg,
+		AgentMessage:   exchange.message,
+		Preamble:       exchange.preamble,
+		Model:          exchange.model,
+		ThreadID:       exchange.threadID,
+		Provider:       exchange.provider,
+		FallbackReason: exchange.fallbackReason,
+		AudioURL:       exchange.audioSourceURL,
+		Transcript:     exchange.transcript,
+	})
+	if err == nil {
+		err = deps.RedisService.SetTaskReplayContext(ctx, msg.ID, string(replay), ttl)
+	}
+	if err != nil {
+		logger.WithError(err).Warn("Failed to store replay context, raw agent response can't be replayed")
+	}
+}
+
+// ReplayTask reprocesses a task's stored raw agent response with the current transform, post-processing and
+// formatting configuration, without calling the agent. Unless dryRun is set the result replaces the task's
+// stored result. tenantID selects the namespace of a routed tenant's task data ("" for the shared one).
+// The raw response must have been stored (GOOGLE_AGENT_ENGINE_STORE_RAW_RESPONSE or store_raw_response).
+func ReplayTask(ctx context.Context, taskID, tenantID string, deps *MessageHandlerDependencies, dryRun bool) (models.ProcessedMessageData, error) {
+	deps = deps.forTenant(tenantID)
+	logger := deps.Logger.WithFields(logrus.Fields{
+		"function":   "ReplayTask",
+		"message_id": taskID,
+		"dry_run":    dryRun,
+	})
+
+	rawResponse, err := deps.RedisService.GetTaskRawResponse(ctx, taskID)
+	if err != nil {
+		return models.ProcessedMessageData{}, fmt.Errorf("failed to load raw agent response: %w", err)
+	}
+	replayJSON, err := deps.RedisService.GetTaskReplayContext(ctx, taskID)
+	if err != nil {
+		return models.ProcessedMessageData{}, fmt.Errorf("failed to load replay context: %w", err)
+	}
+	var replay replayContext
+	if err := json.Unmarshal([]byte(replayJSON), &replay); err != nil {
+		return models.ProcessedMessageData{}, fmt.Errorf("failed to decode replay context: %w", err)
+	}
+	msg := &replay.Message
+
+	ctx = services.WithLocale(ctx, resolveLocale(ctx, msg, deps, logger))
+	processedData, err := transformAgentResponse(ctx, msg, deps, logger, agentExchange{
+		rawResponse:    rawResponse,
+		message:        replay.AgentMessage,
+		preamble:       replay.Preamble,
+		model:          replay.Model,
+		threadID:       replay.ThreadID,
+		provider:       replay.Provider,
+		fallbackReason: replay.FallbackReason,
+		audioSourceURL: replay.AudioURL,
+		transcript:     replay.Transcript,
+		startedAt:      time.Now(),
+	})
+	if err != nil {
+		return models.ProcessedMessageData{}, fmt.Errorf("failed to transform raw agent response: %w", err)
+	}
+	processedData.Metadata = msg.Metadata
+
+	status := processedTaskStatus(processedData)
+	if dryRun {
+		logger.WithField("status", status).Info("Replayed raw agent response (dry run)")
+		return processedData, nil
+	}
+
+	processedBytes, err := json.Marshal(processedData)
+	if err != nil {
+		return models.ProcessedMessageData{}, fmt.Errorf("failed to marshal replayed result: %w", err)
+	}
+	// No user number: a replayed older task must not replace the user's latest result
+	if err := deps.RedisService.CompleteTask(ctx, taskID, "", string(processedBytes), string(status), deps.Config.Redis.TaskResultTTL, deps.Config.GetTaskStatusTTL(string(status))); err != nil {
+		return models.ProcessedMessageData{}, fmt.Errorf("failed to store replayed result: %w", err)
+	}
+
+	logger.WithField("status", status).Info("Replayed raw agent response and stored the updated result")
+	return processedData, nil
+}
@@ -0,0 +1,49 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/services"
+)
+
+// scheduledMessageTolerance is how early a scheduled message may be processed, so messages republished
+// on time aren't parked again over clock skew between replicas
+const scheduledMessageTolerance = time.Second
+
+// deferScheduledMessage parks a message whose scheduled_at is still in the future, reporting whether it did.
+// The message comes back through the queue when due and is processed then. Without a scheduler the message
+// is processed right away; a failure to park it is returned so the consumer retries the delivery.
+func deferScheduledMessage(ctx context.Context, delivery amqp.Delivery, msg *models.QueueMessage, deps *MessageHandlerDependencies, logger *logrus.Entry) (bool, error) {
+	if msg.ScheduledAt == nil {
+		return false, nil
+	}
+	wait := time.Until(*msg.ScheduledAt)
+	if wait <= scheduledMessageTolerance {
+		return false, nil
+	}
+
+	logger = logger.WithField("scheduled_at", msg.ScheduledAt.Format(time.RFC3339))
+	if deps.MessageScheduler == nil {
+		logger.Warn("Message is scheduled for later but scheduling is disabled, processing it now")
+		return false, nil
+	}
+
+	if err := deps.MessageScheduler.Schedule(ctx, services.UserMessageQueue(deps.Config, msg.UserNumber), delivery, *msg.ScheduledAt); err != nil {
+		logger.WithError(err).Error("Failed to schedule message")
+		return true, err
+	}
+
+	// Keep the status around until the message has had time to run
+	ttl := wait + deps.Config.GetTaskStatusTTL(string(models.TaskStatusProcessing))
+	if err := deps.RedisService.SetTaskStatus(ctx, msg.ID, string(models.TaskStatusScheduled), ttl); err != nil {
+		logger.WithError(err).WithField("redis_failure", redisFailureStatusUpdate).Warn("Failed to update task status to scheduled")
+	}
+
+	logger.WithField("wait", wait.Round(time.Second).String()).Info("Message scheduled for later processing")
+	return true, nil
+}
@@ -0,0 +1,155 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/services"
+)
+
+// Tool output summary backends (selected with TOOL_OUTPUT_SUMMARY_BACKEND)
+const (
+	ToolOutputSummaryBackendAgent = "agent"
+	ToolOutputSummaryBackendHTTP  = "http"
+)
+
+// toolOutputSummaryPrompt asks the agent for a single user-facing line describing a tool's output
+const toolOutputSummaryPrompt = `Resuma em uma única frase curta, em português e sem JSON, o resultado abaixo da ferramenta "%s", como se explicasse a um cidadão pelo WhatsApp:
+
+%s`
+
+// NewToolOutputSummarizer constructs the summarizer selected in config, or returns nil when
+// TOOL_OUTPUT_SUMMARY_THRESHOLD is 0
+func NewToolOutputSummarizer(cfg *config.Config, logger *logrus.Logger, agentService GoogleAgentServiceInterface) (services.ToolOutputSummarizer, error) {
+	if cfg.GoogleAgentEngine.ToolOutputSummaryThreshold <= 0 {
+		return nil, nil
+	}
+
+	backend := strings.ToLower(strings.TrimSpace(cfg.GoogleAgentEngine.ToolOutputSummaryBackend))
+	var summarizer services.ToolOutputSummarizer
+	switch backend {
+	case "", ToolOutputSummaryBackendAgent:
+		if agentService == nil {
+			return nil, fmt.Errorf("the agent summarizer requires the Google Agent Engine service")
+		}
+		backend = ToolOutputSummaryBackendAgent
+		summarizer = &AgentToolOutputSummarizer{agentService: agentService, logger: logger}
+	case ToolOutputSummaryBackendHTTP:
+		httpSummarizer, err := services.NewHTTPToolOutputSummarizer(cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+		summarizer = httpSummarizer
+	default:
+		return nil, fmt.Errorf("unknown tool output summary backend %q (available: %s, %s)", backend, ToolOutputSummaryBackendAgent, ToolOutputSummaryBackendHTTP)
+	}
+
+	logger.WithFields(logrus.Fields{
+		"backend":   backend,
+		"threshold": cfg.GoogleAgentEngine.ToolOutputSummaryThreshold,
+	}).Info("Tool output summarization enabled")
+	return summarizer, nil
+}
+
+// AgentToolOutputSummarizer asks the agent for the summary on a throwaway thread, so the user's
+// conversation never sees the request
+type AgentToolOutputSummarizer struct {
+	agentService GoogleAgentServiceInterface
+	logger       *logrus.Logger
+}
+
+// Summarize sends the tool output to the agent and returns its last assistant message
+func (s *AgentToolOutputSummarizer) Summarize(ctx context.Context, toolName, output string) (string, error) {
+	threadID := "tool_summary_" + uuid.New().String()
+	response, err := s.agentService.SendMessage(ctx, threadID, fmt.Sprintf(toolOutputSummaryPrompt, toolName, output))
+	if err != nil {
+		return "", fmt.Errorf("failed to request summary from agent: %w", err)
+	}
+
+	transformer, err := messageTransformerFor(models.ProviderGoogleAgentEngine)
+	if err != nil {
+		return "", err
+	}
+	messages, err := transformer.Transform(response.Content, logrus.NewEntry(s.logger).WithField("thread_id", threadID))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse summary from agent: %w", err)
+	}
+
+	for i := len(messages) - 1; i >= 0; i-- {
+		msgMap, ok := messages[i].(map[string]interface{})
+		if !ok || msgMap["message_type"] != "assistant_message" {
+			continue
+		}
+		if content, ok := msgMap["content"].(string); ok && strings.TrimSpace(content) != "" {
+			return strings.TrimSpace(content), nil
+		}
+	}
+	return "", fmt.Errorf("agent returned no summary")
+}
+
+// summarizeToolReturns replaces tool outputs of at least TOOL_OUTPUT_SUMMARY_THRESHOLD bytes with a short
+// summary, in place, keeping the raw output in raw_tool_return. Outputs that fail to summarize are kept as-is.
+func summarizeToolReturns(ctx context.Context, deps *MessageHandlerDependencies, messages []interface{}, logger *logrus.Entry) {
+	threshold := deps.Config.GoogleAgentEngine.ToolOutputSummaryThreshold
+	if deps.ToolOutputSummarizer == nil || threshold <= 0 {
+		return
+	}
+
+	for _, msgInterface := range messages {
+		msgMap, ok := msgInterface.(map[string]interface{})
+		if !ok || msgMap["message_type"] != "tool_return_message" {
+			continue
+		}
+
+		raw, exists := msgMap["tool_return"]
+		if !exists || raw == nil {
+			raw = msgMap["content"]
+		}
+		if raw == nil {
+			continue
+		}
+
+		// Structured tool output is summarized in its JSON form
+		text, isString := raw.(string)
+		if !isString {
+			encoded, err := json.Marshal(raw)
+			if err != nil {
+				continue
+			}
+			text = string(encoded)
+		}
+		if len(text) < threshold {
+			continue
+		}
+
+		toolName, _ := msgMap["name"].(string)
+		summaryCtx, cancel := withOperationTimeout(ctx, deps.Config.GetToolOutputSummaryTimeout())
+		summary, err := deps.ToolOutputSummarizer.Summarize(summaryCtx, toolName, text)
+		cancel()
+		if err != nil {
+			logger.WithError(err).WithFields(logrus.Fields{
+				"tool_name":     toolName,
+				"output_length": len(text),
+			}).Warn("Failed to summarize tool output, keeping raw content")
+			continue
+		}
+
+		msgMap["raw_tool_return"] = raw
+		msgMap["tool_return"] = summary
+		msgMap["content"] = summary
+		msgMap["summarized"] = true
+
+		logger.WithFields(logrus.Fields{
+			"tool_name":      toolName,
+			"output_length":  len(text),
+			"summary_length": len(summary),
+		}).Info("Summarized long tool output")
+	}
+}
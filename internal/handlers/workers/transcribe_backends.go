@@ -0,0 +1,156 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/services"
+)
+
+// Built-in transcription backend names (selected with TRANSCRIBE_BACKEND)
+const (
+	TranscribeBackendGoogle = "google"
+	TranscribeBackendNoop   = "noop"
+)
+
+// TranscribeBackend is a transcription implementation that may hold resources to release on shutdown
+type TranscribeBackend interface {
+	TranscribeServiceInterface
+	Close() error
+}
+
+// TranscribeBackendFactory constructs a transcription backend from config and its backend-specific options
+type TranscribeBackendFactory func(cfg *config.Config, logger *logrus.Logger, rateLimiter services.RateLimiterInterface, options map[string]string) (TranscribeBackend, error)
+
+var (
+	transcribeBackendsMutex sync.RWMutex
+	transcribeBackends      = map[string]TranscribeBackendFactory{
+		TranscribeBackendGoogle: newGoogleTranscribeBackend,
+		TranscribeBackendNoop:   newNoopTranscribeBackend,
+	}
+)
+
+// RegisterTranscribeBackend makes a transcription backend available under the given name,
+// replacing any backend previously registered with it
+func RegisterTranscribeBackend(name string, factory TranscribeBackendFactory) {
+	transcribeBackendsMutex.Lock()
+	defer transcribeBackendsMutex.Unlock()
+	transcribeBackends[strings.ToLower(name)] = factory
+}
+
+// NewTranscribeBackend constructs the transcription backend selected in config
+func NewTranscribeBackend(cfg *config.Config, logger *logrus.Logger, rateLimiter services.RateLimiterInterface) (TranscribeBackend, error) {
+	name := strings.ToLower(strings.TrimSpace(cfg.Transcribe.Backend))
+	if name == "" {
+		name = TranscribeBackendGoogle
+	}
+
+	backend, err := newTranscribeBackend(name, cfg, logger, rateLimiter, cfg.GetTranscribeBackendOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	logger.WithField("backend", name).Info("Transcription backend initialized")
+	return backend, nil
+}
+
+// NewEscalationTranscribeBackend constructs the backend that re-transcribes unusable or low-confidence
+// transcripts (TRANSCRIBE_ESCALATION_BACKEND), or returns nil when re-transcription is disabled
+func NewEscalationTranscribeBackend(cfg *config.Config, logger *logrus.Logger, rateLimiter services.RateLimiterInterface) (TranscribeBackend, error) {
+	name := strings.ToLower(strings.TrimSpace(cfg.Transcribe.EscalationBackend))
+	if name == "" {
+		return nil, nil
+	}
+
+	backend, err := newTranscribeBackend(name, cfg, logger, rateLimiter, cfg.GetTranscribeEscalationBackendOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	logger.WithFields(logrus.Fields{
+		"backend":        name,
+		"min_confidence": cfg.Transcribe.EscalationMinConfidence,
+	}).Info("Re-transcription backend initialized")
+	return backend, nil
+}
+
+// newTranscribeBackend constructs the named backend with the given options
+func newTranscribeBackend(name string, cfg *config.Config, logger *logrus.Logger, rateLimiter services.RateLimiterInterface, options map[string]string) (TranscribeBackend, error) {
+	transcribeBackendsMutex.RLock()
+	factory, exists := transcribeBackends[name]
+	transcribeBackendsMutex.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("unknown transcription backend %q (available: %s)", name, strings.Join(registeredTranscribeBackends(), ", "))
+	}
+
+	backend, err := factory(cfg, logger, rateLimiter, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize %s transcription backend: %w", name, err)
+	}
+	return backend, nil
+}
+
+// registeredTranscribeBackends returns the sorted names of all registered backends
+func registeredTranscribeBackends() []string {
+	transcribeBackendsMutex.RLock()
+	defer transcribeBackendsMutex.RUnlock()
+
+	names := make([]string, 0, len(transcribeBackends))
+	for name := range transcribeBackends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newGoogleTranscribeBackend wraps the Google Cloud Speech-to-Text service. The "model" option selects the
+// recognition model (default "long").
+func newGoogleTranscribeBackend(cfg *config.Config, logger *logrus.Logger, rateLimiter services.RateLimiterInterface, options map[string]string) (TranscribeBackend, error) {
+	service, err := services.NewTranscribeService(cfg, logger, rateLimiter)
+	if err != nil {
+		return nil, err
+	}
+	service.SetModel(options["model"])
+	return NewTranscribeServiceAdapter(service, cfg), nil
+}
+
+// NoopTranscribeBackend detects audio but never transcribes it, so audio messages take the
+// default fallback path. Used in environments without a transcription provider.
+type NoopTranscribeBackend struct {
+	config *config.Config
+}
+
+// NewNoopTranscribeBackend creates a backend that disables transcription
+func NewNoopTranscribeBackend(cfg *config.Config) *NoopTranscribeBackend {
+	return &NoopTranscribeBackend{config: cfg}
+}
+
+func newNoopTranscribeBackend(cfg *config.Config, _ *logrus.Logger, _ services.RateLimiterInterface, _ map[string]string) (TranscribeBackend, error) {
+	return NewNoopTranscribeBackend(cfg), nil
+}
+
+// TranscribeAudio always fails because transcription is disabled
+func (b *NoopTranscribeBackend) TranscribeAudio(ctx context.Context, audioURL string) (string, error) {
+	return "", fmt.Errorf("transcription is disabled")
+}
+
+// IsAudioURL checks if the URL appears to be an audio file
+func (b *NoopTranscribeBackend) IsAudioURL(url string) bool {
+	return services.IsAudioURL(b.config, url)
+}
+
+// ValidateAudioURL always fails because transcription is disabled
+func (b *NoopTranscribeBackend) ValidateAudioURL(url string) error {
+	return fmt.Errorf("transcription is disabled")
+}
+
+// Close is a no-op
+func (b *NoopTranscribeBackend) Close() error {
+	return nil
+}
@@ -0,0 +1,44 @@
+package workers
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateMessage(t *testing.T) {
+	markerLen := len(truncatedMessageMarker)
+	tail := strings.Repeat("x", markerLen)
+	tests := []struct {
+		name      string
+		message   string
+		maxLength int
+		want      string
+	}{
+		{"fits", "olá", 10, "olá"},
+		{"exact fit", "abc", 3, "abc"},
+		{"ascii", strings.Repeat("a", 50), markerLen + 5, "aaaaa" + truncatedMessageMarker},
+		// "çã" is 4 bytes; a limit landing on the second byte of "ã" backs off to before it
+		{"splits two-byte rune", "açãb" + tail, markerLen + 4, "aç" + truncatedMessageMarker},
+		// "😀" is 4 bytes
+		{"splits four-byte rune", "ab😀c" + tail, markerLen + 3, "ab" + truncatedMessageMarker},
+		{"rune boundary", "ab😀c" + tail, markerLen + 6, "ab😀" + truncatedMessageMarker},
+		{"no room for marker", "ãõé", 3, "ã"},
+		{"limit inside first rune", "😀😀", 2, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateMessage(tt.message, tt.maxLength)
+			if got != tt.want {
+				t.Errorf("truncateMessage(%q, %d) = %q, want %q", tt.message, tt.maxLength, got, tt.want)
+			}
+			if len(got) > tt.maxLength {
+				t.Errorf("len = %d bytes, over the limit of %d", len(got), tt.maxLength)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("truncateMessage(%q, %d) = %q, not valid UTF-8", tt.message, tt.maxLength, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+)
+
+const (
+	// userLockPollInterval is how often a waiting task retries the user's lock
+	userLockPollInterval = 200 * time.Millisecond
+
+	// userLockWaiterTTL is how long a waiting task keeps its place in line without retrying, so crashed
+	// workers don't hold up the queue
+	userLockWaiterTTL = 5 * time.Second
+)
+
+// acquireUserLock waits for the user's lock so that their messages reach the agent one at a time, in
+// arrival order, and returns a function releasing it (safe to call more than once). It is a no-op when
+// USER_LOCK_TTL is 0. Redis failures fall back to proceeding without the lock; running out of time while
+// waiting returns a retriable error.
+func acquireUserLock(ctx context.Context, msg *models.QueueMessage, deps *MessageHandlerDependencies, logger *logrus.Entry) (func(), error) {
+	lockTTL := deps.Config.Redis.UserLockTTL
+	if lockTTL <= 0 {
+		return func() {}, nil
+	}
+
+	startedAt := time.Now()
+	ticker := time.NewTicker(userLockPollInterval)
+	defer ticker.Stop()
+
+	for {
+		acquired, err := deps.RedisService.TryAcquireUserLock(ctx, msg.UserNumber, msg.ID, lockTTL, userLockWaiterTTL)
+		if err != nil {
+			logger.WithError(err).Warn("Failed to acquire user lock, processing without it")
+			return func() {}, nil
+		}
+		if acquired {
+			if waited := time.Since(startedAt); waited >= userLockPollInterval {
+				logger.WithField("waited", waited.String()).Info("Acquired user lock after waiting for earlier messages")
+			}
+			var once sync.Once
+			return func() {
+				once.Do(func() {
+					// Release even if the task's context was cancelled, so the next message isn't held up
+					releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+					defer cancel()
+					if err := deps.RedisService.ReleaseUserLock(releaseCtx, msg.UserNumber, msg.ID); err != nil {
+						logger.WithError(err).Warn("Failed to release user lock, it expires after its TTL")
+					}
+				})
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			leaveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			_ = deps.RedisService.ReleaseUserLock(leaveCtx, msg.UserNumber, msg.ID)
+			cancel()
+			// Uncategorized so shouldRetryError requeues it: the message only waited behind the user's earlier ones
+			return nil, fmt.Errorf("timed out waiting for the user's earlier messages: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
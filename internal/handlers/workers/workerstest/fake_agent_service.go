@@ -0,0 +1,205 @@
+// Package workerstest provides fakes for the dependencies of the worker message handlers, so the
+// processing pipeline can be exercised without the Google Agent Engine.
+package workerstest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	workerhandlers "github.com/prefeitura-rio/app-eai-agent-gateway/internal/handlers/workers"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/services"
+)
+
+var _ workerhandlers.GoogleAgentServiceInterface = (*FakeAgentService)(nil)
+
+// SentMessage records one message sent through a FakeAgentService
+type SentMessage struct {
+	ThreadID  string
+	Content   string
+	ImageURLs []string
+	Model     string                   // Requested with services.WithModel
+	Documents []services.DocumentInput // Attached with services.WithDocuments
+}
+
+// FakeAgentService is an in-memory GoogleAgentServiceInterface. Threads are kept in a map keyed by user
+// ID and every sent message is recorded. Replies come from Respond when set, otherwise from Responses in
+// order (the last one repeats), otherwise a single assistant message echoing the content.
+// The zero value is ready to use and it is safe for concurrent use.
+type FakeAgentService struct {
+	// Respond, when set, produces the reply to every message
+	Respond func(threadID, content string, imageURLs []string) (*models.AgentResponse, error)
+	// Responses are returned in order when Respond is nil
+	Responses []*models.AgentResponse
+
+	// Errors returned by the matching operations when set
+	ThreadErr error
+	SendErr   error
+
+	// Images reports whether the agent accepts images (SupportsImages)
+	Images bool
+	// Documents reports whether the agent accepts documents (SupportsDocuments)
+	Documents bool
+	// State is the reported circuit breaker state; the circuit is open when it is services.CircuitOpen
+	State services.CircuitState
+
+	mu        sync.Mutex
+	threads   map[string]string
+	sent      []SentMessage
+	responded int
+}
+
+// NewFakeAgentService creates a fake replying with the given responses in order
+func NewFakeAgentService(responses ...*models.AgentResponse) *FakeAgentService {
+	return &FakeAgentService{Responses: responses}
+}
+
+// CreateThread starts a new thread for the user, replacing any existing one
+func (f *FakeAgentService) CreateThread(ctx context.Context, userID string) (string, error) {
+	if f.ThreadErr != nil {
+		return "", f.ThreadErr
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.createThreadLocked(userID), nil
+}
+
+// GetOrCreateThread returns the user's thread, creating it if needed
+func (f *FakeAgentService) GetOrCreateThread(ctx context.Context, userID string) (string, error) {
+	if f.ThreadErr != nil {
+		return "", f.ThreadErr
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if threadID, ok := f.threads[userID]; ok {
+		return threadID, nil
+	}
+	return f.createThreadLocked(userID), nil
+}
+
+// createThreadLocked assigns the user a fresh thread ID; f.mu must be held
+func (f *FakeAgentService) createThreadLocked(userID string) string {
+	if f.threads == nil {
+		f.threads = make(map[string]string)
+	}
+	threadID := userID
+	if _, exists := f.threads[userID]; exists {
+		threadID = fmt.Sprintf("%s-%d", userID, len(f.sent)+len(f.threads)+1)
+	}
+	f.threads[userID] = threadID
+	return threadID
+}
+
+// ExpireThread forgets the user's thread so the next message starts a new one
+func (f *FakeAgentService) ExpireThread(ctx context.Context, userID string) error {
+	if f.ThreadErr != nil {
+		return f.ThreadErr
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.threads, userID)
+	return nil
+}
+
+// ResetThread forgets the user's thread and returns how many threads were removed
+func (f *FakeAgentService) ResetThread(ctx context.Context, userID string) (int, error) {
+	if f.ThreadErr != nil {
+		return 0, f.ThreadErr
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.threads[userID]; !ok {
+		return 0, nil
+	}
+	delete(f.threads, userID)
+	return 1, nil
+}
+
+// SendMessage records the message and returns the next reply
+func (f *FakeAgentService) SendMessage(ctx context.Context, threadID string, content string, opts ...services.SendOption) (*models.AgentResponse, error) {
+	return f.SendMultimodalMessage(ctx, threadID, content, nil, opts...)
+}
+
+// SendMultimodalMessage records the message with its images and returns the next reply
+func (f *FakeAgentService) SendMultimodalMessage(ctx context.Context, threadID string, content string, imageURLs []string, opts ...services.SendOption) (*models.AgentResponse, error) {
+	options := services.NewSendOptions(opts...)
+
+	f.mu.Lock()
+	f.sent = append(f.sent, SentMessage{ThreadID: threadID, Content: content, ImageURLs: imageURLs, Model: options.Model, Documents: options.Documents})
+	f.mu.Unlock()
+
+	if f.SendErr != nil {
+		return nil, f.SendErr
+	}
+	if f.Respond != nil {
+		return f.Respond(threadID, content, imageURLs)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.Responses) > 0 {
+		index := f.responded
+		if index >= len(f.Responses) {
+			index = len(f.Responses) - 1
+		}
+		f.responded++
+		return f.Responses[index], nil
+	}
+	return EchoResponse(threadID, content), nil
+}
+
+// SupportsImages reports the configured Images flag
+func (f *FakeAgentService) SupportsImages() bool {
+	return f.Images
+}
+
+// SupportsDocuments reports the configured Documents flag
+func (f *FakeAgentService) SupportsDocuments() bool {
+	return f.Documents
+}
+
+// IsCircuitOpen reports whether State is services.CircuitOpen
+func (f *FakeAgentService) IsCircuitOpen() bool {
+	return f.State == services.CircuitOpen
+}
+
+// CircuitState returns the configured State
+func (f *FakeAgentService) CircuitState() services.CircuitState {
+	return f.State
+}
+
+// Sent returns a copy of the messages sent so far
+func (f *FakeAgentService) Sent() []SentMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]SentMessage(nil), f.sent...)
+}
+
+// ThreadFor returns the user's current thread ID, if any
+func (f *FakeAgentService) ThreadFor(userID string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	threadID, ok := f.threads[userID]
+	return threadID, ok
+}
+
+// EchoResponse builds an agent response with a single assistant message repeating content, shaped like a
+// Google Agent Engine reply so the handlers parse and transform it as they would the real agent's
+func EchoResponse(threadID, content string) *models.AgentResponse {
+	raw := map[string]interface{}{
+		"output": map[string]interface{}{
+			"messages": []interface{}{
+				map[string]interface{}{"type": "human", "content": content},
+				map[string]interface{}{"type": "ai", "content": content},
+			},
+		},
+	}
+	encoded, _ := json.Marshal(raw)
+	return &models.AgentResponse{Content: string(encoded), ThreadID: threadID}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"net/http"
 	"strings"
 
@@ -30,6 +31,22 @@ func SecurityHeaders() gin.HandlerFunc {
 	}
 }
 
+// AdminAuth requires an "Authorization: Bearer <token>" header matching the admin token
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "Unauthorized",
+				"message": "A valid admin token is required",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // RequestSizeLimit limits the size of request bodies
 func RequestSizeLimit(maxSize int64) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,10 +13,17 @@ import (
 type UserWebhookRequest struct {
 	UserNumber      string                 `json:"user_number" binding:"required" example:"5521999999999"`
 	PreviousMessage *string                `json:"previous_message,omitempty" example:"Previous message context"`
-	Message         string                 `json:"message" binding:"required" example:"Hello, how can you help me?"`
+	Message         string                 `json:"message" example:"Hello, how can you help me?"` // Optional when media.url is set
 	Metadata        map[string]interface{} `json:"metadata,omitempty"`
-	Provider        *string                `json:"provider,omitempty" example:"google_agent_engine"`
+	Provider        *string                `json:"provider,omitempty" example:"google_agent_engine"` // Case-insensitive, with - or _ and PROVIDER_ALIASES accepted
 	CallbackURL     *string                `json:"callback_url,omitempty" binding:"omitempty,url" example:"https://example.com/webhook/callback"`
+	Media           *MessageMedia          `json:"media,omitempty"`
+	Locale          *string                `json:"locale,omitempty" example:"pt-BR"`                      // Language of gateway-generated replies (pt, es, en)
+	TenantID        string                 `json:"tenant_id,omitempty" example:"smas"`                    // Selects the tenant's agent preamble and, when routed, its task store
+	Channel         string                 `json:"channel,omitempty" example:"whatsapp"`                  // Delivery channel selecting the reply format (whatsapp, web); defaults to whatsapp
+	ScheduledAt     *time.Time             `json:"scheduled_at,omitempty" example:"2026-01-01T09:00:00Z"` // Process no earlier than this time (e.g. reminders)
+	Model           string                 `json:"model,omitempty" example:"gemini-2.5-flash"`            // Agent model to answer with, one of AGENT_ALLOWED_MODELS (defaults to AGENT_DEFAULT_MODEL)
+	Priority        uint8                  `json:"priority,omitempty" example:"5"`                        // Processed ahead of lower-priority messages, up to RABBITMQ_MAX_PRIORITY (default 0)
 }
 
 // WebhookResponse represents the response for webhook endpoints (matches Python API)
@@ -23,26 +33,126 @@ type WebhookResponse struct {
 	PollingEndpoint string `json:"polling_endpoint" example:"/api/v1/message/response?message_id=123e4567-e89b-12d3-a456-426614174000"`
 }
 
+// ResponsePollingPath returns the endpoint polled for a message's result, scoped to the tenant when one is given
+func ResponsePollingPath(messageID, tenantID string) string {
+	path := "/api/v1/message/response?message_id=" + messageID
+	if tenantID != "" {
+		path += "&tenant_id=" + url.QueryEscape(tenantID)
+	}
+	return path
+}
+
 // MessageResponseRequest represents the query parameters for message response endpoint
 type MessageResponseRequest struct {
 	MessageID string `form:"message_id" binding:"required,uuid" example:"123e4567-e89b-12d3-a456-426614174000"`
+	TenantID  string `form:"tenant_id" example:"smas"` // Tenant the message was submitted for; selects its task store
 }
 
 // MessageResponse represents the response structure for message polling (matches Python API)
 // @Description Message processing response
 type MessageResponse struct {
-	Status string      `json:"status" example:"completed"`
-	Data   interface{} `json:"data,omitempty" swaggertype:"object"`
-	Error  *string     `json:"error,omitempty" example:"Error message if processing failed"`
+	Status       string       `json:"status" example:"completed"`
+	Data         interface{}  `json:"data,omitempty" swaggertype:"object"`
+	Error        *string      `json:"error,omitempty" example:"Error message if processing failed"`
+	ErrorDetails *ErrorResult `json:"error_details,omitempty"` // Structured form of Error
+}
+
+// ErrorResult describes why a task failed. It is stored as JSON by the workers and returned alongside
+// the plain error message by the status endpoints.
+type ErrorResult struct {
+	Code      string `json:"code" example:"agent_timeout"` // Error category (validation, agent_timeout, ..., unknown)
+	Message   string `json:"message" example:"agent call failed: context deadline exceeded"`
+	Retryable bool   `json:"retryable" example:"true"`                           // Whether submitting the message again may succeed
+	Timestamp string `json:"timestamp,omitempty" example:"2025-01-01T12:00:03Z"` // When the task failed (RFC3339)
 }
 
+// ErrorCodeUnknown is the code of uncategorized errors, including plain-text errors stored by older workers
+const ErrorCodeUnknown = "unknown"
+
+// ProcessedMessageSchemaVersion is the current shape of ProcessedMessageData and its transformed messages.
+// Increment it whenever fields are added, removed or change meaning so consumers can branch on it.
+//
+//	1: initial versioned format
+//	2: transformed messages carry unmapped agent fields under "extra"
+//	3: usage_statistics reports "omitted_messages" when the response was capped
+//	4: top-level "usage" holds usage_statistics in messages_only output mode
+//	5: optional "original_message" and "effective_message" echo the inbound text
+//	6: "metadata" echoes the queue message's metadata verbatim
+//	7: tool_call "arguments" is always an object and "tool_call_id" always a string
+//	8: usage_statistics reports "dropped_messages" (and optionally "dropped_entries") for untransformable messages
+//	9: optional "model" names the agent model the response was requested from
+//	10: "thread_id" names the agent conversation thread that produced the response
+//	11: "tool_messages_truncated" (and usage_statistics "omitted_tool_messages") when tool messages were capped
+//	12: "provider" names the agent provider that served the response
+//	13: "moderation_reason" when moderation blocked the message or response (status "blocked")
+//	14: assistant messages split at RESPONSE_MAX_MESSAGE_LENGTH carry "split_part" and "split_parts"
+//	15: usage_statistics reports "estimated_cost" when AGENT_MODEL_PRICES is set
+//	16: messages carry "sender_id" and a derived "name" per message type (RESPONSE_MESSAGE_SENDERS)
+const ProcessedMessageSchemaVersion = 16
+
 // ProcessedMessageData represents the data structure inside the response (matches Python API)
 type ProcessedMessageData struct {
-	Messages    interface{}            `json:"messages" swaggertype:"array"`
-	AgentID     string                 `json:"agent_id" example:"user_12345"`
-	ProcessedAt string                 `json:"processed_at" example:"task-uuid-or-timestamp"`
-	Status      string                 `json:"status" example:"done"`
-	Metadata    map[string]interface{} `json:"metadata,omitempty"` // Original metadata from webhook request
+	SchemaVersion int                    `json:"schema_version" example:"16"`
+	Messages      interface{}            `json:"messages" swaggertype:"array"`
+	AgentID       string                 `json:"agent_id" example:"user_12345"`
+	MessageID     string                 `json:"message_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
+	ProcessedAt   string                 `json:"processed_at" example:"2025-01-01T12:00:03Z"` // Completion timestamp (RFC3339)
+	StartedAt     string                 `json:"started_at,omitempty" example:"2025-01-01T12:00:00.123456789Z"`
+	CompletedAt   string                 `json:"completed_at,omitempty" example:"2025-01-01T12:00:03.456789012Z"`
+	DurationMs    int64                  `json:"duration_ms,omitempty" example:"3333"`
+	Status        string                 `json:"status" example:"done"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"` // Publisher metadata from the queue message, uninterpreted
+	// Why the agent received fallback input instead of the audio transcript (empty when no fallback happened)
+	FallbackReason string `json:"fallback_reason,omitempty" example:"transcription_error"`
+	CorrelationID  string `json:"correlation_id,omitempty" example:"9b2f6c1e-3d4a-4f7b-8c2e-1a5d6e7f8a9b"`
+	// Audio transcript and source URL, kept for auditing when TRANSCRIBE_STORE_TRANSCRIPTS is enabled
+	Transcript string `json:"transcript,omitempty" example:"Qual o horário de funcionamento da clínica?"`
+	AudioURL   string `json:"audio_url,omitempty" example:"https://whatsapp.dados.rio/audio/123.ogg"`
+	// Usage statistics, set instead of the trailing usage_statistics message in messages_only output mode
+	// (absent from both when RESPONSE_OMIT_UNKNOWN_USAGE is set and the agent reported no token counts)
+	Usage map[string]interface{} `json:"usage,omitempty"`
+	// Inbound text as received (e.g. an audio URL) and as sent to the agent after transcription, set when
+	// RESPONSE_INCLUDE_INBOUND_MESSAGE is enabled
+	OriginalMessage  string `json:"original_message,omitempty" example:"https://whatsapp.dados.rio/audio/123.ogg"`
+	EffectiveMessage string `json:"effective_message,omitempty" example:"Qual o horário de funcionamento da clínica?"`
+	// Agent model the response was requested from (absent when the agent's own default was used)
+	Model string `json:"model,omitempty" example:"gemini-2.5-flash"`
+	// Agent conversation thread the response came from (absent when the agent was not called)
+	ThreadID string `json:"thread_id,omitempty" example:"5521999999999-1735732800000"`
+	// Set when tool messages beyond GOOGLE_AGENT_ENGINE_MAX_TOOL_MESSAGES were dropped from the response
+	ToolMessagesTruncated bool `json:"tool_messages_truncated,omitempty" example:"false"`
+	// Agent provider that served the response (a fallback when the message's own provider failed)
+	Provider string `json:"provider,omitempty" example:"google_agent_engine"`
+	// Moderation policy that blocked the message or response (status "blocked")
+	ModerationReason string `json:"moderation_reason,omitempty" example:"input_pattern"`
+}
+
+// Fallback reason codes recorded when audio transcription degrades to the default message
+const (
+	FallbackReasonTranscriptionUnavailable = "transcription_unavailable" // No transcription service configured
+	FallbackReasonTranscriptionError       = "transcription_error"       // The transcription service returned an error
+	FallbackReasonEmptyTranscription       = "empty_transcription"       // The transcript was empty or unrecognizable
+	FallbackReasonAudioURLNotAllowed       = "audio_url_not_allowed"     // The audio URL host was rejected by the host policy
+)
+
+// SetTiming records when processing started and finished, stamping ProcessedAt with the completion time
+func (d *ProcessedMessageData) SetTiming(startedAt, completedAt time.Time) {
+	d.StartedAt = startedAt.UTC().Format(time.RFC3339Nano)
+	d.CompletedAt = completedAt.UTC().Format(time.RFC3339Nano)
+	d.ProcessedAt = completedAt.UTC().Format(time.RFC3339)
+	d.DurationMs = completedAt.Sub(startedAt).Milliseconds()
+}
+
+// StampSchemaVersion sets the current schema version on the data and on each transformed message
+func (d *ProcessedMessageData) StampSchemaVersion() {
+	d.SchemaVersion = ProcessedMessageSchemaVersion
+	if messages, ok := d.Messages.([]interface{}); ok {
+		for _, msg := range messages {
+			if msgMap, ok := msg.(map[string]interface{}); ok {
+				msgMap["schema_version"] = ProcessedMessageSchemaVersion
+			}
+		}
+	}
 }
 
 // TaskStatus represents the status of a message processing task
@@ -53,8 +163,55 @@ const (
 	TaskStatusProcessing TaskStatus = "processing"
 	TaskStatusCompleted  TaskStatus = "completed"
 	TaskStatusFailed     TaskStatus = "failed"
+	TaskStatusDegraded   TaskStatus = "degraded" // Completed with a fallback response because the agent backend was unavailable
+	TaskStatusCancelled  TaskStatus = "cancelled"
+	TaskStatusExpired    TaskStatus = "expired"   // Skipped because it waited in the queue longer than the maximum message age
+	TaskStatusScheduled  TaskStatus = "scheduled" // Held back until its scheduled_at time
+	TaskStatusEmpty      TaskStatus = "empty"     // Completed with a fallback reply because the agent produced no messages
+	TaskStatusBlocked    TaskStatus = "blocked"   // Completed with the moderation safe message because the message or response was blocked
 )
 
+// IsTerminal reports whether the status is final and the task will not be processed further
+func (s TaskStatus) IsTerminal() bool {
+	switch s {
+	case TaskStatusCompleted, TaskStatusFailed, TaskStatusDegraded, TaskStatusEmpty, TaskStatusBlocked, TaskStatusCancelled, TaskStatusExpired:
+		return true
+	default:
+		return false
+	}
+}
+
+// HasResult reports whether a task with this status has a processed result stored
+func (s TaskStatus) HasResult() bool {
+	switch s {
+	case TaskStatusCompleted, TaskStatusDegraded, TaskStatusEmpty, TaskStatusBlocked:
+		return true
+	default:
+		return false
+	}
+}
+
+// TaskStatusResponse represents a task's status with its result or error
+type TaskStatusResponse struct {
+	TaskID       string       `json:"task_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Status       string       `json:"status" example:"completed"`
+	Result       interface{}  `json:"result,omitempty" swaggertype:"object"`
+	Error        *string      `json:"error,omitempty" example:"Error message if processing failed"`
+	ErrorDetails *ErrorResult `json:"error_details,omitempty"` // Structured form of Error
+}
+
+// UserDataPurgeResponse reports the outcome of erasing a user's data
+type UserDataPurgeResponse struct {
+	UserNumber  string `json:"user_number" example:"5521999999999"`
+	KeysRemoved int    `json:"keys_removed" example:"12"`
+}
+
+// CancelTaskResponse represents the response for a task cancellation request
+type CancelTaskResponse struct {
+	MessageID string `json:"message_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Status    string `json:"status" example:"cancel_requested"`
+}
+
 // TaskDebugInfo represents debug information for a task
 type TaskDebugInfo struct {
 	MessageID     string                 `json:"message_id"`
@@ -69,15 +226,126 @@ type TaskDebugInfo struct {
 
 // QueueMessage represents a message in the queue
 type QueueMessage struct {
-	ID              string                 `json:"id"`
-	Type            string                 `json:"type"`
-	UserNumber      string                 `json:"user_number,omitempty"`
-	AgentID         string                 `json:"agent_id,omitempty"`
-	Message         string                 `json:"message"`
-	PreviousMessage *string                `json:"previous_message,omitempty"`
-	Provider        string                 `json:"provider,omitempty"`
-	Timestamp       time.Time              `json:"timestamp"`
-	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+	ID               string                 `json:"id"`
+	Type             string                 `json:"type"`
+	UserNumber       string                 `json:"user_number,omitempty"`
+	AgentID          string                 `json:"agent_id,omitempty"`
+	Message          string                 `json:"message"`
+	PreviousMessage  *string                `json:"previous_message,omitempty"`
+	Provider         string                 `json:"provider,omitempty"`
+	Timestamp        time.Time              `json:"timestamp"`
+	Metadata         map[string]interface{} `json:"metadata,omitempty"` // Opaque publisher context, returned as-is in the result
+	Media            *MessageMedia          `json:"media,omitempty"`
+	DryRun           bool                   `json:"dry_run,omitempty"`            // Return a canned agent response instead of calling the agent
+	StoreRawResponse bool                   `json:"store_raw_response,omitempty"` // Also persist the agent's raw response for debugging
+	CorrelationID    string                 `json:"correlation_id,omitempty"`     // Ties publisher, worker, Redis and agent activity together
+	Locale           string                 `json:"locale,omitempty"`             // User's locale for gateway-generated messages (falls back to their profile)
+	TenantID         string                 `json:"tenant_id,omitempty"`          // Selects the tenant's agent preamble and, when routed, its task store
+	Channel          string                 `json:"channel,omitempty"`            // Delivery channel selecting the reply format; empty means WhatsApp
+	ScheduledAt      *time.Time             `json:"scheduled_at,omitempty"`       // Held back by the worker until this time, then processed
+	Model            string                 `json:"model,omitempty"`              // Agent model to answer with; must be in AGENT_ALLOWED_MODELS
+	Priority         uint8                  `json:"priority,omitempty"`           // AMQP priority it is queued with; higher is consumed first (see RABBITMQ_MAX_PRIORITY)
+}
+
+// CorrelationIDHeader is the AMQP header carrying the correlation ID of a queued message
+const CorrelationIDHeader = "x-correlation-id"
+
+// SignatureHeader is the AMQP header carrying the hex HMAC-SHA256 of a queued message's body
+const SignatureHeader = "x-signature-sha256"
+
+// ProviderGoogleAgentEngine is the agent provider used when a request does not specify one
+const ProviderGoogleAgentEngine = "google_agent_engine"
+
+// Delivery channels, each with its own reply formatting
+const (
+	ChannelWhatsApp = "whatsapp" // WhatsApp markup (the default)
+	ChannelWeb      = "web"      // Web chat, plain text
+)
+
+// SupportedProviders lists the provider values accepted in queue messages
+var SupportedProviders = []string{ProviderGoogleAgentEngine}
+
+// Validate checks that the queue message carries every field required for processing
+func (m *QueueMessage) Validate() error {
+	var problems []string
+
+	if strings.TrimSpace(m.ID) == "" {
+		problems = append(problems, "id is required")
+	}
+	if strings.TrimSpace(m.UserNumber) == "" {
+		problems = append(problems, "user_number is required")
+	}
+	if strings.TrimSpace(m.Message) == "" && (m.Media == nil || m.Media.URL == "") {
+		problems = append(problems, "message is required")
+	}
+
+	// An empty provider is allowed; the worker applies the configured default
+	if m.Provider != "" {
+		supported := false
+		for _, provider := range SupportedProviders {
+			if m.Provider == provider {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			problems = append(problems, fmt.Sprintf("provider %q is not supported (supported: %s)", m.Provider, strings.Join(SupportedProviders, ", ")))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid queue message: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// MessageMedia represents a media attachment sent as a structured field instead of a bare URL
+type MessageMedia struct {
+	URL      string `json:"url" example:"https://whatsapp.dados.rio/media/audio.ogg"`
+	MimeType string `json:"mime_type,omitempty" example:"audio/ogg"`
+	Caption  string `json:"caption,omitempty" example:"Segue meu áudio"`
+}
+
+// IsAudio reports whether the media declares an audio MIME type
+func (m *MessageMedia) IsAudio() bool {
+	if m == nil || m.URL == "" {
+		return false
+	}
+	return strings.HasPrefix(strings.ToLower(m.MimeType), "audio/")
+}
+
+// IsImage reports whether the media declares an image MIME type
+func (m *MessageMedia) IsImage() bool {
+	if m == nil || m.URL == "" {
+		return false
+	}
+	return strings.HasPrefix(strings.ToLower(m.MimeType), "image/")
+}
+
+// documentMimePrefixes identify document MIME types: PDFs, Word/Excel/PowerPoint and OpenDocument files,
+// RTF and plain text (including CSV)
+var documentMimePrefixes = []string{
+	"application/pdf",
+	"application/msword",
+	"application/rtf",
+	"application/vnd.ms-",
+	"application/vnd.openxmlformats-officedocument.",
+	"application/vnd.oasis.opendocument.",
+	"text/",
+}
+
+// IsDocument reports whether the media declares a document MIME type
+func (m *MessageMedia) IsDocument() bool {
+	if m == nil || m.URL == "" {
+		return false
+	}
+	mimeType := strings.ToLower(m.MimeType)
+	for _, prefix := range documentMimePrefixes {
+		if strings.HasPrefix(mimeType, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 // Note: Agent management models removed - were Letta-specific
@@ -128,6 +396,29 @@ type CallbackPayload struct {
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// TaskEvent represents the notification pushed to the task event webhook when a task finishes
+type TaskEvent struct {
+	MessageID     string  `json:"message_id"`
+	Status        string  `json:"status"`
+	ResultPointer string  `json:"result_pointer,omitempty"` // Polling endpoint for the stored result (completions only)
+	Error         *string `json:"error,omitempty"`
+	Timestamp     string  `json:"timestamp"`
+}
+
+// ProviderEvent is sent to the messaging provider to reflect processing progress to the user
+type ProviderEvent struct {
+	UserNumber string `json:"user_number"`
+	Event      string `json:"event"` // "processing"
+	Timestamp  string `json:"timestamp"`
+}
+
+// OutboundMessage is posted to the messaging provider to deliver a processed reply to the user
+type OutboundMessage struct {
+	UserNumber string   `json:"user_number"`
+	Messages   []string `json:"messages"` // Reply texts, formatted for the user's channel, in delivery order
+	Timestamp  string   `json:"timestamp"`
+}
+
 // CallbackInfo represents callback metadata stored in Redis
 type CallbackInfo struct {
 	URL         string    `json:"url"`
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+)
+
+// ErrAudioURLNotAllowed marks audio URLs rejected by the host policy before any fetch; match it with errors.Is
+var ErrAudioURLNotAllowed = errors.New("audio URL not allowed")
+
+// IsAudioURL reports whether the URL ends in one of the configured audio extensions (case-insensitive).
+// It is the single audio detection rule shared by the gateway, workers and transcription backends.
+func IsAudioURL(cfg *config.Config, audioURL string) bool {
+	lower := strings.ToLower(audioURL)
+	for _, ext := range cfg.GetAudioExtensions() {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckAudioURLHost enforces the audio host policy: the URL must be HTTP(S), its host must not be denied,
+// must not be a loopback or private address, and must be allowlisted when an allowlist is configured.
+func CheckAudioURLHost(cfg *config.Config, audioURL string) error {
+	parsed, err := url.Parse(audioURL)
+	if err != nil {
+		return fmt.Errorf("%w: invalid URL format: %v", ErrAudioURLNotAllowed, err)
+	}
+	if parsed.Scheme != "https" && parsed.Scheme != "http" {
+		return fmt.Errorf("%w: unsupported URL scheme: %s", ErrAudioURLNotAllowed, parsed.Scheme)
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(parsed.Hostname(), "."))
+	if host == "" {
+		return fmt.Errorf("%w: URL must have a host", ErrAudioURLNotAllowed)
+	}
+
+	if matchesHostList(host, cfg.GetTranscribeDeniedHosts()) {
+		return fmt.Errorf("%w: host %s is denied", ErrAudioURLNotAllowed, host)
+	}
+
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: host %s is a loopback address", ErrAudioURLNotAllowed, host)
+	}
+	if ip := net.ParseIP(host); ip != nil && isPrivateAddress(ip) {
+		return fmt.Errorf("%w: host %s is a private address", ErrAudioURLNotAllowed, host)
+	}
+
+	if allowed := cfg.GetTranscribeAllowedHosts(); len(allowed) > 0 && !matchesHostList(host, allowed) {
+		return fmt.Errorf("%w: host %s is not in the allowed hosts", ErrAudioURLNotAllowed, host)
+	}
+
+	return nil
+}
+
+// matchesHostList reports whether host equals an entry of the list or is a subdomain of one
+func matchesHostList(host string, hosts []string) bool {
+	for _, entry := range hosts {
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPrivateAddress reports whether ip is loopback, private, link-local or unspecified
+func isPrivateAddress(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified()
+}
+
+// audioDownloadDialer returns a dialer that refuses connections to private addresses, so hostnames
+// resolving (or redirecting) to internal services are blocked after DNS resolution as well
+func audioDownloadDialer() *net.Dialer {
+	return &net.Dialer{
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: invalid address %s", ErrAudioURLNotAllowed, address)
+			}
+			if ip := net.ParseIP(host); ip != nil && isPrivateAddress(ip) {
+				return fmt.Errorf("%w: refusing to connect to private address %s", ErrAudioURLNotAllowed, host)
+			}
+			return nil
+		},
+	}
+}
+
+// newAudioDownloadClient creates the HTTP client used to fetch audio files, guarded by audioDownloadDialer
+func newAudioDownloadClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = audioDownloadDialer().DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+}
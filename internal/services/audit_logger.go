@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+)
+
+// Audit trail events recorded while processing a user message
+const (
+	AuditEventReceived  = "message_received"  // Processing started
+	AuditEventProcessed = "message_processed" // A result was produced; Status is the task status
+	AuditEventFailed    = "message_failed"    // Processing failed; Retriable tells whether the attempt may be retried
+	AuditEventCoalesced = "message_coalesced" // Answered as part of another task's batch
+	AuditEventCancelled = "message_cancelled" // Cancelled before a result was produced
+)
+
+// AuditEvent is one entry of the audit trail: who sent which message, when, to which agent, and the outcome
+type AuditEvent struct {
+	Time           time.Time `json:"time"`
+	Event          string    `json:"event"`
+	MessageID      string    `json:"message_id"`
+	CorrelationID  string    `json:"correlation_id,omitempty"`
+	TenantID       string    `json:"tenant_id,omitempty"`
+	UserNumber     string    `json:"user_number,omitempty"`
+	Provider       string    `json:"provider,omitempty"`
+	AgentID        string    `json:"agent_id,omitempty"`
+	ThreadID       string    `json:"thread_id,omitempty"`
+	Model          string    `json:"model,omitempty"`
+	Status         string    `json:"status,omitempty"`
+	FallbackReason string    `json:"fallback_reason,omitempty"`
+	ErrorCategory  string    `json:"error_category,omitempty"`
+	Retriable      bool      `json:"retriable,omitempty"`
+	DurationMs     int64     `json:"duration_ms,omitempty"`
+}
+
+// AuditLogger records the audit trail of processed messages, apart from the operational logs
+type AuditLogger interface {
+	Record(ctx context.Context, event AuditEvent) error
+}
+
+// NDJSONAuditLogger writes audit events as newline-delimited JSON, one complete line per write. Files are
+// opened append-only, so existing entries are never rewritten.
+type NDJSONAuditLogger struct {
+	mu     sync.Mutex
+	writer io.Writer
+	closer io.Closer // Set when the logger owns the destination
+}
+
+// NewNDJSONAuditLogger creates an audit logger writing to AUDIT_LOG_OUTPUT: "stdout", "stderr" or a file path
+func NewNDJSONAuditLogger(cfg *config.Config, logger *logrus.Logger) (*NDJSONAuditLogger, error) {
+	output := strings.TrimSpace(cfg.Observability.AuditLogOutput)
+
+	var auditLogger *NDJSONAuditLogger
+	switch strings.ToLower(output) {
+	case "":
+		return nil, fmt.Errorf("audit log output is not configured")
+	case "stdout":
+		auditLogger = NewNDJSONAuditWriter(os.Stdout)
+	case "stderr":
+		auditLogger = NewNDJSONAuditWriter(os.Stderr)
+	default:
+		file, err := os.OpenFile(output, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log %s: %w", output, err)
+		}
+		auditLogger = &NDJSONAuditLogger{writer: file, closer: file}
+	}
+
+	logger.WithField("output", output).Info("Audit logging enabled")
+	return auditLogger, nil
+}
+
+// NewNDJSONAuditWriter creates an audit logger writing to w, which the caller keeps ownership of
+func NewNDJSONAuditWriter(w io.Writer) *NDJSONAuditLogger {
+	return &NDJSONAuditLogger{writer: w}
+}
+
+// Record writes the event as a single JSON line, stamping it with the current time if it has none
+func (a *NDJSONAuditLogger) Record(ctx context.Context, event AuditEvent) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to serialize audit event: %w", err)
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.writer.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit event: %w", err)
+	}
+	return nil
+}
+
+// Close closes the audit log file, if the logger opened one
+func (a *NDJSONAuditLogger) Close() error {
+	if a.closer == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.closer.Close()
+}
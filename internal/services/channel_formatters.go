@@ -0,0 +1,79 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ChannelFormatter renders agent markdown for one delivery channel
+type ChannelFormatter interface {
+	FormatContent(content string) string
+}
+
+// WhatsAppFormatter converts markdown to WhatsApp markup and applies WhatsApp's message limits
+type WhatsAppFormatter struct {
+	service *MessageFormatterService
+}
+
+// FormatContent converts markdown to WhatsApp markup, truncating over-long messages
+func (f WhatsAppFormatter) FormatContent(content string) string {
+	formatted := f.service.convertMarkdownToWhatsApp(content)
+	formatted = f.service.applyWhatsAppLimits(formatted)
+	return f.service.cleanupWhitespace(formatted)
+}
+
+// PassthroughFormatter leaves content as the agent wrote it (MESSAGE_FORMATTING_MODE=none)
+type PassthroughFormatter struct{}
+
+// FormatContent returns the content unchanged
+func (PassthroughFormatter) FormatContent(content string) string {
+	return content
+}
+
+var (
+	plainCodeFencePattern  = regexp.MustCompile("(?m)^```[a-zA-Z0-9_+-]*\\s*$")
+	plainInlineCodePattern = regexp.MustCompile("`([^`\n]+)`")
+	plainImagePattern      = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)[^)]*\)`)
+	plainLinkPattern       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)[^)]*\)`)
+	plainHeadingPattern    = regexp.MustCompile(`(?m)^#{1,6}\s+`)
+	plainQuotePattern      = regexp.MustCompile(`(?m)^>\s?`)
+	plainBulletPattern     = regexp.MustCompile(`(?m)^(\s*)[*+]\s+`)
+	plainRulePattern       = regexp.MustCompile(`(?m)^[ \t]*(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	plainBoldPattern       = regexp.MustCompile(`(\*\*|__)(\S(?:.*?\S)?)(\*\*|__)`)
+	plainItalicPattern     = regexp.MustCompile(`(^|[^\w*])[*_](\S(?:[^*_\n]*?\S)?)[*_]($|[^\w*])`)
+	plainStrikePattern     = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+	plainBlankLinesPattern = regexp.MustCompile(`\n{3,}`)
+)
+
+// PlainTextFormatter strips markdown for channels that render text verbatim, keeping link targets
+type PlainTextFormatter struct{}
+
+// FormatContent removes markdown syntax, writing links as "text (url)"
+func (PlainTextFormatter) FormatContent(content string) string {
+	text := strings.ReplaceAll(content, "\r\n", "\n")
+
+	text = plainCodeFencePattern.ReplaceAllString(text, "")
+	text = plainInlineCodePattern.ReplaceAllString(text, "$1")
+	text = plainImagePattern.ReplaceAllString(text, "$1 ($2)")
+	text = plainLinkPattern.ReplaceAllStringFunc(text, func(match string) string {
+		parts := plainLinkPattern.FindStringSubmatch(match)
+		if parts[1] == parts[2] {
+			return parts[2]
+		}
+		return parts[1] + " (" + parts[2] + ")"
+	})
+	text = plainHeadingPattern.ReplaceAllString(text, "")
+	text = plainQuotePattern.ReplaceAllString(text, "")
+	text = plainRulePattern.ReplaceAllString(text, "")
+	text = plainBulletPattern.ReplaceAllString(text, "$1- ")
+	text = plainBoldPattern.ReplaceAllString(text, "$2")
+	text = plainItalicPattern.ReplaceAllString(text, "$1$2$3")
+	text = plainStrikePattern.ReplaceAllString(text, "$1")
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	text = plainBlankLinesPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrCircuitOpen is returned when a call is rejected because the circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// String returns a human-readable name for the circuit state
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreaker guards calls to an external dependency, failing fast after repeated failures.
+// It follows the usual closed -> open -> half-open cycle: once failureThreshold consecutive
+// failures are recorded the circuit opens for resetTimeout, after which a single probe call
+// is let through to decide whether to close the circuit again or reopen it.
+type CircuitBreaker struct {
+	name   string
+	logger *logrus.Logger
+
+	mutex            sync.Mutex
+	state            CircuitState
+	failureCount     int
+	openUntil        time.Time
+	halfOpenInFlight bool
+
+	failureThreshold int           // Consecutive failures before opening (<= 0 disables the breaker)
+	resetTimeout     time.Duration // Time to stay open before allowing a probe
+}
+
+// NewCircuitBreaker creates a new circuit breaker in the closed state
+func NewCircuitBreaker(name string, failureThreshold int, resetTimeout time.Duration, logger *logrus.Logger) *CircuitBreaker {
+	if resetTimeout <= 0 {
+		resetTimeout = 30 * time.Second // Default reset timeout
+	}
+
+	return &CircuitBreaker{
+		name:             name,
+		logger:           logger,
+		state:            CircuitClosed,
+		failureThreshold: failureThreshold,
+		resetTimeout:     resetTimeout,
+	}
+}
+
+// Allow checks whether a call may proceed, returning an error wrapping ErrCircuitOpen if not
+func (cb *CircuitBreaker) Allow() error {
+	if cb == nil || cb.failureThreshold <= 0 {
+		return nil
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	switch cb.state {
+	case CircuitOpen:
+		if time.Now().Before(cb.openUntil) {
+			return fmt.Errorf("%s %w (retry after %v)", cb.name, ErrCircuitOpen, time.Until(cb.openUntil).Round(time.Second))
+		}
+		// Timeout expired — transition to half-open and let this call probe the dependency
+		cb.state = CircuitHalfOpen
+		cb.halfOpenInFlight = true
+		cb.logger.WithField("circuit", cb.name).Info("Circuit breaker half-open — testing recovery")
+		return nil
+	case CircuitHalfOpen:
+		// Only one probe at a time while half-open
+		if cb.halfOpenInFlight {
+			return fmt.Errorf("%s %w (recovery probe in progress)", cb.name, ErrCircuitOpen)
+		}
+		cb.halfOpenInFlight = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// RecordSuccess records a successful call, closing the circuit if it was probing
+func (cb *CircuitBreaker) RecordSuccess() {
+	if cb == nil || cb.failureThreshold <= 0 {
+		return
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.failureCount = 0
+	cb.halfOpenInFlight = false
+	if cb.state != CircuitClosed {
+		cb.state = CircuitClosed
+		cb.logger.WithField("circuit", cb.name).Info("Circuit breaker closed - service recovered")
+	}
+}
+
+// RecordFailure records a failed call, opening the circuit once the threshold is reached
+func (cb *CircuitBreaker) RecordFailure() {
+	if cb == nil || cb.failureThreshold <= 0 {
+		return
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.failureCount++
+	cb.halfOpenInFlight = false
+
+	switch cb.state {
+	case CircuitHalfOpen:
+		// Recovery probe failed — reopen circuit
+		cb.state = CircuitOpen
+		cb.openUntil = time.Now().Add(cb.resetTimeout)
+		cb.logger.WithFields(logrus.Fields{
+			"circuit":    cb.name,
+			"open_until": cb.openUntil,
+		}).Warn("Circuit breaker reopened after failure in half-open state")
+	case CircuitClosed:
+		if cb.failureCount >= cb.failureThreshold {
+			cb.state = CircuitOpen
+			cb.openUntil = time.Now().Add(cb.resetTimeout)
+			cb.logger.WithFields(logrus.Fields{
+				"circuit":       cb.name,
+				"failure_count": cb.failureCount,
+				"open_until":    cb.openUntil,
+			}).Warn("Circuit breaker opened due to repeated failures")
+		}
+	}
+}
+
+// Release ends a call without recording an outcome, freeing the half-open probe slot
+func (cb *CircuitBreaker) Release() {
+	if cb == nil || cb.failureThreshold <= 0 {
+		return
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	cb.halfOpenInFlight = false
+}
+
+// State returns the current circuit state
+func (cb *CircuitBreaker) State() CircuitState {
+	if cb == nil {
+		return CircuitClosed
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	return cb.state
+}
+
+// IsOpen returns true if the circuit is open and calls are currently being rejected
+func (cb *CircuitBreaker) IsOpen() bool {
+	if cb == nil || cb.failureThreshold <= 0 {
+		return false
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	return cb.state == CircuitOpen && time.Now().Before(cb.openUntil)
+}
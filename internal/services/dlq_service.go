@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+)
+
+// ReplayedAtHeader records when a dead letter was replayed to its queue (RFC3339); workers age a replayed
+// message from this time rather than from its original enqueue
+const ReplayedAtHeader = "x-replayed-at"
+
+// DeadLetter is a message found in a dead-letter queue
+type DeadLetter struct {
+	Message     models.QueueMessage `json:"message"`
+	Body        string              `json:"body"`
+	Headers     amqp.Table          `json:"headers,omitempty"`
+	PublishedAt time.Time           `json:"published_at"`
+	Reason      string              `json:"reason,omitempty"` // First death reason (rejected, expired, ...)
+	DecodeError string              `json:"decode_error,omitempty"`
+}
+
+// DeadLetterFilter selects dead letters by message ID, user number and enqueue time; zero values match everything
+type DeadLetterFilter struct {
+	MessageIDs []string
+	UserNumber string
+	Since      time.Time
+	Until      time.Time
+}
+
+// Matches reports whether the dead letter satisfies every filter criterion
+func (f DeadLetterFilter) Matches(letter *DeadLetter) bool {
+	if len(f.MessageIDs) > 0 {
+		found := false
+		for _, id := range f.MessageIDs {
+			if letter.Message.ID == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.UserNumber != "" && letter.Message.UserNumber != f.UserNumber {
+		return false
+	}
+	if !f.Since.IsZero() && letter.Message.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && letter.Message.Timestamp.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// DLQService lists and replays messages from the dead-letter queues
+type DLQService struct {
+	config       *config.Config
+	logger       *logrus.Logger
+	rabbitMQ     *RabbitMQService
+	redisService *RedisService
+}
+
+// NewDLQService creates a new dead-letter queue service
+func NewDLQService(cfg *config.Config, logger *logrus.Logger, rabbitMQ *RabbitMQService, redisService *RedisService) *DLQService {
+	return &DLQService{
+		config:       cfg,
+		logger:       logger,
+		rabbitMQ:     rabbitMQ,
+		redisService: redisService,
+	}
+}
+
+// DeadLetterQueueName returns the dead-letter queue for a main queue
+func DeadLetterQueueName(queueName string) string {
+	return queueName + "_dlq"
+}
+
+// List returns the dead letters of the queue matching the filter (up to limit, 0 = all) without removing them
+func (s *DLQService) List(ctx context.Context, queueName string, filter DeadLetterFilter, limit int) ([]DeadLetter, error) {
+	var letters []DeadLetter
+	err := s.scan(ctx, queueName, func(_ amqp.Delivery, letter *DeadLetter) (bool, error) {
+		if !filter.Matches(letter) {
+			return false, nil
+		}
+		letters = append(letters, *letter)
+		return limit > 0 && len(letters) >= limit, nil
+	})
+	return letters, err
+}
+
+// Replay publishes the dead letters matching the filter (up to limit, 0 = all) back to the main queue,
+// resetting their retry count and task status, and removes them from the dead-letter queue.
+// With dryRun set, matching messages are reported but left untouched.
+func (s *DLQService) Replay(ctx context.Context, queueName string, filter DeadLetterFilter, limit int, dryRun bool) ([]DeadLetter, error) {
+	var replayed []DeadLetter
+	err := s.scan(ctx, queueName, func(delivery amqp.Delivery, letter *DeadLetter) (bool, error) {
+		if letter.DecodeError != "" || !filter.Matches(letter) {
+			return false, nil
+		}
+
+		if !dryRun {
+			if err := s.republish(ctx, queueName, delivery); err != nil {
+				return true, err
+			}
+			if err := delivery.Ack(false); err != nil {
+				return true, fmt.Errorf("failed to remove replayed message %s from DLQ: %w", letter.Message.ID, err)
+			}
+			if err := s.redisService.SetTaskStatus(ctx, letter.Message.ID, string(models.TaskStatusPending), s.config.GetTaskStatusTTL(string(models.TaskStatusPending))); err != nil {
+				s.logger.WithError(err).WithField("message_id", letter.Message.ID).Warn("Failed to reset status of replayed task")
+			}
+		}
+
+		s.logger.WithFields(logrus.Fields{
+			"message_id":  letter.Message.ID,
+			"user_number": letter.Message.UserNumber,
+			"queue":       queueName,
+			"dry_run":     dryRun,
+		}).Info("Replayed dead letter")
+
+		replayed = append(replayed, *letter)
+		return limit > 0 && len(replayed) >= limit, nil
+	})
+	return replayed, err
+}
+
+// scan fetches every message of the queue's DLQ on a dedicated channel and hands it to visit until visit
+// reports done. Messages that are not acknowledged by visit are returned to the DLQ when the channel closes.
+func (s *DLQService) scan(ctx context.Context, queueName string, visit func(amqp.Delivery, *DeadLetter) (bool, error)) error {
+	ch, err := s.rabbitMQ.OpenChannel()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = ch.Close() }()
+
+	dlqName := DeadLetterQueueName(queueName)
+	queue, err := ch.QueueDeclarePassive(dlqName, true, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to inspect dead-letter queue %s: %w", dlqName, err)
+	}
+
+	// Bound the scan by the initial depth so requeued or newly dead-lettered messages aren't visited twice
+	for i := 0; i < queue.Messages; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		delivery, ok, err := ch.Get(dlqName, false)
+		if err != nil {
+			return fmt.Errorf("failed to read from dead-letter queue %s: %w", dlqName, err)
+		}
+		if !ok {
+			return nil
+		}
+
+		done, err := visit(delivery, decodeDeadLetter(delivery))
+		if err != nil || done {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// republish sends the dead-lettered delivery back to the main queue with its retry state cleared
+func (s *DLQService) republish(ctx context.Context, queueName string, delivery amqp.Delivery) error {
+	headers := amqp.Table{}
+	for k, v := range delivery.Headers {
+		switch k {
+		case "x-retry-count", "x-delay", "x-death", "x-first-death-exchange", "x-first-death-queue", "x-first-death-reason",
+			"x-last-death-exchange", "x-last-death-queue", "x-last-death-reason":
+			continue
+		}
+		headers[k] = v
+	}
+	headers[ReplayedAtHeader] = time.Now().UTC().Format(time.RFC3339)
+
+	publishing := amqp.Publishing{
+		ContentType:  delivery.ContentType,
+		Body:         delivery.Body,
+		DeliveryMode: amqp.Persistent,
+		Priority:     delivery.Priority,
+		Headers:      headers,
+		Timestamp:    time.Now(),
+		MessageId:    delivery.MessageId,
+	}
+
+	if err := s.rabbitMQ.PublishRaw(ctx, queueName, publishing); err != nil {
+		return fmt.Errorf("failed to replay message to %s: %w", queueName, err)
+	}
+	return nil
+}
+
+// decodeDeadLetter parses the queue message and death information of a dead-lettered delivery
+func decodeDeadLetter(delivery amqp.Delivery) *DeadLetter {
+	letter := &DeadLetter{
+		Body:        string(delivery.Body),
+		Headers:     delivery.Headers,
+		PublishedAt: delivery.Timestamp,
+	}
+
+	if reason, ok := delivery.Headers["x-first-death-reason"].(string); ok {
+		letter.Reason = reason
+	}
+
+	if err := DefaultMessageCodecs().Decode(delivery.ContentType, delivery.Body, &letter.Message); err != nil {
+		letter.DecodeError = err.Error()
+	}
+
+	return letter
+}
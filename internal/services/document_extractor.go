@@ -0,0 +1,129 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+)
+
+// DocumentInput is a document attached to a user message, referenced by URL
+type DocumentInput struct {
+	URL      string
+	MimeType string // Declared by the publisher or guessed from the extension; may be empty
+}
+
+// IsDocumentURL reports whether the URL's path ends in one of the configured document extensions
+// (case-insensitive), ignoring any query string such as a signed URL's
+func IsDocumentURL(cfg *config.Config, documentURL string) bool {
+	urlPath := documentURL
+	if parsed, err := url.Parse(documentURL); err == nil && parsed.Path != "" {
+		urlPath = parsed.Path
+	}
+	lower := strings.ToLower(urlPath)
+	for _, ext := range cfg.GetDocumentExtensions() {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewDocumentInput references the document at documentURL, guessing its MIME type from the extension
+// when mimeType is empty
+func NewDocumentInput(documentURL, mimeType string) DocumentInput {
+	if mimeType == "" {
+		urlPath := documentURL
+		if parsed, err := url.Parse(documentURL); err == nil {
+			urlPath = parsed.Path
+		}
+		if guessed := mime.TypeByExtension(strings.ToLower(path.Ext(urlPath))); guessed != "" {
+			mimeType, _, _ = strings.Cut(guessed, ";")
+		}
+	}
+	return DocumentInput{URL: documentURL, MimeType: mimeType}
+}
+
+// DocumentExtractor turns a document into plain text for agents that can't read documents themselves
+type DocumentExtractor interface {
+	ExtractText(ctx context.Context, document DocumentInput) (string, error)
+}
+
+// HTTPDocumentExtractor asks an external service for the text: it POSTs {"url", "mime_type"} to
+// DOCUMENT_EXTRACTOR_URL and expects {"text": "..."} back
+type HTTPDocumentExtractor struct {
+	logger     *logrus.Logger
+	url        string
+	httpClient *http.Client
+}
+
+// NewDocumentExtractor returns the extractor for DOCUMENT_EXTRACTOR_URL, or nil when none is configured
+func NewDocumentExtractor(cfg *config.Config, logger *logrus.Logger) DocumentExtractor {
+	extractorURL := strings.TrimSpace(cfg.GoogleAgentEngine.DocumentExtractorURL)
+	if extractorURL == "" {
+		return nil
+	}
+
+	logger.WithField("timeout", cfg.GetDocumentExtractorTimeout().String()).Info("Document text extraction enabled")
+	return &HTTPDocumentExtractor{
+		logger:     logger,
+		url:        extractorURL,
+		httpClient: &http.Client{Timeout: cfg.GetDocumentExtractorTimeout()},
+	}
+}
+
+// ExtractText posts the document reference and returns the text from the response
+func (e *HTTPDocumentExtractor) ExtractText(ctx context.Context, document DocumentInput) (string, error) {
+	payloadBytes, err := json.Marshal(map[string]string{
+		"url":       document.URL,
+		"mime_type": document.MimeType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize extraction request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(payloadBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "EAI-Agent-Gateway/1.0")
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read extraction response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", &HTTPError{
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+		}
+	}
+
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("failed to parse extraction response: %w", err)
+	}
+	if strings.TrimSpace(result.Text) == "" {
+		return "", fmt.Errorf("extraction response has no text")
+	}
+	return strings.TrimSpace(result.Text), nil
+}
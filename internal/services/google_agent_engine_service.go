@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -27,6 +28,7 @@ type GoogleAgentEngineService struct {
 	redisService RedisServiceInterface
 	httpClient   *http.Client
 	tokenSource  oauth2.TokenSource // Direct token source, no temp files
+	breaker      *CircuitBreaker    // Fails fast when the reasoning engine keeps erroring
 }
 
 // ReasoningEngineRequest represents the request structure for reasoning engine queries
@@ -55,7 +57,10 @@ type RedisServiceInterface interface {
 	Get(ctx context.Context, key string) (string, error)
 	Set(ctx context.Context, key string, value string, ttl time.Duration) error
 	SetValue(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error)
+	Expire(ctx context.Context, key string, ttl time.Duration) (bool, error)
 	Delete(ctx context.Context, key string) error
+	DeleteKeys(ctx context.Context, keys ...string) (int, error)
 }
 
 // createTokenSourceFromCredentials creates a token source directly from credentials JSON
@@ -131,12 +136,20 @@ func NewGoogleAgentEngineService(
 		redisService: redisService,
 		httpClient:   httpClient,
 		tokenSource:  tokenSource,
+		breaker: NewCircuitBreaker(
+			"google_agent_engine",
+			cfg.GoogleAgentEngine.CircuitFailureThreshold,
+			cfg.GoogleAgentEngine.CircuitResetTimeout,
+			logger,
+		),
 	}
 
 	logger.WithFields(logrus.Fields{
-		"project_id":          cfg.GoogleAgentEngine.ProjectID,
-		"location":            cfg.GoogleAgentEngine.Location,
-		"reasoning_engine_id": cfg.GoogleAgentEngine.ReasoningEngineID,
+		"project_id":                cfg.GoogleAgentEngine.ProjectID,
+		"location":                  cfg.GoogleAgentEngine.Location,
+		"reasoning_engine_id":       cfg.GoogleAgentEngine.ReasoningEngineID,
+		"circuit_failure_threshold": cfg.GoogleAgentEngine.CircuitFailureThreshold,
+		"circuit_reset_timeout":     cfg.GoogleAgentEngine.CircuitResetTimeout,
 	}).Info("Google Agent Engine service initialized")
 
 	return service, nil
@@ -151,8 +164,10 @@ func (s *GoogleAgentEngineService) CreateThread(ctx context.Context, userID stri
 		return "", fmt.Errorf("rate limit exceeded: %w", err)
 	}
 
-	// Use userID (phone number) directly as thread ID
-	threadID := userID
+	threadID, err := s.newThreadID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
 
 	// Store thread information in Redis
 	threadInfo := ThreadInfo{
@@ -168,12 +183,15 @@ func (s *GoogleAgentEngineService) CreateThread(ctx context.Context, userID stri
 		return "", fmt.Errorf("failed to marshal thread info: %w", err)
 	}
 
-	// Store thread info with TTL
-	threadKey := fmt.Sprintf("thread:%s", threadID)
-
-	if err := s.redisService.SetValue(ctx, threadKey, string(threadData), s.config.Redis.AgentIDCacheTTL); err != nil {
+	// Store thread info with TTL, under the user mapping and (for fresh threads) the thread ID
+	if err := s.redisService.SetValue(ctx, threadKey(userID), string(threadData), s.threadTTL()); err != nil {
 		return "", fmt.Errorf("failed to store thread info: %w", err)
 	}
+	if threadID != userID {
+		if err := s.redisService.SetValue(ctx, threadKey(threadID), string(threadData), s.threadTTL()); err != nil {
+			return "", fmt.Errorf("failed to store thread info: %w", err)
+		}
+	}
 
 	s.logger.WithFields(logrus.Fields{
 		"user_id":   userID,
@@ -183,29 +201,43 @@ func (s *GoogleAgentEngineService) CreateThread(ctx context.Context, userID stri
 	return threadID, nil
 }
 
-// GetOrCreateThread gets an existing thread for a user or creates a new one
+// GetOrCreateThread gets an existing thread for a user or creates a new one.
+// With a thread idle TTL configured, a thread unused for longer than the TTL is treated as absent.
 func (s *GoogleAgentEngineService) GetOrCreateThread(ctx context.Context, userID string) (string, error) {
-	// Use userID directly as thread ID
-	threadID := userID
-	threadKey := fmt.Sprintf("thread:%s", threadID)
+	userKey := threadKey(userID)
 
 	// Try to get existing thread
-	threadData, err := s.redisService.Get(ctx, threadKey)
+	threadData, err := s.redisService.Get(ctx, userKey)
 
 	if err == nil && threadData != "" {
 		var threadInfo ThreadInfo
 		if err := json.Unmarshal([]byte(threadData), &threadInfo); err == nil {
-			// Update last used time
+			// Mappings written before the thread ID was stored refer to the user's own thread
+			if threadInfo.ThreadID == "" {
+				threadInfo.ThreadID = userID
+			}
+
+			idleTTL := s.config.GoogleAgentEngine.ThreadIdleTTL
+			if idleTTL > 0 && !threadInfo.LastUsedAt.IsZero() && time.Since(threadInfo.LastUsedAt) > idleTTL {
+				s.logger.WithFields(logrus.Fields{
+					"user_id":      userID,
+					"thread_id":    threadInfo.ThreadID,
+					"last_used_at": threadInfo.LastUsedAt,
+				}).Info("Thread expired after inactivity, starting a new one")
+				return s.CreateThread(ctx, userID)
+			}
+
+			// Update last used time, refreshing the mapping TTL
 			threadInfo.LastUsedAt = time.Now()
 			updatedData, _ := json.Marshal(threadInfo)
-			_ = s.redisService.SetValue(ctx, threadKey, string(updatedData), s.config.Redis.AgentIDCacheTTL)
+			_ = s.redisService.SetValue(ctx, userKey, string(updatedData), s.threadTTL())
 
 			s.logger.WithFields(logrus.Fields{
 				"user_id":   userID,
-				"thread_id": threadID,
+				"thread_id": threadInfo.ThreadID,
 			}).Debug("Using existing thread")
 
-			return threadID, nil
+			return threadInfo.ThreadID, nil
 		}
 	}
 
@@ -213,23 +245,211 @@ func (s *GoogleAgentEngineService) GetOrCreateThread(ctx context.Context, userID
 	return s.CreateThread(ctx, userID)
 }
 
+// WarmThread keeps a recently active user's thread ready for their next message: an existing thread's
+// expiry is refreshed (its last-used time is left alone, so idle expiry still applies) and a missing one
+// is pre-created. It never overwrites thread info, so it can't race with live messages for the user.
+// It reports whether a thread was created.
+func (s *GoogleAgentEngineService) WarmThread(ctx context.Context, userID string) (bool, error) {
+	userKey := threadKey(userID)
+
+	if threadData, err := s.redisService.Get(ctx, userKey); err == nil && threadData != "" {
+		var threadInfo ThreadInfo
+		if err := json.Unmarshal([]byte(threadData), &threadInfo); err == nil {
+			// Idle threads are rotated by the user's next message; refreshing them would only delay that
+			idleTTL := s.config.GoogleAgentEngine.ThreadIdleTTL
+			if idleTTL > 0 && !threadInfo.LastUsedAt.IsZero() && time.Since(threadInfo.LastUsedAt) > idleTTL {
+				return false, nil
+			}
+
+			if _, err := s.redisService.Expire(ctx, userKey, s.threadTTL()); err != nil {
+				return false, fmt.Errorf("failed to refresh thread: %w", err)
+			}
+			if threadInfo.ThreadID != "" && threadInfo.ThreadID != userID {
+				_, _ = s.redisService.Expire(ctx, threadKey(threadInfo.ThreadID), s.threadTTL())
+			}
+			return false, nil
+		}
+	}
+
+	threadID, err := s.newThreadID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	threadData, err := json.Marshal(ThreadInfo{
+		ThreadID:   threadID,
+		UserID:     userID,
+		CreatedAt:  now,
+		LastUsedAt: now,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal thread info: %w", err)
+	}
+
+	// Only claim the mapping if no live message created a thread in the meantime
+	created, err := s.redisService.SetNX(ctx, userKey, string(threadData), s.threadTTL())
+	if err != nil || !created {
+		return false, err
+	}
+	if threadID != userID {
+		if err := s.redisService.SetValue(ctx, threadKey(threadID), string(threadData), s.threadTTL()); err != nil {
+			return true, fmt.Errorf("failed to store thread info: %w", err)
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":   userID,
+		"thread_id": threadID,
+	}).Debug("Pre-created thread")
+
+	return true, nil
+}
+
+// ExpireThread ends the user's current conversation so the next message starts a fresh thread
+func (s *GoogleAgentEngineService) ExpireThread(ctx context.Context, userID string) error {
+	if threadData, err := s.redisService.Get(ctx, threadKey(userID)); err == nil && threadData != "" {
+		var threadInfo ThreadInfo
+		if err := json.Unmarshal([]byte(threadData), &threadInfo); err == nil && threadInfo.ThreadID != "" && threadInfo.ThreadID != userID {
+			_ = s.redisService.Delete(ctx, threadKey(threadInfo.ThreadID))
+		}
+	}
+
+	if err := s.redisService.Delete(ctx, threadKey(userID)); err != nil {
+		return fmt.Errorf("failed to expire thread: %w", err)
+	}
+
+	// The user's own ID can't be reused as a thread ID any more, since the agent keeps its context
+	if err := s.redisService.SetValue(ctx, threadRotatedKey(userID), "1", 0); err != nil {
+		return fmt.Errorf("failed to mark thread as expired: %w", err)
+	}
+
+	s.logger.WithField("user_id", userID).Info("Thread expired")
+	return nil
+}
+
+// ResetThread forgets the user's thread mapping entirely, including the thread ID rotation flag,
+// and returns how many keys were removed
+func (s *GoogleAgentEngineService) ResetThread(ctx context.Context, userID string) (int, error) {
+	keys := []string{threadKey(userID), threadRotatedKey(userID)}
+	if threadData, err := s.redisService.Get(ctx, threadKey(userID)); err == nil && threadData != "" {
+		var threadInfo ThreadInfo
+		if err := json.Unmarshal([]byte(threadData), &threadInfo); err == nil && threadInfo.ThreadID != "" && threadInfo.ThreadID != userID {
+			keys = append(keys, threadKey(threadInfo.ThreadID))
+		}
+	}
+
+	removed, err := s.redisService.DeleteKeys(ctx, keys...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reset thread: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":      userID,
+		"keys_removed": removed,
+	}).Info("Thread reset")
+	return removed, nil
+}
+
+// newThreadID picks the ID for a user's new thread. Users keep their own ID as thread ID unless
+// threads can expire, in which case each thread gets a fresh ID so the agent starts without context.
+func (s *GoogleAgentEngineService) newThreadID(ctx context.Context, userID string) (string, error) {
+	rotated := false
+	if s.config.GoogleAgentEngine.ThreadIdleTTL > 0 {
+		rotated = true
+	} else if flag, err := s.redisService.Get(ctx, threadRotatedKey(userID)); err == nil && flag != "" {
+		rotated = true
+	}
+
+	if !rotated {
+		return userID, nil
+	}
+	return fmt.Sprintf("%s-%d", userID, time.Now().UnixMilli()), nil
+}
+
+// threadTTL returns the expiry applied to thread info, refreshed on every message
+func (s *GoogleAgentEngineService) threadTTL() time.Duration {
+	if idleTTL := s.config.GoogleAgentEngine.ThreadIdleTTL; idleTTL > 0 {
+		return idleTTL
+	}
+	return s.config.Redis.AgentIDCacheTTL
+}
+
+// threadKey returns the Redis key holding thread info for a user or thread ID
+func threadKey(id string) string {
+	return fmt.Sprintf("thread:%s", id)
+}
+
+// threadRotatedKey returns the Redis key marking that a user's threads must use fresh IDs
+func threadRotatedKey(userID string) string {
+	return fmt.Sprintf("thread:rotated:%s", userID)
+}
+
+// SendOptions are per-call settings for sending a message to the agent
+type SendOptions struct {
+	Model     string          // Model the agent should answer with (empty = the agent's default)
+	Documents []DocumentInput // Documents forwarded to document-capable agents with the message
+}
+
+// SendOption sets one of the SendOptions
+type SendOption func(*SendOptions)
+
+// WithDocuments attaches documents to the message, for agents that read them (SupportsDocuments)
+func WithDocuments(documents ...DocumentInput) SendOption {
+	return func(o *SendOptions) {
+		o.Documents = append(o.Documents, documents...)
+	}
+}
+
+// WithModel asks the agent to answer with the given model
+func WithModel(model string) SendOption {
+	return func(o *SendOptions) {
+		o.Model = model
+	}
+}
+
+// NewSendOptions applies the options in order
+func NewSendOptions(opts ...SendOption) SendOptions {
+	var options SendOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
 // SendMessage sends a message to a thread and returns the agent's response
-func (s *GoogleAgentEngineService) SendMessage(ctx context.Context, threadID string, content string) (*models.AgentResponse, error) {
+func (s *GoogleAgentEngineService) SendMessage(ctx context.Context, threadID string, content string, opts ...SendOption) (*models.AgentResponse, error) {
+	return s.SendMultimodalMessage(ctx, threadID, content, nil, opts...)
+}
+
+// SendMultimodalMessage sends a message with optional image references to a thread and returns
+// the agent's response. Images are only forwarded to vision-capable agents.
+func (s *GoogleAgentEngineService) SendMultimodalMessage(ctx context.Context, threadID string, content string, imageURLs []string, opts ...SendOption) (*models.AgentResponse, error) {
 	start := time.Now()
+	options := NewSendOptions(opts...)
 
 	s.logger.WithFields(logrus.Fields{
 		"thread_id":      threadID,
 		"content_length": len(content),
+		"image_count":    len(imageURLs),
+		"document_count": len(options.Documents),
+		"model":          options.Model,
 	}).Debug("Sending message to thread")
 
+	if len(imageURLs) > 0 && !s.SupportsImages() {
+		return nil, fmt.Errorf("image inputs are not supported by the configured agent")
+	}
+	if len(options.Documents) > 0 && !s.SupportsDocuments() {
+		return nil, fmt.Errorf("document inputs are not supported by the configured agent")
+	}
+
 	// Apply rate limiting
 	if err := s.rateLimiter.Wait(ctx, "google_agent_engine"); err != nil {
 		return nil, fmt.Errorf("rate limit exceeded: %w", err)
 	}
 
 	// Get thread info and validate
-	threadKey := fmt.Sprintf("thread:%s", threadID)
-	threadData, err := s.redisService.Get(ctx, threadKey)
+	threadData, err := s.redisService.Get(ctx, threadKey(threadID))
 	if err != nil {
 		return nil, fmt.Errorf("thread not found: %w", err)
 	}
@@ -239,12 +459,25 @@ func (s *GoogleAgentEngineService) SendMessage(ctx context.Context, threadID str
 		return nil, fmt.Errorf("failed to parse thread info: %w", err)
 	}
 
+	// Fail fast while the reasoning engine is known to be unhealthy
+	if err := s.breaker.Allow(); err != nil {
+		return nil, fmt.Errorf("google agent engine unavailable: %w", err)
+	}
+
 	// Call the reasoning engine via HTTP REST API
-	responseContent, err := s.queryReasoningEngine(ctx, threadID, content)
+	responseContent, err := s.queryReasoningEngine(ctx, threadID, content, imageURLs, options)
 	if err != nil {
+		// Caller-side cancellation and rate limiting say nothing about backend health
+		var rateLimitErr *RateLimitError
+		if errors.Is(err, context.Canceled) || errors.As(err, &rateLimitErr) {
+			s.breaker.Release()
+		} else {
+			s.breaker.RecordFailure()
+		}
 		s.logger.WithError(err).WithField("thread_id", threadID).Error("Failed to query reasoning engine")
 		return nil, fmt.Errorf("failed to get AI response: %w", err)
 	}
+	s.breaker.RecordSuccess()
 
 	if responseContent == "" {
 		responseContent = "I apologize, but I couldn't generate a response. Please try again."
@@ -257,7 +490,7 @@ func (s *GoogleAgentEngineService) SendMessage(ctx context.Context, threadID str
 	threadInfo.LastUsedAt = time.Now()
 	threadInfo.MessageCount++
 	updatedData, _ := json.Marshal(threadInfo)
-	_ = s.redisService.SetValue(ctx, threadKey, string(updatedData), s.config.Redis.AgentIDCacheTTL)
+	_ = s.redisService.SetValue(ctx, threadKey(threadID), string(updatedData), s.threadTTL())
 
 	// Generate response message ID
 	messageID := fmt.Sprintf("msg_%s_%d", threadID, time.Now().UnixNano())
@@ -331,6 +564,11 @@ func (s *GoogleAgentEngineService) postQuery(ctx context.Context, accessToken st
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
 
+	// Forward the correlation ID so agent-side logs can be matched to the originating message
+	if correlationID := GetCorrelationID(ctx); correlationID != "" {
+		req.Header.Set("X-Request-ID", correlationID)
+	}
+
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %w", err)
@@ -342,6 +580,9 @@ func (s *GoogleAgentEngineService) postQuery(ctx context.Context, accessToken st
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, newRateLimitError(resp.Header, bodyBytes)
+	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return nil, fmt.Errorf("non-2xx response: %d - %s", resp.StatusCode, string(bodyBytes))
 	}
@@ -382,6 +623,9 @@ func (s *GoogleAgentEngineService) pollOperation(ctx context.Context, accessToke
 			return nil, fmt.Errorf("failed to read poll response: %w", err)
 		}
 
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return nil, newRateLimitError(resp.Header, bodyBytes)
+		}
 		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 			return nil, fmt.Errorf("poll non-2xx response: %d - %s", resp.StatusCode, string(bodyBytes))
 		}
@@ -412,26 +656,71 @@ func (s *GoogleAgentEngineService) extractOperationName(resp map[string]interfac
 	return ""
 }
 
+// SupportsImages reports whether the configured agent accepts image inputs
+func (s *GoogleAgentEngineService) SupportsImages() bool {
+	return s.config.GoogleAgentEngine.SupportsImages
+}
+
+// SupportsDocuments reports whether the configured agent accepts document inputs
+func (s *GoogleAgentEngineService) SupportsDocuments() bool {
+	return s.config.GoogleAgentEngine.SupportsDocuments
+}
+
+// buildMessageContent builds the human message content, using the multimodal content-block
+// format (text + image_url and file parts) when images or documents are attached
+func buildMessageContent(message string, imageURLs []string, documents []DocumentInput) interface{} {
+	if len(imageURLs) == 0 && len(documents) == 0 {
+		return message
+	}
+
+	parts := make([]map[string]interface{}, 0, len(imageURLs)+len(documents)+1)
+	if message != "" {
+		parts = append(parts, map[string]interface{}{"type": "text", "text": message})
+	}
+	for _, imageURL := range imageURLs {
+		parts = append(parts, map[string]interface{}{
+			"type":      "image_url",
+			"image_url": map[string]interface{}{"url": imageURL},
+		})
+	}
+	for _, document := range documents {
+		part := map[string]interface{}{
+			"type":        "file",
+			"source_type": "url",
+			"url":         document.URL,
+		}
+		if document.MimeType != "" {
+			part["mime_type"] = document.MimeType
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
 // queryReasoningEngine makes a request to the reasoning engine with proper async handling
-func (s *GoogleAgentEngineService) queryReasoningEngine(ctx context.Context, threadID, message string) (string, error) {
+func (s *GoogleAgentEngineService) queryReasoningEngine(ctx context.Context, threadID, message string, imageURLs []string, options SendOptions) (string, error) {
 	accessToken, err := s.getAccessToken(ctx)
 	if err != nil {
 		return "", fmt.Errorf("failed to get access token: %w", err)
 	}
 
 	// Build payload matching the sandbox pattern
+	configurable := map[string]interface{}{
+		"thread_id": threadID,
+	}
+	if options.Model != "" {
+		configurable["model"] = options.Model
+	}
 	payload := map[string]interface{}{
 		"classMethod": "async_query",
 		"input": map[string]interface{}{
 			"input": map[string]interface{}{
 				"messages": []map[string]interface{}{
-					{"role": "human", "content": message},
+					{"role": "human", "content": buildMessageContent(message, imageURLs, options.Documents)},
 				},
 			},
 			"config": map[string]interface{}{
-				"configurable": map[string]interface{}{
-					"thread_id": threadID,
-				},
+				"configurable": configurable,
 			},
 		},
 	}
@@ -439,6 +728,7 @@ func (s *GoogleAgentEngineService) queryReasoningEngine(ctx context.Context, thr
 	s.logger.WithFields(logrus.Fields{
 		"thread_id":      threadID,
 		"message_length": len(message),
+		"image_count":    len(imageURLs),
 	}).Debug("Making async_query call to reasoning engine")
 
 	resp, err := s.postQuery(ctx, accessToken, payload)
@@ -524,6 +814,16 @@ func (s *GoogleAgentEngineService) extractContentFromResponse(response interface
 	return string(responseBytes), nil
 }
 
+// IsCircuitOpen returns true if calls to the reasoning engine are currently being short-circuited
+func (s *GoogleAgentEngineService) IsCircuitOpen() bool {
+	return s.breaker.IsOpen()
+}
+
+// CircuitState returns the current state of the reasoning engine circuit breaker
+func (s *GoogleAgentEngineService) CircuitState() CircuitState {
+	return s.breaker.State()
+}
+
 // Close closes the Google Agent Engine client
 func (s *GoogleAgentEngineService) Close() error {
 	// HTTP client doesn't need explicit closing
@@ -544,7 +844,7 @@ func (s *GoogleAgentEngineService) HealthCheck(ctx context.Context) error {
 	defer cancel()
 
 	// Test with a simple health check query to the reasoning engine
-	_, err := s.queryReasoningEngine(ctx, "health-check", "Health check - please respond with 'OK'")
+	_, err := s.queryReasoningEngine(ctx, "health-check", "Health check - please respond with 'OK'", nil, SendOptions{})
 	if err != nil {
 		if strings.Contains(err.Error(), "context deadline exceeded") {
 			return fmt.Errorf("google Agent Engine health check timeout")
@@ -459,6 +459,13 @@ func (r *RedisHealthChecker) Check(ctx context.Context) ComponentHealth {
 		"total_operations": cacheMetrics.TotalOperations,
 		"errors":           cacheMetrics.Errors,
 		"last_reset_time":  cacheMetrics.LastResetTime,
+
+		"set_failures":         cacheMetrics.SetFailures,
+		"task_status_failures": cacheMetrics.TaskStatusFailures,
+		"task_result_failures": cacheMetrics.TaskResultFailures,
+
+		"archive_hits":           cacheMetrics.ArchiveHits,
+		"archive_write_failures": cacheMetrics.ArchiveWriteFailures,
 	}
 
 	// Get pool statistics
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"strings"
+)
+
+// MessageKey identifies a user-facing message in the catalog
+type MessageKey string
+
+// User-facing messages generated by the gateway
+const (
+	MsgUnknownError        MessageKey = "unknown_error"
+	MsgUnexpectedError     MessageKey = "unexpected_error"
+	MsgHighDemand          MessageKey = "high_demand"
+	MsgRequestTooLong      MessageKey = "request_too_long"
+	MsgUnavailable         MessageKey = "unavailable"
+	MsgConfigurationIssue  MessageKey = "configuration_issue"
+	MsgAuthenticationIssue MessageKey = "authentication_issue"
+	MsgAccessDenied        MessageKey = "access_denied"
+	MsgNotFound            MessageKey = "not_found"
+	MsgMessageTooLong      MessageKey = "message_too_long"
+	MsgUnsupportedFormat   MessageKey = "unsupported_format"
+	MsgFileTooLarge        MessageKey = "file_too_large"
+	MsgInvalidLink         MessageKey = "invalid_link"
+	MsgTranscriptionFailed MessageKey = "transcription_failed"
+	MsgFormattingFailed    MessageKey = "formatting_failed"
+	MsgEmptyResponse       MessageKey = "empty_response"
+	MsgFallbackInput       MessageKey = "fallback_input" // Sent to the agent when an audio message can't be transcribed
+	MsgContentBlocked      MessageKey = "content_blocked"
+)
+
+// LocaleKey is the context key holding the user's locale
+var LocaleKey = ContextKey("locale")
+
+// messageCatalog holds the user-facing messages by language
+var messageCatalog = map[string]map[MessageKey]string{
+	"pt": {
+		MsgUnknownError:        "Ocorreu um erro desconhecido. Por favor, tente novamente.",
+		MsgUnexpectedError:     "Encontrei um problema inesperado. Por favor, tente novamente e, se o problema persistir, entre em contato com o suporte.",
+		MsgHighDemand:          "Estou com uma demanda alta no momento. Por favor, aguarde um instante e tente novamente.",
+		MsgRequestTooLong:      "A solicitação demorou demais para ser processada. Por favor, tente novamente com uma mensagem mais curta.",
+		MsgUnavailable:         "Estou temporariamente indisponível. Por favor, tente novamente em alguns instantes.",
+		MsgConfigurationIssue:  "Há um problema de configuração. Por favor, entre em contato com o suporte.",
+		MsgAuthenticationIssue: "Há um problema de autenticação. Por favor, entre em contato com o suporte.",
+		MsgAccessDenied:        "Acesso negado. Por favor, entre em contato com o suporte.",
+		MsgNotFound:            "O recurso solicitado não foi encontrado. Por favor, tente novamente.",
+		MsgMessageTooLong:      "Sua mensagem é muito longa. Por favor, tente com uma mensagem mais curta.",
+		MsgUnsupportedFormat:   "O formato do arquivo enviado não é suportado. Por favor, tente com outro formato.",
+		MsgFileTooLarge:        "O arquivo enviado é muito grande. Por favor, tente com um arquivo menor.",
+		MsgInvalidLink:         "O link informado não está acessível. Por favor, verifique o link e tente novamente.",
+		MsgTranscriptionFailed: "Não consegui processar seu áudio. Por favor, grave novamente ou envie uma mensagem de texto.",
+		MsgFormattingFailed:    "Tive problemas para formatar a resposta. Aqui está o conteúdo original: ",
+		MsgEmptyResponse:       "Desculpe, não consegui gerar uma resposta. Por favor, tente novamente.",
+		MsgFallbackInput:       "Ajuda",
+		MsgContentBlocked:      "Desculpe, não posso ajudar com esse assunto. Posso ajudar com outra dúvida?",
+	},
+	"es": {
+		MsgUnknownError:        "Ocurrió un error desconocido. Por favor, inténtalo de nuevo.",
+		MsgUnexpectedError:     "Encontré un problema inesperado. Por favor, inténtalo de nuevo y, si el problema persiste, contacta con soporte.",
+		MsgHighDemand:          "Estoy con mucha demanda en este momento. Por favor, espera un momento e inténtalo de nuevo.",
+		MsgRequestTooLong:      "La solicitud tardó demasiado en procesarse. Por favor, inténtalo de nuevo con un mensaje más corto.",
+		MsgUnavailable:         "No estoy disponible temporalmente. Por favor, inténtalo de nuevo en unos momentos.",
+		MsgConfigurationIssue:  "Hay un problema de configuración. Por favor, contacta con soporte.",
+		MsgAuthenticationIssue: "Hay un problema de autenticación. Por favor, contacta con soporte.",
+		MsgAccessDenied:        "Acceso denegado. Por favor, contacta con soporte.",
+		MsgNotFound:            "No se encontró el recurso solicitado. Por favor, inténtalo de nuevo.",
+		MsgMessageTooLong:      "Tu mensaje es demasiado largo. Por favor, inténtalo con un mensaje más corto.",
+		MsgUnsupportedFormat:   "El formato del archivo enviado no es compatible. Por favor, inténtalo con otro formato.",
+		MsgFileTooLarge:        "El archivo enviado es demasiado grande. Por favor, inténtalo con un archivo más pequeño.",
+		MsgInvalidLink:         "El enlace proporcionado no es accesible. Por favor, revisa el enlace e inténtalo de nuevo.",
+		MsgTranscriptionFailed: "No pude procesar tu audio. Por favor, grábalo de nuevo o envía un mensaje de texto.",
+		MsgFormattingFailed:    "Tuve problemas para formatear la respuesta. Este es el contenido original: ",
+		MsgEmptyResponse:       "Lo siento, no pude generar una respuesta. Por favor, inténtalo de nuevo.",
+		MsgFallbackInput:       "Ayuda",
+		MsgContentBlocked:      "Lo siento, no puedo ayudar con ese tema. ¿Puedo ayudarte con otra consulta?",
+	},
+	"en": {
+		MsgUnknownError:        "An unknown error occurred. Please try again.",
+		MsgUnexpectedError:     "I encountered an unexpected issue. Please try again, and if the problem persists, contact support.",
+		MsgHighDemand:          "I'm currently experiencing high demand. Please wait a moment and try again.",
+		MsgRequestTooLong:      "The request took too long to process. Please try again with a shorter message.",
+		MsgUnavailable:         "I'm temporarily unavailable. Please try again in a few moments.",
+		MsgConfigurationIssue:  "There's a configuration issue. Please contact support.",
+		MsgAuthenticationIssue: "There's an authentication issue. Please contact support.",
+		MsgAccessDenied:        "Access denied. Please contact support.",
+		MsgNotFound:            "The requested resource was not found. Please try again.",
+		MsgMessageTooLong:      "Your message is too long. Please try with a shorter message.",
+		MsgUnsupportedFormat:   "The file format you sent is not supported. Please try with a different format.",
+		MsgFileTooLarge:        "The file you sent is too large. Please try with a smaller file.",
+		MsgInvalidLink:         "The link you provided is not accessible. Please check the link and try again.",
+		MsgTranscriptionFailed: "I couldn't process your audio message. Please try recording it again or send a text message.",
+		MsgFormattingFailed:    "I had trouble formatting the response. Here's the raw content: ",
+		MsgEmptyResponse:       "I apologize, but I couldn't generate a response. Please try again.",
+		MsgFallbackInput:       "Help",
+		MsgContentBlocked:      "Sorry, I can't help with that topic. Is there anything else I can help you with?",
+	},
+}
+
+// NormalizeLocale reduces a locale such as "pt-BR" or "es_AR" to a catalog language,
+// returning "" when the language is not in the catalog
+func NormalizeLocale(locale string) string {
+	language := strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(language, "-_"); i >= 0 {
+		language = language[:i]
+	}
+	if _, ok := messageCatalog[language]; !ok {
+		return ""
+	}
+	return language
+}
+
+// LocalizedMessage returns the message for the locale, falling back to defaultLocale and then to English
+func LocalizedMessage(locale, defaultLocale string, key MessageKey) string {
+	for _, candidate := range []string{locale, defaultLocale, "en"} {
+		if messages, ok := messageCatalog[NormalizeLocale(candidate)]; ok {
+			if message, ok := messages[key]; ok {
+				return message
+			}
+		}
+	}
+	return string(key)
+}
+
+// WithLocale adds the user's locale to the context
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, LocaleKey, locale)
+}
+
+// GetLocale retrieves the user's locale from the context
+func GetLocale(ctx context.Context) string {
+	if locale, ok := ctx.Value(LocaleKey).(string); ok {
+		return locale
+	}
+	return ""
+}
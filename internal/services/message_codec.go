@@ -0,0 +1,129 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/ugorji/go/codec"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+)
+
+// ContentTypeJSON is the content type of JSON-encoded queue messages, also assumed when none is set
+const ContentTypeJSON = "application/json"
+
+// ContentTypeMsgpack is the content type of msgpack-encoded queue messages
+const ContentTypeMsgpack = "application/msgpack"
+
+// MessageCodec decodes queue messages published with a given content type
+type MessageCodec interface {
+	// ContentTypes lists the media types (without parameters) this codec handles
+	ContentTypes() []string
+	// Decode parses a delivery body into the queue message
+	Decode(body []byte, msg *models.QueueMessage) error
+}
+
+// MessageCodecRegistry selects a codec by the content type of a delivery, falling back to JSON when unset
+type MessageCodecRegistry struct {
+	mu     sync.RWMutex
+	codecs map[string]MessageCodec
+}
+
+// NewMessageCodecRegistry creates a registry with the JSON and msgpack codecs registered
+func NewMessageCodecRegistry() *MessageCodecRegistry {
+	registry := &MessageCodecRegistry{codecs: make(map[string]MessageCodec)}
+	registry.Register(JSONMessageCodec{})
+	registry.Register(NewMsgpackMessageCodec())
+	return registry
+}
+
+var (
+	defaultCodecsOnce sync.Once
+	defaultCodecs     *MessageCodecRegistry
+)
+
+// DefaultMessageCodecs returns the shared registry with the built-in codecs
+func DefaultMessageCodecs() *MessageCodecRegistry {
+	defaultCodecsOnce.Do(func() {
+		defaultCodecs = NewMessageCodecRegistry()
+	})
+	return defaultCodecs
+}
+
+// Register adds a codec for each of its content types, replacing any codec registered for them
+func (r *MessageCodecRegistry) Register(c MessageCodec) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, contentType := range c.ContentTypes() {
+		r.codecs[strings.ToLower(contentType)] = c
+	}
+}
+
+// ForContentType returns the codec for a delivery's content type; an empty content type means JSON
+func (r *MessageCodecRegistry) ForContentType(contentType string) (MessageCodec, error) {
+	mediaType := ContentTypeJSON
+	if strings.TrimSpace(contentType) != "" {
+		parsed, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, fmt.Errorf("invalid content type %q: %w", contentType, err)
+		}
+		mediaType = parsed
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.codecs[mediaType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported content type %q", contentType)
+	}
+	return c, nil
+}
+
+// Decode parses a delivery body with the codec matching its content type
+func (r *MessageCodecRegistry) Decode(contentType string, body []byte, msg *models.QueueMessage) error {
+	c, err := r.ForContentType(contentType)
+	if err != nil {
+		return err
+	}
+	return c.Decode(body, msg)
+}
+
+// JSONMessageCodec decodes JSON queue messages
+type JSONMessageCodec struct{}
+
+// ContentTypes returns the JSON media types
+func (JSONMessageCodec) ContentTypes() []string {
+	return []string{ContentTypeJSON, "text/json"}
+}
+
+// Decode parses a JSON queue message
+func (JSONMessageCodec) Decode(body []byte, msg *models.QueueMessage) error {
+	return json.Unmarshal(body, msg)
+}
+
+// MsgpackMessageCodec decodes msgpack queue messages, using the same field names as JSON
+type MsgpackMessageCodec struct {
+	handle *codec.MsgpackHandle
+}
+
+// NewMsgpackMessageCodec creates a msgpack codec that decodes strings and maps as JSON would
+func NewMsgpackMessageCodec() *MsgpackMessageCodec {
+	handle := &codec.MsgpackHandle{}
+	handle.RawToString = true
+	handle.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	return &MsgpackMessageCodec{handle: handle}
+}
+
+// ContentTypes returns the msgpack media types
+func (c *MsgpackMessageCodec) ContentTypes() []string {
+	return []string{ContentTypeMsgpack, "application/x-msgpack"}
+}
+
+// Decode parses a msgpack queue message
+func (c *MsgpackMessageCodec) Decode(body []byte, msg *models.QueueMessage) error {
+	return codec.NewDecoderBytes(body, c.handle).Decode(msg)
+}
@@ -2,14 +2,18 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
 )
 
 // Consumer represents a message consumer for a specific queue
@@ -20,6 +24,13 @@ type Consumer struct {
 	concurrency int
 	handler     MessageHandler
 
+	// Concurrency limit shared with the manager's other consumers (nil = unlimited)
+	semaphore chan struct{}
+	inFlight  int64
+
+	// Optional; holds back fetching while consumption is paused (e.g. during a Redis outage)
+	gate ConsumerGate
+
 	// Consumer lifecycle
 	isRunning bool
 	stopChan  chan struct{}
@@ -27,11 +38,25 @@ type Consumer struct {
 	mutex     sync.RWMutex
 }
 
+// consumerResubscribeInterval is how often a worker whose channel closed checks for a new connection
+const consumerResubscribeInterval = time.Second
+
+// ConsumerGate decides when consumers may fetch the next message
+type ConsumerGate interface {
+	// WaitReady blocks until consumption may continue, returning false if ctx or stop ends first
+	WaitReady(ctx context.Context, stop <-chan struct{}) bool
+}
+
 // ConsumerManager manages multiple consumers
 type ConsumerManager struct {
 	consumers map[string]*Consumer
+	gate      ConsumerGate
 	mutex     sync.RWMutex
 	logger    *logrus.Logger
+
+	// WORKER_MAX_CONCURRENCY slots shared by every consumer, created with the first one (nil = unlimited)
+	semaphore       chan struct{}
+	semaphoreLoaded bool
 }
 
 // NewConsumerManager creates a new consumer manager
@@ -143,15 +168,7 @@ func (c *Consumer) workerLoop(ctx context.Context, workerID int) {
 	})
 
 	// Start consuming for this worker
-	msgs, err := c.rabbitMQ.channel.Consume(
-		c.queueName, // queue
-		consumerTag, // consumer tag (unique across containers)
-		false,       // auto-ack
-		false,       // exclusive
-		false,       // no-local
-		false,       // no-wait
-		nil,         // arguments
-	)
+	msgs, err := c.consume(consumerTag)
 	if err != nil {
 		logger.WithError(err).Error("Failed to start consuming messages")
 		return
@@ -160,6 +177,12 @@ func (c *Consumer) workerLoop(ctx context.Context, workerID int) {
 	logger.Info("Worker started consuming messages")
 
 	for {
+		// Leave messages in the queue while consumption is paused
+		if c.gate != nil && !c.gate.WaitReady(ctx, c.stopChan) {
+			logger.Info("Worker stopped while consumption was paused")
+			return
+		}
+
 		select {
 		case <-ctx.Done():
 			logger.Info("Worker context cancelled")
@@ -169,12 +192,105 @@ func (c *Consumer) workerLoop(ctx context.Context, workerID int) {
 			return
 		case msg, ok := <-msgs:
 			if !ok {
-				logger.Info("Message channel closed for worker")
+				// The delivery channel closes with the connection; subscribe again once it is re-established
+				logger.Warn("Message channel closed for worker, waiting for RabbitMQ to reconnect")
+				if msgs, ok = c.resubscribe(ctx, consumerTag, logger); !ok {
+					logger.Info("Worker stopped while waiting for RabbitMQ")
+					return
+				}
+				continue
+			}
+			if !c.acquire(ctx) {
+				// Shutting down while waiting for a slot; hand the message back to the queue
+				if err := msg.Nack(false, true); err != nil {
+					logger.WithError(err).Error("Failed to requeue message on shutdown")
+				}
 				return
 			}
 			c.processMessageWithRetry(ctx, msg, logger)
+			c.release()
+		}
+	}
+}
+
+// consume registers this worker's consumer tag on the service's current channel
+func (c *Consumer) consume(consumerTag string) (<-chan amqp.Delivery, error) {
+	c.rabbitMQ.mutex.Lock()
+	defer c.rabbitMQ.mutex.Unlock()
+
+	if !c.rabbitMQ.isConnected || c.rabbitMQ.isShutdown {
+		return nil, fmt.Errorf("RabbitMQ connection is not available")
+	}
+
+	return c.rabbitMQ.channel.Consume(
+		c.queueName, // queue
+		consumerTag, // consumer tag (unique across containers)
+		false,       // auto-ack
+		false,       // exclusive
+		false,       // no-local
+		false,       // no-wait
+		nil,         // arguments
+	)
+}
+
+// resubscribe waits for the service to reconnect and consumes again, returning false if the consumer or
+// service stops first
+func (c *Consumer) resubscribe(ctx context.Context, consumerTag string, logger *logrus.Entry) (<-chan amqp.Delivery, bool) {
+	ticker := time.NewTicker(consumerResubscribeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-c.stopChan:
+			return nil, false
+		case <-ticker.C:
+		}
+
+		c.rabbitMQ.mutex.Lock()
+		shutdown := c.rabbitMQ.isShutdown
+		c.rabbitMQ.mutex.Unlock()
+		if shutdown {
+			return nil, false
+		}
+
+		msgs, err := c.consume(consumerTag)
+		if err != nil {
+			logger.WithError(err).Debug("RabbitMQ not ready yet, retrying subscription")
+			continue
+		}
+		logger.Info("Worker resumed consuming messages after reconnection")
+		return msgs, true
+	}
+}
+
+// acquire waits for a free concurrency slot, returning false if the consumer stops first
+func (c *Consumer) acquire(ctx context.Context) bool {
+	if c.semaphore != nil {
+		select {
+		case c.semaphore <- struct{}{}:
+		case <-ctx.Done():
+			return false
+		case <-c.stopChan:
+			return false
 		}
 	}
+	atomic.AddInt64(&c.inFlight, 1)
+	return true
+}
+
+// release frees the concurrency slot taken by acquire
+func (c *Consumer) release() {
+	atomic.AddInt64(&c.inFlight, -1)
+	if c.semaphore != nil {
+		<-c.semaphore
+	}
+}
+
+// InFlight returns the number of messages currently being handled
+func (c *Consumer) InFlight() int64 {
+	return atomic.LoadInt64(&c.inFlight)
 }
 
 // processMessage handles a single message
@@ -243,16 +359,63 @@ func (c *Consumer) processMessageWithRetry(ctx context.Context, msg amqp.Deliver
 
 	maxRetries := int64(c.rabbitMQ.config.RabbitMQ.MaxRetries)
 
+	// In early mode the delivery is acknowledged before processing; retries are then republished
+	// and nothing is handed back to the broker
+	acked := false
+	if c.rabbitMQ.config.GetAckMode() == config.AckModeEarly {
+		if err := msg.Ack(false); err != nil {
+			logger.WithError(err).Error("Failed to acknowledge message before processing")
+		}
+		acked = true
+	}
+
 	// Create a context with timeout for message processing
 	msgCtx, cancel := context.WithTimeout(ctx, c.rabbitMQ.config.RabbitMQ.MessageTimeout)
 	defer cancel()
 
 	// Process the message
 	err := c.handler(msgCtx, msg)
+
+	// The handler asked for a deferred redelivery (e.g. rate limiting): wait, then requeue without counting a retry
+	var retryLater *RetryLaterError
+	if errors.As(err, &retryLater) {
+		logger.WithError(err).WithField("retry_delay", retryLater.Delay).Warn("Message deferred, requeueing after delay")
+		select {
+		case <-time.After(retryLater.Delay):
+		case <-ctx.Done():
+		}
+		if acked {
+			c.publishRetryMessage(msg, retryCount, 0, logger)
+			return
+		}
+		if err := msg.Nack(false, true); err != nil {
+			logger.WithError(err).Error("Failed to requeue deferred message")
+		}
+		return
+	}
+
+	// The handler rejected the message outright: send it to the DLQ without retrying
+	var rejected *RejectError
+	if errors.As(err, &rejected) {
+		if acked {
+			logger.WithError(err).Error("Message rejected after it was already acknowledged, dropping it")
+			return
+		}
+		logger.WithError(err).Error("Message rejected, sending to DLQ without retrying")
+		if err := msg.Reject(false); err != nil {
+			logger.WithError(err).Error("Failed to reject message to DLQ")
+		}
+		return
+	}
+
 	if err != nil {
 		logger.WithError(err).WithField("retry_count", retryCount).Error("Message processing failed")
 
 		if retryCount >= maxRetries {
+			if acked {
+				logger.WithField("max_retries", maxRetries).Error("Message exceeded maximum retries and was already acknowledged, dropping it")
+				return
+			}
 			logger.WithField("max_retries", maxRetries).Error("Message exceeded maximum retries, sending to DLQ")
 			// Send to DLQ
 			if err := msg.Reject(false); err != nil {
@@ -272,13 +435,19 @@ func (c *Consumer) processMessageWithRetry(ctx context.Context, msg amqp.Deliver
 		c.publishRetryMessage(msg, retryCount+1, retryDelay, logger)
 
 		// Acknowledge original message
-		if err := msg.Ack(false); err != nil {
-			logger.WithError(err).Error("Failed to acknowledge message for retry")
+		if !acked {
+			if err := msg.Ack(false); err != nil {
+				logger.WithError(err).Error("Failed to acknowledge message for retry")
+			}
 		}
 		return
 	}
 
 	// Acknowledge successful processing
+	if acked {
+		logger.Debug("Message processed successfully")
+		return
+	}
 	if err := msg.Ack(false); err != nil {
 		logger.WithError(err).Error("Failed to acknowledge message")
 	} else {
@@ -324,6 +493,7 @@ func (c *Consumer) publishRetryMessage(originalMsg amqp.Delivery, retryCount int
 			ContentType:  originalMsg.ContentType,
 			Body:         originalMsg.Body,
 			DeliveryMode: amqp.Persistent,
+			Priority:     originalMsg.Priority,
 			Headers:      headers,
 			Timestamp:    time.Now(),
 			MessageId:    originalMsg.MessageId + "_retry_" + fmt.Sprintf("%d", retryCount),
@@ -338,6 +508,13 @@ func (c *Consumer) publishRetryMessage(originalMsg amqp.Delivery, retryCount int
 	}
 }
 
+// SetGate makes consumers added afterwards wait on gate before fetching each message
+func (cm *ConsumerManager) SetGate(gate ConsumerGate) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+	cm.gate = gate
+}
+
 // AddConsumer adds a new consumer to the manager
 func (cm *ConsumerManager) AddConsumer(ctx context.Context, rabbitMQ *RabbitMQService, queueName string, concurrency int, handler MessageHandler) error {
 	cm.mutex.Lock()
@@ -347,12 +524,26 @@ func (cm *ConsumerManager) AddConsumer(ctx context.Context, rabbitMQ *RabbitMQSe
 		return fmt.Errorf("consumer for queue %s already exists", queueName)
 	}
 
+	// Cap simultaneous handler invocations across all queues and keep the (channel-wide) prefetch in line so
+	// excess deliveries stay in the queues
+	if !cm.semaphoreLoaded {
+		if maxConcurrency := rabbitMQ.config.RabbitMQ.MaxConcurrency; maxConcurrency > 0 {
+			if err := rabbitMQ.SetChannelPrefetch(maxConcurrency); err != nil {
+				return fmt.Errorf("failed to apply prefetch for queue %s: %w", queueName, err)
+			}
+			cm.semaphore = make(chan struct{}, maxConcurrency)
+		}
+		cm.semaphoreLoaded = true
+	}
+
 	consumer := &Consumer{
 		rabbitMQ:    rabbitMQ,
 		logger:      cm.logger,
 		queueName:   queueName,
 		concurrency: concurrency,
 		handler:     handler,
+		gate:        cm.gate,
+		semaphore:   cm.semaphore,
 		stopChan:    make(chan struct{}),
 	}
 
@@ -369,8 +560,9 @@ func (cm *ConsumerManager) AddConsumer(ctx context.Context, rabbitMQ *RabbitMQSe
 	cm.consumers[queueName] = consumer
 
 	cm.logger.WithFields(logrus.Fields{
-		"queue":       queueName,
-		"concurrency": concurrency,
+		"queue":           queueName,
+		"concurrency":     concurrency,
+		"max_concurrency": cap(consumer.semaphore),
 	}).Info("Added consumer to manager")
 
 	return nil
@@ -429,6 +621,18 @@ func (cm *ConsumerManager) StopAll() error {
 	return nil
 }
 
+// InFlight returns the number of messages currently being handled across all consumers
+func (cm *ConsumerManager) InFlight() int64 {
+	cm.mutex.RLock()
+	defer cm.mutex.RUnlock()
+
+	var total int64
+	for _, consumer := range cm.consumers {
+		total += consumer.InFlight()
+	}
+	return total
+}
+
 // GetConsumerStats returns statistics for all consumers
 func (cm *ConsumerManager) GetConsumerStats() map[string]interface{} {
 	cm.mutex.RLock()
@@ -438,9 +642,11 @@ func (cm *ConsumerManager) GetConsumerStats() map[string]interface{} {
 	for queueName, consumer := range cm.consumers {
 		consumer.mutex.RLock()
 		stats[queueName] = map[string]interface{}{
-			"queue":       queueName,
-			"concurrency": consumer.concurrency,
-			"is_running":  consumer.isRunning,
+			"queue":           queueName,
+			"concurrency":     consumer.concurrency,
+			"max_concurrency": cap(consumer.semaphore),
+			"in_flight":       consumer.InFlight(),
+			"is_running":      consumer.isRunning,
 		}
 		consumer.mutex.RUnlock()
 	}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+)
+
+func TestConsumersShareMaxConcurrency(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.RabbitMQ.MaxConcurrency = 2
+	rabbitMQ := &RabbitMQService{config: cfg} // Not connected: the prefetch is kept for the next connection
+	manager := NewConsumerManager(logrus.New())
+
+	ctx := context.Background()
+	// No worker goroutines, so the test drives acquire/release itself
+	for _, queue := range []string{"user_messages", "user_messages.shard.0", "user_messages.shard.1"} {
+		if err := manager.AddConsumer(ctx, rabbitMQ, queue, 0, nil); err != nil {
+			t.Fatalf("AddConsumer(%s): %v", queue, err)
+		}
+	}
+	if rabbitMQ.channelPrefetch != 2 {
+		t.Errorf("channel prefetch = %d, want %d", rabbitMQ.channelPrefetch, 2)
+	}
+
+	first, second, third := manager.consumers["user_messages"], manager.consumers["user_messages.shard.0"], manager.consumers["user_messages.shard.1"]
+	if !first.acquire(ctx) || !second.acquire(ctx) {
+		t.Fatal("acquire() = false with free slots")
+	}
+
+	// Both slots are taken by other queues' consumers, so the third must wait
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if third.acquire(timeoutCtx) {
+		t.Fatal("acquire() = true over WORKER_MAX_CONCURRENCY across queues")
+	}
+	if inFlight := manager.InFlight(); inFlight != 2 {
+		t.Errorf("InFlight() = %d, want %d", inFlight, 2)
+	}
+
+	first.release()
+	if !third.acquire(ctx) {
+		t.Error("acquire() = false after a slot was released")
+	}
+}
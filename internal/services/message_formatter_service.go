@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -13,93 +14,146 @@ import (
 	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
 )
 
-// MessageFormatterService implements the MessageFormatterInterface for WhatsApp formatting
+// MessageFormatterService implements the MessageFormatterInterface, formatting agent replies for the
+// channel they are delivered on (WhatsApp unless another registered channel is requested)
 type MessageFormatterService struct {
 	config *config.Config
 	logger *logrus.Logger
+
+	formattersMutex sync.RWMutex
+	formatters      map[string]ChannelFormatter
 }
 
-// NewMessageFormatterService creates a new message formatter service
+// NewMessageFormatterService creates a new message formatter service with the WhatsApp and web formatters, or
+// leaving content unformatted on every channel when MESSAGE_FORMATTING_MODE is "none"
 func NewMessageFormatterService(cfg *config.Config, logger *logrus.Logger) *MessageFormatterService {
+	unformatted := cfg != nil && cfg.GoogleAgentEngine.FormattingMode == config.FormattingModeNone
+	service := newMessageFormatterService(cfg, logger, unformatted)
+
+	logger.WithField("unformatted", unformatted).Info("Message formatter service initialized")
+	return service
+}
+
+// NewUnformattedMessageFormatter creates a formatter that validates content and words error messages like
+// the service but leaves content unformatted, for callers without a configured formatter
+func NewUnformattedMessageFormatter(cfg *config.Config, logger *logrus.Logger) *MessageFormatterService {
+	return newMessageFormatterService(cfg, logger, true)
+}
+
+func newMessageFormatterService(cfg *config.Config, logger *logrus.Logger, unformatted bool) *MessageFormatterService {
 	service := &MessageFormatterService{
 		config: cfg,
 		logger: logger,
 	}
-
-	logger.Info("Message formatter service initialized")
+	if unformatted {
+		service.formatters = map[string]ChannelFormatter{
+			models.ChannelWhatsApp: PassthroughFormatter{},
+			models.ChannelWeb:      PassthroughFormatter{},
+		}
+	} else {
+		service.formatters = map[string]ChannelFormatter{
+			models.ChannelWhatsApp: WhatsAppFormatter{service: service},
+			models.ChannelWeb:      PlainTextFormatter{},
+		}
+	}
 	return service
 }
 
+// RegisterChannelFormatter sets the formatter used for a channel, replacing any formatter registered for it
+func (m *MessageFormatterService) RegisterChannelFormatter(channel string, formatter ChannelFormatter) {
+	m.formattersMutex.Lock()
+	defer m.formattersMutex.Unlock()
+	m.formatters[strings.ToLower(channel)] = formatter
+}
+
+// formatterFor returns the channel's formatter, falling back to WhatsApp for empty or unknown channels
+func (m *MessageFormatterService) formatterFor(channel string) (ChannelFormatter, string) {
+	m.formattersMutex.RLock()
+	defer m.formattersMutex.RUnlock()
+
+	if formatter, exists := m.formatters[strings.ToLower(channel)]; exists {
+		return formatter, strings.ToLower(channel)
+	}
+	if channel != "" {
+		m.logger.WithField("channel", channel).Debug("No formatter for channel, using WhatsApp formatting")
+	}
+	return m.formatters[models.ChannelWhatsApp], models.ChannelWhatsApp
+}
+
 // FormatForWhatsApp converts agent response to WhatsApp-compatible format
 func (m *MessageFormatterService) FormatForWhatsApp(ctx context.Context, response *models.AgentResponse) (string, error) {
+	return m.FormatForChannel(ctx, models.ChannelWhatsApp, response)
+}
+
+// FormatForChannel converts agent response to the format of the delivery channel
+func (m *MessageFormatterService) FormatForChannel(ctx context.Context, channel string, response *models.AgentResponse) (string, error) {
 	start := time.Now()
 
 	if response == nil {
 		return "", fmt.Errorf("agent response is nil")
 	}
 
+	formatter, channel := m.formatterFor(channel)
+
 	m.logger.WithFields(logrus.Fields{
 		"message_id":     response.MessageID,
 		"thread_id":      response.ThreadID,
+		"channel":        channel,
 		"content_length": len(response.Content),
-	}).Debug("Formatting response for WhatsApp")
+	}).Debug("Formatting response for channel")
 
 	if response.Content == "" {
 		m.logger.Warn("Empty content in agent response")
-		return "I apologize, but I couldn't generate a response. Please try again.", nil
+		return m.localize(ctx, MsgEmptyResponse), nil
 	}
 
-	// Convert markdown to WhatsApp formatting
-	formatted := m.convertMarkdownToWhatsApp(response.Content)
-
-	// Apply character limits and formatting rules
-	formatted = m.applyWhatsAppLimits(formatted)
-
-	// Clean up extra whitespace
-	formatted = m.cleanupWhitespace(formatted)
+	formatted := formatter.FormatContent(response.Content)
 
 	duration := time.Since(start)
 	m.logger.WithFields(logrus.Fields{
 		"message_id":       response.MessageID,
+		"channel":          channel,
 		"original_length":  len(response.Content),
 		"formatted_length": len(formatted),
 		"duration_ms":      duration.Milliseconds(),
-	}).Debug("WhatsApp formatting completed")
+	}).Debug("Channel formatting completed")
 
 	return formatted, nil
 }
 
-// FormatErrorMessage creates a user-friendly error message
+// FormatErrorMessage creates a user-friendly error message in the locale carried by ctx (see WithLocale)
 func (m *MessageFormatterService) FormatErrorMessage(ctx context.Context, err error) string {
 	if err == nil {
-		return "An unknown error occurred. Please try again."
+		return m.localize(ctx, MsgUnknownError)
 	}
 
 	errorMsg := err.Error()
 
 	// Common error patterns and their user-friendly messages
-	errorMappings := map[string]string{
-		"rate limit":           "I'm currently experiencing high demand. Please wait a moment and try again.",
-		"timeout":              "The request took too long to process. Please try again with a shorter message.",
-		"context deadline":     "The request took too long to process. Please try again with a shorter message.",
-		"connection refused":   "I'm temporarily unavailable. Please try again in a few moments.",
-		"service unavailable":  "I'm temporarily unavailable. Please try again in a few moments.",
-		"invalid credentials":  "There's a configuration issue. Please contact support.",
-		"unauthorized":         "There's an authentication issue. Please contact support.",
-		"forbidden":            "Access denied. Please contact support.",
-		"not found":            "The requested resource was not found. Please try again.",
-		"too large":            "Your message is too long. Please try with a shorter message.",
-		"unsupported format":   "The file format you sent is not supported. Please try with a different format.",
-		"file size":            "The file you sent is too large. Please try with a smaller file.",
-		"invalid url":          "The link you provided is not accessible. Please check the link and try again.",
-		"transcription failed": "I couldn't process your audio message. Please try recording it again or send a text message.",
-		"formatting failed":    "I had trouble formatting the response. Here's the raw content: ",
+	errorMappings := map[string]MessageKey{
+		"rate limit":           MsgHighDemand,
+		"timeout":              MsgRequestTooLong,
+		"context deadline":     MsgRequestTooLong,
+		"connection refused":   MsgUnavailable,
+		"service unavailable":  MsgUnavailable,
+		"circuit breaker":      MsgUnavailable,
+		"invalid credentials":  MsgConfigurationIssue,
+		"unauthorized":         MsgAuthenticationIssue,
+		"forbidden":            MsgAccessDenied,
+		"not found":            MsgNotFound,
+		"too large":            MsgMessageTooLong,
+		"unsupported format":   MsgUnsupportedFormat,
+		"file size":            MsgFileTooLarge,
+		"invalid url":          MsgInvalidLink,
+		"transcription failed": MsgTranscriptionFailed,
+		"formatting failed":    MsgFormattingFailed,
 	}
 
 	// Check for known error patterns
 	lowerError := strings.ToLower(errorMsg)
-	for pattern, friendlyMsg := range errorMappings {
+	for pattern, key := range errorMappings {
 		if strings.Contains(lowerError, pattern) {
+			friendlyMsg := m.localize(ctx, key)
 			m.logger.WithFields(logrus.Fields{
 				"original_error": errorMsg,
 				"pattern":        pattern,
@@ -111,7 +165,12 @@ func (m *MessageFormatterService) FormatErrorMessage(ctx context.Context, err er
 
 	// For unknown errors, provide a generic friendly message
 	m.logger.WithField("original_error", errorMsg).Debug("Using generic error message")
-	return "I encountered an unexpected issue. Please try again, and if the problem persists, contact support."
+	return m.localize(ctx, MsgUnexpectedError)
+}
+
+// localize returns the catalog message in the context's locale, falling back to the default locale
+func (m *MessageFormatterService) localize(ctx context.Context, key MessageKey) string {
+	return LocalizedMessage(GetLocale(ctx), m.config.GoogleAgentEngine.DefaultLocale, key)
 }
 
 // ValidateMessageContent validates message content before processing
@@ -127,7 +186,7 @@ func (m *MessageFormatterService) ValidateMessageContent(content string) error {
 	}
 
 	if len(content) > maxLength {
-		return fmt.Errorf("message content exceeds maximum length of %d characters", maxLength)
+		return fmt.Errorf("message content exceeds maximum length of %d bytes", maxLength)
 	}
 
 	// Check for potentially harmful content patterns
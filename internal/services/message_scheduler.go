@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+)
+
+// scheduledMessageBatchSize caps how many due messages are claimed per Redis round trip
+const scheduledMessageBatchSize = 100
+
+// scheduledMessage is a delivery parked in Redis until its scheduled time. Only string headers (correlation
+// ID, signature, trace context) are kept; the body is republished unchanged so its signature stays valid.
+type scheduledMessage struct {
+	Queue       string            `json:"queue"`
+	MessageID   string            `json:"message_id,omitempty"`
+	ContentType string            `json:"content_type,omitempty"`
+	Body        []byte            `json:"body"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Priority    uint8             `json:"priority,omitempty"`
+}
+
+// MessageScheduler holds queue messages with a future scheduled_at in Redis and republishes them to their
+// queue once due. Claims are atomic, so any number of worker replicas can run a scheduler.
+type MessageScheduler struct {
+	config       *config.Config
+	logger       *logrus.Logger
+	redisService *RedisService
+	rabbitMQ     *RabbitMQService
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewMessageScheduler creates a scheduler polling every SCHEDULED_MESSAGES_POLL_INTERVAL, or returns nil
+// when the interval is 0 (scheduled messages are then processed on arrival)
+func NewMessageScheduler(cfg *config.Config, logger *logrus.Logger, redisService *RedisService, rabbitMQ *RabbitMQService) *MessageScheduler {
+	if cfg.RabbitMQ.ScheduledPollInterval <= 0 {
+		return nil
+	}
+	return &MessageScheduler{
+		config:       cfg,
+		logger:       logger,
+		redisService: redisService,
+		rabbitMQ:     rabbitMQ,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+// Schedule parks the delivery until dueAt, after which it is republished to queueName
+func (s *MessageScheduler) Schedule(ctx context.Context, queueName string, delivery amqp.Delivery, dueAt time.Time) error {
+	message := scheduledMessage{
+		Queue:       queueName,
+		MessageID:   delivery.MessageId,
+		ContentType: delivery.ContentType,
+		Body:        delivery.Body,
+		Priority:    delivery.Priority,
+	}
+	for key, value := range delivery.Headers {
+		if str, ok := value.(string); ok {
+			if message.Headers == nil {
+				message.Headers = make(map[string]string)
+			}
+			message.Headers[key] = str
+		}
+	}
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to serialize scheduled message: %w", err)
+	}
+	if err := s.redisService.ScheduleMessage(ctx, string(payload), dueAt); err != nil {
+		return fmt.Errorf("failed to store scheduled message: %w", err)
+	}
+	return nil
+}
+
+// Start publishes due messages every poll interval until Stop is called
+func (s *MessageScheduler) Start() {
+	interval := s.config.RabbitMQ.ScheduledPollInterval
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				if _, err := s.PublishDue(ctx); err != nil {
+					s.logger.WithError(err).Warn("Failed to publish due scheduled messages")
+				}
+				cancel()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+
+	s.logger.WithField("poll_interval", interval).Info("Message scheduler started")
+}
+
+// Stop ends the polling loop and waits for an in-progress pass to finish
+func (s *MessageScheduler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	<-s.done
+}
+
+// PublishDue claims the messages whose time has come and republishes them, returning how many were published.
+// Messages that fail to publish are scheduled again for the next poll.
+func (s *MessageScheduler) PublishDue(ctx context.Context) (int, error) {
+	published := 0
+	for {
+		payloads, err := s.redisService.ClaimDueScheduledMessages(ctx, time.Now(), scheduledMessageBatchSize)
+		if err != nil {
+			return published, err
+		}
+
+		for _, payload := range payloads {
+			var message scheduledMessage
+			if err := json.Unmarshal([]byte(payload), &message); err != nil {
+				s.logger.WithError(err).Error("Dropping unreadable scheduled message")
+				continue
+			}
+
+			if err := s.publish(ctx, message); err != nil {
+				logger := s.logger.WithError(err).WithField("message_id", message.MessageID)
+				if rescheduleErr := s.redisService.ScheduleMessage(ctx, payload, time.Now().Add(s.config.RabbitMQ.ScheduledPollInterval)); rescheduleErr != nil {
+					logger.WithField("reschedule_error", rescheduleErr.Error()).Error("Failed to publish scheduled message and to schedule it again, message lost")
+					continue
+				}
+				logger.Warn("Failed to publish scheduled message, retrying on the next poll")
+				continue
+			}
+			published++
+		}
+
+		if len(payloads) < scheduledMessageBatchSize || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if published > 0 {
+		s.logger.WithField("published", published).Debug("Published due scheduled messages")
+	}
+	return published, nil
+}
+
+// publish republishes a scheduled message to its queue
+func (s *MessageScheduler) publish(ctx context.Context, message scheduledMessage) error {
+	headers := amqp.Table{}
+	for key, value := range message.Headers {
+		headers[key] = value
+	}
+
+	return s.rabbitMQ.PublishRaw(ctx, message.Queue, amqp.Publishing{
+		ContentType:  message.ContentType,
+		Body:         message.Body,
+		DeliveryMode: amqp.Persistent,
+		Priority:     message.Priority,
+		MessageId:    message.MessageID,
+		Timestamp:    time.Now(),
+		Headers:      headers,
+	})
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+)
+
+// MessageSender delivers a processed reply to the user through their messaging channel
+type MessageSender interface {
+	Send(ctx context.Context, userNumber string, messages []string) error
+}
+
+// ProviderWebhookSender delivers replies by posting them to the messaging provider's send endpoint.
+// Each call is a single attempt; the worker logs failures and leaves the stored result for polling.
+type ProviderWebhookSender struct {
+	logger     *logrus.Logger
+	config     *config.Config
+	sendURL    string
+	httpClient *http.Client
+}
+
+// NewProviderWebhookSender creates a sender for the configured provider send URL
+func NewProviderWebhookSender(logger *logrus.Logger, cfg *config.Config) *ProviderWebhookSender {
+	timeout := cfg.Callback.ProviderSendTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &ProviderWebhookSender{
+		logger:     logger,
+		config:     cfg,
+		sendURL:    cfg.Callback.ProviderSendURL,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Send posts the messages, in order, for delivery to the user
+func (s *ProviderWebhookSender) Send(ctx context.Context, userNumber string, messages []string) error {
+	payloadBytes, err := json.Marshal(models.OutboundMessage{
+		UserNumber: userNumber,
+		Messages:   messages,
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to serialize outbound message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.sendURL, bytes.NewReader(payloadBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "EAI-Agent-Gateway/1.0")
+
+	// Lets the provider drop a repeated delivery of the same reply
+	if correlationID := GetCorrelationID(ctx); correlationID != "" {
+		req.Header.Set("X-Correlation-ID", correlationID)
+	}
+
+	// Sign deliveries with the callback secret when HMAC is enabled
+	if s.config.Callback.EnableHMAC && s.config.Callback.HMACSecret != "" {
+		req.Header.Set("X-Signature-SHA256", generateHMACSignature(payloadBytes, s.config.Callback.HMACSecret))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return &HTTPError{
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_number":   userNumber,
+		"message_count": len(messages),
+	}).Debug("Reply sent to provider")
+	return nil
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+)
+
+// SignQueueMessage returns the hex-encoded HMAC-SHA256 signature of a queue message body
+func SignQueueMessage(body []byte, secret string) string {
+	return generateHMACSignature(body, secret)
+}
+
+// VerifyQueueMessageSignature checks the signature header of a delivery against its body
+func VerifyQueueMessageSignature(delivery amqp.Delivery, secret string) error {
+	signature, _ := delivery.Headers[models.SignatureHeader].(string)
+	signature = strings.TrimPrefix(strings.TrimSpace(signature), "sha256=")
+	if signature == "" {
+		return fmt.Errorf("missing %s header", models.SignatureHeader)
+	}
+
+	provided, err := hex.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("malformed %s header: %w", models.SignatureHeader, err)
+	}
+	expected, _ := hex.DecodeString(SignQueueMessage(delivery.Body, secret))
+	if !hmac.Equal(provided, expected) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+// signPublishing adds the body's signature header when QUEUE_SIGNATURE_SECRET is set
+func (r *RabbitMQService) signPublishing(publishing *amqp.Publishing) {
+	secret := r.config.Security.QueueSignatureSecret
+	if secret == "" {
+		return
+	}
+	if publishing.Headers == nil {
+		publishing.Headers = amqp.Table{}
+	}
+	publishing.Headers[models.SignatureHeader] = SignQueueMessage(publishing.Body, secret)
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	pubsub "google.golang.org/api/pubsub/v1"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+)
+
+// MessageSink receives a copy of every successfully processed message, e.g. for analytics
+type MessageSink interface {
+	Publish(ctx context.Context, data models.ProcessedMessageData) error
+}
+
+// NoopMessageSink discards processed messages; it is used when no export is configured
+type NoopMessageSink struct{}
+
+// Publish does nothing
+func (NoopMessageSink) Publish(context.Context, models.ProcessedMessageData) error {
+	return nil
+}
+
+// NewMessageSink returns the Pub/Sub sink when MESSAGE_EXPORT_PUBSUB_TOPIC is set, or a no-op sink otherwise
+func NewMessageSink(cfg *config.Config, logger *logrus.Logger, otelService *OTelService) (MessageSink, error) {
+	if cfg.Callback.MessageExportTopic == "" {
+		return NoopMessageSink{}, nil
+	}
+	return NewPubSubMessageSink(cfg, logger, otelService)
+}
+
+// PubSubMessageSink publishes processed messages as JSON to a Google Cloud Pub/Sub topic
+type PubSubMessageSink struct {
+	logger      *logrus.Logger
+	service     *pubsub.Service
+	topic       string
+	timeout     time.Duration
+	otelService *OTelService // Optional; meters publishes as external API calls
+
+	published atomic.Int64
+	failed    atomic.Int64
+}
+
+// NewPubSubMessageSink creates a sink for the configured topic, authenticating with SERVICE_ACCOUNT when
+// set and Application Default Credentials otherwise
+func NewPubSubMessageSink(cfg *config.Config, logger *logrus.Logger, otelService *OTelService) (*PubSubMessageSink, error) {
+	var opts []option.ClientOption
+	if cfg.GoogleCloud.ServiceAccount != "" {
+		creds, err := decodeServiceAccount(cfg.GoogleCloud.ServiceAccount)
+		if err != nil {
+			return nil, fmt.Errorf("decoding SERVICE_ACCOUNT: %w", err)
+		}
+		opts = append(opts, option.WithCredentialsJSON(creds))
+	}
+
+	service, err := pubsub.NewService(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Pub/Sub client: %w", err)
+	}
+
+	timeout := cfg.Callback.MessageExportTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	topic := pubSubTopicName(cfg.Callback.MessageExportTopic, cfg.GoogleCloud.ProjectID)
+	logger.WithField("topic", topic).Info("Processed message export to Pub/Sub enabled")
+
+	return &PubSubMessageSink{
+		logger:      logger,
+		service:     service,
+		topic:       topic,
+		timeout:     timeout,
+		otelService: otelService,
+	}, nil
+}
+
+// pubSubTopicName expands a short topic ID to "projects/<project>/topics/<topic>"
+func pubSubTopicName(topic, projectID string) string {
+	if strings.HasPrefix(topic, "projects/") {
+		return topic
+	}
+	return fmt.Sprintf("projects/%s/topics/%s", projectID, topic)
+}
+
+// Publish sends the processed message to the topic, tagging it with its message and correlation IDs
+func (s *PubSubMessageSink) Publish(ctx context.Context, data models.ProcessedMessageData) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		s.failed.Add(1)
+		return fmt.Errorf("failed to serialize processed message: %w", err)
+	}
+
+	request := &pubsub.PublishRequest{
+		Messages: []*pubsub.PubsubMessage{{
+			Data: base64.StdEncoding.EncodeToString(payload),
+			Attributes: map[string]string{
+				"message_id":     data.MessageID,
+				"correlation_id": data.CorrelationID,
+				"schema_version": fmt.Sprintf("%d", data.SchemaVersion),
+			},
+		}},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	publish := func(ctx context.Context) (int, error) {
+		response, err := s.service.Projects.Topics.Publish(s.topic, request).Context(ctx).Do()
+		if err != nil {
+			if apiErr, ok := err.(*googleapi.Error); ok {
+				return apiErr.Code, err
+			}
+			return 0, err
+		}
+		return response.HTTPStatusCode, nil
+	}
+
+	if s.otelService != nil {
+		err = s.otelService.TraceExternalAPICall(ctx, "pubsub", s.topic, "POST", publish)
+	} else {
+		_, err = publish(ctx)
+	}
+	if err != nil {
+		s.failed.Add(1)
+		return fmt.Errorf("failed to publish processed message to %s: %w", s.topic, err)
+	}
+
+	s.published.Add(1)
+	return nil
+}
+
+// Stats returns how many processed messages were published and how many failed
+func (s *PubSubMessageSink) Stats() (published, failed int64) {
+	return s.published.Load(), s.failed.Load()
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+)
+
+// ModerationDecision is a moderator's verdict on a piece of content
+type ModerationDecision struct {
+	Blocked bool
+	Reason  string // Policy that blocked the content, recorded in the result as moderation_reason
+}
+
+// Moderator screens the messages users send and the responses the agent produces against a moderation policy
+type Moderator interface {
+	// CheckInput screens the user's message before it is sent to the agent
+	CheckInput(ctx context.Context, content string) (ModerationDecision, error)
+	// CheckOutput screens the response text before it is returned to the user
+	CheckOutput(ctx context.Context, content string) (ModerationDecision, error)
+}
+
+// NoopModerator allows all content
+type NoopModerator struct{}
+
+// CheckInput allows the message
+func (NoopModerator) CheckInput(ctx context.Context, content string) (ModerationDecision, error) {
+	return ModerationDecision{}, nil
+}
+
+// CheckOutput allows the response
+func (NoopModerator) CheckOutput(ctx context.Context, content string) (ModerationDecision, error) {
+	return ModerationDecision{}, nil
+}
+
+// Reasons recorded by RegexModerator
+const (
+	ModerationReasonInputPattern  = "input_pattern"  // The message matched MODERATION_INPUT_PATTERN
+	ModerationReasonOutputPattern = "output_pattern" // The response matched MODERATION_OUTPUT_PATTERN
+)
+
+// RegexModerator blocks content matching the configured patterns
+type RegexModerator struct {
+	input  *regexp.Regexp
+	output *regexp.Regexp
+}
+
+// NewModerator creates the moderator for MODERATION_INPUT_PATTERN and MODERATION_OUTPUT_PATTERN, or a
+// NoopModerator when neither is set
+func NewModerator(cfg *config.Config, logger *logrus.Logger) (Moderator, error) {
+	inputPattern := cfg.Security.ModerationInputPattern
+	outputPattern := cfg.Security.ModerationOutputPattern
+	if inputPattern == "" && outputPattern == "" {
+		return NoopModerator{}, nil
+	}
+
+	moderator := &RegexModerator{}
+	var err error
+	if inputPattern != "" {
+		if moderator.input, err = regexp.Compile(inputPattern); err != nil {
+			return nil, fmt.Errorf("invalid moderation input pattern: %w", err)
+		}
+	}
+	if outputPattern != "" {
+		if moderator.output, err = regexp.Compile(outputPattern); err != nil {
+			return nil, fmt.Errorf("invalid moderation output pattern: %w", err)
+		}
+	}
+
+	logger.WithFields(logrus.Fields{
+		"screens_input":  moderator.input != nil,
+		"screens_output": moderator.output != nil,
+	}).Info("Content moderation enabled")
+
+	return moderator, nil
+}
+
+// CheckInput blocks messages matching the input pattern
+func (m *RegexModerator) CheckInput(ctx context.Context, content string) (ModerationDecision, error) {
+	if m.input != nil && m.input.MatchString(content) {
+		return ModerationDecision{Blocked: true, Reason: ModerationReasonInputPattern}, nil
+	}
+	return ModerationDecision{}, nil
+}
+
+// CheckOutput blocks responses matching the output pattern
+func (m *RegexModerator) CheckOutput(ctx context.Context, content string) (ModerationDecision, error) {
+	if m.output != nil && m.output.MatchString(content) {
+		return ModerationDecision{Blocked: true, Reason: ModerationReasonOutputPattern}, nil
+	}
+	return ModerationDecision{}, nil
+}
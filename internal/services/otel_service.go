@@ -43,6 +43,8 @@ type OTelService struct {
 
 	externalAPICalls    metric.Int64Counter
 	externalAPIDuration metric.Float64Histogram
+
+	rabbitMQReconnects metric.Int64Counter
 }
 
 // OTelConfig configures OpenTelemetry service
@@ -270,6 +272,15 @@ func (s *OTelService) initMetricInstruments() error {
 		return fmt.Errorf("failed to create external API duration histogram: %w", err)
 	}
 
+	// Connection metrics
+	s.rabbitMQReconnects, err = s.meter.Int64Counter(
+		"rabbitmq_reconnects_total",
+		metric.WithDescription("Total number of RabbitMQ reconnection attempts by result"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create RabbitMQ reconnects counter: %w", err)
+	}
+
 	return nil
 }
 
@@ -391,8 +402,10 @@ func (s *OTelService) TraceWorkerTask(ctx context.Context, workerType, taskType
 
 	// Determine status
 	status := "success"
+	errorType := "none"
 	if err != nil {
 		status = "failure"
+		errorType = ErrorCategory(err)
 		span.RecordError(err)
 		span.SetAttributes(attribute.String("error", "true"))
 	}
@@ -402,6 +415,7 @@ func (s *OTelService) TraceWorkerTask(ctx context.Context, workerType, taskType
 		attribute.String("worker_type", workerType),
 		attribute.String("task_type", taskType),
 		attribute.String("status", status),
+		attribute.String("error_type", errorType),
 	))
 
 	s.workerTaskDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(
@@ -569,3 +583,8 @@ func (s *OTelService) UpdateCacheHitRatio(ctx context.Context, cacheType string,
 		attribute.String("cache_type", cacheType),
 	))
 }
+
+// RecordRabbitMQReconnect counts a reconnection attempt; result is "success", "failure" or "exhausted"
+func (s *OTelService) RecordRabbitMQReconnect(ctx context.Context, result string) {
+	s.rabbitMQReconnects.Add(ctx, 1, metric.WithAttributes(attribute.String("result", result)))
+}
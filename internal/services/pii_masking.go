@@ -0,0 +1,58 @@
+package services
+
+import (
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+)
+
+// Placeholders substituted for personal data by MaskPII
+const (
+	MaskedEmail = "[EMAIL]"
+	MaskedCPF   = "[CPF]"
+	MaskedCNPJ  = "[CNPJ]"
+	MaskedPhone = "[PHONE]"
+)
+
+// minMaskedPhoneDigits is the fewest digits in a run of numbers masked as a phone number (a local number
+// without area code); shorter runs such as amounts, years or protocol fragments are kept
+const minMaskedPhoneDigits = 8
+
+var (
+	piiEmailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	piiCNPJPattern  = regexp.MustCompile(`\b\d{2}\.?\d{3}\.?\d{3}/?\d{4}-?\d{2}\b`)
+	piiCPFPattern   = regexp.MustCompile(`\b\d{3}\.?\d{3}\.?\d{3}-?\d{2}\b`)
+	piiPhonePattern = regexp.MustCompile(`\+?\(?\d[\d\s().-]*\d`)
+)
+
+// MaskPII replaces email addresses, CNPJ and CPF numbers (formatted or not) and phone numbers in text with
+// placeholders. It errs on the side of masking: any run of at least 8 digits, separators allowed, is treated
+// as a phone number.
+func MaskPII(text string) string {
+	text = piiEmailPattern.ReplaceAllString(text, MaskedEmail)
+	text = piiCNPJPattern.ReplaceAllString(text, MaskedCNPJ)
+	text = piiCPFPattern.ReplaceAllString(text, MaskedCPF)
+	return piiPhonePattern.ReplaceAllStringFunc(text, func(match string) string {
+		digits := 0
+		for _, r := range match {
+			if r >= '0' && r <= '9' {
+				digits++
+			}
+		}
+		if digits < minMaskedPhoneDigits {
+			return match
+		}
+		return MaskedPhone
+	})
+}
+
+// LogMessageBody logs a message body with MaskPII applied, only when LOG_MESSAGE_BODIES is enabled and
+// the logger is at debug level. The unmasked text is never logged.
+func LogMessageBody(cfg *config.Config, logger *logrus.Entry, field string, body string, msg string) {
+	if cfg == nil || !cfg.Observability.LogMessageBodies || !logger.Logger.IsLevelEnabled(logrus.DebugLevel) {
+		return
+	}
+	logger.WithField(field, MaskPII(body)).Debug(msg)
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+)
+
+func TestMaskPII(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		// CPF
+		{"formatted CPF", "Meu CPF é 123.456.789-09", "Meu CPF é [CPF]"},
+		{"unformatted CPF", "cpf 12345678909, obrigado", "cpf [CPF], obrigado"},
+		{"CPF without check digit dash", "123.456.78909", "[CPF]"},
+
+		// CNPJ
+		{"formatted CNPJ", "CNPJ 12.345.678/0001-95 da empresa", "CNPJ [CNPJ] da empresa"},
+		{"unformatted CNPJ", "12345678000195", "[CNPJ]"},
+
+		// Email
+		{"email", "escreva para maria.silva+rio@prefeitura.rio.gov.br hoje", "escreva para [EMAIL] hoje"},
+		{"email in brackets", "<joao_1@exemplo.com>", "<[EMAIL]>"},
+
+		// Phone
+		{"international phone", "ligue +55 21 99999-9999", "ligue [PHONE]"},
+		{"phone with area code", "(21) 3456-7890", "[PHONE]"},
+		{"local phone", "tel 3456-7890.", "tel [PHONE]."},
+
+		// Several kinds in one message
+		{"mixed", "CPF 123.456.789-09, email a@b.com, cel (21) 98888-7777",
+			"CPF [CPF], email [EMAIL], cel [PHONE]"},
+
+		// Near misses that must be kept
+		{"money", "A taxa é R$ 1.234,56", "A taxa é R$ 1.234,56"},
+		{"date", "vence em 12/05/2025", "vence em 12/05/2025"},
+		{"year and time", "em 2025 às 14:30", "em 2025 às 14:30"},
+		{"short protocol", "protocolo 1234567", "protocolo 1234567"},
+		{"email without domain", "usuario@localhost", "usuario@localhost"},
+		{"at sign in text", "nos vemos @ praça", "nos vemos @ praça"},
+		{"plain text", "Qual o horário da clínica?", "Qual o horário da clínica?"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaskPII(tt.text); got != tt.want {
+				t.Errorf("MaskPII(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogMessageBody(t *testing.T) {
+	body := "meu cpf é 123.456.789-09"
+	tests := []struct {
+		name     string
+		enabled  bool
+		level    logrus.Level
+		wantLogs int
+	}{
+		{"enabled at debug", true, logrus.DebugLevel, 1},
+		{"enabled at info", true, logrus.InfoLevel, 0},
+		{"disabled", false, logrus.DebugLevel, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, hook := test.NewNullLogger()
+			logger.SetLevel(tt.level)
+			cfg := &config.Config{}
+			cfg.Observability.LogMessageBodies = tt.enabled
+
+			LogMessageBody(cfg, logrus.NewEntry(logger), "message", body, "Received message")
+
+			entries := hook.AllEntries()
+			if len(entries) != tt.wantLogs {
+				t.Fatalf("logged %d entries, want %d", len(entries), tt.wantLogs)
+			}
+			for _, entry := range entries {
+				if got := entry.Data["message"]; got != "meu cpf é [CPF]" {
+					t.Errorf("logged message = %q, want it masked", got)
+				}
+			}
+		})
+	}
+}
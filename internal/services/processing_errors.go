@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message processing error categories; match them with errors.Is
+var (
+	ErrValidation          = errors.New("validation error")
+	ErrUnsupportedProvider = errors.New("unsupported provider")
+	ErrAgentUnavailable    = errors.New("agent unavailable")
+	ErrAgentTimeout        = errors.New("agent timeout")
+	ErrAgentRateLimited    = errors.New("agent rate limited")
+	ErrResponseParse       = errors.New("response parse error")
+	ErrInternal            = errors.New("internal error")
+)
+
+// errorCategoryNames are the labels reported in logs, spans and metrics for each category
+var errorCategoryNames = []struct {
+	category error
+	name     string
+}{
+	{ErrValidation, "validation"},
+	{ErrUnsupportedProvider, "unsupported_provider"},
+	{ErrAgentTimeout, "agent_timeout"},
+	{ErrAgentRateLimited, "agent_rate_limited"},
+	{ErrAgentUnavailable, "agent_unavailable"},
+	{ErrResponseParse, "response_parse"},
+	{ErrInternal, "internal"},
+}
+
+// ProcessingError is a message processing failure tagged with its category. Its message is the
+// human-readable description; errors.Is matches both the category and the underlying cause.
+type ProcessingError struct {
+	Category error
+	Message  string
+	Err      error
+}
+
+// NewProcessingError creates a categorized processing error wrapping an optional cause
+func NewProcessingError(category error, message string, err error) *ProcessingError {
+	return &ProcessingError{Category: category, Message: message, Err: err}
+}
+
+// NewAgentCallError categorizes a failed agent call as a timeout, a rate limit or the agent being unavailable
+func NewAgentCallError(message string, err error) *ProcessingError {
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return NewProcessingError(ErrAgentRateLimited, message, err)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return NewProcessingError(ErrAgentTimeout, message, err)
+	}
+	return NewProcessingError(ErrAgentUnavailable, message, err)
+}
+
+// Error returns the message followed by the cause, if any
+func (e *ProcessingError) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+// Unwrap exposes both the category and the cause to errors.Is and errors.As
+func (e *ProcessingError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Category}
+	}
+	return []error{e.Category, e.Err}
+}
+
+// ErrorCategory returns the category label of a processing error, or "unknown" for uncategorized errors
+func ErrorCategory(err error) string {
+	for _, c := range errorCategoryNames {
+		if errors.Is(err, c.category) {
+			return c.name
+		}
+	}
+	return "unknown"
+}
+
+// RateLimitError is returned when the agent backend rejects a call with 429 Too Many Requests.
+// RetryAfter holds the server's Retry-After hint (0 when absent).
+type RateLimitError struct {
+	RetryAfter time.Duration
+	Body       string
+}
+
+// Error describes the rate limit and its retry hint
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limited (retry after %s): %s", e.RetryAfter, e.Body)
+	}
+	return "rate limited: " + e.Body
+}
+
+// newRateLimitError builds a RateLimitError from a 429 response's Retry-After header
+func newRateLimitError(header http.Header, body []byte) *RateLimitError {
+	return &RateLimitError{
+		RetryAfter: parseRetryAfter(header.Get("Retry-After"), time.Now()),
+		Body:       string(body),
+	}
+}
+
+// parseRetryAfter parses a Retry-After value given in seconds or as an HTTP date; invalid or past values yield 0
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// RejectError asks the consumer to dead-letter the message right away, without retries, because
+// redelivering it can never succeed (e.g. a bad signature)
+type RejectError struct {
+	Err error
+}
+
+// NewRejectError wraps err as an immediate rejection
+func NewRejectError(err error) *RejectError {
+	return &RejectError{Err: err}
+}
+
+// Error returns the underlying error
+func (e *RejectError) Error() string {
+	return "rejected: " + e.Err.Error()
+}
+
+// Unwrap exposes the underlying error
+func (e *RejectError) Unwrap() error {
+	return e.Err
+}
+
+// RetryLaterError asks the consumer to requeue the message after Delay instead of acknowledging it
+// or scheduling a counted retry
+type RetryLaterError struct {
+	Delay time.Duration
+	Err   error
+}
+
+// NewRetryLaterError wraps err as a deferred retry after delay
+func NewRetryLaterError(delay time.Duration, err error) *RetryLaterError {
+	return &RetryLaterError{Delay: delay, Err: err}
+}
+
+// Error returns the underlying error with the retry delay
+func (e *RetryLaterError) Error() string {
+	return fmt.Sprintf("retry after %s: %v", e.Delay, e.Err)
+}
+
+// Unwrap exposes the underlying error
+func (e *RetryLaterError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+)
+
+// ProviderWebhookNotifier posts processing events to the messaging provider's webhook so it can show
+// a typing indicator or read receipt. Events are best effort: a single attempt with a short timeout.
+type ProviderWebhookNotifier struct {
+	logger     *logrus.Logger
+	config     *config.Config
+	webhookURL string
+	httpClient *http.Client
+}
+
+// NewProviderWebhookNotifier creates a notifier for the configured provider webhook URL
+func NewProviderWebhookNotifier(logger *logrus.Logger, cfg *config.Config) *ProviderWebhookNotifier {
+	timeout := cfg.Callback.ProviderNotifyTimeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+
+	return &ProviderWebhookNotifier{
+		logger:     logger,
+		config:     cfg,
+		webhookURL: cfg.Callback.ProviderNotifyURL,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// MarkProcessing tells the provider that the user's message is being processed
+func (n *ProviderWebhookNotifier) MarkProcessing(ctx context.Context, userNumber string) error {
+	payloadBytes, err := json.Marshal(models.ProviderEvent{
+		UserNumber: userNumber,
+		Event:      "processing",
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to serialize provider event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhookURL, bytes.NewReader(payloadBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "EAI-Agent-Gateway/1.0")
+
+	// Sign events with the callback secret when HMAC is enabled
+	if n.config.Callback.EnableHMAC && n.config.Callback.HMACSecret != "" {
+		req.Header.Set("X-Signature-SHA256", generateHMACSignature(payloadBytes, n.config.Callback.HMACSecret))
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return &HTTPError{
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+		}
+	}
+
+	n.logger.WithField("user_number", userNumber).Debug("Provider notified that processing started")
+	return nil
+}
@@ -5,12 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/sirupsen/logrus"
 
 	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
 )
 
 // CircuitState represents the state of the circuit breaker
@@ -57,6 +59,12 @@ type RabbitMQService struct {
 	notifyReconnect chan bool
 	isShutdown      bool
 
+	// Reconnection outcome reporting
+	otelService       *OTelService // Optional; counts reconnection attempts
+	reconnectFailed   chan error   // Receives once when reconnection attempts are exhausted
+	reconnects        atomic.Int64
+	reconnectFailures atomic.Int64
+
 	// Circuit breaker state
 	circuitState     CircuitState
 	circuitMutex     sync.RWMutex
@@ -67,6 +75,9 @@ type RabbitMQService struct {
 	// Circuit breaker configuration
 	failureThreshold int           // Number of failures before opening circuit
 	resetTimeout     time.Duration // Time to wait before trying again (half-open)
+
+	// Channel-wide prefetch limit shared by all consumers (0 = per-consumer QoS only)
+	channelPrefetch int
 }
 
 // MessagePublisher defines the interface for publishing messages
@@ -101,6 +112,7 @@ func NewRabbitMQService(cfg *config.Config, logger *logrus.Logger) (*RabbitMQSer
 		config:          cfg,
 		logger:          logger,
 		notifyReconnect: make(chan bool),
+		reconnectFailed: make(chan error, 1),
 
 		// Circuit breaker defaults
 		circuitState:     CircuitClosed,
@@ -360,12 +372,21 @@ func (r *RabbitMQService) connect() error {
 	}
 
 	// Set QoS for fair dispatch
-	if err := ch.Qos(1, 0, false); err != nil {
+	if err := ch.Qos(r.config.GetPrefetchCount(), 0, false); err != nil {
 		_ = ch.Close()
 		_ = conn.Close()
 		return fmt.Errorf("failed to set QoS: %w", err)
 	}
 
+	// Reapply the channel-wide prefetch limit after reconnection
+	if r.channelPrefetch > 0 {
+		if err := ch.Qos(r.channelPrefetch, 0, true); err != nil {
+			_ = ch.Close()
+			_ = conn.Close()
+			return fmt.Errorf("failed to set channel prefetch: %w", err)
+		}
+	}
+
 	r.connection = conn
 	r.channel = ch
 	r.isConnected = true
@@ -404,6 +425,22 @@ func (r *RabbitMQService) connect() error {
 	return nil
 }
 
+// SetChannelPrefetch limits the number of unacknowledged deliveries across all consumers on the channel
+func (r *RabbitMQService) SetChannelPrefetch(prefetch int) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.channelPrefetch = prefetch
+	if !r.isConnected {
+		return nil // Applied on the next connection
+	}
+
+	if err := r.channel.Qos(prefetch, 0, true); err != nil {
+		return fmt.Errorf("failed to set channel prefetch: %w", err)
+	}
+	return nil
+}
+
 // setupTopology declares exchanges, queues, and bindings based on configuration
 func (r *RabbitMQService) setupTopology() error {
 	// Declare main exchange
@@ -432,21 +469,43 @@ func (r *RabbitMQService) setupTopology() error {
 		return fmt.Errorf("failed to declare DLX exchange %s: %w", r.config.RabbitMQ.DLXExchange, err)
 	}
 
+	// User message queues deliver higher-priority messages first, when priorities are enabled
+	var queueArgs amqp.Table
+	if r.config.RabbitMQ.MaxPriority > 0 {
+		queueArgs = amqp.Table{"x-max-priority": r.config.RabbitMQ.MaxPriority}
+	}
+
 	// Declare user messages queue
-	if err := r.declareQueueWithDLX(r.config.RabbitMQ.UserQueue); err != nil {
+	if err := r.declareQueueWithDLX(r.config.RabbitMQ.UserQueue, queueArgs); err != nil {
 		return fmt.Errorf("failed to declare user queue: %w", err)
 	}
 
 	// Declare user messages queue
-	if err := r.declareQueueWithDLX(r.config.RabbitMQ.UserMessagesQueue); err != nil {
+	if err := r.declareQueueWithDLX(r.config.RabbitMQ.UserMessagesQueue, queueArgs); err != nil {
 		return fmt.Errorf("failed to declare user messages queue: %w", err)
 	}
 
+	// Declare user message shard queues, when messages are sharded by user
+	shardArgs := amqp.Table{}
+	for key, value := range queueArgs {
+		shardArgs[key] = value
+	}
+	if r.config.RabbitMQ.ShardSingleActiveConsumer {
+		shardArgs["x-single-active-consumer"] = true
+	}
+	shardQueues := r.config.GetUserMessageShardQueues()
+	for _, queue := range shardQueues {
+		if err := r.declareQueueWithDLX(queue, shardArgs); err != nil {
+			return fmt.Errorf("failed to declare user message shard queue %s: %w", queue, err)
+		}
+	}
+
 	// Declare dead letter queues
 	queues := []string{
 		r.config.RabbitMQ.UserQueue,
 		r.config.RabbitMQ.UserMessagesQueue,
 	}
+	queues = append(queues, shardQueues...)
 
 	for _, queue := range queues {
 		if err := r.declareDLQ(queue + "_dlq"); err != nil {
@@ -458,13 +517,16 @@ func (r *RabbitMQService) setupTopology() error {
 	return nil
 }
 
-// declareQueueWithDLX declares a queue with dead letter exchange configuration
-func (r *RabbitMQService) declareQueueWithDLX(queueName string) error {
+// declareQueueWithDLX declares a queue with dead letter exchange configuration and any extra arguments
+func (r *RabbitMQService) declareQueueWithDLX(queueName string, extraArgs amqp.Table) error {
 	args := amqp.Table{
 		"x-dead-letter-exchange":    r.config.RabbitMQ.DLXExchange,
 		"x-dead-letter-routing-key": queueName + "_dlq",
 		"x-message-ttl":             300000, // 5 minutes TTL
 	}
+	for key, value := range extraArgs {
+		args[key] = value
+	}
 
 	_, err := r.channel.QueueDeclare(
 		queueName, // name
@@ -529,10 +591,13 @@ func (r *RabbitMQService) PublishMessage(ctx context.Context, queueName string,
 		ContentType:  "application/json",
 		Body:         body,
 		DeliveryMode: amqp.Persistent, // Make message persistent
+		Priority:     messagePriority(message),
 		Timestamp:    time.Now(),
 		MessageId:    fmt.Sprintf("%d", time.Now().UnixNano()),
 	}
 
+	r.signPublishing(&publishing)
+
 	// Try to use channel pool for better concurrency
 	if r.channelPool != nil {
 		err = r.channelPool.PublishWithPool(ctx, r.config.RabbitMQ.Exchange, queueName, publishing)
@@ -590,6 +655,63 @@ func (r *RabbitMQService) PublishMessage(ctx context.Context, queueName string,
 	return nil
 }
 
+// PublishRaw publishes a prepared AMQP publishing as-is, e.g. to replay a dead-lettered delivery
+func (r *RabbitMQService) PublishRaw(ctx context.Context, queueName string, publishing amqp.Publishing) error {
+	if err := r.checkCircuitBreaker(); err != nil {
+		return err
+	}
+
+	var err error
+	if r.channelPool != nil {
+		err = r.channelPool.PublishWithPool(ctx, r.config.RabbitMQ.Exchange, queueName, publishing)
+	} else {
+		r.mutex.Lock()
+		if !r.isConnected {
+			err = fmt.Errorf("RabbitMQ connection is not available")
+		} else {
+			err = r.channel.PublishWithContext(ctx, r.config.RabbitMQ.Exchange, queueName, false, false, publishing)
+		}
+		r.mutex.Unlock()
+	}
+
+	if err != nil {
+		r.recordFailure()
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	r.recordSuccess()
+	return nil
+}
+
+// OpenChannel opens a dedicated channel on the current connection; the caller must close it
+func (r *RabbitMQService) OpenChannel() (*amqp.Channel, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.isConnected {
+		return nil, fmt.Errorf("RabbitMQ connection is not available")
+	}
+
+	ch, err := r.connection.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+	return ch, nil
+}
+
+// messagePriority returns the AMQP priority a message is published with: a queue message's own priority,
+// otherwise 0
+func messagePriority(message interface{}) uint8 {
+	switch m := message.(type) {
+	case models.QueueMessage:
+		return m.Priority
+	case *models.QueueMessage:
+		return m.Priority
+	default:
+		return 0
+	}
+}
+
 // PublishMessageWithHeaders publishes a message with custom headers (for trace context)
 func (r *RabbitMQService) PublishMessageWithHeaders(ctx context.Context, queueName string, message interface{}, headers map[string]interface{}) error {
 	// Check circuit breaker first (fast fail)
@@ -613,11 +735,14 @@ func (r *RabbitMQService) PublishMessageWithHeaders(ctx context.Context, queueNa
 		ContentType:  "application/json",
 		Body:         body,
 		DeliveryMode: amqp.Persistent,
+		Priority:     messagePriority(message),
 		Timestamp:    time.Now(),
 		MessageId:    fmt.Sprintf("%d", time.Now().UnixNano()),
 		Headers:      amqpHeaders,
 	}
 
+	r.signPublishing(&publishing)
+
 	// Try to use channel pool for better concurrency
 	if r.channelPool != nil {
 		err = r.channelPool.PublishWithPool(ctx, r.config.RabbitMQ.Exchange, queueName, publishing)
@@ -705,6 +830,8 @@ func (r *RabbitMQService) PublishMessageWithDelay(ctx context.Context, queueName
 		Headers:      headers,
 	}
 
+	r.signPublishing(&publishing)
+
 	// Try to use channel pool for better concurrency
 	if r.channelPool != nil {
 		err = r.channelPool.PublishWithPool(ctx, r.config.RabbitMQ.Exchange, queueName, publishing)
@@ -765,6 +892,8 @@ func (r *RabbitMQService) PublishPriorityMessage(ctx context.Context, queueName
 		MessageId:    fmt.Sprintf("%d", time.Now().UnixNano()),
 	}
 
+	r.signPublishing(&publishing)
+
 	// Try to use channel pool for better concurrency
 	if r.channelPool != nil {
 		err = r.channelPool.PublishWithPool(ctx, r.config.RabbitMQ.Exchange, queueName, publishing)
@@ -815,7 +944,9 @@ func (r *RabbitMQService) handleReconnect() {
 			r.mutex.Lock()
 			r.isConnected = false
 			r.mutex.Unlock()
-			r.reconnect()
+			if !r.reconnect() {
+				return
+			}
 
 		case err := <-r.notifyChanClose:
 			if r.isShutdown {
@@ -825,49 +956,92 @@ func (r *RabbitMQService) handleReconnect() {
 			r.mutex.Lock()
 			r.isConnected = false
 			r.mutex.Unlock()
-			r.reconnect()
+			if !r.reconnect() {
+				return
+			}
 
 		case <-r.notifyReconnect:
 			if r.isShutdown {
 				return
 			}
 			r.logger.Info("Manual reconnection requested")
-			r.reconnect()
+			if !r.reconnect() {
+				return
+			}
 		}
 	}
 }
 
-// reconnect attempts to reconnect to RabbitMQ with exponential backoff
-func (r *RabbitMQService) reconnect() {
-	retryCount := 0
-	maxRetries := r.config.RabbitMQ.MaxRetries
+// reconnect attempts to reconnect to RabbitMQ with exponential backoff (RABBITMQ_RECONNECT_*). When the
+// attempts are exhausted the failure is reported on ReconnectFailed and monitoring stops.
+func (r *RabbitMQService) reconnect() bool {
+	maxAttempts := r.config.RabbitMQ.ReconnectMaxAttempts
+	startedAt := time.Now()
 
-	for retryCount < maxRetries {
+	var lastErr error
+	for attempt := 0; maxAttempts <= 0 || attempt < maxAttempts; attempt++ {
 		if r.isShutdown {
-			return
+			return false
 		}
 
-		// Exponential backoff
-		delay := time.Duration(retryCount*retryCount+1) * time.Second
+		delay := r.config.GetReconnectDelay(attempt)
 		r.logger.WithFields(logrus.Fields{
-			"retry_count": retryCount + 1,
-			"max_retries": maxRetries,
-			"delay":       delay,
-		}).Info("Attempting to reconnect to RabbitMQ")
+			"attempt":      attempt + 1,
+			"max_attempts": maxAttempts,
+			"delay":        delay.String(),
+		}).Warn("Attempting to reconnect to RabbitMQ")
 
 		time.Sleep(delay)
 
-		if err := r.connect(); err != nil {
-			r.logger.WithError(err).WithField("retry_count", retryCount+1).Error("Failed to reconnect to RabbitMQ")
-			retryCount++
+		if lastErr = r.connect(); lastErr != nil {
+			r.reconnectFailures.Add(1)
+			r.recordReconnect("failure")
+			r.logger.WithError(lastErr).WithField("attempt", attempt+1).Error("Failed to reconnect to RabbitMQ")
 			continue
 		}
 
-		r.logger.Info("Successfully reconnected to RabbitMQ")
-		return
+		r.reconnects.Add(1)
+		r.recordReconnect("success")
+		r.logger.WithFields(logrus.Fields{
+			"attempts": attempt + 1,
+			"downtime": time.Since(startedAt).String(),
+		}).Info("Successfully reconnected to RabbitMQ")
+		return true
+	}
+
+	r.recordReconnect("exhausted")
+	r.logger.WithField("max_attempts", maxAttempts).Error("Failed to reconnect to RabbitMQ after maximum attempts")
+	select {
+	case r.reconnectFailed <- fmt.Errorf("failed to reconnect to RabbitMQ after %d attempts: %w", maxAttempts, lastErr):
+	default:
+	}
+	return false
+}
+
+// recordReconnect counts a reconnection attempt in OpenTelemetry when configured
+func (r *RabbitMQService) recordReconnect(result string) {
+	if r.otelService != nil {
+		r.otelService.RecordRabbitMQReconnect(context.Background(), result)
 	}
+}
 
-	r.logger.WithField("max_retries", maxRetries).Error("Failed to reconnect to RabbitMQ after maximum retries")
+// SetOTelService enables reconnection metrics
+func (r *RabbitMQService) SetOTelService(otelService *OTelService) {
+	r.otelService = otelService
+}
+
+// ReconnectFailed receives an error once reconnection attempts are exhausted; the process should exit so
+// orchestration can restart it
+func (r *RabbitMQService) ReconnectFailed() <-chan error {
+	return r.reconnectFailed
+}
+
+// GetReconnectStats returns the number of successful reconnections and failed attempts
+func (r *RabbitMQService) GetReconnectStats() map[string]interface{} {
+	return map[string]interface{}{
+		"reconnects":         r.reconnects.Load(),
+		"reconnect_failures": r.reconnectFailures.Load(),
+	}
 }
 
 // HealthCheck implements the HealthChecker interface
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+)
+
+// Pinger checks whether a backing store is reachable
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// RedisOutageGuard pauses message consumption during a sustained Redis outage. Handlers report the outcome
+// of their result/status writes; after REDIS_OUTAGE_PAUSE_THRESHOLD consecutive failures the guard trips
+// and consumers stop fetching until Redis answers a ping again.
+type RedisOutageGuard struct {
+	pinger          Pinger
+	logger          *logrus.Logger
+	threshold       int64
+	recheckInterval time.Duration
+
+	consecutiveFailures atomic.Int64
+	paused              atomic.Bool
+	pausedAt            time.Time
+	pauses              atomic.Int64
+	mutex               sync.Mutex // Serializes pausing/resuming and the recovery ping
+}
+
+// NewRedisOutageGuard returns a guard for the configured threshold, or nil when pausing is disabled
+func NewRedisOutageGuard(cfg *config.Config, logger *logrus.Logger, pinger Pinger) *RedisOutageGuard {
+	if cfg.Redis.OutagePauseThreshold <= 0 {
+		return nil
+	}
+
+	recheckInterval := cfg.Redis.OutageRecheckInterval
+	if recheckInterval <= 0 {
+		recheckInterval = 5 * time.Second
+	}
+
+	logger.WithFields(logrus.Fields{
+		"failure_threshold": cfg.Redis.OutagePauseThreshold,
+		"recheck_interval":  recheckInterval.String(),
+	}).Info("Consumption pauses on sustained Redis write failures")
+
+	return &RedisOutageGuard{
+		pinger:          pinger,
+		logger:          logger,
+		threshold:       int64(cfg.Redis.OutagePauseThreshold),
+		recheckInterval: recheckInterval,
+	}
+}
+
+// RecordWrite reports the outcome of a Redis write and returns whether consumption is paused
+func (g *RedisOutageGuard) RecordWrite(err error) bool {
+	if err == nil {
+		g.consecutiveFailures.Store(0)
+		return g.paused.Load()
+	}
+
+	failures := g.consecutiveFailures.Add(1)
+	if failures >= g.threshold && !g.paused.Load() {
+		g.mutex.Lock()
+		if !g.paused.Load() {
+			g.pausedAt = time.Now()
+			g.paused.Store(true)
+			g.pauses.Add(1)
+			g.logger.WithError(err).WithField("consecutive_failures", failures).Error("Sustained Redis write failures, pausing message consumption")
+		}
+		g.mutex.Unlock()
+	}
+	return g.paused.Load()
+}
+
+// IsPaused reports whether consumption is paused
+func (g *RedisOutageGuard) IsPaused() bool {
+	return g.paused.Load()
+}
+
+// RecheckInterval is how often Redis is pinged while paused
+func (g *RedisOutageGuard) RecheckInterval() time.Duration {
+	return g.recheckInterval
+}
+
+// WaitReady blocks while consumption is paused, pinging Redis every recheck interval, and returns false if
+// ctx is cancelled or stop is closed first
+func (g *RedisOutageGuard) WaitReady(ctx context.Context, stop <-chan struct{}) bool {
+	if !g.paused.Load() {
+		return true
+	}
+
+	ticker := time.NewTicker(g.recheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-stop:
+			return false
+		case <-ticker.C:
+		}
+
+		if g.tryResume(ctx) {
+			return true
+		}
+	}
+}
+
+// tryResume pings Redis and lifts the pause when it answers
+func (g *RedisOutageGuard) tryResume(ctx context.Context) bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if !g.paused.Load() {
+		return true
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, g.recheckInterval)
+	defer cancel()
+	if err := g.pinger.Ping(pingCtx); err != nil {
+		g.logger.WithError(err).Debug("Redis still unavailable, consumption stays paused")
+		return false
+	}
+
+	g.consecutiveFailures.Store(0)
+	g.paused.Store(false)
+	g.logger.WithField("paused_for", time.Since(g.pausedAt).String()).Info("Redis recovered, resuming message consumption")
+	return true
+}
+
+// GetStats returns the guard's state for health and metrics reporting
+func (g *RedisOutageGuard) GetStats() map[string]interface{} {
+	return map[string]interface{}{
+		"paused":               g.paused.Load(),
+		"consecutive_failures": g.consecutiveFailures.Load(),
+		"pauses":               g.pauses.Load(),
+		"failure_threshold":    g.threshold,
+	}
+}
@@ -1,9 +1,15 @@
 package services
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"sync"
 	"time"
 
@@ -11,8 +17,15 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
 )
 
+// ErrKeyNotFound is returned by reads of keys that don't exist; match it with errors.Is
+var ErrKeyNotFound = errors.New("key not found")
+
+// ErrTaskStatusNotStored is wrapped by CompleteTask errors when the result was stored but the final status was not
+var ErrTaskStatusNotStored = errors.New("task status not stored")
+
 // CacheMetrics tracks cache hit/miss statistics
 type CacheMetrics struct {
 	mu              sync.RWMutex
@@ -23,6 +36,15 @@ type CacheMetrics struct {
 	Errors          int64     `json:"errors"`
 	TotalOperations int64     `json:"total_operations"`
 	LastResetTime   time.Time `json:"last_reset_time"`
+
+	// Write failures by operation, so result-store degradation can be alerted on separately
+	SetFailures        int64 `json:"set_failures"`
+	TaskStatusFailures int64 `json:"task_status_failures"`
+	TaskResultFailures int64 `json:"task_result_failures"`
+
+	// Archive tier: results served from the archive after a primary miss, and failed archive writes
+	ArchiveHits          int64 `json:"archive_hits"`
+	ArchiveWriteFailures int64 `json:"archive_write_failures"`
 }
 
 // GetHitRatio returns the cache hit ratio as a percentage
@@ -60,6 +82,11 @@ func (m *CacheMetrics) Reset() {
 	m.Deletes = 0
 	m.Errors = 0
 	m.TotalOperations = 0
+	m.SetFailures = 0
+	m.TaskStatusFailures = 0
+	m.TaskResultFailures = 0
+	m.ArchiveHits = 0
+	m.ArchiveWriteFailures = 0
 	m.LastResetTime = time.Now()
 }
 
@@ -76,15 +103,27 @@ func (m *CacheMetrics) GetSnapshot() CacheMetrics {
 		Errors:          m.Errors,
 		TotalOperations: m.TotalOperations,
 		LastResetTime:   m.LastResetTime,
+
+		SetFailures:        m.SetFailures,
+		TaskStatusFailures: m.TaskStatusFailures,
+		TaskResultFailures: m.TaskResultFailures,
+
+		ArchiveHits:          m.ArchiveHits,
+		ArchiveWriteFailures: m.ArchiveWriteFailures,
 	}
 }
 
 // RedisService handles Redis operations with connection pooling
 type RedisService struct {
-	client  *redis.Client
-	logger  *logrus.Logger
-	config  *config.Config
-	metrics *CacheMetrics
+	client    *redis.Client
+	logger    *logrus.Logger
+	config    *config.Config
+	metrics   *CacheMetrics
+	keyPrefix string // Namespace applied to every key (see prefixedKey)
+
+	// Task result tiers: results are written to the primary and mirrored to the optional archive
+	results ResultStore
+	archive ResultStore
 }
 
 // CacheInterface defines the contract for caching operations
@@ -150,31 +189,78 @@ func NewRedisService(cfg *config.Config, logger *logrus.Logger) (*RedisService,
 	}
 
 	logger.WithFields(logrus.Fields{
-		"dsn":       cfg.Redis.DSN,
-		"pool_size": cfg.Redis.PoolSize,
-		"min_idle":  cfg.Redis.MinIdleConnections,
-		"max_idle":  cfg.Redis.MaxIdleConnections,
+		"dsn":        cfg.Redis.DSN,
+		"pool_size":  cfg.Redis.PoolSize,
+		"min_idle":   cfg.Redis.MinIdleConnections,
+		"max_idle":   cfg.Redis.MaxIdleConnections,
+		"key_prefix": cfg.Redis.KeyPrefix,
 	}).Info("Redis service initialized successfully")
 
-	return &RedisService{
+	service := &RedisService{
 		client: client,
 		logger: logger,
 		config: cfg,
 		metrics: &CacheMetrics{
 			LastResetTime: time.Now(),
 		},
-	}, nil
+		keyPrefix: cfg.Redis.KeyPrefix,
+	}
+	service.results = primaryResultStore{redis: service}
+
+	if cfg.Redis.ArchiveEnabled {
+		archive, err := NewArchiveResultStore(cfg, logger)
+		if err != nil {
+			_ = client.Close()
+			return nil, err
+		}
+		service.archive = archive
+	}
+
+	return service, nil
+}
+
+// SetArchiveResultStore replaces the archive tier for task results (nil disables it)
+func (r *RedisService) SetArchiveResultStore(archive ResultStore) {
+	r.archive = archive
+}
+
+// WithKeyPrefix returns a view of the service sharing its connection and metrics that namespaces every key
+// with keyPrefix and keeps task results in results (nil = this Redis, under the new prefix). The archive tier
+// is not carried over, so nothing written through the view lands outside its namespace. Close the service,
+// not its views.
+func (r *RedisService) WithKeyPrefix(keyPrefix string, results ResultStore) *RedisService {
+	view := &RedisService{
+		client:    r.client,
+		logger:    r.logger,
+		config:    r.config,
+		metrics:   r.metrics,
+		keyPrefix: keyPrefix,
+		results:   results,
+	}
+	if view.results == nil {
+		view.results = primaryResultStore{redis: view}
+	}
+	return view
+}
+
+// prefixedKey namespaces a key with the configured prefix so that every read and
+// write issued through the service lands in the same keyspace
+func (r *RedisService) prefixedKey(key string) string {
+	if r.keyPrefix == "" {
+		return key
+	}
+	return r.keyPrefix + ":" + key
 }
 
 // Get retrieves a value by key
 func (r *RedisService) Get(ctx context.Context, key string) (string, error) {
 	r.recordOperation()
 
-	result := r.client.Get(ctx, key)
+	result := r.client.Get(ctx, r.prefixedKey(key))
 	if err := result.Err(); err != nil {
 		if err == redis.Nil {
 			r.recordMiss()
-			return "", fmt.Errorf("key not found: %s", key)
+			return "", fmt.Errorf("%w: %s", ErrKeyNotFound, key)
 		}
 		r.recordError()
 		r.logger.WithError(err).WithField("key", key).Error("Failed to get value from Redis")
@@ -189,8 +275,9 @@ func (r *RedisService) Get(ctx context.Context, key string) (string, error) {
 func (r *RedisService) SetValue(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
 	r.recordOperation()
 
-	if err := r.client.Set(ctx, key, value, ttl).Err(); err != nil {
+	if err := r.client.Set(ctx, r.prefixedKey(key), value, ttl).Err(); err != nil {
 		r.recordError()
+		r.recordSetFailure()
 		r.logger.WithError(err).WithFields(logrus.Fields{
 			"key": key,
 			"ttl": ttl,
@@ -211,7 +298,7 @@ func (r *RedisService) Set(ctx context.Context, key string, value string, ttl ti
 func (r *RedisService) Delete(ctx context.Context, key string) error {
 	r.recordOperation()
 
-	if err := r.client.Del(ctx, key).Err(); err != nil {
+	if err := r.client.Del(ctx, r.prefixedKey(key)).Err(); err != nil {
 		r.recordError()
 		r.logger.WithError(err).WithField("key", key).Error("Failed to delete key from Redis")
 		return fmt.Errorf("redis delete error: %w", err)
@@ -221,9 +308,32 @@ func (r *RedisService) Delete(ctx context.Context, key string) error {
 	return nil
 }
 
+// DeleteKeys removes the given keys and returns how many existed
+func (r *RedisService) DeleteKeys(ctx context.Context, keys ...string) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	r.recordOperation()
+
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = r.prefixedKey(key)
+	}
+
+	removed, err := r.client.Del(ctx, prefixed...).Result()
+	if err != nil {
+		r.recordError()
+		r.logger.WithError(err).WithField("keys", len(keys)).Error("Failed to delete keys from Redis")
+		return 0, fmt.Errorf("redis delete error: %w", err)
+	}
+
+	r.recordDelete()
+	return int(removed), nil
+}
+
 // Exists checks if a key exists
 func (r *RedisService) Exists(ctx context.Context, key string) (bool, error) {
-	result := r.client.Exists(ctx, key)
+	result := r.client.Exists(ctx, r.prefixedKey(key))
 	if err := result.Err(); err != nil {
 		r.logger.WithError(err).WithField("key", key).Error("Failed to check key existence in Redis")
 		return false, fmt.Errorf("redis exists error: %w", err)
@@ -231,6 +341,436 @@ func (r *RedisService) Exists(ctx context.Context, key string) (bool, error) {
 	return result.Val() > 0, nil
 }
 
+// SetNX stores a value only if the key does not exist, returning true if it was set
+func (r *RedisService) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	r.recordOperation()
+
+	result := r.client.SetNX(ctx, r.prefixedKey(key), value, ttl)
+	if err := result.Err(); err != nil {
+		r.recordError()
+		r.logger.WithError(err).WithField("key", key).Error("Failed to setnx value in Redis")
+		return false, fmt.Errorf("redis setnx error: %w", err)
+	}
+
+	if result.Val() {
+		r.recordSet()
+	}
+	return result.Val(), nil
+}
+
+// AppendToList pushes a value to the tail of a list and refreshes the list TTL
+func (r *RedisService) AppendToList(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	r.recordOperation()
+
+	fullKey := r.prefixedKey(key)
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, fullKey, value)
+		pipe.Expire(ctx, fullKey, ttl)
+		return nil
+	})
+	if err != nil {
+		r.recordError()
+		r.logger.WithError(err).WithField("key", key).Error("Failed to append to list in Redis")
+		return fmt.Errorf("redis rpush error: %w", err)
+	}
+
+	r.recordSet()
+	return nil
+}
+
+// DrainList atomically returns every element of a list and deletes it
+func (r *RedisService) DrainList(ctx context.Context, key string) ([]string, error) {
+	r.recordOperation()
+
+	fullKey := r.prefixedKey(key)
+	var rangeCmd *redis.StringSliceCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		rangeCmd = pipe.LRange(ctx, fullKey, 0, -1)
+		pipe.Del(ctx, fullKey)
+		return nil
+	})
+	if err != nil {
+		r.recordError()
+		r.logger.WithError(err).WithField("key", key).Error("Failed to drain list from Redis")
+		return nil, fmt.Errorf("redis drain error: %w", err)
+	}
+
+	r.recordDelete()
+	return rangeCmd.Val(), nil
+}
+
+// Expire refreshes the TTL of an existing key without touching its value; it reports whether the key exists
+func (r *RedisService) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	r.recordOperation()
+
+	result := r.client.Expire(ctx, r.prefixedKey(key), ttl)
+	if err := result.Err(); err != nil {
+		r.recordError()
+		r.logger.WithError(err).WithField("key", key).Error("Failed to set expiry in Redis")
+		return false, fmt.Errorf("redis expire error: %w", err)
+	}
+	return result.Val(), nil
+}
+
+// userActivityKey is the sorted set of user numbers scored by their last message time (unix seconds)
+const userActivityKey = "activity:users"
+
+// RecordUserActivity marks the user as active at the given time
+func (r *RedisService) RecordUserActivity(ctx context.Context, userNumber string, at time.Time) error {
+	r.recordOperation()
+
+	if err := r.client.ZAdd(ctx, r.prefixedKey(userActivityKey), redis.Z{Score: float64(at.Unix()), Member: userNumber}).Err(); err != nil {
+		r.recordError()
+		r.logger.WithError(err).WithField("user_number", userNumber).Error("Failed to record user activity in Redis")
+		return fmt.Errorf("redis zadd error: %w", err)
+	}
+
+	r.recordSet()
+	return nil
+}
+
+// GetActiveUsers returns up to limit users (0 = all) active since the given time, most recent first
+func (r *RedisService) GetActiveUsers(ctx context.Context, since time.Time, limit int) ([]string, error) {
+	r.recordOperation()
+
+	users, err := r.client.ZRevRangeByScore(ctx, r.prefixedKey(userActivityKey), &redis.ZRangeBy{
+		Min:   fmt.Sprintf("%d", since.Unix()),
+		Max:   "+inf",
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		r.recordError()
+		r.logger.WithError(err).Error("Failed to get active users from Redis")
+		return nil, fmt.Errorf("redis zrevrangebyscore error: %w", err)
+	}
+	return users, nil
+}
+
+// TrimUserActivity forgets users whose last activity is before the given time
+func (r *RedisService) TrimUserActivity(ctx context.Context, before time.Time) error {
+	r.recordOperation()
+
+	if err := r.client.ZRemRangeByScore(ctx, r.prefixedKey(userActivityKey), "-inf", fmt.Sprintf("(%d", before.Unix())).Err(); err != nil {
+		r.recordError()
+		r.logger.WithError(err).Error("Failed to trim user activity in Redis")
+		return fmt.Errorf("redis zremrangebyscore error: %w", err)
+	}
+
+	r.recordDelete()
+	return nil
+}
+
+// userTasksKey is the sorted set of a user's task IDs scored by creation time (unix milliseconds)
+func userTasksKey(userNumber string) string {
+	return fmt.Sprintf("user:tasks:%s", userNumber)
+}
+
+// RecordUserTask adds a task to the user's recent task index, keeping only the newest maxTasks entries.
+// Re-recording a task (e.g. on redelivery) keeps its original time.
+func (r *RedisService) RecordUserTask(ctx context.Context, userNumber, taskID string, at time.Time, maxTasks int, ttl time.Duration) error {
+	r.recordOperation()
+
+	key := r.prefixedKey(userTasksKey(userNumber))
+	pipe := r.client.TxPipeline()
+	pipe.ZAddNX(ctx, key, redis.Z{Score: float64(at.UnixMilli()), Member: taskID})
+	pipe.ZRemRangeByRank(ctx, key, 0, int64(-maxTasks-1))
+	if ttl > 0 {
+		pipe.Expire(ctx, key, ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		r.recordError()
+		r.logger.WithError(err).WithField("user_number", userNumber).Error("Failed to record user task in Redis")
+		return fmt.Errorf("redis user task index error: %w", err)
+	}
+
+	r.recordSet()
+	return nil
+}
+
+// GetRecentTasks returns up to limit (0 = all) of the user's most recent task IDs, newest first
+func (r *RedisService) GetRecentTasks(ctx context.Context, userNumber string, limit int) ([]string, error) {
+	r.recordOperation()
+
+	tasks, err := r.client.ZRevRange(ctx, r.prefixedKey(userTasksKey(userNumber)), 0, int64(limit-1)).Result()
+	if err != nil {
+		r.recordError()
+		r.logger.WithError(err).WithField("user_number", userNumber).Error("Failed to get recent tasks from Redis")
+		return nil, fmt.Errorf("redis zrevrange error: %w", err)
+	}
+	return tasks, nil
+}
+
+// taskKeyFormats are the per-task keys written by the gateway and the workers
+var taskKeyFormats = []string{
+	"task:status:%s",
+	"task:result:%s",
+	"task:error:%s",
+	"task:raw_response:%s",
+	"task:replay_context:%s",
+	"task:cancel:%s",
+	"task:retry:%s",
+	"task:created:%s",
+	"task:metadata:%s",
+	"task:trace:%s",
+	"task:message:%s",
+	"callback:url:%s",
+}
+
+// userLockKey holds the task ID currently allowed to talk to the user's thread
+func userLockKey(userNumber string) string {
+	return fmt.Sprintf("user:lock:%s", userNumber)
+}
+
+// userLockQueueKey is the sorted set of tasks waiting for the user's lock, scored by arrival (unix milliseconds)
+func userLockQueueKey(userNumber string) string {
+	return fmt.Sprintf("user:lock:queue:%s", userNumber)
+}
+
+// userLockWaitersKey maps each task waiting for the user's lock to its heartbeat deadline (unix milliseconds)
+func userLockWaitersKey(userNumber string) string {
+	return fmt.Sprintf("user:lock:waiters:%s", userNumber)
+}
+
+// acquireUserLockScript queues the task (KEYS[2]) and refreshes its heartbeat deadline (KEYS[3]), drops
+// queued tasks whose heartbeat expired, and takes the lock (KEYS[1]) when the task is first in line. Times
+// come from the Redis server's clock so workers' clock skew doesn't matter.
+var acquireUserLockScript = redis.NewScript(`
+local lock, queue, waiters = KEYS[1], KEYS[2], KEYS[3]
+local task, lockTTL, waiterTTL = ARGV[1], tonumber(ARGV[2]), tonumber(ARGV[3])
+
+if redis.call("GET", lock) == task then
+	return 1
+end
+
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+redis.call("ZADD", queue, "NX", now, task)
+redis.call("HSET", waiters, task, now + waiterTTL)
+redis.call("PEXPIRE", queue, lockTTL + waiterTTL)
+redis.call("PEXPIRE", waiters, lockTTL + waiterTTL)
+
+while true do
+	local head = redis.call("ZRANGE", queue, 0, 0)[1]
+	if head == nil or head == task then
+		break
+	end
+	local deadline = tonumber(redis.call("HGET", waiters, head))
+	if deadline and deadline > now then
+		return 0
+	end
+	redis.call("ZREM", queue, head)
+	redis.call("HDEL", waiters, head)
+end
+
+if redis.call("SET", lock, task, "NX", "PX", lockTTL) then
+	redis.call("ZREM", queue, task)
+	redis.call("HDEL", waiters, task)
+	return 1
+end
+return 0
+`)
+
+// releaseUserLockScript releases the lock (KEYS[1]) if the task (ARGV[1]) holds it and leaves the queue
+// (KEYS[2]) and its waiters (KEYS[3])
+var releaseUserLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("DEL", KEYS[1])
+end
+redis.call("ZREM", KEYS[2], ARGV[1])
+redis.call("HDEL", KEYS[3], ARGV[1])
+return 1
+`)
+
+// userLockKeys returns the keys of the user's lock scripts: the lock, its queue and the queue's heartbeats
+func (r *RedisService) userLockKeys(userNumber string) []string {
+	return []string{
+		r.prefixedKey(userLockKey(userNumber)),
+		r.prefixedKey(userLockQueueKey(userNumber)),
+		r.prefixedKey(userLockWaitersKey(userNumber)),
+	}
+}
+
+// TryAcquireUserLock queues the task for the user's lock and takes it if the task is first in line and the
+// lock is free, holding it for at most lockTTL. Waiters must call it again before waiterTTL elapses to keep
+// their place; the queue is served in arrival order.
+func (r *RedisService) TryAcquireUserLock(ctx context.Context, userNumber, taskID string, lockTTL, waiterTTL time.Duration) (bool, error) {
+	r.recordOperation()
+
+	acquired, err := acquireUserLockScript.Run(ctx, r.client, r.userLockKeys(userNumber),
+		taskID, lockTTL.Milliseconds(), waiterTTL.Milliseconds()).Int()
+	if err != nil {
+		r.recordError()
+		r.logger.WithError(err).WithField("user_number", userNumber).Error("Failed to acquire user lock in Redis")
+		return false, fmt.Errorf("redis user lock error: %w", err)
+	}
+	return acquired == 1, nil
+}
+
+// ReleaseUserLock releases the user's lock if the task holds it and removes the task from the lock's queue
+func (r *RedisService) ReleaseUserLock(ctx context.Context, userNumber, taskID string) error {
+	r.recordOperation()
+
+	if err := releaseUserLockScript.Run(ctx, r.client, r.userLockKeys(userNumber), taskID).Err(); err != nil {
+		r.recordError()
+		r.logger.WithError(err).WithField("user_number", userNumber).Error("Failed to release user lock in Redis")
+		return fmt.Errorf("redis user lock error: %w", err)
+	}
+	return nil
+}
+
+// PurgeUserData deletes the user's indexed tasks (status, result, error and related keys), their task
+// index, latest result, profile, cached transcripts, pending coalesced messages, scheduled messages and
+// activity entry from this namespace, including results kept in a dedicated result store or the archive,
+// returning how many keys and entries were removed. Tasks that fell out of the capped index aren't reachable
+// here and are left to expire with their TTLs.
+func (r *RedisService) PurgeUserData(ctx context.Context, userNumber string) (int, error) {
+	taskIDs, err := r.GetRecentTasks(ctx, userNumber, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list user tasks: %w", err)
+	}
+
+	r.recordOperation()
+	transcriptKeys, err := r.client.SMembers(ctx, r.prefixedKey(userTranscriptsKey(userNumber))).Result()
+	if err != nil {
+		r.recordError()
+		return 0, fmt.Errorf("failed to list user transcripts: %w", err)
+	}
+
+	keys := []string{
+		userTasksKey(userNumber),
+		latestResultKey(userNumber),
+		fmt.Sprintf("user:locale:%s", userNumber),
+		fmt.Sprintf("agent:id:%s", userNumber),
+		fmt.Sprintf("coalesce:buffer:%s", userNumber),
+		userTranscriptsKey(userNumber),
+	}
+	keys = append(keys, transcriptKeys...)
+	for _, taskID := range taskIDs {
+		for _, format := range taskKeyFormats {
+			keys = append(keys, fmt.Sprintf(format, taskID))
+		}
+	}
+
+	removed, err := r.DeleteKeys(ctx, keys...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete user keys: %w", err)
+	}
+
+	// Results may also live outside this Redis: in a tenant's dedicated store, or copied to the archive
+	resultKeys := []string{latestResultKey(userNumber)}
+	for _, taskID := range taskIDs {
+		resultKeys = append(resultKeys, fmt.Sprintf("task:result:%s", taskID))
+	}
+	for _, store := range []ResultStore{r.results, r.archive} {
+		if _, primary := store.(primaryResultStore); store == nil || primary {
+			continue
+		}
+		storeRemoved, err := store.DeleteResults(ctx, resultKeys...)
+		if err != nil {
+			return removed, fmt.Errorf("failed to delete user results: %w", err)
+		}
+		removed += storeRemoved
+	}
+
+	r.recordOperation()
+	activityRemoved, err := r.client.ZRem(ctx, r.prefixedKey(userActivityKey), userNumber).Result()
+	if err != nil {
+		r.recordError()
+		return removed, fmt.Errorf("failed to remove user activity: %w", err)
+	}
+
+	removed += int(activityRemoved)
+
+	scheduledRemoved, err := r.removeScheduledMessages(ctx, userNumber)
+	if err != nil {
+		return removed, fmt.Errorf("failed to remove scheduled messages: %w", err)
+	}
+
+	return removed + scheduledRemoved, nil
+}
+
+// removeScheduledMessages drops the user's messages still waiting in the scheduled set. The set isn't
+// indexed by user, so every payload is decoded to find the user it belongs to.
+func (r *RedisService) removeScheduledMessages(ctx context.Context, userNumber string) (int, error) {
+	r.recordOperation()
+
+	key := r.prefixedKey(scheduledMessagesKey)
+	payloads, err := r.client.ZRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		r.recordError()
+		return 0, fmt.Errorf("redis zrange error: %w", err)
+	}
+
+	var members []interface{}
+	for _, payload := range payloads {
+		var scheduled scheduledMessage
+		if err := json.Unmarshal([]byte(payload), &scheduled); err != nil {
+			continue
+		}
+		var msg models.QueueMessage
+		if err := DefaultMessageCodecs().Decode(scheduled.ContentType, scheduled.Body, &msg); err != nil {
+			continue
+		}
+		if msg.UserNumber == userNumber {
+			members = append(members, payload)
+		}
+	}
+	if len(members) == 0 {
+		return 0, nil
+	}
+
+	removed, err := r.client.ZRem(ctx, key, members...).Result()
+	if err != nil {
+		r.recordError()
+		return 0, fmt.Errorf("redis zrem error: %w", err)
+	}
+
+	r.recordDelete()
+	return int(removed), nil
+}
+
+// scheduledMessagesKey is the sorted set of serialized scheduled messages scored by their due time (unix milliseconds)
+const scheduledMessagesKey = "scheduled:messages"
+
+// ScheduleMessage stores a serialized message to be claimed with ClaimDueScheduledMessages once due
+func (r *RedisService) ScheduleMessage(ctx context.Context, payload string, dueAt time.Time) error {
+	r.recordOperation()
+
+	err := r.client.ZAdd(ctx, r.prefixedKey(scheduledMessagesKey), redis.Z{Score: float64(dueAt.UnixMilli()), Member: payload}).Err()
+	if err != nil {
+		r.recordError()
+		r.recordSetFailure()
+		return fmt.Errorf("redis zadd error: %w", err)
+	}
+
+	r.recordSet()
+	return nil
+}
+
+// claimDueScheduledMessagesScript pops up to ARGV[2] members of KEYS[1] scored at or before ARGV[1], so each
+// due message is handed to exactly one caller
+var claimDueScheduledMessagesScript = redis.NewScript(`
+local due = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, ARGV[2])
+if #due > 0 then
+	redis.call("ZREM", KEYS[1], unpack(due))
+end
+return due
+`)
+
+// ClaimDueScheduledMessages removes and returns up to limit scheduled messages due at or before now
+func (r *RedisService) ClaimDueScheduledMessages(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	r.recordOperation()
+
+	payloads, err := claimDueScheduledMessagesScript.Run(ctx, r.client, []string{r.prefixedKey(scheduledMessagesKey)},
+		now.UnixMilli(), limit).StringSlice()
+	if err != nil {
+		r.recordError()
+		return nil, fmt.Errorf("redis scheduled message claim error: %w", err)
+	}
+	return payloads, nil
+}
+
 // SetJSON stores a JSON-encoded value
 func (r *RedisService) SetJSON(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
 	jsonData, err := json.Marshal(value)
@@ -257,7 +797,139 @@ func (r *RedisService) GetJSON(ctx context.Context, key string, dest interface{}
 // SetTaskStatus stores task status with configured TTL
 func (r *RedisService) SetTaskStatus(ctx context.Context, taskID string, status string, ttl time.Duration) error {
 	key := fmt.Sprintf("task:status:%s", taskID)
-	return r.SetValue(ctx, key, status, ttl)
+	if err := r.SetValue(ctx, key, status, ttl); err != nil {
+		r.recordTaskStatusFailure()
+		return err
+	}
+	if r.config != nil && r.config.Redis.StaleTaskMaxAge > 0 {
+		r.trackInFlightTask(ctx, taskID, status)
+	}
+	return nil
+}
+
+// inFlightTasksKey is the sorted set of pending/processing task IDs scored by when they first entered
+// that state (unix milliseconds), scanned by the stale task janitor
+const inFlightTasksKey = "tasks:in_flight"
+
+// trackInFlightTask adds a pending/processing task to the in-flight index, keeping its original time on
+// redelivery, and removes it once it reaches any other status (scheduled tasks re-enter when they start).
+// Failures only delay stale task detection.
+func (r *RedisService) trackInFlightTask(ctx context.Context, taskID string, status string) {
+	r.recordOperation()
+
+	key := r.prefixedKey(inFlightTasksKey)
+	var err error
+	if models.TaskStatus(status).IsTerminal() || status == string(models.TaskStatusScheduled) {
+		err = r.client.ZRem(ctx, key, taskID).Err()
+	} else {
+		err = r.client.ZAddNX(ctx, key, redis.Z{Score: float64(time.Now().UnixMilli()), Member: taskID}).Err()
+	}
+	if err != nil {
+		r.recordError()
+		r.logger.WithError(err).WithField("task_id", taskID).Warn("Failed to update in-flight task index")
+	}
+}
+
+// GetStaleInFlightTasks returns up to limit task IDs that entered pending/processing before the given time
+func (r *RedisService) GetStaleInFlightTasks(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	r.recordOperation()
+
+	tasks, err := r.client.ZRangeByScore(ctx, r.prefixedKey(inFlightTasksKey), &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   fmt.Sprintf("(%d", before.UnixMilli()),
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		r.recordError()
+		return nil, fmt.Errorf("redis zrangebyscore error: %w", err)
+	}
+	return tasks, nil
+}
+
+// failStaleTaskScript marks the task (KEYS[1] status, KEYS[2] error) failed only if it is still pending or
+// processing, so a worker finishing at the same moment wins, and drops it from the in-flight index (KEYS[3])
+var failStaleTaskScript = redis.NewScript(`
+local status = redis.call("GET", KEYS[1])
+redis.call("ZREM", KEYS[3], ARGV[4])
+if status == "pending" or status == "processing" then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[3])
+	redis.call("SET", KEYS[2], ARGV[2], "PX", ARGV[3])
+	return 1
+end
+return 0
+`)
+
+// FailStaleTask marks a task that is still pending/processing as failed with the given error, returning
+// false when the task already moved on (or its status expired). Either way it leaves the in-flight index.
+func (r *RedisService) FailStaleTask(ctx context.Context, taskID string, result *models.ErrorResult, ttl time.Duration) (bool, error) {
+	errorData, err := json.Marshal(result)
+	if err != nil {
+		return false, fmt.Errorf("failed to serialize task error: %w", err)
+	}
+
+	r.recordOperation()
+
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	keys := []string{
+		r.prefixedKey(fmt.Sprintf("task:status:%s", taskID)),
+		r.prefixedKey(fmt.Sprintf("task:error:%s", taskID)),
+		r.prefixedKey(inFlightTasksKey),
+	}
+	failed, err := failStaleTaskScript.Run(ctx, r.client, keys,
+		string(models.TaskStatusFailed), string(errorData), ttl.Milliseconds(), taskID).Int()
+	if err != nil {
+		r.recordError()
+		return false, fmt.Errorf("redis stale task error: %w", err)
+	}
+	return failed == 1, nil
+}
+
+// requeueFailedTaskScript moves the task (KEYS[1] status) from failed to pending and deletes its error
+// (KEYS[2]) in one step, so only one of several concurrent retries gets to republish it. The task re-enters
+// the in-flight index (KEYS[3]) when ARGV[3] is set. Returns the deleted error, or false if not failed.
+var requeueFailedTaskScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= "failed" then
+	return false
+end
+local taskError = redis.call("GET", KEYS[2]) or ""
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+redis.call("DEL", KEYS[2])
+if ARGV[3] == "1" then
+	redis.call("ZADD", KEYS[3], "NX", ARGV[4], ARGV[5])
+end
+return taskError
+`)
+
+// RequeueFailedTask resets a failed task to pending and deletes its stored error, returning the error so a
+// retry that can't be published may restore it. It returns false when the task isn't failed, e.g. because
+// a concurrent retry already requeued it.
+func (r *RedisService) RequeueFailedTask(ctx context.Context, taskID string, ttl time.Duration) (string, bool, error) {
+	r.recordOperation()
+
+	trackInFlight := "0"
+	if r.config != nil && r.config.Redis.StaleTaskMaxAge > 0 {
+		trackInFlight = "1"
+	}
+	keys := []string{
+		r.prefixedKey(fmt.Sprintf("task:status:%s", taskID)),
+		r.prefixedKey(fmt.Sprintf("task:error:%s", taskID)),
+		r.prefixedKey(inFlightTasksKey),
+	}
+	taskError, err := requeueFailedTaskScript.Run(ctx, r.client, keys,
+		string(models.TaskStatusPending), ttl.Milliseconds(), trackInFlight, time.Now().UnixMilli(), taskID).Text()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		r.recordError()
+		r.recordTaskStatusFailure()
+		return "", false, fmt.Errorf("redis requeue task error: %w", err)
+	}
+
+	r.recordSet()
+	return taskError, true, nil
 }
 
 // GetTaskStatus retrieves task status
@@ -266,16 +938,326 @@ func (r *RedisService) GetTaskStatus(ctx context.Context, taskID string) (string
 	return r.Get(ctx, key)
 }
 
-// SetTaskResult stores task result with configured TTL
+// compressedValueMarker prefixes gzip-compressed values. JSON never starts with this byte, so values
+// without it (including those written before compression was enabled) are read as plain JSON.
+const compressedValueMarker byte = 0x00
+
+// SetTaskResult stores task result with configured TTL, gzip-compressing it when enabled and large enough
 func (r *RedisService) SetTaskResult(ctx context.Context, taskID string, result interface{}, ttl time.Duration) error {
+	if err := r.storeTaskResult(ctx, taskID, result, ttl); err != nil {
+		r.recordTaskResultFailure()
+		return err
+	}
+	return nil
+}
+
+// storeTaskResult encodes and writes a task result to the primary store, then mirrors it to the archive
+// in the background; SetTaskResult counts primary failures
+func (r *RedisService) storeTaskResult(ctx context.Context, taskID string, result interface{}, ttl time.Duration) error {
 	key := fmt.Sprintf("task:result:%s", taskID)
-	return r.SetJSON(ctx, key, result, ttl)
+
+	value, err := r.encodeTaskResult(key, result)
+	if err != nil {
+		return err
+	}
+
+	if err := r.results.SetResult(ctx, key, value, ttl); err != nil {
+		return err
+	}
+
+	if r.archive != nil {
+		go r.archiveTaskResult(context.WithoutCancel(ctx), key, value)
+	}
+	return nil
+}
+
+// encodeTaskResult marshals a task result, gzip-compressing it when enabled and large enough
+func (r *RedisService) encodeTaskResult(key string, result interface{}) ([]byte, error) {
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	if !r.config.Redis.ResultCompression || len(jsonData) < r.config.Redis.ResultCompressionThreshold {
+		return jsonData, nil
+	}
+
+	compressed, err := compressValue(jsonData)
+	if err != nil {
+		r.logger.WithError(err).WithField("key", key).Warn("Failed to compress task result, storing uncompressed")
+		return jsonData, nil
+	}
+	return compressed, nil
+}
+
+// archiveTaskResult copies an encoded result to the archive tier; failures are logged and counted only
+func (r *RedisService) archiveTaskResult(ctx context.Context, key string, value []byte) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := r.archive.SetResult(ctx, key, value, r.config.Redis.ArchiveResultTTL); err != nil {
+		r.metrics.mu.Lock()
+		r.metrics.ArchiveWriteFailures++
+		r.metrics.mu.Unlock()
+		r.logger.WithError(err).WithField("key", key).Warn("Failed to archive task result")
+	}
+}
+
+// CompleteTask stores a finished task's result and final status. When results are kept in this Redis both
+// are written in a single MULTI/EXEC round trip, so the status is never visible without its result; if the
+// transaction fails neither is stored and it counts as a result failure. With a dedicated result store the
+// result is written first, and a status failure after it is returned wrapping ErrTaskStatusNotStored.
+// When REDIS_STORE_LATEST_RESULT is enabled and userNumber is set, the result also replaces the user's latest
+// result (see GetLatestResult), in the same transaction or right after the task's result.
+func (r *RedisService) CompleteTask(ctx context.Context, taskID, userNumber string, result interface{}, status string, resultTTL, statusTTL time.Duration) error {
+	latestKey := ""
+	if userNumber != "" && r.config != nil && r.config.Redis.StoreLatestResult {
+		latestKey = latestResultKey(userNumber)
+	}
+
+	if _, ok := r.results.(primaryResultStore); !ok {
+		if err := r.SetTaskResult(ctx, taskID, result, resultTTL); err != nil {
+			return err
+		}
+		if latestKey != "" {
+			if err := r.storeLatestResult(ctx, latestKey, result, resultTTL); err != nil {
+				r.recordTaskResultFailure()
+				return err
+			}
+		}
+		if err := r.SetTaskStatus(ctx, taskID, status, statusTTL); err != nil {
+			return fmt.Errorf("%w: %w", ErrTaskStatusNotStored, err)
+		}
+		return nil
+	}
+
+	resultKey := fmt.Sprintf("task:result:%s", taskID)
+	value, err := r.encodeTaskResult(resultKey, result)
+	if err != nil {
+		r.recordTaskResultFailure()
+		return err
+	}
+
+	r.recordOperation()
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, r.prefixedKey(resultKey), value, resultTTL)
+		if latestKey != "" {
+			pipe.Set(ctx, r.prefixedKey(latestKey), value, resultTTL)
+		}
+		pipe.Set(ctx, r.prefixedKey(fmt.Sprintf("task:status:%s", taskID)), status, statusTTL)
+		if r.config != nil && r.config.Redis.StaleTaskMaxAge > 0 && models.TaskStatus(status).IsTerminal() {
+			pipe.ZRem(ctx, r.prefixedKey(inFlightTasksKey), taskID)
+		}
+		return nil
+	})
+	if err != nil {
+		r.recordError()
+		r.recordSetFailure()
+		r.recordTaskResultFailure()
+		r.logger.WithError(err).WithFields(logrus.Fields{
+			"task_id": taskID,
+			"status":  status,
+		}).Error("Failed to store task result and status in Redis")
+		return fmt.Errorf("redis complete task error: %w", err)
+	}
+	r.recordSet()
+
+	if r.archive != nil {
+		go r.archiveTaskResult(context.WithoutCancel(ctx), resultKey, value)
+	}
+	return nil
+}
+
+// latestResultKey holds the result of the user's most recently completed task
+func latestResultKey(userNumber string) string {
+	return fmt.Sprintf("user:latest_result:%s", userNumber)
+}
+
+// storeLatestResult encodes and writes a result as the user's latest to the result store
+func (r *RedisService) storeLatestResult(ctx context.Context, key string, result interface{}, ttl time.Duration) error {
+	value, err := r.encodeTaskResult(key, result)
+	if err != nil {
+		return err
+	}
+	return r.results.SetResult(ctx, key, value, ttl)
+}
+
+// GetLatestResult retrieves the result of the user's most recently completed task, stored when
+// REDIS_STORE_LATEST_RESULT is enabled, returning ErrKeyNotFound when there is none
+func (r *RedisService) GetLatestResult(ctx context.Context, userNumber string, dest interface{}) error {
+	key := latestResultKey(userNumber)
+
+	jsonData, err := r.results.GetResult(ctx, key)
+	if err != nil {
+		return err
+	}
+	return r.decodeResult(key, jsonData, dest)
 }
 
-// GetTaskResult retrieves task result
+// GetTaskResult retrieves task result from the primary store, falling back to the archive on a miss, and
+// transparently decompresses it if needed
 func (r *RedisService) GetTaskResult(ctx context.Context, taskID string, dest interface{}) error {
 	key := fmt.Sprintf("task:result:%s", taskID)
-	return r.GetJSON(ctx, key, dest)
+
+	jsonData, err := r.results.GetResult(ctx, key)
+	if errors.Is(err, ErrKeyNotFound) && r.archive != nil {
+		archived, archiveErr := r.archive.GetResult(ctx, key)
+		if archiveErr == nil {
+			r.metrics.mu.Lock()
+			r.metrics.ArchiveHits++
+			r.metrics.mu.Unlock()
+			jsonData, err = archived, nil
+		} else if !errors.Is(archiveErr, ErrKeyNotFound) {
+			r.logger.WithError(archiveErr).WithField("key", key).Warn("Failed to read task result from archive")
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return r.decodeResult(key, jsonData, dest)
+}
+
+// decodeResult unmarshals a stored result into dest, transparently decompressing it if needed
+func (r *RedisService) decodeResult(key string, jsonData []byte, dest interface{}) error {
+	if len(jsonData) > 0 && jsonData[0] == compressedValueMarker {
+		var err error
+		if jsonData, err = decompressValue(jsonData); err != nil {
+			r.logger.WithError(err).WithField("key", key).Error("Failed to decompress task result from Redis")
+			return err
+		}
+	}
+
+	if err := json.Unmarshal(jsonData, dest); err != nil {
+		r.logger.WithError(err).WithField("key", key).Error("Failed to unmarshal JSON from Redis")
+		return fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+	return nil
+}
+
+// compressValue gzips data behind the compressed value marker
+func compressValue(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte(compressedValueMarker)
+
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress value: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress value: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressValue reverses compressValue
+func decompressValue(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data[1:]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress value: %w", err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress value: %w", err)
+	}
+	return decompressed, nil
+}
+
+// SetTaskError stores the structured error of a failed task as JSON
+func (r *RedisService) SetTaskError(ctx context.Context, taskID string, result *models.ErrorResult, ttl time.Duration) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to serialize task error: %w", err)
+	}
+	key := fmt.Sprintf("task:error:%s", taskID)
+	return r.SetValue(ctx, key, string(data), ttl)
+}
+
+// GetTaskError retrieves the error stored for a failed task. Plain-text errors (stored before errors
+// were structured) come back with the unknown code and the text as message.
+func (r *RedisService) GetTaskError(ctx context.Context, taskID string) (*models.ErrorResult, error) {
+	key := fmt.Sprintf("task:error:%s", taskID)
+	raw, err := r.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var result models.ErrorResult
+	if err := json.Unmarshal([]byte(raw), &result); err != nil || result.Message == "" {
+		return &models.ErrorResult{Code: models.ErrorCodeUnknown, Message: raw}, nil
+	}
+	return &result, nil
+}
+
+// SetTaskRawResponse stores the agent's raw, untransformed response for debugging
+func (r *RedisService) SetTaskRawResponse(ctx context.Context, taskID string, rawResponse string, ttl time.Duration) error {
+	key := fmt.Sprintf("task:raw_response:%s", taskID)
+	return r.SetValue(ctx, key, rawResponse, ttl)
+}
+
+// GetTaskRawResponse retrieves the agent's raw response stored for debugging
+func (r *RedisService) GetTaskRawResponse(ctx context.Context, taskID string) (string, error) {
+	key := fmt.Sprintf("task:raw_response:%s", taskID)
+	return r.Get(ctx, key)
+}
+
+// SetTaskReplayContext stores what the agent was asked alongside its raw response, so the response can be
+// reprocessed later without calling the agent again
+func (r *RedisService) SetTaskReplayContext(ctx context.Context, taskID string, replayContext string, ttl time.Duration) error {
+	key := fmt.Sprintf("task:replay_context:%s", taskID)
+	return r.SetValue(ctx, key, replayContext, ttl)
+}
+
+// GetTaskReplayContext retrieves the replay context stored with the task's raw response
+func (r *RedisService) GetTaskReplayContext(ctx context.Context, taskID string) (string, error) {
+	key := fmt.Sprintf("task:replay_context:%s", taskID)
+	return r.Get(ctx, key)
+}
+
+// TaskEventChannel is the pub/sub channel task events are published on for a task
+func (r *RedisService) TaskEventChannel(taskID string) string {
+	return r.prefixedKey(fmt.Sprintf("task:events:%s", taskID))
+}
+
+// UserEventChannel is the pub/sub channel events of all of a user's tasks are published on
+func (r *RedisService) UserEventChannel(userNumber string) string {
+	return r.prefixedKey(fmt.Sprintf("user:events:%s", userNumber))
+}
+
+// PublishTaskEvent publishes a task status change on the task's channel and, when userNumber is set, on
+// the user's channel, for the gateway to push to subscribed WebSocket clients
+func (r *RedisService) PublishTaskEvent(ctx context.Context, userNumber string, event models.TaskEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task event: %w", err)
+	}
+
+	r.recordOperation()
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Publish(ctx, r.TaskEventChannel(event.MessageID), payload)
+		if userNumber != "" {
+			pipe.Publish(ctx, r.UserEventChannel(userNumber), payload)
+		}
+		return nil
+	})
+	if err != nil {
+		r.recordError()
+		return fmt.Errorf("redis publish error: %w", err)
+	}
+	return nil
+}
+
+// SetTaskCancelled flags a task for cooperative cancellation by the worker
+func (r *RedisService) SetTaskCancelled(ctx context.Context, taskID string, ttl time.Duration) error {
+	key := fmt.Sprintf("task:cancel:%s", taskID)
+	return r.SetValue(ctx, key, "1", ttl)
+}
+
+// IsTaskCancelled checks whether a task has been flagged for cancellation
+func (r *RedisService) IsTaskCancelled(ctx context.Context, taskID string) (bool, error) {
+	key := fmt.Sprintf("task:cancel:%s", taskID)
+	return r.Exists(ctx, key)
 }
 
 // SetAgentID caches agent ID for a user with configured TTL
@@ -308,6 +1290,71 @@ func (r *RedisService) GetCallbackURL(ctx context.Context, messageID string) (st
 	return r.Get(ctx, key)
 }
 
+// StoreTaskMessage keeps the task's original queue message (JSON) so the task can be requeued later
+func (r *RedisService) StoreTaskMessage(ctx context.Context, taskID string, message string, ttl time.Duration) error {
+	key := fmt.Sprintf("task:message:%s", taskID)
+	return r.SetValue(ctx, key, message, ttl)
+}
+
+// GetTaskMessage retrieves the task's original queue message
+func (r *RedisService) GetTaskMessage(ctx context.Context, taskID string) (string, error) {
+	key := fmt.Sprintf("task:message:%s", taskID)
+	return r.Get(ctx, key)
+}
+
+// SetUserLocale stores the user's preferred locale in their profile (no expiry)
+func (r *RedisService) SetUserLocale(ctx context.Context, userNumber string, locale string) error {
+	key := fmt.Sprintf("user:locale:%s", userNumber)
+	return r.SetValue(ctx, key, locale, 0)
+}
+
+// GetUserLocale retrieves the user's preferred locale from their profile
+func (r *RedisService) GetUserLocale(ctx context.Context, userNumber string) (string, error) {
+	key := fmt.Sprintf("user:locale:%s", userNumber)
+	return r.Get(ctx, key)
+}
+
+// SetCachedTranscript caches the transcript of an audio URL, keyed by the URL's SHA-256, and indexes the
+// cache key under the user who sent the audio so PurgeUserData can find it
+func (r *RedisService) SetCachedTranscript(ctx context.Context, userNumber, audioURL, transcript string, ttl time.Duration) error {
+	r.recordOperation()
+
+	key := transcriptCacheKey(audioURL)
+	indexKey := r.prefixedKey(userTranscriptsKey(userNumber))
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, r.prefixedKey(key), transcript, ttl)
+		pipe.SAdd(ctx, indexKey, key)
+		pipe.Expire(ctx, indexKey, ttl)
+		return nil
+	})
+	if err != nil {
+		r.recordError()
+		r.recordSetFailure()
+		r.logger.WithError(err).WithField("user_number", userNumber).Error("Failed to cache transcript in Redis")
+		return fmt.Errorf("redis set error: %w", err)
+	}
+
+	r.recordSet()
+	return nil
+}
+
+// GetCachedTranscript retrieves the cached transcript of an audio URL
+func (r *RedisService) GetCachedTranscript(ctx context.Context, audioURL string) (string, error) {
+	return r.Get(ctx, transcriptCacheKey(audioURL))
+}
+
+// userTranscriptsKey is the set of transcript cache keys created from a user's audio; it lives as long as
+// the newest of them
+func userTranscriptsKey(userNumber string) string {
+	return fmt.Sprintf("user:transcripts:%s", userNumber)
+}
+
+// transcriptCacheKey hashes the audio URL so signed query strings don't end up in key names
+func transcriptCacheKey(audioURL string) string {
+	sum := sha256.Sum256([]byte(audioURL))
+	return "transcript:" + hex.EncodeToString(sum[:])
+}
+
 // DeleteCallbackURL removes callback URL for a message
 func (r *RedisService) DeleteCallbackURL(ctx context.Context, messageID string) error {
 	key := fmt.Sprintf("callback:url:%s", messageID)
@@ -323,8 +1370,14 @@ func (r *RedisService) Ping(ctx context.Context) error {
 	return nil
 }
 
-// Close closes the Redis connection
+// Close closes the Redis connection, and the archive's if it has one
 func (r *RedisService) Close() error {
+	if closer, ok := r.archive.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			r.logger.WithError(err).Warn("Failed to close Redis archive connection")
+		}
+	}
+
 	if err := r.client.Close(); err != nil {
 		r.logger.WithError(err).Error("Failed to close Redis connection")
 		return fmt.Errorf("redis close error: %w", err)
@@ -370,6 +1423,24 @@ func (r *RedisService) recordError() {
 	r.metrics.Errors++
 }
 
+func (r *RedisService) recordSetFailure() {
+	r.metrics.mu.Lock()
+	defer r.metrics.mu.Unlock()
+	r.metrics.SetFailures++
+}
+
+func (r *RedisService) recordTaskStatusFailure() {
+	r.metrics.mu.Lock()
+	defer r.metrics.mu.Unlock()
+	r.metrics.TaskStatusFailures++
+}
+
+func (r *RedisService) recordTaskResultFailure() {
+	r.metrics.mu.Lock()
+	defer r.metrics.mu.Unlock()
+	r.metrics.TaskResultFailures++
+}
+
 // GetMetrics returns a snapshot of current cache metrics
 func (r *RedisService) GetMetrics() CacheMetrics {
 	return r.metrics.GetSnapshot()
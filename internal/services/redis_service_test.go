@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+)
+
+// newTestRedisService returns a RedisService backed by an in-memory Redis
+func newTestRedisService(t *testing.T) (*RedisService, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	service := &RedisService{
+		client:  client,
+		logger:  logrus.New(),
+		config:  &config.Config{},
+		metrics: &CacheMetrics{LastResetTime: time.Now()},
+	}
+	service.results = primaryResultStore{redis: service}
+	return service, server
+}
+
+// scheduleTestMessage parks a JSON queue message for userNumber in the scheduled set
+func scheduleTestMessage(t *testing.T, service *RedisService, taskID, userNumber string) {
+	t.Helper()
+	body, err := json.Marshal(models.QueueMessage{ID: taskID, Type: "user_message", UserNumber: userNumber, Message: "mais tarde"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := json.Marshal(scheduledMessage{Queue: "user_messages", MessageID: taskID, ContentType: "application/json", Body: body})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.ScheduleMessage(context.Background(), string(payload), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPurgeUserDataRemovesEveryKind(t *testing.T) {
+	service, server := newTestRedisService(t)
+	ctx := context.Background()
+	const user, other = "5521999999999", "5521888888888"
+
+	if err := service.RecordUserTask(ctx, user, "task-1", time.Now(), 10, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.SetTaskStatus(ctx, "task-1", string(models.TaskStatusCompleted), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.SetUserLocale(ctx, user, "pt-BR"); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.RecordUserActivity(ctx, user, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.SetCachedTranscript(ctx, user, "https://media.example/audio.ogg", "olá", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.SetCachedTranscript(ctx, other, "https://media.example/other.ogg", "oi", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.AppendToList(ctx, "coalesce:buffer:"+user, `{"task_id":"task-2"}`, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	scheduleTestMessage(t, service, "task-3", user)
+	scheduleTestMessage(t, service, "task-4", other)
+
+	removed, err := service.PurgeUserData(ctx, user)
+	if err != nil {
+		t.Fatalf("PurgeUserData() error = %v", err)
+	}
+	// Task index, task status, locale, transcript and its index, coalesce buffer, activity entry, scheduled message
+	if removed != 8 {
+		t.Errorf("PurgeUserData() removed = %d, want 8", removed)
+	}
+
+	for _, key := range []string{
+		userTasksKey(user),
+		"task:status:task-1",
+		"user:locale:" + user,
+		transcriptCacheKey("https://media.example/audio.ogg"),
+		userTranscriptsKey(user),
+		"coalesce:buffer:" + user,
+	} {
+		if server.Exists(key) {
+			t.Errorf("key %q survived the purge", key)
+		}
+	}
+	if activity, _ := server.ZMembers(userActivityKey); len(activity) != 0 {
+		t.Errorf("activity entries = %v, want none", activity)
+	}
+
+	// The other user's data is untouched
+	if transcript, err := service.GetCachedTranscript(ctx, "https://media.example/other.ogg"); err != nil || transcript != "oi" {
+		t.Errorf("other user's transcript = %q, %v; want %q", transcript, err, "oi")
+	}
+	scheduled, err := server.ZMembers(scheduledMessagesKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scheduled) != 1 {
+		t.Fatalf("scheduled messages left = %d, want 1", len(scheduled))
+	}
+	var left scheduledMessage
+	if err := json.Unmarshal([]byte(scheduled[0]), &left); err != nil {
+		t.Fatal(err)
+	}
+	if left.MessageID != "task-4" {
+		t.Errorf("scheduled message left = %q, want %q", left.MessageID, "task-4")
+	}
+}
+
+func TestRequeueFailedTaskOnlyOnce(t *testing.T) {
+	service, server := newTestRedisService(t)
+	service.config.Redis.StaleTaskMaxAge = time.Minute
+	ctx := context.Background()
+
+	errorResult := &models.ErrorResult{Code: "agent_error", Message: "boom"}
+	if err := service.SetTaskStatus(ctx, "task-1", string(models.TaskStatusFailed), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.SetTaskError(ctx, "task-1", errorResult, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	taskError, requeued, err := service.RequeueFailedTask(ctx, "task-1", time.Hour)
+	if err != nil || !requeued {
+		t.Fatalf("RequeueFailedTask() = %v, %v; want requeued", requeued, err)
+	}
+	var restored models.ErrorResult
+	if err := json.Unmarshal([]byte(taskError), &restored); err != nil || restored.Message != "boom" {
+		t.Errorf("returned error = %q, want the stored error", taskError)
+	}
+	if status, _ := service.GetTaskStatus(ctx, "task-1"); status != string(models.TaskStatusPending) {
+		t.Errorf("status = %q, want %q", status, models.TaskStatusPending)
+	}
+	if server.Exists("task:error:task-1") {
+		t.Error("task error survived the requeue")
+	}
+	if inFlight, _ := server.ZMembers(inFlightTasksKey); len(inFlight) != 1 {
+		t.Errorf("in-flight tasks = %v, want the requeued task", inFlight)
+	}
+
+	// A concurrent retry that read "failed" before the first one won must not requeue it again
+	if _, requeued, err := service.RequeueFailedTask(ctx, "task-1", time.Hour); err != nil || requeued {
+		t.Errorf("second RequeueFailedTask() = %v, %v; want not requeued", requeued, err)
+	}
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+)
+
+// ResultStore holds encoded task results by key. Reads of missing keys return an error wrapping
+// ErrKeyNotFound.
+type ResultStore interface {
+	SetResult(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	GetResult(ctx context.Context, key string) ([]byte, error)
+	DeleteResults(ctx context.Context, keys ...string) (int, error)
+}
+
+// primaryResultStore keeps results in the service's own Redis, going through SetValue/Get so they are
+// prefixed and counted like every other key
+type primaryResultStore struct {
+	redis *RedisService
+}
+
+// SetResult writes the result to the primary Redis
+func (s primaryResultStore) SetResult(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.redis.SetValue(ctx, key, value, ttl)
+}
+
+// GetResult reads the result from the primary Redis
+func (s primaryResultStore) GetResult(ctx context.Context, key string) ([]byte, error) {
+	value, err := s.redis.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(value), nil
+}
+
+// DeleteResults removes the results from the primary Redis, returning how many existed
+func (s primaryResultStore) DeleteResults(ctx context.Context, keys ...string) (int, error) {
+	return s.redis.DeleteKeys(ctx, keys...)
+}
+
+// RedisResultStore keeps results in a separate Redis-compatible store, used as the archive tier and for
+// tenants routed to their own result store
+type RedisResultStore struct {
+	client    *redis.Client
+	keyPrefix string
+}
+
+// NewArchiveResultStore connects to REDIS_ARCHIVE_DSN. An unreachable archive is only logged, since
+// every archive operation is best effort.
+func NewArchiveResultStore(cfg *config.Config, logger *logrus.Logger) (*RedisResultStore, error) {
+	opts, err := redis.ParseURL(cfg.Redis.ArchiveDSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis archive DSN: %w", err)
+	}
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		logger.WithError(err).Warn("Redis archive is not reachable yet, archived results may be missing")
+	}
+
+	logger.WithFields(logrus.Fields{
+		"dsn":        cfg.Redis.ArchiveDSN,
+		"result_ttl": cfg.Redis.ArchiveResultTTL.String(),
+	}).Info("Task result archive enabled")
+
+	return &RedisResultStore{client: client, keyPrefix: cfg.Redis.KeyPrefix}, nil
+}
+
+// NewRedisResultStore connects to the Redis at dsn, namespacing keys with keyPrefix. Unlike the archive it
+// holds the only copy of the results written to it, so an unreachable store is an error.
+func NewRedisResultStore(dsn, keyPrefix string) (*RedisResultStore, error) {
+	opts, err := redis.ParseURL(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis result store DSN: %w", err)
+	}
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis result store: %w", err)
+	}
+
+	return &RedisResultStore{client: client, keyPrefix: keyPrefix}, nil
+}
+
+// key applies the same namespace as the primary store
+func (s *RedisResultStore) key(key string) string {
+	if s.keyPrefix == "" {
+		return key
+	}
+	return s.keyPrefix + ":" + key
+}
+
+// SetResult writes the result to the store
+func (s *RedisResultStore) SetResult(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := s.client.Set(ctx, s.key(key), value, ttl).Err(); err != nil {
+		return fmt.Errorf("redis result store set error: %w", err)
+	}
+	return nil
+}
+
+// GetResult reads the result from the store
+func (s *RedisResultStore) GetResult(ctx context.Context, key string) ([]byte, error) {
+	value, err := s.client.Get(ctx, s.key(key)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, key)
+		}
+		return nil, fmt.Errorf("redis result store get error: %w", err)
+	}
+	return value, nil
+}
+
+// DeleteResults removes the results from the store, returning how many existed
+func (s *RedisResultStore) DeleteResults(ctx context.Context, keys ...string) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	namespaced := make([]string, len(keys))
+	for i, key := range keys {
+		namespaced[i] = s.key(key)
+	}
+	removed, err := s.client.Del(ctx, namespaced...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("redis result store delete error: %w", err)
+	}
+	return int(removed), nil
+}
+
+// Close closes the store's connection
+func (s *RedisResultStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+)
+
+const (
+	// staleTaskJanitorLockKey is held by the replica running a sweep; it is left to expire so that sweeps
+	// across all replicas happen at most once per interval
+	staleTaskJanitorLockKey = "janitor:stale_tasks:lock"
+
+	// staleTaskSweepBatchSize caps how many stale tasks one sweep fails
+	staleTaskSweepBatchSize = 500
+
+	// staleTaskErrorCode is the error code stored for the tasks the janitor fails
+	staleTaskErrorCode = "stale_task"
+
+	// staleTaskJitter is the fraction of the interval each wait is randomly shortened or lengthened by,
+	// so replicas started together don't contend for the lock on every tick
+	staleTaskJitter = 0.2
+)
+
+// StaleTaskJanitor periodically marks tasks stuck in pending/processing (e.g. after a worker crashed
+// mid-flight) as failed, so status polling ends instead of reporting "processing" forever
+type StaleTaskJanitor struct {
+	config       *config.Config
+	logger       *logrus.Logger
+	redisService *RedisService
+	tenants      *TenantResolver    // Optional; routed tenants' in-flight tasks are swept as well
+	notifier     *TaskEventNotifier // Optional; reports the failures like any other failed task
+	owner        string
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewStaleTaskJanitor creates a janitor for tasks older than REDIS_STALE_TASK_MAX_AGE, or returns nil
+// when it is disabled. With a tenant resolver, the namespace of every routed tenant is swept too.
+func NewStaleTaskJanitor(cfg *config.Config, logger *logrus.Logger, redisService *RedisService, tenants *TenantResolver, notifier *TaskEventNotifier) *StaleTaskJanitor {
+	if cfg.Redis.StaleTaskMaxAge <= 0 {
+		return nil
+	}
+
+	hostname, _ := os.Hostname()
+	return &StaleTaskJanitor{
+		config:       cfg,
+		logger:       logger,
+		redisService: redisService,
+		tenants:      tenants,
+		notifier:     notifier,
+		owner:        fmt.Sprintf("%s-%s", hostname, uuid.New().String()),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+// sweepInterval returns the configured interval between sweeps
+func (j *StaleTaskJanitor) sweepInterval() time.Duration {
+	if interval := j.config.Redis.StaleTaskSweepInterval; interval > 0 {
+		return interval
+	}
+	return time.Minute
+}
+
+// Start runs a sweep after every jittered interval until Stop is called
+func (j *StaleTaskJanitor) Start() {
+	interval := j.sweepInterval()
+
+	go func() {
+		defer close(j.done)
+
+		for {
+			wait := time.Duration(float64(interval) * (1 - staleTaskJitter + 2*staleTaskJitter*rand.Float64()))
+			timer := time.NewTimer(wait)
+
+			select {
+			case <-timer.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				if _, err := j.SweepOnce(ctx); err != nil {
+					j.logger.WithError(err).Warn("Stale task sweep failed")
+				}
+				cancel()
+			case <-j.stop:
+				timer.Stop()
+				return
+			}
+		}
+	}()
+
+	j.logger.WithFields(logrus.Fields{
+		"interval": interval,
+		"max_age":  j.config.Redis.StaleTaskMaxAge,
+	}).Info("Stale task janitor started")
+}
+
+// Stop ends the sweep loop and waits for an in-progress sweep to finish
+func (j *StaleTaskJanitor) Stop() {
+	j.stopOnce.Do(func() {
+		close(j.stop)
+	})
+	<-j.done
+}
+
+// SweepOnce fails the tasks that have been pending/processing for longer than the maximum age, in the
+// default namespace and every routed tenant's, unless another replica swept within the last interval.
+// It returns how many tasks were failed.
+func (j *StaleTaskJanitor) SweepOnce(ctx context.Context) (int, error) {
+	acquired, err := j.redisService.SetNX(ctx, staleTaskJanitorLockKey, j.owner, j.sweepInterval())
+	if err != nil {
+		return 0, fmt.Errorf("failed to acquire janitor lock: %w", err)
+	}
+	if !acquired {
+		return 0, nil
+	}
+
+	stores := []*RedisService{j.redisService}
+	if j.tenants != nil {
+		stores = j.tenants.Services()
+	}
+
+	failed := 0
+	for _, store := range stores {
+		if ctx.Err() != nil {
+			break
+		}
+		storeFailed, err := j.sweepStore(ctx, store)
+		failed += storeFailed
+		if err != nil {
+			return failed, err
+		}
+	}
+	return failed, nil
+}
+
+// sweepStore fails the stale tasks in one namespace's in-flight index
+func (j *StaleTaskJanitor) sweepStore(ctx context.Context, store *RedisService) (int, error) {
+	maxAge := j.config.Redis.StaleTaskMaxAge
+	taskIDs, err := store.GetStaleInFlightTasks(ctx, time.Now().Add(-maxAge), staleTaskSweepBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list stale tasks under %q: %w", store.keyPrefix, err)
+	}
+
+	errorMessage := fmt.Sprintf("task timed out: still processing after %s", maxAge)
+	errorResult := &models.ErrorResult{
+		Code:      staleTaskErrorCode,
+		Message:   errorMessage,
+		Retryable: true,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+	ttl := j.config.GetTaskStatusTTL(string(models.TaskStatusFailed))
+
+	failed := 0
+	for _, taskID := range taskIDs {
+		if ctx.Err() != nil {
+			break
+		}
+
+		wasFailed, err := store.FailStaleTask(ctx, taskID, errorResult, ttl)
+		if err != nil {
+			j.logger.WithError(err).WithField("task_id", taskID).Warn("Failed to mark stale task as failed")
+			continue
+		}
+		if !wasFailed {
+			continue
+		}
+
+		failed++
+		j.logger.WithFields(logrus.Fields{
+			"task_id":    taskID,
+			"key_prefix": store.keyPrefix,
+			"max_age":    maxAge,
+		}).Warn("Marked stale task as failed")
+		event := models.TaskEvent{
+			MessageID: taskID,
+			Status:    string(models.TaskStatusFailed),
+			Error:     &errorMessage,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		}
+		if j.notifier != nil {
+			j.notifier.Notify(event)
+		}
+		// The user isn't known here, so only the task's own subscribers hear about it
+		if j.config.Redis.PublishTaskEvents {
+			if err := store.PublishTaskEvent(ctx, "", event); err != nil {
+				j.logger.WithError(err).WithField("task_id", taskID).Warn("Failed to publish stale task event")
+			}
+		}
+	}
+
+	j.logger.WithFields(logrus.Fields{
+		"key_prefix": store.keyPrefix,
+		"candidates": len(taskIDs),
+		"failed":     failed,
+	}).Debug("Stale task sweep completed")
+
+	return failed, nil
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+)
+
+func TestSweepOnceCoversTenantNamespaces(t *testing.T) {
+	service, _ := newTestRedisService(t)
+	service.config.Redis.StaleTaskMaxAge = time.Millisecond
+	tenant := service.WithKeyPrefix("tenant-a", nil)
+	resolver := &TenantResolver{
+		logger:   service.logger,
+		fallback: service,
+		tenants:  map[string]*RedisService{"a": tenant},
+	}
+
+	ctx := context.Background()
+	if err := service.SetTaskStatus(ctx, "default-task", string(models.TaskStatusProcessing), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := tenant.SetTaskStatus(ctx, "tenant-task", string(models.TaskStatusPending), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	janitor := NewStaleTaskJanitor(service.config, service.logger, service, resolver, nil)
+	failed, err := janitor.SweepOnce(ctx)
+	if err != nil {
+		t.Fatalf("SweepOnce() error = %v", err)
+	}
+	if failed != 2 {
+		t.Errorf("SweepOnce() failed = %d, want 2", failed)
+	}
+
+	for _, check := range []struct {
+		store  *RedisService
+		taskID string
+	}{{service, "default-task"}, {tenant, "tenant-task"}} {
+		status, err := check.store.GetTaskStatus(ctx, check.taskID)
+		if err != nil || status != string(models.TaskStatusFailed) {
+			t.Errorf("%s status = %q, %v; want %q", check.taskID, status, err, models.TaskStatusFailed)
+		}
+	}
+}
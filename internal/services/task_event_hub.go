@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+)
+
+// taskEventBuffer is how many undelivered events a subscriber may fall behind by before events are dropped
+const taskEventBuffer = 16
+
+// TaskEventHub fans task events published by the workers (RedisService.PublishTaskEvent) out to the
+// gateway's WebSocket clients. All clients share one Redis pub/sub connection, subscribed to each channel
+// only while some client listens on it.
+type TaskEventHub struct {
+	logger *logrus.Logger
+	pubsub *redis.PubSub
+
+	mutex       sync.Mutex
+	subscribers map[string]map[chan models.TaskEvent]struct{} // By channel
+}
+
+// NewTaskEventHub returns a hub receiving events over the Redis connection, or nil when
+// REDIS_PUBLISH_TASK_EVENTS is disabled
+func NewTaskEventHub(cfg *config.Config, logger *logrus.Logger, redisService *RedisService) *TaskEventHub {
+	if !cfg.Redis.PublishTaskEvents {
+		return nil
+	}
+
+	hub := &TaskEventHub{
+		logger:      logger,
+		pubsub:      redisService.client.Subscribe(context.Background()),
+		subscribers: make(map[string]map[chan models.TaskEvent]struct{}),
+	}
+	go hub.run()
+
+	logger.Info("Task events are pushed to WebSocket subscribers")
+	return hub
+}
+
+// Subscribe returns the events published on the channel (see RedisService.TaskEventChannel and
+// UserEventChannel) and a function that ends the subscription; the events channel is never closed
+func (h *TaskEventHub) Subscribe(ctx context.Context, channel string) (<-chan models.TaskEvent, func(), error) {
+	events := make(chan models.TaskEvent, taskEventBuffer)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if len(h.subscribers[channel]) == 0 {
+		if err := h.pubsub.Subscribe(ctx, channel); err != nil {
+			return nil, nil, fmt.Errorf("failed to subscribe to task events: %w", err)
+		}
+		h.subscribers[channel] = make(map[chan models.TaskEvent]struct{})
+	}
+	h.subscribers[channel][events] = struct{}{}
+
+	unsubscribe := func() {
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+
+		delete(h.subscribers[channel], events)
+		if len(h.subscribers[channel]) == 0 {
+			delete(h.subscribers, channel)
+			if err := h.pubsub.Unsubscribe(context.Background(), channel); err != nil {
+				h.logger.WithError(err).WithField("channel", channel).Warn("Failed to unsubscribe from task events")
+			}
+		}
+	}
+	return events, unsubscribe, nil
+}
+
+// run delivers each published event to the channel's subscribers, dropping it for those too far behind
+func (h *TaskEventHub) run() {
+	for message := range h.pubsub.Channel() {
+		var event models.TaskEvent
+		if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+			h.logger.WithError(err).WithField("channel", message.Channel).Warn("Ignoring malformed task event")
+			continue
+		}
+
+		h.mutex.Lock()
+		for events := range h.subscribers[message.Channel] {
+			select {
+			case events <- event:
+			default:
+				h.logger.WithFields(logrus.Fields{
+					"channel":    message.Channel,
+					"message_id": event.MessageID,
+				}).Warn("Task event subscriber is too slow, dropping event")
+			}
+		}
+		h.mutex.Unlock()
+	}
+}
+
+// Close ends every subscription and releases the pub/sub connection
+func (h *TaskEventHub) Close() error {
+	return h.pubsub.Close()
+}
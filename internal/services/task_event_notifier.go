@@ -0,0 +1,126 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+)
+
+// TaskEventNotifier pushes task completion/failure events to a single configured webhook.
+// Unlike per-message callbacks, events carry no result body — only a pointer to poll it from.
+type TaskEventNotifier struct {
+	logger     *logrus.Logger
+	config     *config.Config
+	webhookURL string
+	timeout    time.Duration
+	maxRetries int
+	httpClient *http.Client
+}
+
+// NewTaskEventNotifier creates a new task event notifier for the configured webhook URL
+func NewTaskEventNotifier(logger *logrus.Logger, cfg *config.Config) *TaskEventNotifier {
+	timeout := time.Duration(cfg.Callback.EventWebhookTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	return &TaskEventNotifier{
+		logger:     logger,
+		config:     cfg,
+		webhookURL: cfg.Callback.EventWebhookURL,
+		timeout:    timeout,
+		maxRetries: cfg.Callback.EventWebhookMaxRetries,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Notify sends the event asynchronously; delivery failures are logged and never returned
+func (n *TaskEventNotifier) Notify(event models.TaskEvent) {
+	if n == nil || n.webhookURL == "" {
+		return
+	}
+
+	go func() {
+		if err := n.send(context.Background(), event); err != nil {
+			n.logger.WithError(err).WithFields(logrus.Fields{
+				"message_id": event.MessageID,
+				"status":     event.Status,
+			}).Warn("Failed to deliver task event")
+		}
+	}()
+}
+
+// send delivers the event with retry logic and exponential backoff
+func (n *TaskEventNotifier) send(ctx context.Context, event models.TaskEvent) error {
+	payloadBytes, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to serialize task event: %w", err)
+	}
+
+	for attempt := 0; attempt <= n.maxRetries; attempt++ {
+		if attempt > 0 {
+			// Exponential backoff: 1s, 2s, 4s
+			time.Sleep(time.Duration(1<<uint(attempt-1)) * time.Second)
+		}
+
+		err = n.post(ctx, payloadBytes)
+		if err == nil {
+			n.logger.WithFields(logrus.Fields{
+				"message_id": event.MessageID,
+				"status":     event.Status,
+				"attempts":   attempt + 1,
+			}).Debug("Task event delivered")
+			return nil
+		}
+
+		if !isRetriableError(err) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("task event failed after %d attempts: %w", n.maxRetries+1, err)
+}
+
+// post sends a single HTTP POST request to the event webhook with its own timeout
+func (n *TaskEventNotifier) post(ctx context.Context, payloadBytes []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhookURL, bytes.NewReader(payloadBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "EAI-Agent-Gateway/1.0")
+
+	// Sign events with the callback secret when HMAC is enabled
+	if n.config.Callback.EnableHMAC && n.config.Callback.HMACSecret != "" {
+		req.Header.Set("X-Signature-SHA256", generateHMACSignature(payloadBytes, n.config.Callback.HMACSecret))
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return &HTTPError{
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+		}
+	}
+
+	return nil
+}
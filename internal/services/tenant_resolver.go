@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+)
+
+// TenantResolver selects where a tenant's task data is kept (REDIS_TENANT_ROUTES). Routed tenants get a
+// view of the RedisService under their own key prefix, writing results to their own store when configured;
+// every other tenant shares the default service.
+type TenantResolver struct {
+	logger   *logrus.Logger
+	fallback *RedisService
+	tenants  map[string]*RedisService
+	stores   []*RedisResultStore
+}
+
+// NewTenantResolver builds the tenant views of redisService, or returns nil when no tenant routes are
+// configured (all task data then stays under REDIS_KEY_PREFIX)
+func NewTenantResolver(cfg *config.Config, logger *logrus.Logger, redisService *RedisService) (*TenantResolver, error) {
+	routes, err := cfg.GetTenantRedisRoutes()
+	if err != nil {
+		return nil, err
+	}
+	if len(routes) == 0 {
+		return nil, nil
+	}
+
+	resolver := &TenantResolver{
+		logger:   logger,
+		fallback: redisService,
+		tenants:  make(map[string]*RedisService, len(routes)),
+	}
+	for tenantID, route := range routes {
+		var results ResultStore
+		if route.ResultStoreDSN != "" {
+			store, err := NewRedisResultStore(route.ResultStoreDSN, route.KeyPrefix)
+			if err != nil {
+				resolver.Close()
+				return nil, fmt.Errorf("tenant %q: %w", tenantID, err)
+			}
+			resolver.stores = append(resolver.stores, store)
+			results = store
+		}
+		resolver.tenants[tenantID] = redisService.WithKeyPrefix(route.KeyPrefix, results)
+
+		logger.WithFields(logrus.Fields{
+			"tenant_id":           tenantID,
+			"key_prefix":          route.KeyPrefix,
+			"dedicated_result_db": route.ResultStoreDSN != "",
+		}).Info("Tenant task data routed")
+	}
+
+	return resolver, nil
+}
+
+// Redis returns the service holding the tenant's task data, the default one for tenants without a route
+func (t *TenantResolver) Redis(tenantID string) *RedisService {
+	if service, ok := t.tenants[tenantID]; ok && tenantID != "" {
+		return service
+	}
+	return t.fallback
+}
+
+// IsRouted reports whether the tenant's task data is kept apart from the default namespace
+func (t *TenantResolver) IsRouted(tenantID string) bool {
+	_, ok := t.tenants[tenantID]
+	return ok && tenantID != ""
+}
+
+// Services returns the default service followed by every routed tenant's view, ordered by tenant ID
+func (t *TenantResolver) Services() []*RedisService {
+	tenantIDs := make([]string, 0, len(t.tenants))
+	for tenantID := range t.tenants {
+		tenantIDs = append(tenantIDs, tenantID)
+	}
+	sort.Strings(tenantIDs)
+
+	services := []*RedisService{t.fallback}
+	for _, tenantID := range tenantIDs {
+		services = append(services, t.tenants[tenantID])
+	}
+	return services
+}
+
+// PurgeUserData purges the user's data from the default namespace and every routed tenant's, since the
+// same user may have written to any of them, returning how many keys were removed in total
+func (t *TenantResolver) PurgeUserData(ctx context.Context, userNumber string) (int, error) {
+	removed, err := t.fallback.PurgeUserData(ctx, userNumber)
+	if err != nil {
+		return removed, err
+	}
+	for tenantID, service := range t.tenants {
+		tenantRemoved, err := service.PurgeUserData(ctx, userNumber)
+		removed += tenantRemoved
+		if err != nil {
+			return removed, fmt.Errorf("tenant %q: %w", tenantID, err)
+		}
+	}
+	return removed, nil
+}
+
+// Close closes the tenants' dedicated result stores; the shared RedisService is closed by its owner
+func (t *TenantResolver) Close() {
+	for _, store := range t.stores {
+		if err := store.Close(); err != nil {
+			t.logger.WithError(err).Warn("Failed to close tenant result store")
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+)
+
+// ThreadWarmer periodically keeps the threads of recently active users ready, so the first message
+// after a pause doesn't pay for thread setup
+type ThreadWarmer struct {
+	config       *config.Config
+	logger       *logrus.Logger
+	agentService *GoogleAgentEngineService
+	redisService *RedisService
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewThreadWarmer creates a new thread warmer
+func NewThreadWarmer(cfg *config.Config, logger *logrus.Logger, agentService *GoogleAgentEngineService, redisService *RedisService) *ThreadWarmer {
+	return &ThreadWarmer{
+		config:       cfg,
+		logger:       logger,
+		agentService: agentService,
+		redisService: redisService,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+// Start runs a warming pass every THREAD_WARMER_INTERVAL until Stop is called
+func (w *ThreadWarmer) Start() {
+	interval := w.config.GoogleAgentEngine.ThreadWarmerInterval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	go func() {
+		defer close(w.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				if _, _, err := w.WarmOnce(ctx); err != nil {
+					w.logger.WithError(err).Warn("Thread warming pass failed")
+				}
+				cancel()
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+
+	w.logger.WithFields(logrus.Fields{
+		"interval":        interval,
+		"activity_window": w.config.GoogleAgentEngine.ThreadWarmerActivityWindow,
+		"batch_size":      w.config.GoogleAgentEngine.ThreadWarmerBatchSize,
+	}).Info("Thread warmer started")
+}
+
+// Stop ends the warming loop and waits for an in-progress pass to finish
+func (w *ThreadWarmer) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+}
+
+// WarmOnce warms the threads of users active within the activity window (up to the batch size) and
+// forgets older activity. It returns how many threads were refreshed and how many were created.
+func (w *ThreadWarmer) WarmOnce(ctx context.Context) (refreshed int, created int, err error) {
+	since := time.Now().Add(-w.config.GoogleAgentEngine.ThreadWarmerActivityWindow)
+
+	if err := w.redisService.TrimUserActivity(ctx, since); err != nil {
+		w.logger.WithError(err).Warn("Failed to trim stale user activity")
+	}
+
+	users, err := w.redisService.GetActiveUsers(ctx, since, w.config.GoogleAgentEngine.ThreadWarmerBatchSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list active users: %w", err)
+	}
+
+	for _, user := range users {
+		if ctx.Err() != nil {
+			break
+		}
+
+		wasCreated, err := w.agentService.WarmThread(ctx, user)
+		if err != nil {
+			w.logger.WithError(err).WithField("user_id", user).Warn("Failed to warm thread")
+			continue
+		}
+		if wasCreated {
+			created++
+		} else {
+			refreshed++
+		}
+	}
+
+	w.logger.WithFields(logrus.Fields{
+		"active_users": len(users),
+		"refreshed":    refreshed,
+		"created":      created,
+	}).Debug("Thread warming pass completed")
+
+	return refreshed, created, nil
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+)
+
+// Redactor removes sensitive segments from tool output before it reaches the user
+type Redactor interface {
+	// Redact returns the content with sensitive segments replaced and how many were replaced
+	Redact(content string) (string, int)
+}
+
+// defaultRedactionPatterns match internal data commonly leaked by tools: credentials, SQL and stack traces
+var defaultRedactionPatterns = []string{
+	`(?i)\b(?:api[_-]?key|access[_-]?token|secret|password|passwd)\b\s*[:=]\s*["']?[^\s"',;]+`,
+	`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`,
+	`\bAIza[0-9A-Za-z\-_]{35}\b`,
+	`\bAKIA[0-9A-Z]{16}\b`,
+	`\bsk-[A-Za-z0-9_\-]{20,}\b`,
+	`(?is)\b(?:SELECT\s+.+?\s+FROM|INSERT\s+INTO|UPDATE\s+\w+\s+SET|DELETE\s+FROM)\b[^;]*;?`,
+	`(?s)Traceback \(most recent call last\):.*`,
+	`(?s)goroutine \d+ \[[^\]]+\]:.*`,
+	`(?m)^\s*at [\w$.<>]+\([^)]*\)\s*$`,
+}
+
+// RegexRedactor replaces every match of its patterns with a placeholder
+type RegexRedactor struct {
+	patterns    []*regexp.Regexp
+	placeholder string
+}
+
+// NewRegexRedactor creates a redactor with the built-in patterns plus TOOL_OUTPUT_REDACTION_PATTERN, if set
+func NewRegexRedactor(cfg *config.Config, logger *logrus.Logger) (*RegexRedactor, error) {
+	sources := defaultRedactionPatterns
+	if extra := cfg.Security.ToolOutputRedactionPattern; extra != "" {
+		sources = append(append([]string{}, sources...), extra)
+	}
+
+	patterns := make([]*regexp.Regexp, 0, len(sources))
+	for _, source := range sources {
+		pattern, err := regexp.Compile(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", source, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	placeholder := cfg.Security.ToolOutputRedactionPlaceholder
+	if placeholder == "" {
+		placeholder = "[REDACTED]"
+	}
+
+	logger.WithField("patterns", len(patterns)).Info("Tool output redaction enabled")
+
+	return &RegexRedactor{
+		patterns:    patterns,
+		placeholder: placeholder,
+	}, nil
+}
+
+// Redact replaces every pattern match in content with the placeholder
+func (r *RegexRedactor) Redact(content string) (string, int) {
+	count := 0
+	for _, pattern := range r.patterns {
+		content = pattern.ReplaceAllStringFunc(content, func(string) string {
+			count++
+			return r.placeholder
+		})
+	}
+	return content, count
+}
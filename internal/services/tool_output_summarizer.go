@@ -0,0 +1,88 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+)
+
+// ToolOutputSummarizer turns a large tool output into a short human-readable line for the user
+type ToolOutputSummarizer interface {
+	Summarize(ctx context.Context, toolName, output string) (string, error)
+}
+
+// HTTPToolOutputSummarizer asks an external service for the summary: it POSTs {"tool_name", "content"}
+// to TOOL_OUTPUT_SUMMARY_URL and expects {"summary": "..."} back
+type HTTPToolOutputSummarizer struct {
+	logger     *logrus.Logger
+	url        string
+	httpClient *http.Client
+}
+
+// NewHTTPToolOutputSummarizer creates a summarizer for the configured URL
+func NewHTTPToolOutputSummarizer(cfg *config.Config, logger *logrus.Logger) (*HTTPToolOutputSummarizer, error) {
+	url := strings.TrimSpace(cfg.GoogleAgentEngine.ToolOutputSummaryURL)
+	if url == "" {
+		return nil, fmt.Errorf("TOOL_OUTPUT_SUMMARY_URL is required for the http summarizer")
+	}
+
+	return &HTTPToolOutputSummarizer{
+		logger:     logger,
+		url:        url,
+		httpClient: &http.Client{Timeout: cfg.GetToolOutputSummaryTimeout()},
+	}, nil
+}
+
+// Summarize posts the tool output and returns the summary from the response
+func (s *HTTPToolOutputSummarizer) Summarize(ctx context.Context, toolName, output string) (string, error) {
+	payloadBytes, err := json.Marshal(map[string]string{
+		"tool_name": toolName,
+		"content":   output,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize summary request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(payloadBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "EAI-Agent-Gateway/1.0")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read summary response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", &HTTPError{
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+		}
+	}
+
+	var result struct {
+		Summary string `json:"summary"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("failed to parse summary response: %w", err)
+	}
+	if strings.TrimSpace(result.Summary) == "" {
+		return "", fmt.Errorf("summary response has no summary")
+	}
+	return strings.TrimSpace(result.Summary), nil
+}
@@ -7,7 +7,6 @@ import (
 	"io"
 	"mime"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -76,8 +75,12 @@ type TranscribeService struct {
 	logger      *logrus.Logger
 	client      *speech.Client
 	rateLimiter RateLimiterInterface
+	model       string // Speech-to-Text recognition model ("long" unless set with SetModel)
 }
 
+// defaultRecognitionModel is the Speech-to-Text model used unless the backend options choose another
+const defaultRecognitionModel = "long"
+
 // NewTranscribeService creates a new transcription service
 func NewTranscribeService(
 	cfg *config.Config,
@@ -116,6 +119,7 @@ func NewTranscribeService(
 		logger:      logger,
 		client:      client,
 		rateLimiter: rateLimiter,
+		model:       defaultRecognitionModel,
 	}
 
 	logger.WithFields(logrus.Fields{
@@ -127,6 +131,14 @@ func NewTranscribeService(
 	return service, nil
 }
 
+// SetModel selects the Speech-to-Text recognition model (e.g. a stronger tier for re-transcription);
+// an empty model keeps the current one. Call it before the service is used.
+func (s *TranscribeService) SetModel(model string) {
+	if model != "" {
+		s.model = model
+	}
+}
+
 // TranscribeFromURL downloads an audio file from URL and transcribes it
 func (s *TranscribeService) TranscribeFromURL(ctx context.Context, audioURL string) (*TranscriptionResult, error) {
 	start := time.Now()
@@ -221,7 +233,7 @@ func (s *TranscribeService) TranscribeFromFile(ctx context.Context, filePath str
 				MaxAlternatives:            int32(s.config.Transcribe.MaxAlternatives),
 			},
 			LanguageCodes: []string{s.config.Transcribe.LanguageCode},
-			Model:         "long",
+			Model:         s.model,
 		},
 		AudioSource: &speechpb.RecognizeRequest_Content{
 			Content: audioData,
@@ -257,15 +269,16 @@ func (s *TranscribeService) TranscribeFromFile(ctx context.Context, filePath str
 	return result, nil
 }
 
+// ValidateAudioURL checks the audio URL against the host policy and allowed URLs without fetching it.
+// Rejections wrap ErrAudioURLNotAllowed.
+func (s *TranscribeService) ValidateAudioURL(audioURL string) error {
+	return s.validateURL(audioURL)
+}
+
 // validateURL validates that the audio URL is allowed
 func (s *TranscribeService) validateURL(audioURL string) error {
-	parsed, err := url.Parse(audioURL)
-	if err != nil {
-		return fmt.Errorf("invalid URL format: %w", err)
-	}
-
-	if parsed.Scheme != "https" && parsed.Scheme != "http" {
-		return fmt.Errorf("unsupported URL scheme: %s", parsed.Scheme)
+	if err := CheckAudioURLHost(s.config, audioURL); err != nil {
+		return err
 	}
 
 	allowedDomains := s.config.GetTranscribeAllowedDomains()
@@ -275,7 +288,7 @@ func (s *TranscribeService) validateURL(audioURL string) error {
 		}
 	}
 
-	return fmt.Errorf("URL not in allowed domains: %v", allowedDomains)
+	return fmt.Errorf("%w: URL not in allowed domains: %v", ErrAudioURLNotAllowed, allowedDomains)
 }
 
 // validateFile validates the audio file
@@ -341,7 +354,7 @@ func (s *TranscribeService) transcribeFromMemory(ctx context.Context, audioData
 				MaxAlternatives:            int32(s.config.Transcribe.MaxAlternatives),
 			},
 			LanguageCodes: []string{s.config.Transcribe.LanguageCode},
-			Model:         "long",
+			Model:         s.model,
 		},
 		AudioSource: &speechpb.RecognizeRequest_Content{
 			Content: audioData,
@@ -393,9 +406,7 @@ func (s *TranscribeService) downloadFileToMemory(ctx context.Context, audioURL s
 	}
 
 	// Perform request
-	client := &http.Client{
-		Timeout: s.config.Transcribe.DownloadTimeout,
-	}
+	client := newAudioDownloadClient(s.config.Transcribe.DownloadTimeout)
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download file: %w", err)
@@ -441,9 +452,7 @@ func (s *TranscribeService) downloadFile(ctx context.Context, audioURL string) (
 	}
 
 	// Perform request
-	client := &http.Client{
-		Timeout: s.config.Transcribe.DownloadTimeout,
-	}
+	client := newAudioDownloadClient(s.config.Transcribe.DownloadTimeout)
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to download file: %w", err)
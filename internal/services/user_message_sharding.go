@@ -0,0 +1,37 @@
+package services
+
+import (
+	"hash/fnv"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+)
+
+// UserMessageShard maps a user number to one of shards buckets with jump consistent hashing, so a user
+// always lands on the same shard and changing the shard count only moves the users it has to
+func UserMessageShard(userNumber string, shards int) int {
+	if shards <= 1 {
+		return 0
+	}
+
+	hasher := fnv.New64a()
+	_, _ = hasher.Write([]byte(userNumber))
+	key := hasher.Sum64()
+
+	// Lamping & Veach, "A Fast, Minimal Memory, Consistent Hash Algorithm"
+	bucket, next := int64(-1), int64(0)
+	for next < int64(shards) {
+		bucket = next
+		key = key*2862933555777941757 + 1
+		next = int64(float64(bucket+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(bucket)
+}
+
+// UserMessageQueue returns the queue a user's messages are published to: their shard queue when
+// RABBITMQ_USER_MESSAGE_SHARDS is set, otherwise the single user messages queue
+func UserMessageQueue(cfg *config.Config, userNumber string) string {
+	if cfg.RabbitMQ.UserMessageShards <= 0 {
+		return cfg.RabbitMQ.UserMessagesQueue
+	}
+	return cfg.GetUserMessageShardQueue(UserMessageShard(userNumber, cfg.RabbitMQ.UserMessageShards))
+}
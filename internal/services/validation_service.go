@@ -339,6 +339,13 @@ func (v *ValidationService) ValidateAudioURL(audioURL string) *ValidationResult
 		return result
 	}
 
+	// Enforce the audio host policy (allow/deny lists, no private addresses)
+	if err := CheckAudioURLHost(v.config, audioURL); err != nil {
+		result.Valid = false
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
 	// Check against blocked domains
 	if v.isDomainBlocked(parsedURL.Host) {
 		result.Valid = false
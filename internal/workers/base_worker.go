@@ -159,7 +159,7 @@ func (w *BaseWorker) handleMessage(ctx context.Context, delivery amqp.Delivery)
 	procCtx.Logger.Info("Processing message")
 
 	// Set processing status in Redis
-	err = w.deps.RedisService.SetTaskStatus(ctx, procCtx.MessageID, string(models.TaskStatusProcessing), w.deps.Config.Redis.TaskStatusTTL)
+	err = w.deps.RedisService.SetTaskStatus(ctx, procCtx.MessageID, string(models.TaskStatusProcessing), w.deps.Config.GetTaskStatusTTL(string(models.TaskStatusProcessing)))
 	if err != nil {
 		procCtx.Logger.WithError(err).Error("Failed to set processing status")
 		// Continue processing anyway
@@ -182,7 +182,7 @@ func (w *BaseWorker) handleMessage(ctx context.Context, delivery amqp.Delivery)
 		}
 
 		// Set completed status
-		err = w.deps.RedisService.SetTaskStatus(ctx, procCtx.MessageID, string(models.TaskStatusCompleted), w.deps.Config.Redis.TaskStatusTTL)
+		err = w.deps.RedisService.SetTaskStatus(ctx, procCtx.MessageID, string(models.TaskStatusCompleted), w.deps.Config.GetTaskStatusTTL(string(models.TaskStatusCompleted)))
 		if err != nil {
 			procCtx.Logger.WithError(err).Error("Failed to set completed status")
 		}
@@ -194,13 +194,13 @@ func (w *BaseWorker) handleMessage(ctx context.Context, delivery amqp.Delivery)
 		redisCtx, redisCancel := context.WithTimeout(context.Background(), 5*time.Second)
 		errorKey := "task:error:" + procCtx.MessageID
 		errorMsg := w.deps.MessageFormatter.FormatErrorMessage(redisCtx, result.Error)
-		err = w.deps.RedisService.Set(redisCtx, errorKey, errorMsg, w.deps.Config.Redis.TaskStatusTTL)
+		err = w.deps.RedisService.Set(redisCtx, errorKey, errorMsg, w.deps.Config.GetTaskStatusTTL(string(models.TaskStatusFailed)))
 		if err != nil {
 			procCtx.Logger.WithError(err).Error("Failed to store error in Redis")
 		}
 
 		// Set failed status
-		err = w.deps.RedisService.SetTaskStatus(redisCtx, procCtx.MessageID, string(models.TaskStatusFailed), w.deps.Config.Redis.TaskStatusTTL)
+		err = w.deps.RedisService.SetTaskStatus(redisCtx, procCtx.MessageID, string(models.TaskStatusFailed), w.deps.Config.GetTaskStatusTTL(string(models.TaskStatusFailed)))
 		if err != nil {
 			procCtx.Logger.WithError(err).Error("Failed to update task status to failed")
 		}
@@ -0,0 +1,305 @@
+//go:build integration
+
+// Package integration runs the worker's consume → process → store loop against a real RabbitMQ and
+// Redis, with a fake agent in place of the Google Agent Engine. TestMain starts both in containers
+// (Docker required); set RABBITMQ_URL and REDIS_DSN to use running instances instead. Run:
+//
+//	go test -tags=integration ./tests/integration/...
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/testcontainers/testcontainers-go"
+	tcrabbitmq "github.com/testcontainers/testcontainers-go/modules/rabbitmq"
+	tcredis "github.com/testcontainers/testcontainers-go/modules/redis"
+
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/config"
+	workerhandlers "github.com/prefeitura-rio/app-eai-agent-gateway/internal/handlers/workers"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/handlers/workers/workerstest"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/models"
+	"github.com/prefeitura-rio/app-eai-agent-gateway/internal/services"
+)
+
+const (
+	redisImage    = "redis:7-alpine"
+	rabbitMQImage = "rabbitmq:3.13-management-alpine"
+)
+
+// placeholderEnv satisfies the required settings the worker loop never uses with a fake agent
+var placeholderEnv = map[string]string{
+	"APP_PREFIX":          "integration",
+	"REASONING_ENGINE_ID": "integration",
+	"PROJECT_ID":          "integration",
+	"PROJECT_NUMBER":      "0",
+	"LOCATION":            "us-central1",
+	"SERVICE_ACCOUNT":     "integration",
+	"GCS_BUCKET":          "integration",
+	"EAI_AGENT_URL":       "http://localhost",
+	"EAI_AGENT_TOKEN":     "integration",
+	"LLM_MODEL":           "integration",
+	"EMBEDDING_MODEL":     "integration",
+}
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+// run starts the RabbitMQ and Redis containers the suite needs, unless RABBITMQ_URL and REDIS_DSN
+// already point at running instances, and removes them once the tests finish
+func run(m *testing.M) int {
+	if os.Getenv("RABBITMQ_URL") == "" || os.Getenv("REDIS_DSN") == "" {
+		terminate, err := startContainers(context.Background())
+		defer terminate()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "integration: %v\n", err)
+			return 1
+		}
+	}
+	if os.Getenv("REDIS_BACKEND") == "" {
+		_ = os.Setenv("REDIS_BACKEND", os.Getenv("REDIS_DSN"))
+	}
+	for key, value := range placeholderEnv {
+		if os.Getenv(key) == "" {
+			_ = os.Setenv(key, value)
+		}
+	}
+	return m.Run()
+}
+
+// startContainers runs Redis and RabbitMQ and exports their URLs as REDIS_DSN and RABBITMQ_URL. The
+// returned function removes whichever containers were started, even when err is set.
+func startContainers(ctx context.Context) (terminate func(), err error) {
+	var started []testcontainers.Container
+	terminate = func() {
+		for _, container := range started {
+			_ = testcontainers.TerminateContainer(container)
+		}
+	}
+
+	redisContainer, err := tcredis.Run(ctx, redisImage)
+	if redisContainer != nil {
+		started = append(started, redisContainer)
+	}
+	if err != nil {
+		return terminate, fmt.Errorf("start Redis: %w", err)
+	}
+	redisDSN, err := redisContainer.ConnectionString(ctx)
+	if err != nil {
+		return terminate, fmt.Errorf("get Redis connection string: %w", err)
+	}
+
+	rabbitMQContainer, err := tcrabbitmq.Run(ctx, rabbitMQImage)
+	if rabbitMQContainer != nil {
+		started = append(started, rabbitMQContainer)
+	}
+	if err != nil {
+		return terminate, fmt.Errorf("start RabbitMQ: %w", err)
+	}
+	rabbitMQURL, err := rabbitMQContainer.AmqpURL(ctx)
+	if err != nil {
+		return terminate, fmt.Errorf("get RabbitMQ URL: %w", err)
+	}
+
+	_ = os.Setenv("REDIS_DSN", redisDSN)
+	_ = os.Setenv("RABBITMQ_URL", rabbitMQURL)
+	return terminate, nil
+}
+
+// worker is one test's running consumer, isolated in its own queues and Redis key prefix
+type worker struct {
+	redis    *services.RedisService
+	rabbitMQ *services.RabbitMQService
+	queue    string
+	agent    *workerstest.FakeAgentService
+}
+
+// startWorker connects to the test RabbitMQ and Redis and consumes a fresh queue with the real user
+// message handler backed by agent
+func startWorker(t *testing.T, agent *workerstest.FakeAgentService) *worker {
+	t.Helper()
+
+	run := uuid.NewString()[:8]
+	queue := "integration.user_messages." + run
+	t.Setenv("RABBITMQ_USER_QUEUE", "integration.user."+run)
+	t.Setenv("RABBITMQ_USER_MESSAGES_QUEUE", queue)
+	t.Setenv("RABBITMQ_MAX_RETRIES", "0")
+	t.Setenv("REDIS_KEY_PREFIX", "integration:"+run)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	redisService, err := services.NewRedisService(cfg, logger)
+	if err != nil {
+		t.Fatalf("connect to Redis: %v", err)
+	}
+	rabbitMQService, err := services.NewRabbitMQService(cfg, logger)
+	if err != nil {
+		_ = redisService.Close()
+		t.Fatalf("connect to RabbitMQ: %v", err)
+	}
+
+	deps := &workerhandlers.MessageHandlerDependencies{
+		Logger:             logger,
+		Config:             cfg,
+		RedisService:       redisService,
+		GoogleAgentService: agent,
+		MessageFormatter:   services.NewMessageFormatterService(cfg, logger),
+		AgentIDResolver:    workerhandlers.DefaultAgentIDResolver,
+	}
+	if err := deps.CheckFormatter(); err != nil {
+		t.Fatalf("formatter: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	consumers := services.NewConsumerManager(logger)
+	if err := consumers.AddConsumer(ctx, rabbitMQService, queue, 1, workerhandlers.CreateUserMessageHandler(deps)); err != nil {
+		t.Fatalf("start consumer: %v", err)
+	}
+
+	t.Cleanup(func() {
+		cancel()
+		_ = consumers.StopAll()
+		if channel, err := rabbitMQService.OpenChannel(); err == nil {
+			for _, name := range []string{queue, queue + "_dlq", cfg.RabbitMQ.UserQueue, cfg.RabbitMQ.UserQueue + "_dlq"} {
+				_, _ = channel.QueueDelete(name, false, false, false)
+			}
+			_ = channel.Close()
+		}
+		_ = rabbitMQService.Close()
+		_ = redisService.Close()
+	})
+
+	return &worker{redis: redisService, rabbitMQ: rabbitMQService, queue: queue, agent: agent}
+}
+
+// publish enqueues the message the way the gateway does: pending status first, then the queue
+func (w *worker) publish(t *testing.T, msg models.QueueMessage) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := w.redis.SetTaskStatus(ctx, msg.ID, string(models.TaskStatusPending), time.Hour); err != nil {
+		t.Fatalf("set pending status: %v", err)
+	}
+	if err := w.rabbitMQ.PublishMessage(ctx, w.queue, msg); err != nil {
+		t.Fatalf("publish message: %v", err)
+	}
+}
+
+// waitForStatus polls the task until it reaches a terminal status, returning it
+func (w *worker) waitForStatus(t *testing.T, taskID string) models.TaskStatus {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		status, err := w.redis.GetTaskStatus(context.Background(), taskID)
+		if err == nil && models.TaskStatus(status).IsTerminal() {
+			return models.TaskStatus(status)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("task %s did not finish in time", taskID)
+	return ""
+}
+
+func newQueueMessage(message string) models.QueueMessage {
+	return models.QueueMessage{
+		ID:         uuid.NewString(),
+		UserNumber: "5521999999999",
+		Message:    message,
+		Provider:   models.ProviderGoogleAgentEngine,
+		Metadata:   map[string]interface{}{"source": "integration"},
+	}
+}
+
+func TestWorkerStoresProcessedMessage(t *testing.T) {
+	w := startWorker(t, workerstest.NewFakeAgentService())
+	msg := newQueueMessage("Qual o horário de funcionamento da clínica?")
+
+	w.publish(t, msg)
+
+	if status := w.waitForStatus(t, msg.ID); status != models.TaskStatusCompleted {
+		t.Fatalf("status = %s, want %s", status, models.TaskStatusCompleted)
+	}
+
+	// The worker stores the processed data as a JSON-encoded string, as the polling endpoint reads it
+	var stored string
+	if err := w.redis.GetTaskResult(context.Background(), msg.ID, &stored); err != nil {
+		t.Fatalf("read result: %v", err)
+	}
+	var result models.ProcessedMessageData
+	if err := json.Unmarshal([]byte(stored), &result); err != nil {
+		t.Fatalf("decode result %q: %v", stored, err)
+	}
+	if result.MessageID != msg.ID {
+		t.Errorf("result message_id = %q, want %q", result.MessageID, msg.ID)
+	}
+	if result.AgentID != "user_"+msg.UserNumber {
+		t.Errorf("result agent_id = %q, want %q", result.AgentID, "user_"+msg.UserNumber)
+	}
+	if result.Metadata["source"] != "integration" {
+		t.Errorf("result metadata = %v, want the publisher's metadata", result.Metadata)
+	}
+	if messages, ok := result.Messages.([]interface{}); !ok || len(messages) == 0 {
+		t.Errorf("result messages = %v, want the agent's reply", result.Messages)
+	}
+
+	sent := w.agent.Sent()
+	if len(sent) != 1 || sent[0].Content != msg.Message {
+		t.Errorf("agent received %+v, want the user's message once", sent)
+	}
+}
+
+func TestWorkerFailsInvalidMessageWithoutRetry(t *testing.T) {
+	w := startWorker(t, workerstest.NewFakeAgentService())
+	msg := newQueueMessage("")
+
+	w.publish(t, msg)
+
+	if status := w.waitForStatus(t, msg.ID); status != models.TaskStatusFailed {
+		t.Fatalf("status = %s, want %s", status, models.TaskStatusFailed)
+	}
+	if _, err := w.redis.GetTaskError(context.Background(), msg.ID); err != nil {
+		t.Errorf("read task error: %v", err)
+	}
+	if sent := w.agent.Sent(); len(sent) != 0 {
+		t.Errorf("agent received %d messages, want none", len(sent))
+	}
+}
+
+func TestWorkerFailsTaskWhenAgentFails(t *testing.T) {
+	agent := workerstest.NewFakeAgentService()
+	agent.SendErr = errors.New("agent unreachable: connection refused")
+	w := startWorker(t, agent)
+	msg := newQueueMessage("Olá")
+
+	w.publish(t, msg)
+
+	// RABBITMQ_MAX_RETRIES=0, so the first retriable failure is final
+	if status := w.waitForStatus(t, msg.ID); status != models.TaskStatusFailed {
+		t.Fatalf("status = %s, want %s", status, models.TaskStatusFailed)
+	}
+	taskErr, err := w.redis.GetTaskError(context.Background(), msg.ID)
+	if err != nil {
+		t.Fatalf("read task error: %v", err)
+	}
+	if taskErr.Message == "" {
+		t.Error("stored task error has no message")
+	}
+	if sent := len(agent.Sent()); sent != 1 {
+		t.Errorf("agent called %d times, want %d", sent, 1)
+	}
+}